@@ -0,0 +1,142 @@
+package redisttl
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Reasons a key is flagged for.
+const (
+	FindingNoTTL       = "no-ttl"
+	FindingTTLTooLong  = "ttl-too-long"
+	FindingDeleted     = "deleted"
+	FindingOverwritten = "overwritten"
+)
+
+// AuditFinding is a key which doesn't comply with the TTL policy audited, or
+// whose TTL didn't stick after being written.
+type AuditFinding struct {
+	Key string
+	// TTL is the TTL the key had, -1 when it had none and -2 when it was
+	// deleted.
+	TTL    time.Duration
+	Reason string
+}
+
+// Audit collects the keys flagged in "audit" and "verify" modes, and by
+// Scanner.VerifyWrites. It is safe for concurrent use.
+type Audit struct {
+	// MaxFindings caps the number of findings retained, 0 keeping them all.
+	// Violations are counted regardless.
+	MaxFindings int
+
+	mu         sync.Mutex
+	checked    int64
+	violations int64
+	findings   []AuditFinding
+}
+
+// observe flags key when it has no TTL, or one greater than maxTTL when
+// maxTTL is greater than 0.
+func (a *Audit) observe(key string, ttl, maxTTL time.Duration) {
+	switch {
+	case ttl < 0:
+		a.record(key, ttl, FindingNoTTL)
+	case maxTTL > 0 && ttl > maxTTL:
+		a.record(key, ttl, FindingTTLTooLong)
+	default:
+		a.record(key, ttl, "")
+	}
+}
+
+// record counts a checked key, flagged for reason unless it is empty.
+func (a *Audit) record(key string, ttl time.Duration, reason string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.checked++
+	if reason == "" {
+		return
+	}
+	a.violations++
+	if a.MaxFindings == 0 || len(a.findings) < a.MaxFindings {
+		a.findings = append(a.findings, AuditFinding{Key: key, TTL: ttl, Reason: reason})
+	}
+}
+
+// Checked returns the number of keys audited.
+func (a *Audit) Checked() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.checked
+}
+
+// Violations returns the number of keys flagged.
+func (a *Audit) Violations() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.violations
+}
+
+// Findings returns the retained findings sorted by key.
+func (a *Audit) Findings() []AuditFinding {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	findings := append([]AuditFinding(nil), a.findings...)
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Key < findings[j].Key
+	})
+	return findings
+}
+
+// expectedTTL returns the TTL key should have once the mode applied to it,
+// desired being the TTL the key is set to.
+func (f *Scanner) expectedTTL(ctx context.Context, key string, desired time.Duration) (time.Duration, bool) {
+	var next func(cur time.Duration) (time.Duration, bool)
+	switch f.Mode {
+	case "exp", "gt", "lt", "nx", "xx":
+		return desired, true
+	case "persist":
+		return noTTL, true
+	case "percent":
+		next = f.percent
+	case "extend":
+		next = f.extend
+	case "shrink":
+		next = f.shrink
+	default:
+		return 0, false
+	}
+	cur, err := f.Client.PTTL(ctx, key).Result()
+	if err != nil || cur <= 0 {
+		return 0, false
+	}
+	ttl, _ := next(cur)
+	return max(ttl, time.Millisecond), true
+}
+
+// verifyWrite re-reads the TTL of key, written at start, and flags it when
+// it was deleted or its TTL changed since, e.g. by the application.
+func (f *Scanner) verifyWrite(ctx context.Context, key string, expected time.Duration, start time.Time) {
+	ttl, err := f.Client.PTTL(ctx, key).Result()
+	if err != nil {
+		return
+	}
+	// the TTL keeps decreasing after the write
+	slack := time.Since(start) + time.Second
+	switch {
+	case ttl == -2:
+		f.Audit.record(key, ttl, FindingDeleted)
+	case (expected == noTTL) != (ttl == noTTL):
+		f.Audit.record(key, ttl, FindingOverwritten)
+	case ttl > expected || ttl < expected-slack:
+		f.Audit.record(key, ttl, FindingOverwritten)
+	default:
+		f.Audit.record(key, ttl, "")
+	}
+}
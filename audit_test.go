@@ -0,0 +1,135 @@
+package redisttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestAudit(t *testing.T) {
+	a := &Audit{MaxFindings: 2}
+	a.observe("c", -1, 0)
+	a.observe("a", time.Hour, 0)
+	a.observe("b", 2*time.Hour, time.Hour)
+	a.observe("d", time.Minute, time.Hour)
+	a.observe("e", -1, time.Hour)
+
+	if a.Checked() != 5 || a.Violations() != 3 {
+		t.Fatalf("unexpected counts: checked=%d violations=%d", a.Checked(), a.Violations())
+	}
+	expected := []AuditFinding{
+		{Key: "b", TTL: 2 * time.Hour, Reason: FindingTTLTooLong},
+		{Key: "c", TTL: -1, Reason: FindingNoTTL},
+	}
+	findings := a.Findings()
+	if len(findings) != len(expected) {
+		t.Fatalf("want: %v got: %v", expected, findings)
+	}
+	for i := range expected {
+		if findings[i] != expected[i] {
+			t.Fatalf("want: %v got: %v", expected, findings)
+		}
+	}
+
+	// a nil audit ignores observations
+	var none *Audit
+	none.observe("a", -1, 0)
+}
+
+// racingHook simulates the application changing keys right after they are
+// expired.
+type racingHook struct {
+	s *miniredis.Miniredis
+}
+
+func (h racingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if cmd.Name() != "expire" {
+			return err
+		}
+		switch cmd.Args()[1] {
+		case "far":
+			h.s.Del("far")
+		case "fiz":
+			h.s.SetTTL("fiz", 5*time.Minute)
+		}
+		return err
+	}
+}
+
+func (h racingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h racingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestVerifyWrites(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo", "far", "fiz"} {
+		_ = s.Set(k, "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(racingHook{s: s})
+
+	audit := &Audit{}
+	f := Scanner{
+		Mode:         "exp",
+		ScanPrefix:   "f*",
+		Client:       rdb,
+		DesiredTTL:   time.Hour,
+		Audit:        audit,
+		VerifyWrites: true,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []AuditFinding{
+		{Key: "far", TTL: -2, Reason: FindingDeleted},
+		{Key: "fiz", TTL: 5 * time.Minute, Reason: FindingOverwritten},
+	}
+	findings := audit.Findings()
+	if audit.Checked() != 3 || len(findings) != len(expected) {
+		t.Fatalf("checked %d keys, want: %v got: %v", audit.Checked(), expected, findings)
+	}
+	for i := range expected {
+		if findings[i] != expected[i] {
+			t.Fatalf("want: %v got: %v", expected, findings)
+		}
+	}
+}
+
+func TestVerifyWritesAdjustedTTL(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	s.SetTTL("foo", time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	audit := &Audit{}
+	f := Scanner{
+		Mode:         "extend",
+		ScanPrefix:   "f*",
+		Client:       rdb,
+		TTLDelta:     time.Hour,
+		Audit:        audit,
+		VerifyWrites: true,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if audit.Checked() != 1 || audit.Violations() != 0 {
+		t.Fatalf("unexpected findings: %v", audit.Findings())
+	}
+}
@@ -0,0 +1,199 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// batchExpire applies exp, gt, lt, nx or xx mode, ARGV[1], to every key
+// with the TTL in milliseconds at the same position in ARGV after the mode
+// and the tolerance, ARGV[2]. It returns, for every key, 1 when its TTL was
+// set, 2 when it already was within the tolerance, and 0 otherwise.
+var batchExpire = redis.NewScript(`
+local mode = ARGV[1]
+local tolerance = tonumber(ARGV[2])
+local results = {}
+for i, key in ipairs(KEYS) do
+	local want = tonumber(ARGV[i + 2])
+	local ttl = redis.call('PTTL', key)
+	local apply = ttl ~= -2
+	if (mode == 'nx' and ttl ~= -1) or (mode == 'xx' and ttl == -1) then
+		apply = false
+	end
+	if (mode == 'gt' and (ttl == -1 or want <= ttl)) or (mode == 'lt' and ttl ~= -1 and want >= ttl) then
+		apply = false
+	end
+	if apply and tolerance > 0 and ttl >= 0 and math.abs(ttl - want) <= tolerance then
+		results[i] = 2
+	elseif apply then
+		results[i] = redis.call('PEXPIRE', key, want)
+	else
+		results[i] = 0
+	end
+end
+return results
+`)
+
+// batchModes are the modes BatchScript supports.
+var batchModes = map[string]bool{
+	"exp": true,
+	"gt":  true,
+	"lt":  true,
+	"nx":  true,
+	"xx":  true,
+}
+
+const (
+	scriptUntouched = 0
+	scriptModified  = 1
+	scriptTolerated = 2
+)
+
+// scriptBatch is a group of keys sent to batchExpire at once.
+type scriptBatch struct {
+	keys []string
+	ttls []time.Duration
+}
+
+// applyScript is applyBatch for BatchScript.
+func (f *Scanner) applyScript(ctx context.Context, r *scanRun, keys []string) error {
+	if err := f.loadTypes(ctx, r, keys); err != nil {
+		return err
+	}
+	var batch scriptBatch
+	for _, key := range keys {
+		desired, covered := f.desiredTTL(key, r.types[key])
+		if !covered || f.protected(key) || !f.sampled(key) {
+			r.observeKey(key, 0, false, nil)
+			continue
+		}
+		if err := f.throttle(ctx, r); err != nil {
+			return err
+		}
+		batch.keys = append(batch.keys, key)
+		batch.ttls = append(batch.ttls, desired)
+	}
+
+	for _, b := range f.slotBatches(batch) {
+		if err := f.runScript(ctx, r, b); err != nil {
+			return err
+		}
+		for i, key := range b.keys {
+			if err := f.applyCompanions(ctx, r, key, b.ttls[i]); err != nil {
+				return err
+			}
+			f.inspect(ctx, r, key)
+		}
+	}
+	return nil
+}
+
+// runScript sends b to batchExpire and records the outcome of every key.
+func (f *Scanner) runScript(ctx context.Context, r *scanRun, b scriptBatch) error {
+	r.live.key.Store(b.keys[0])
+	var tolerance int64
+	if f.tolerates() {
+		tolerance = f.Tolerance.Milliseconds()
+	}
+	args := []any{f.Mode, tolerance}
+	for _, ttl := range b.ttls {
+		// EXPIRE has a resolution of a second
+		args = append(args, ttl.Truncate(time.Second).Milliseconds())
+	}
+
+	start := time.Now()
+	results, err := batchExpire.Run(ctx, f.Client, b.keys, args...).Int64Slice()
+	elapsed := time.Since(start)
+	if err == nil && len(results) != len(b.keys) {
+		err = fmt.Errorf("batch script returned %d results for %d keys", len(results), len(b.keys))
+	}
+	if err != nil {
+		log.Printf("batch script error: %v\n", err)
+		for _, key := range b.keys {
+			r.observeKey(key, 0, false, err)
+		}
+		return f.failFast(strings.Join(b.keys, ","), err)
+	}
+
+	for i, key := range b.keys {
+		// the whole batch is a single command, timed once
+		d := time.Duration(0)
+		if i == 0 {
+			d = elapsed
+		}
+		r.observeKey(key, d, results[i] == scriptModified, nil)
+		switch results[i] {
+		case scriptModified:
+			f.Projection.observe(b.ttls[i])
+			f.indexExpiry(ctx, key, b.ttls[i])
+			f.streamChange(ctx, key, unknownTTL, b.ttls[i])
+			f.logModified(ctx, key, unknownTTL, b.ttls[i], d)
+		case scriptTolerated:
+			r.summary.observeTolerated()
+		}
+	}
+	return nil
+}
+
+// cluster reports whether Client is a cluster client or a node of one, see
+// Scanner.Cluster.
+func (f *Scanner) cluster() bool {
+	_, ok := f.Client.(*redis.ClusterClient)
+	return ok || f.Cluster
+}
+
+// slotBatches splits b by hash slot on a cluster, since a script may only
+// access keys of the same slot there.
+func (f *Scanner) slotBatches(b scriptBatch) []scriptBatch {
+	if len(b.keys) == 0 {
+		return nil
+	}
+	if !f.cluster() {
+		return []scriptBatch{b}
+	}
+	var batches []scriptBatch
+	slots := make(map[uint16]int)
+	for i, key := range b.keys {
+		slot := hashSlot(key)
+		n, found := slots[slot]
+		if !found {
+			n = len(batches)
+			slots[slot] = n
+			batches = append(batches, scriptBatch{})
+		}
+		batches[n].keys = append(batches[n].keys, key)
+		batches[n].ttls = append(batches[n].ttls, b.ttls[i])
+	}
+	return batches
+}
+
+// hashSlot returns the Redis Cluster hash slot of key, honoring hash tags.
+func hashSlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return crc16(key) % 16384
+}
+
+// crc16 is the CRC-16/XMODEM checksum Redis Cluster hashes keys with.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
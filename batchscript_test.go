@@ -0,0 +1,238 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBatchScript(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	testCases := map[string]struct {
+		mode      string
+		tolerance time.Duration
+		expected  map[string]time.Duration
+		modified  int64
+	}{
+		"exp": {
+			mode:     "exp",
+			expected: map[string]time.Duration{"none": time.Hour, "short": time.Hour, "long": time.Hour},
+			modified: 3,
+		},
+		"gt": {
+			mode:     "gt",
+			expected: map[string]time.Duration{"none": 0, "short": time.Hour, "long": 2 * time.Hour},
+			modified: 1,
+		},
+		"lt": {
+			mode:     "lt",
+			expected: map[string]time.Duration{"none": time.Hour, "short": time.Minute, "long": time.Hour},
+			modified: 2,
+		},
+		"nx": {
+			mode:     "nx",
+			expected: map[string]time.Duration{"none": time.Hour, "short": time.Minute, "long": 2 * time.Hour},
+			modified: 1,
+		},
+		"xx": {
+			mode:     "xx",
+			expected: map[string]time.Duration{"none": 0, "short": time.Hour, "long": time.Hour},
+			modified: 2,
+		},
+		"exp within tolerance": {
+			mode:      "exp",
+			tolerance: 90 * time.Minute,
+			expected:  map[string]time.Duration{"none": time.Hour, "short": time.Minute, "long": 2 * time.Hour},
+			modified:  1,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_ = s.Set("none", "bar")
+			_ = s.Set("short", "bar")
+			s.SetTTL("short", time.Minute)
+			_ = s.Set("long", "bar")
+			s.SetTTL("long", 2*time.Hour)
+
+			summary := &Summary{}
+			f := Scanner{
+				Mode:        tc.mode,
+				ScanPrefix:  "*",
+				Client:      rdb,
+				DesiredTTL:  time.Hour,
+				Tolerance:   tc.tolerance,
+				BatchScript: true,
+				Summary:     summary,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for k, dur := range tc.expected {
+				if ttl := s.TTL(k); ttl != dur {
+					t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+				}
+			}
+			if summary.Scanned.Load() != 3 || summary.Modified.Load() != tc.modified {
+				t.Fatalf("unexpected summary: %s", summary)
+			}
+		})
+	}
+}
+
+func TestBatchScriptUnsupportedMode(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	f := Scanner{
+		Mode:        "persist",
+		ScanPrefix:  "*",
+		Client:      redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		BatchScript: true,
+	}
+	if err := f.Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	f.Mode, f.DesiredTTL, f.VerifyWrites = "exp", time.Hour, true
+	if err := f.Run(context.Background()); !errors.Is(err, errInvalidMode) {
+		t.Fatalf("want errInvalidMode verifying writes, got %v", err)
+	}
+}
+
+func TestHashSlot(t *testing.T) {
+	testCases := map[string]uint16{
+		"foo":                  12182,
+		"123456789":            12739,
+		"{user1000}.following": hashSlot("user1000"),
+		"{user1000}.followers": hashSlot("user1000"),
+		"foo{{bar}}zap":        hashSlot("{bar"),
+	}
+	for key, want := range testCases {
+		if got := hashSlot(key); got != want {
+			t.Fatalf("want slot %d for %s, got %d", want, key, got)
+		}
+	}
+}
+
+func TestSlotBatches(t *testing.T) {
+	b := scriptBatch{
+		keys: []string{"{a}:1", "{b}:1", "{a}:2"},
+		ttls: []time.Duration{time.Second, time.Minute, time.Hour},
+	}
+
+	f := Scanner{Client: redis.NewClient(&redis.Options{})}
+	if batches := f.slotBatches(b); len(batches) != 1 {
+		t.Fatalf("want a single batch outside a cluster, got %v", batches)
+	}
+
+	f.Cluster = true
+	batches := f.slotBatches(b)
+	expected := []scriptBatch{
+		{keys: []string{"{a}:1", "{a}:2"}, ttls: []time.Duration{time.Second, time.Hour}},
+		{keys: []string{"{b}:1"}, ttls: []time.Duration{time.Minute}},
+	}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Fatalf("want %v, got %v", expected, batches)
+	}
+}
+
+// crossSlotHook rejects the scripts and transactions accessing keys of
+// several hash slots the way a cluster node does.
+type crossSlotHook struct{}
+
+var errCrossSlot = readOnlyError("CROSSSLOT Keys in request don't hash to the same slot")
+
+func sameSlot(keys []string) bool {
+	for _, key := range keys {
+		if hashSlot(key) != hashSlot(keys[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (crossSlotHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (crossSlotHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		if name := cmd.Name(); name != "eval" && name != "evalsha" {
+			return next(ctx, cmd)
+		}
+		n, _ := args[2].(int)
+		var keys []string
+		for _, arg := range args[3 : 3+n] {
+			keys = append(keys, arg.(string))
+		}
+		if !sameSlot(keys) {
+			cmd.SetErr(errCrossSlot)
+			return errCrossSlot
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (crossSlotHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(cmds) == 0 || cmds[0].Name() != "multi" {
+			return next(ctx, cmds)
+		}
+		var keys []string
+		for _, cmd := range cmds[1 : len(cmds)-1] {
+			keys = append(keys, cmd.Args()[1].(string))
+		}
+		if !sameSlot(keys) {
+			for _, cmd := range cmds {
+				cmd.SetErr(errCrossSlot)
+			}
+			return errCrossSlot
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestBatchScriptCluster(t *testing.T) {
+	s := miniredis.RunT(t)
+	// the client of a single node, as when scanning one primary of a cluster
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(crossSlotHook{})
+	keys := []string{"{a}:1", "{b}:1", "{a}:2", "{c}:1"}
+	for _, key := range keys {
+		_ = s.Set(key, "bar")
+	}
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:        "exp",
+		ScanPrefix:  "*",
+		Client:      rdb,
+		DesiredTTL:  time.Hour,
+		BatchScript: true,
+		Cluster:     true,
+		FailFast:    true,
+		Summary:     summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, key := range keys {
+		if ttl := s.TTL(key); ttl != time.Hour {
+			t.Fatalf("want %s expiring in an hour, got %v", key, ttl)
+		}
+	}
+	if summary.Modified.Load() != int64(len(keys)) || summary.Errors.Load() != 0 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
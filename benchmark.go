@@ -0,0 +1,265 @@
+package redisttl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bottlenecks reported by a Benchmark.
+const (
+	// BottleneckLimiter means the configured rate is below what the target
+	// sustains.
+	BottleneckLimiter = "limiter"
+	// BottleneckRTT means most of the time is spent waiting on the network.
+	BottleneckRTT = "rtt"
+	// BottleneckServerCPU means the server main thread is close to saturation.
+	BottleneckServerCPU = "server-cpu"
+	// BottleneckClient means none of the above, the tool itself is the limit.
+	BottleneckClient = "client"
+)
+
+const (
+	benchPrefix    = "redis-ttl-bench:"
+	benchBatchSize = 1000
+	benchPings     = 20
+	// benchTTL is set on the synthetic keys, which are deleted afterwards.
+	benchTTL = time.Hour
+)
+
+// Benchmark measures the highest scan+expire rate a node sustains, without
+// any rate limiting, to help pick a realistic rate limit.
+type Benchmark struct {
+	Client     redis.Cmdable
+	ScanClient redis.Cmdable
+	// Node labels the result, typically the address of the scanned node.
+	Node string
+	// ScanPrefix selects the existing keys measured when Keys is 0. Those
+	// keys are only read, with PTTL, which costs the server about as much as
+	// an EXPIRE does.
+	ScanPrefix string
+	ScanCount  int64
+	// Keys is the number of synthetic keys created, expired then deleted.
+	Keys int
+	// Duration bounds the measurement.
+	Duration time.Duration
+	// RPS is the configured rate limit the measured rate is compared with.
+	RPS float64
+	// Results collects the outcome of the run.
+	Results *BenchmarkResults
+}
+
+// BenchmarkResult is the outcome of a Benchmark run against a node.
+type BenchmarkResult struct {
+	Node    string
+	Keys    int64
+	Elapsed time.Duration
+	// RTT is the median PING round trip time.
+	RTT time.Duration
+	// ServerCPU is the server CPU time used per second of measurement.
+	ServerCPU  float64
+	RPS        float64
+	Bottleneck string
+}
+
+// Rate returns the measured keys per second.
+func (r BenchmarkResult) Rate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Keys) / r.Elapsed.Seconds()
+}
+
+func (r BenchmarkResult) bottleneck() string {
+	// every key costs a round trip, so an RTT bound run spends most of its
+	// time waiting on the network
+	waiting := float64(r.Keys) * r.RTT.Seconds()
+	switch {
+	case r.RPS > 0 && r.RPS < r.Rate():
+		return BottleneckLimiter
+	case r.ServerCPU >= 0.8:
+		return BottleneckServerCPU
+	case waiting >= 0.7*r.Elapsed.Seconds():
+		return BottleneckRTT
+	default:
+		return BottleneckClient
+	}
+}
+
+// BenchmarkResults collects the results of the benchmarks of a run. It is
+// safe for concurrent use.
+type BenchmarkResults struct {
+	mu      sync.Mutex
+	results []BenchmarkResult
+}
+
+func (b *BenchmarkResults) add(r BenchmarkResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results = append(b.results, r)
+}
+
+// All returns the results collected so far.
+func (b *BenchmarkResults) All() []BenchmarkResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]BenchmarkResult(nil), b.results...)
+}
+
+func (b *Benchmark) Run(ctx context.Context) error {
+	rtt, err := medianRTT(ctx, b.scanClient())
+	if err != nil {
+		return fmt.Errorf("benchmark rtt: %w", err)
+	}
+
+	pattern, op := b.ScanPrefix, b.readTTL
+	if b.Keys > 0 {
+		id := strconv.FormatInt(time.Now().UnixNano(), 36)
+		defer b.cleanup(ctx, id)
+		if err := b.seed(ctx, id); err != nil {
+			return fmt.Errorf("benchmark seed: %w", err)
+		}
+		pattern, op = benchPrefix+id+":*", b.expire
+	}
+
+	cpuBefore, err := readServerCPU(ctx, b.scanClient())
+	if err != nil {
+		return fmt.Errorf("benchmark cpu: %w", err)
+	}
+	start := time.Now()
+	n, err := b.measure(ctx, pattern, op)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	cpuAfter, err := readServerCPU(ctx, b.scanClient())
+	if err != nil {
+		return fmt.Errorf("benchmark cpu: %w", err)
+	}
+
+	r := BenchmarkResult{
+		Node:      b.Node,
+		Keys:      n,
+		Elapsed:   elapsed,
+		RTT:       rtt,
+		ServerCPU: (cpuAfter - cpuBefore) / elapsed.Seconds(),
+		RPS:       b.RPS,
+	}
+	r.Bottleneck = r.bottleneck()
+	b.Results.add(r)
+	return nil
+}
+
+// measure scans and processes keys matching pattern as fast as possible,
+// until the keyspace is exhausted or Duration elapses.
+func (b *Benchmark) measure(ctx context.Context, pattern string, op func(context.Context, string) error) (int64, error) {
+	deadline := time.Now().Add(b.Duration)
+	var n int64
+	var cursor uint64
+	for {
+		keys, next, err := b.scanClient().Scan(ctx, cursor, pattern, b.ScanCount).Result()
+		if err != nil {
+			return n, fmt.Errorf("iter error: %w", err)
+		}
+		for _, key := range keys {
+			if err := op(ctx, key); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if next == 0 || (b.Duration > 0 && time.Now().After(deadline)) {
+			return n, nil
+		}
+		cursor = next
+	}
+}
+
+func (b *Benchmark) readTTL(ctx context.Context, key string) error {
+	return b.Client.PTTL(ctx, key).Err()
+}
+
+func (b *Benchmark) expire(ctx context.Context, key string) error {
+	return b.Client.Expire(ctx, key, benchTTL).Err()
+}
+
+func (b *Benchmark) seed(ctx context.Context, id string) error {
+	return b.batches(ctx, id, func(p redis.Pipeliner, key string) {
+		p.Set(ctx, key, "x", 0)
+	})
+}
+
+// cleanup deletes the synthetic keys, even when the run was canceled.
+func (b *Benchmark) cleanup(ctx context.Context, id string) {
+	ctx = context.WithoutCancel(ctx)
+	_ = b.batches(ctx, id, func(p redis.Pipeliner, key string) {
+		p.Del(ctx, key)
+	})
+}
+
+func (b *Benchmark) batches(ctx context.Context, id string, fn func(p redis.Pipeliner, key string)) error {
+	for i := 0; i < b.Keys; i += benchBatchSize {
+		_, err := b.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for j := i; j < min(i+benchBatchSize, b.Keys); j++ {
+				fn(p, benchPrefix+id+":"+strconv.Itoa(j))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Benchmark) scanClient() redis.Cmdable {
+	if b.ScanClient != nil {
+		return b.ScanClient
+	}
+	return b.Client
+}
+
+func medianRTT(ctx context.Context, c redis.Cmdable) (time.Duration, error) {
+	rtts := make([]time.Duration, benchPings)
+	for i := range rtts {
+		start := time.Now()
+		if err := c.Ping(ctx).Err(); err != nil {
+			return 0, err
+		}
+		rtts[i] = time.Since(start)
+	}
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	return rtts[len(rtts)/2], nil
+}
+
+// readServerCPU returns the CPU seconds the server consumed since it started.
+func readServerCPU(ctx context.Context, c redis.Cmdable) (float64, error) {
+	raw, err := c.Info(ctx, "cpu").Result()
+	if err != nil {
+		return 0, err
+	}
+	return parseCPUInfo(raw)
+}
+
+func parseCPUInfo(raw string) (float64, error) {
+	var total float64
+	sc := bufio.NewScanner(strings.NewReader(raw))
+	for sc.Scan() {
+		name, value, found := strings.Cut(strings.TrimSpace(sc.Text()), ":")
+		if !found || (name != "used_cpu_sys" && name != "used_cpu_user") {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s value %q: %w", name, value, err)
+		}
+		total += f
+	}
+	return total, sc.Err()
+}
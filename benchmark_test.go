@@ -0,0 +1,123 @@
+package redisttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBenchmarkSyntheticKeys(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(&infoHook{replies: []string{"used_cpu_sys:1.5\r\nused_cpu_user:0.5\r\n"}})
+
+	results := &BenchmarkResults{}
+	b := Benchmark{
+		Client:  rdb,
+		Node:    s.Addr(),
+		Keys:    2500,
+		Results: results,
+	}
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	all := results.All()
+	if len(all) != 1 {
+		t.Fatalf("expected 1 result, got: %d", len(all))
+	}
+	r := all[0]
+	if r.Keys != 2500 || r.Node != s.Addr() || r.Rate() <= 0 || r.Bottleneck == "" {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+
+	// synthetic keys are deleted, existing ones untouched
+	if keys := s.Keys(); len(keys) != 1 || keys[0] != "foo" {
+		t.Fatalf("expected only foo to be left, got: %v", keys)
+	}
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("existing keys should not be modified, got ttl: %v", ttl)
+	}
+}
+
+func TestBenchmarkExistingKeysReadOnly(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	_ = s.Set("zoo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(&infoHook{replies: []string{"used_cpu_sys:1\r\nused_cpu_user:1\r\n"}})
+
+	results := &BenchmarkResults{}
+	b := Benchmark{
+		Client:     rdb,
+		ScanPrefix: "f*",
+		Results:    results,
+	}
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := results.All()[0]; r.Keys != 2 {
+		t.Fatalf("expected 2 keys, got: %d", r.Keys)
+	}
+	for _, k := range []string{"foo", "far"} {
+		if ttl := s.TTL(k); ttl != 0 {
+			t.Fatalf("existing keys should not be modified, got ttl: %v for %s", ttl, k)
+		}
+	}
+}
+
+func TestBenchmarkBottleneck(t *testing.T) {
+	testCases := map[string]struct {
+		r        BenchmarkResult
+		expected string
+	}{
+		"rate limit below sustained rate": {
+			r:        BenchmarkResult{Keys: 1000, Elapsed: time.Second, RPS: 100},
+			expected: BottleneckLimiter,
+		},
+		"busy server": {
+			r:        BenchmarkResult{Keys: 1000, Elapsed: time.Second, ServerCPU: 0.95},
+			expected: BottleneckServerCPU,
+		},
+		"round trips dominate": {
+			r:        BenchmarkResult{Keys: 1000, Elapsed: time.Second, RTT: 900 * time.Microsecond},
+			expected: BottleneckRTT,
+		},
+		"neither": {
+			r:        BenchmarkResult{Keys: 1000, Elapsed: time.Second, RTT: 100 * time.Microsecond, RPS: 5000},
+			expected: BottleneckClient,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.r.bottleneck(); got != tc.expected {
+				t.Fatalf("want: %s got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	cpu, err := parseCPUInfo("# CPU\r\nused_cpu_sys:1.25\r\nused_cpu_user:2.5\r\nused_cpu_sys_children:9\r\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cpu != 3.75 {
+		t.Fatalf("want: 3.75 got: %v", cpu)
+	}
+	if _, err := parseCPUInfo("used_cpu_sys:lots\r\n"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
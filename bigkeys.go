@@ -0,0 +1,110 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BigKey is a key exceeding one of the BigKeyDetector thresholds.
+type BigKey struct {
+	Key      string
+	Type     string
+	Bytes    int64
+	Elements int64
+}
+
+// BigKeyDetector flags the scanned keys whose memory usage or element count
+// exceeds a threshold. It is safe for concurrent use.
+type BigKeyDetector struct {
+	// MaxBytes is the MEMORY USAGE threshold, 0 disables the check.
+	MaxBytes int64
+	// MaxElements is the element count threshold for lists, sets, sorted
+	// sets, hashes and streams, 0 disables the check.
+	MaxElements int64
+
+	mu   sync.Mutex
+	keys []BigKey
+}
+
+// Keys returns the flagged keys, largest first.
+func (d *BigKeyDetector) Keys() []BigKey {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keys := append([]BigKey(nil), d.keys...)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Bytes != keys[j].Bytes {
+			return keys[i].Bytes > keys[j].Bytes
+		}
+		return keys[i].Elements > keys[j].Elements
+	})
+	return keys
+}
+
+// inspect measures key and records it when it exceeds a threshold.
+// keyType may be empty when the scan isn't restricted to a type.
+func (d *BigKeyDetector) inspect(ctx context.Context, c redis.Cmdable, key, keyType string) error {
+	bk := BigKey{Key: key, Type: keyType}
+
+	if d.MaxBytes > 0 {
+		n, err := c.MemoryUsage(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			// the key expired since it was scanned
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("memory usage: %w", err)
+		}
+		bk.Bytes = n
+	}
+
+	if d.MaxElements > 0 {
+		if bk.Type == "" {
+			t, err := c.Type(ctx, key).Result()
+			if err != nil {
+				return fmt.Errorf("type: %w", err)
+			}
+			bk.Type = t
+		}
+		n, err := elementCount(ctx, c, key, bk.Type)
+		if err != nil {
+			return err
+		}
+		bk.Elements = n
+	}
+
+	if (d.MaxBytes > 0 && bk.Bytes > d.MaxBytes) || (d.MaxElements > 0 && bk.Elements > d.MaxElements) {
+		d.mu.Lock()
+		d.keys = append(d.keys, bk)
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+func elementCount(ctx context.Context, c redis.Cmdable, key, keyType string) (int64, error) {
+	var cmd *redis.IntCmd
+	switch keyType {
+	case "list":
+		cmd = c.LLen(ctx, key)
+	case "set":
+		cmd = c.SCard(ctx, key)
+	case "zset":
+		cmd = c.ZCard(ctx, key)
+	case "hash":
+		cmd = c.HLen(ctx, key)
+	case "stream":
+		cmd = c.XLen(ctx, key)
+	default:
+		// strings and other types have no elements
+		return 0, nil
+	}
+	n, err := cmd.Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", cmd.Name(), err)
+	}
+	return n, nil
+}
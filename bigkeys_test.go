@@ -0,0 +1,84 @@
+package redisttl
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryUsageHook upper cases the MEMORY subcommand, miniredis rejects the
+// lower case one go-redis sends.
+type memoryUsageHook struct{}
+
+func (memoryUsageHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "memory" {
+			cmd.Args()[1] = "USAGE"
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (memoryUsageHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (memoryUsageHook) DialHook(hook redis.DialHook) redis.DialHook {
+	return hook
+}
+
+func TestBigKeyDetector(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("small", "v")
+	_ = s.Set("large", strings.Repeat("v", 4096))
+	_, _ = s.SetAdd("members", "a", "b", "c")
+	_, _ = s.SetAdd("few", "a")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(memoryUsageHook{})
+
+	d := &BigKeyDetector{MaxBytes: 1024, MaxElements: 2}
+	f := Scanner{
+		Mode:       "noop",
+		ScanPrefix: "*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		BigKeys:    d,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys := d.Keys()
+	if len(keys) != 2 {
+		t.Fatalf("want 2 big keys, got: %+v", keys)
+	}
+	if keys[0].Key != "large" || keys[0].Type != "string" || keys[0].Bytes <= 1024 {
+		t.Fatalf("unexpected first big key: %+v", keys[0])
+	}
+	if keys[1].Key != "members" || keys[1].Type != "set" || keys[1].Elements != 3 {
+		t.Fatalf("unexpected second big key: %+v", keys[1])
+	}
+}
+
+func TestBigKeyDetectorMissingKey(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(memoryUsageHook{})
+
+	d := &BigKeyDetector{MaxBytes: 1}
+	if err := d.inspect(context.Background(), rdb, "gone", ""); err != nil {
+		t.Fatalf("a key expiring mid-scan should be ignored, got: %v", err)
+	}
+	if len(d.Keys()) != 0 {
+		t.Fatalf("expected no big keys, got: %+v", d.Keys())
+	}
+}
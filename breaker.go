@@ -0,0 +1,102 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// CircuitBreaker pauses a run while the node fails too many of the commands
+// sent for its keys, rather than hammering a struggling shard. Once open, it
+// waits for Pause, then half-opens and sends Probes PINGs, resuming the run
+// when all of them succeed and pausing again otherwise.
+type CircuitBreaker struct {
+	// Threshold is the ratio of failed keys among the last Window ones at
+	// which the breaker opens.
+	Threshold float64
+	// Window is the number of latest keys the error rate is computed over,
+	// the breaker never opening before that many were processed.
+	Window int
+	// Pause is how long the breaker stays open before probing the node.
+	Pause time.Duration
+	// Probes is the number of PINGs which must succeed to close the breaker,
+	// 1 when 0.
+	Probes int
+}
+
+// breakerState is the outcome of the latest keys of a run, see
+// CircuitBreaker.
+type breakerState struct {
+	failed []bool
+	next   int
+	filled bool
+	errors int
+}
+
+func newBreakerState(b *CircuitBreaker) *breakerState {
+	if b == nil || b.Window <= 0 {
+		return nil
+	}
+	return &breakerState{failed: make([]bool, b.Window)}
+}
+
+func (s *breakerState) observe(err error) {
+	if s == nil {
+		return
+	}
+	if s.failed[s.next] {
+		s.errors--
+	}
+	s.failed[s.next] = err != nil
+	if err != nil {
+		s.errors++
+	}
+	s.next = (s.next + 1) % len(s.failed)
+	s.filled = s.filled || s.next == 0
+}
+
+func (s *breakerState) reset() {
+	clear(s.failed)
+	s.next, s.filled, s.errors = 0, false, 0
+}
+
+// open reports whether the error rate of the latest keys crossed threshold.
+func (s *breakerState) open(threshold float64) bool {
+	return s != nil && s.filled && float64(s.errors)/float64(len(s.failed)) >= threshold
+}
+
+// checkBreaker blocks while the CircuitBreaker is open, until the node
+// answers the probes.
+func (f *Scanner) checkBreaker(ctx context.Context, r *scanRun) error {
+	b := f.CircuitBreaker
+	if b == nil || !r.breaker.open(b.Threshold) {
+		return nil
+	}
+	r.summary.observeBreakerTrip()
+	log.Printf("circuit breaker: %d of the last %d keys failed, pausing for %s\n", r.breaker.errors, b.Window, b.Pause)
+	for {
+		if err := f.clock().Sleep(ctx, b.Pause); err != nil {
+			return err
+		}
+		if err := f.probe(ctx, max(b.Probes, 1)); err != nil {
+			log.Printf("circuit breaker: probe failed, pausing for %s: %v\n", b.Pause, err)
+			continue
+		}
+		log.Println("circuit breaker: probes succeeded, resuming")
+		r.breaker.reset()
+		return nil
+	}
+}
+
+// probe sends n PINGs to the node, returning the first failure.
+func (f *Scanner) probe(ctx context.Context, n int) error {
+	for range n {
+		if err := f.wait(ctx); err != nil {
+			return err
+		}
+		if err := f.Client.Ping(ctx).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
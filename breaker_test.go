@@ -0,0 +1,104 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBreakerState(t *testing.T) {
+	errFailed := errors.New("failed")
+	testCases := map[string]struct {
+		outcomes []error
+		open     bool
+	}{
+		"window not filled": {
+			outcomes: []error{errFailed, errFailed},
+		},
+		"below threshold": {
+			outcomes: []error{errFailed, nil, nil, nil},
+		},
+		"at threshold": {
+			outcomes: []error{errFailed, nil, errFailed, nil},
+			open:     true,
+		},
+		"older failures slide out": {
+			outcomes: []error{errFailed, errFailed, errFailed, nil, nil, nil, nil},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := newBreakerState(&CircuitBreaker{Window: 4})
+			for _, err := range tc.outcomes {
+				s.observe(err)
+			}
+			if s.open(0.5) != tc.open {
+				t.Fatalf("want open %v, got %d errors in %v", tc.open, s.errors, s.failed)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := range 4 {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(&hook{cmdName: "expire", err: errors.New("loading")})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Summary:    summary,
+		CircuitBreaker: &CircuitBreaker{
+			Threshold: 0.5,
+			Window:    2,
+			Pause:     time.Millisecond,
+		},
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the window is emptied when the breaker closes, and the last two keys
+	// fill it again with no key left to trip it
+	if n := summary.BreakerTrips.Load(); n != 1 {
+		t.Fatalf("want the breaker tripped once, got %d: %s", n, summary)
+	}
+}
+
+func TestCircuitBreakerCanceled(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:2", "bar")
+	_ = s.Set("foo:3", "bar")
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(&hook{cmdName: "expire", err: errors.New("loading")})
+	rdb.AddHook(&hook{cmdName: "ping", err: errors.New("loading")})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		CircuitBreaker: &CircuitBreaker{
+			Threshold: 1,
+			Window:    1,
+			Pause:     time.Millisecond,
+		},
+	}
+	// the probes keep failing, the breaker never closes
+	if err := f.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want the run to time out, got %v", err)
+	}
+}
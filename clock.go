@@ -0,0 +1,80 @@
+package redisttl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it to pass. A Scanner reads it for its
+// jitter, Window, KillSwitch and MemoryGuard pauses, progress intervals and
+// the expiry times it computes, so a fake one runs it deterministically,
+// without sleeping real time.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for d, or until ctx is done, returning its error.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock is the Clock of the system, the default one.
+type SystemClock struct{}
+
+// Now implements Clock.
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Sleep implements Clock.
+func (SystemClock) Sleep(ctx context.Context, d time.Duration) error { return sleep(ctx, d) }
+
+// FakeClock is a Clock whose time only moves when it sleeps or is advanced.
+// It is safe for concurrent use.
+type FakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+// NewFakeClock returns a FakeClock telling now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d right away, unless ctx is done.
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.slept += d
+	return nil
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Slept returns how long the clock slept in total.
+func (c *FakeClock) Slept() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slept
+}
+
+// clock returns Clock, the SystemClock when unset.
+func (f *Scanner) clock() Clock {
+	if f.Clock != nil {
+		return f.Clock
+	}
+	return SystemClock{}
+}
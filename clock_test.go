@@ -0,0 +1,85 @@
+package redisttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestFakeClock(t *testing.T) {
+	noon := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		scanner func(f *Scanner)
+		slept   time.Duration
+		maxWait time.Duration
+	}{
+		"waits for the window": {
+			scanner: func(f *Scanner) {
+				f.Window = &Window{Start: 13 * time.Hour, End: 14 * time.Hour, Location: time.UTC}
+			},
+			slept: time.Hour,
+		},
+		"within the window": {
+			scanner: func(f *Scanner) {
+				f.Window = &Window{Start: 11 * time.Hour, End: 14 * time.Hour, Location: time.UTC}
+			},
+		},
+		"chunk pauses": {
+			scanner: func(f *Scanner) {
+				f.ChunkSize, f.ChunkPause = 1, time.Minute
+			},
+			slept: 3 * time.Minute,
+		},
+		"jitter": {
+			scanner: func(f *Scanner) {
+				f.Jitter = time.Second
+			},
+			maxWait: 3 * time.Second,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+				_ = s.Set(key, "bar")
+			}
+			clock := NewFakeClock(noon)
+			f := Scanner{
+				Mode:        "exp",
+				ScanPrefix:  "foo:*",
+				Client:      redis.NewClient(&redis.Options{Addr: s.Addr()}),
+				DesiredTTL:  time.Hour,
+				Clock:       clock,
+				ExpiryIndex: "expiries",
+			}
+			tc.scanner(&f)
+			start := time.Now()
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("want no real time slept, took %v", elapsed)
+			}
+			slept := clock.Slept()
+			if tc.maxWait > 0 {
+				if slept > tc.maxWait {
+					t.Fatalf("want at most %v slept, got %v", tc.maxWait, slept)
+				}
+			} else if slept != tc.slept {
+				t.Fatalf("want %v slept, got %v", tc.slept, slept)
+			}
+
+			// the expiry times are computed on the clock as well
+			score, err := s.ZScore("expiries", "foo:1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at := time.UnixMilli(int64(score)); at.Before(noon.Add(time.Hour)) || at.After(clock.Now().Add(time.Hour)) {
+				t.Fatalf("want foo:1 expiring an hour from the clock, got %v", at)
+			}
+		})
+	}
+}
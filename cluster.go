@@ -0,0 +1,315 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Node roles reported in a ClusterTopology.
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// SlotRange is an inclusive range of hash slots.
+type SlotRange struct {
+	Start int
+	End   int
+}
+
+// ClusterNode describes a single node of a Redis cluster.
+type ClusterNode struct {
+	ID       string
+	Addr     string
+	Hostname string
+	Role     string
+	// PrimaryID is the ID of the primary a replica replicates from.
+	PrimaryID string
+	Slots     []SlotRange
+	Healthy   bool
+}
+
+// DialAddr returns the address to dial n at: Addr, or, with preferHostname,
+// its announced hostname with the port of Addr, since the TLS certificates
+// of managed clusters are issued for the hostnames rather than the IPs.
+// Nodes announcing no hostname are dialed at Addr.
+func (n ClusterNode) DialAddr(preferHostname bool) string {
+	if !preferHostname || n.Hostname == "" {
+		return n.Addr
+	}
+	_, port, err := net.SplitHostPort(n.Addr)
+	if err != nil {
+		return n.Addr
+	}
+	return net.JoinHostPort(n.Hostname, port)
+}
+
+// ClusterTopology is a snapshot of the layout of a Redis cluster.
+type ClusterTopology struct {
+	Nodes []ClusterNode
+}
+
+// Primaries returns the healthy primaries of the cluster.
+func (t ClusterTopology) Primaries() []ClusterNode {
+	var primaries []ClusterNode
+	for _, n := range t.Nodes {
+		if n.Role == RolePrimary && n.Healthy {
+			primaries = append(primaries, n)
+		}
+	}
+	return primaries
+}
+
+// ClusterSlots is the number of hash slots of a Redis cluster.
+const ClusterSlots = 16384
+
+// UncoveredSlots returns the ranges of slots served by no healthy primary,
+// whose keys scanning every primary would miss. It is empty when the
+// primaries cover the whole cluster.
+func (t ClusterTopology) UncoveredSlots() []SlotRange {
+	covered := make([]bool, ClusterSlots)
+	for _, n := range t.Primaries() {
+		for _, r := range n.Slots {
+			for slot := max(r.Start, 0); slot <= min(r.End, ClusterSlots-1); slot++ {
+				covered[slot] = true
+			}
+		}
+	}
+	var uncovered []SlotRange
+	for slot := 0; slot < ClusterSlots; slot++ {
+		if covered[slot] {
+			continue
+		}
+		if n := len(uncovered); n > 0 && uncovered[n-1].End == slot-1 {
+			uncovered[n-1].End = slot
+		} else {
+			uncovered = append(uncovered, SlotRange{Start: slot, End: slot})
+		}
+	}
+	return uncovered
+}
+
+// Replicas returns the replicas of the primary with the given ID.
+func (t ClusterTopology) Replicas(primaryID string) []ClusterNode {
+	var replicas []ClusterNode
+	for _, n := range t.Nodes {
+		if n.Role == RoleReplica && n.PrimaryID == primaryID {
+			replicas = append(replicas, n)
+		}
+	}
+	return replicas
+}
+
+// SlotPrimary returns the healthy primary serving slot, false when none
+// does.
+func (t ClusterTopology) SlotPrimary(slot int) (ClusterNode, bool) {
+	for _, n := range t.Primaries() {
+		for _, r := range n.Slots {
+			if slot >= r.Start && slot <= r.End {
+				return n, true
+			}
+		}
+	}
+	return ClusterNode{}, false
+}
+
+// ParseClusterNodes parses the output of CLUSTER NODES.
+func ParseClusterNodes(nodes string) (ClusterTopology, error) {
+	t := ClusterTopology{}
+	for _, line := range strings.Split(nodes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		n, err := parseClusterNodesLine(fields)
+		if err != nil {
+			return ClusterTopology{}, err
+		}
+		t.Nodes = append(t.Nodes, n)
+	}
+	return t, nil
+}
+
+// parseClusterNodesLine parses a line of the form:
+// <id> <ip:port@cport[,hostname]> <flags> <primary> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+func parseClusterNodesLine(fields []string) (ClusterNode, error) {
+	addr, hostname, _ := strings.Cut(fields[1], ",")
+	addr, _, _ = strings.Cut(addr, "@")
+	addr = nodeAddr(addr)
+
+	flags := strings.Split(fields[2], ",")
+	n := ClusterNode{
+		ID:       fields[0],
+		Addr:     addr,
+		Hostname: hostname,
+		Role:     RoleReplica,
+		Healthy: !hasFlag(flags, "fail") && !hasFlag(flags, "noaddr") &&
+			fields[7] == "connected" && addr != "",
+	}
+	if hasFlag(flags, "master") {
+		n.Role = RolePrimary
+	} else if fields[3] != "-" {
+		n.PrimaryID = fields[3]
+	}
+
+	for _, s := range fields[8:] {
+		if strings.HasPrefix(s, "[") {
+			// slot being imported or migrated
+			continue
+		}
+		r, err := parseSlotRange(s)
+		if err != nil {
+			return ClusterNode{}, fmt.Errorf("node %s: %w", n.ID, err)
+		}
+		n.Slots = append(n.Slots, r)
+	}
+	return n, nil
+}
+
+// nodeAddr returns the address to dial a node announced as ip:port at,
+// empty for a node without address (":0"). IPv6 addresses are announced
+// without brackets, e.g. "2001:db8::1:6379", the port being after the last
+// colon, and are returned bracketed, as net.Dial expects them.
+func nodeAddr(s string) string {
+	if strings.HasPrefix(s, "[") {
+		// already bracketed
+		host, port, err := net.SplitHostPort(s)
+		if err != nil || host == "" {
+			return ""
+		}
+		return net.JoinHostPort(host, port)
+	}
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 || i == len(s)-1 {
+		return ""
+	}
+	host, port := s[:i], s[i+1:]
+	if p, err := strconv.Atoi(port); err != nil || p == 0 {
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func parseSlotRange(s string) (SlotRange, error) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		end = start
+	}
+	a, err := strconv.Atoi(start)
+	if err != nil {
+		return SlotRange{}, fmt.Errorf("invalid slot range %q: %w", s, err)
+	}
+	b, err := strconv.Atoi(end)
+	if err != nil {
+		return SlotRange{}, fmt.Errorf("invalid slot range %q: %w", s, err)
+	}
+	return SlotRange{Start: a, End: b}, nil
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// TopologyFromClusterShards builds a ClusterTopology from the output of
+// CLUSTER SHARDS.
+func TopologyFromClusterShards(shards []redis.ClusterShard) ClusterTopology {
+	t := ClusterTopology{}
+	for _, shard := range shards {
+		slots := make([]SlotRange, 0, len(shard.Slots))
+		for _, r := range shard.Slots {
+			slots = append(slots, SlotRange{Start: int(r.Start), End: int(r.End)})
+		}
+
+		primaryID := ""
+		for _, node := range shard.Nodes {
+			if node.Role == "master" {
+				primaryID = node.ID
+			}
+		}
+
+		for _, node := range shard.Nodes {
+			addr := shardNodeAddr(node)
+			n := ClusterNode{
+				ID:       node.ID,
+				Addr:     addr,
+				Hostname: node.Hostname,
+				Role:     RoleReplica,
+				Healthy:  node.Health == "online" && addr != "",
+			}
+			if node.Role == "master" {
+				n.Role = RolePrimary
+				n.Slots = slots
+			} else {
+				n.PrimaryID = primaryID
+			}
+			t.Nodes = append(t.Nodes, n)
+		}
+	}
+	return t
+}
+
+func shardNodeAddr(node redis.Node) string {
+	host := node.Endpoint
+	if host == "" || host == "?" {
+		host = node.IP
+	}
+	port := node.Port
+	if port == 0 {
+		port = node.TLSPort
+	}
+	if host == "" || port == 0 {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.FormatInt(port, 10))
+}
+
+// DiscoverTopology asks c for the current cluster topology. CLUSTER SHARDS
+// is used when the server supports it (Redis 7.0 and later), CLUSTER NODES
+// otherwise.
+func DiscoverTopology(ctx context.Context, c redis.Cmdable) (ClusterTopology, error) {
+	var t ClusterTopology
+	if shards, err := c.ClusterShards(ctx).Result(); err == nil {
+		t = TopologyFromClusterShards(shards)
+	} else {
+		nodes, err := c.ClusterNodes(ctx).Result()
+		if err != nil {
+			return ClusterTopology{}, fmt.Errorf("cluster nodes: %w", err)
+		}
+		if t, err = ParseClusterNodes(nodes); err != nil {
+			return ClusterTopology{}, err
+		}
+	}
+	if len(t.Primaries()) == 0 {
+		return ClusterTopology{}, fmt.Errorf("no primary found in cluster topology")
+	}
+	return t, nil
+}
+
+// ResolveConfigEndpoint expands a cluster configuration endpoint, such as
+// the one ElastiCache provides, into the address of every node its DNS name
+// currently resolves to.
+func ResolveConfigEndpoint(ctx context.Context, endpoint string) ([]string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration endpoint %s: %w", endpoint, err)
+	}
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve configuration endpoint %s: %w", endpoint, err)
+	}
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip, port))
+	}
+	return addrs, nil
+}
@@ -0,0 +1,254 @@
+package redisttl
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseClusterNodes(t *testing.T) {
+	nodes := `07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004,replica.example.com slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002,node2.example.com master - 0 1426238316232 2 connected 5461-10922
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 127.0.0.1:30003@31003 master - 0 1426238318243 3 connected 10923-16383 [10924->-e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca]
+6ec23923021cf3ffec47632106199cb7f496ce01 127.0.0.1:30005@31005 master,fail - 1426238316232 0 5 disconnected
+824fe116063bc5fcf9f4ffd895bc17aee7731ac3 :0@0 master,noaddr - 0 0 0 disconnected
+e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001 myself,master - 0 0 1 connected 0-5459 5460
+`
+	topology, err := ParseClusterNodes(nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(topology.Nodes) != 6 {
+		t.Fatalf("want 6 nodes, got: %d", len(topology.Nodes))
+	}
+
+	var addrs []string
+	for _, n := range topology.Primaries() {
+		addrs = append(addrs, n.Addr)
+	}
+	want := []string{"127.0.0.1:30002", "127.0.0.1:30003", "127.0.0.1:30001"}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("want: %v got: %v", want, addrs)
+	}
+
+	primary := topology.Primaries()[2]
+	if wantSlots := []SlotRange{{Start: 0, End: 5459}, {Start: 5460, End: 5460}}; !reflect.DeepEqual(primary.Slots, wantSlots) {
+		t.Fatalf("want: %v got: %v", wantSlots, primary.Slots)
+	}
+	if primary.Hostname != "" || topology.Primaries()[0].Hostname != "node2.example.com" {
+		t.Fatalf("unexpected hostnames: %+v", topology.Primaries())
+	}
+
+	if got := topology.Primaries()[0].DialAddr(true); got != "node2.example.com:30002" {
+		t.Fatalf("want the hostname with the port of the node, got: %s", got)
+	}
+	if got := primary.DialAddr(true); got != primary.Addr {
+		t.Fatalf("a node without hostname should be dialed at its address, got: %s", got)
+	}
+
+	replicas := topology.Replicas(primary.ID)
+	if len(replicas) != 1 || replicas[0].Addr != "127.0.0.1:30004" || replicas[0].Hostname != "replica.example.com" {
+		t.Fatalf("unexpected replicas: %+v", replicas)
+	}
+
+	if _, err := ParseClusterNodes("a 127.0.0.1:1@2 master - 0 0 1 connected x-y"); err == nil {
+		t.Fatal("expected error for invalid slot range, got nil")
+	}
+}
+
+func TestParseClusterNodesIPv6(t *testing.T) {
+	nodes := `07c37dfeb235213a872192d90877d0cd55635b91 2001:db8::2:6379@16379,node2.example.com master - 0 1426238316232 2 connected 0-8191
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 ::1:6380@16380 master - 0 1426238316232 2 connected 8192-16383
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f [2001:db8::3]:6379@16379 slave 07c37dfeb235213a872192d90877d0cd55635b91 0 1426238318243 3 connected
+824fe116063bc5fcf9f4ffd895bc17aee7731ac3 :0@0 master,noaddr - 0 0 0 disconnected
+`
+	topology, err := ParseClusterNodes(nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var addrs []string
+	for _, n := range topology.Primaries() {
+		addrs = append(addrs, n.Addr)
+	}
+	want := []string{"[2001:db8::2]:6379", "[::1]:6380"}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("want: %v got: %v", want, addrs)
+	}
+	if got := topology.Primaries()[0].DialAddr(true); got != "node2.example.com:6379" {
+		t.Fatalf("want the hostname with the port of the node, got: %s", got)
+	}
+	if replicas := topology.Replicas(topology.Nodes[0].ID); len(replicas) != 1 || replicas[0].Addr != "[2001:db8::3]:6379" {
+		t.Fatalf("unexpected replicas: %+v", replicas)
+	}
+	if n := topology.Nodes[3]; n.Healthy || n.Addr != "" {
+		t.Fatalf("a node without address should be unhealthy, got: %+v", n)
+	}
+}
+
+func TestUncoveredSlots(t *testing.T) {
+	tests := map[string]struct {
+		nodes []ClusterNode
+		want  []SlotRange
+	}{
+		"covered": {
+			nodes: []ClusterNode{
+				{Role: RolePrimary, Healthy: true, Slots: []SlotRange{{Start: 0, End: 8191}}},
+				{Role: RolePrimary, Healthy: true, Slots: []SlotRange{{Start: 8192, End: 16383}}},
+			},
+		},
+		"gaps": {
+			nodes: []ClusterNode{
+				{Role: RolePrimary, Healthy: true, Slots: []SlotRange{{Start: 1, End: 8191}, {Start: 8193, End: 16000}}},
+			},
+			want: []SlotRange{{Start: 0, End: 0}, {Start: 8192, End: 8192}, {Start: 16001, End: 16383}},
+		},
+		"failed primary": {
+			nodes: []ClusterNode{
+				{Role: RolePrimary, Healthy: true, Slots: []SlotRange{{Start: 0, End: 8191}}},
+				{Role: RolePrimary, Slots: []SlotRange{{Start: 8192, End: 16383}}},
+				{Role: RoleReplica, Healthy: true, Slots: []SlotRange{{Start: 8192, End: 16383}}},
+			},
+			want: []SlotRange{{Start: 8192, End: 16383}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ClusterTopology{Nodes: tc.nodes}.UncoveredSlots()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want: %v got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSlotPrimary(t *testing.T) {
+	topology := ClusterTopology{Nodes: []ClusterNode{
+		{Addr: "a:6379", Role: RolePrimary, Slots: []SlotRange{{Start: 0, End: 8191}}},
+		{Addr: "b:6379", Role: RolePrimary, Healthy: true, Slots: []SlotRange{{Start: 0, End: 8191}}},
+		{Addr: "c:6379", Role: RolePrimary, Healthy: true, Slots: []SlotRange{{Start: 8192, End: 16000}}},
+	}}
+	if n, found := topology.SlotPrimary(100); !found || n.Addr != "b:6379" {
+		t.Fatalf("want the healthy primary of slot 100, got %+v", n)
+	}
+	if n, found := topology.SlotPrimary(16383); found {
+		t.Fatalf("want no primary for slot 16383, got %+v", n)
+	}
+}
+
+func TestDiscoverTopology(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	topology, err := DiscoverTopology(context.Background(), rdb)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(topology.Primaries()) != 1 {
+		t.Fatalf("want one primary, got: %v", topology.Primaries())
+	}
+
+	s.SetError("fault-injected")
+	if _, err := DiscoverTopology(context.Background(), rdb); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestResolveConfigEndpoint(t *testing.T) {
+	ctx := context.Background()
+
+	addrs, err := ResolveConfigEndpoint(ctx, "127.0.0.1:6379")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"127.0.0.1:6379"}; !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("want: %v got: %v", want, addrs)
+	}
+
+	if _, err := ResolveConfigEndpoint(ctx, "missing-port"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestTopologyFromClusterShards(t *testing.T) {
+	shards := []redis.ClusterShard{
+		{
+			Slots: []redis.SlotRange{{Start: 0, End: 8191}},
+			Nodes: []redis.Node{
+				{ID: "a", Endpoint: "10.0.0.1", IP: "10.0.0.1", Port: 6379, Role: "master", Health: "online"},
+				{ID: "b", Endpoint: "10.0.0.2", IP: "10.0.0.2", Port: 6379, Role: "replica", Health: "online"},
+			},
+		},
+		{
+			Slots: []redis.SlotRange{{Start: 8192, End: 16383}},
+			Nodes: []redis.Node{
+				{ID: "c", Endpoint: "node3.example.com", IP: "10.0.0.3", TLSPort: 6380, Role: "master", Health: "online"},
+				{ID: "d", Endpoint: "?", IP: "::1", Port: 6379, Role: "master", Health: "online"},
+				{ID: "e", Endpoint: "10.0.0.5", Port: 6379, Role: "master", Health: "failed"},
+			},
+		},
+	}
+	topology := TopologyFromClusterShards(shards)
+
+	var addrs []string
+	for _, n := range topology.Primaries() {
+		addrs = append(addrs, n.Addr)
+	}
+	want := []string{"10.0.0.1:6379", "node3.example.com:6380", "[::1]:6379"}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("want: %v got: %v", want, addrs)
+	}
+
+	if slots := topology.Primaries()[0].Slots; !reflect.DeepEqual(slots, []SlotRange{{Start: 0, End: 8191}}) {
+		t.Fatalf("unexpected slots: %v", slots)
+	}
+	if replicas := topology.Replicas("a"); len(replicas) != 1 || replicas[0].ID != "b" {
+		t.Fatalf("unexpected replicas: %+v", replicas)
+	}
+}
+
+// shardsHook answers CLUSTER SHARDS like a Redis 7 server would.
+type shardsHook struct {
+	shards []redis.ClusterShard
+}
+
+func (h *shardsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.FullName() != "cluster shards" {
+			return next(ctx, cmd)
+		}
+		cmd.(*redis.ClusterShardsCmd).SetVal(h.shards)
+		return nil
+	}
+}
+
+func (h *shardsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *shardsHook) DialHook(hook redis.DialHook) redis.DialHook {
+	return hook
+}
+
+func TestDiscoverTopologyPrefersClusterShards(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(&shardsHook{shards: []redis.ClusterShard{{
+		Nodes: []redis.Node{{IP: "10.0.0.1", Port: 6379, Role: "master", Health: "online"}},
+	}}})
+
+	topology, err := DiscoverTopology(context.Background(), rdb)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primaries := topology.Primaries(); len(primaries) != 1 || primaries[0].Addr != "10.0.0.1:6379" {
+		t.Fatalf("unexpected primaries: %+v", primaries)
+	}
+}
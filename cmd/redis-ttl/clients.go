@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// credentials returns the CredentialsProvider of every client, nil when the
+// default user authenticates without a password. Secret managers are only
+// asked again once --credentials-refresh elapsed.
+func (c *config) credentials() func() (username, password string) {
+	var p redisttl.CredentialProvider
+	switch {
+	case c.redisPasswordFile != "":
+		p = &redisttl.FileCredentials{Username: c.redisUsername, Path: c.redisPasswordFile}
+	case c.redisPasswordEnv != "":
+		p = &redisttl.EnvCredentials{Username: c.redisUsername, Var: c.redisPasswordEnv}
+	case c.redisAWSSecret != "" || c.redisGCPSecret != "":
+		secret := redisttl.AWSSecretCredentials(c.redisAWSSecret)
+		if c.redisGCPSecret != "" {
+			secret = redisttl.GCPSecretCredentials(c.redisGCPSecret)
+		}
+		secret.Username = c.redisUsername
+		p = &redisttl.CachedCredentials{Provider: secret, TTL: c.credentialsRefresh}
+	case c.redisUsername != "":
+		username := c.redisUsername
+		return func() (string, string) { return username, "" }
+	default:
+		return nil
+	}
+	return redisttl.CredentialsFunc(p)
+}
+
+// credentialSources returns the number of password sources set.
+func (c *config) credentialSources() int {
+	n := 0
+	for _, source := range []string{c.redisPasswordFile, c.redisPasswordEnv, c.redisAWSSecret, c.redisGCPSecret} {
+		if source != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// dialer returns the Dialer of every client, nil for the default one: the
+// --socks5-proxy, dialing the addresses --addr-map maps the nodes to.
+func (c *config) dialer() (redisttl.DialFunc, error) {
+	var dial redisttl.DialFunc
+	if c.socks5Proxy != "" {
+		u, err := url.Parse(c.socks5Proxy)
+		switch {
+		case err != nil:
+			return nil, err
+		case u.Scheme != "socks5" || u.Host == "":
+			return nil, fmt.Errorf("want socks5://[user:password@]host:port, got %s", c.socks5Proxy)
+		}
+		d := &redisttl.SOCKS5Dialer{Addr: u.Host}
+		if u.User != nil {
+			d.Username = u.User.Username()
+			d.Password, _ = u.User.Password()
+		}
+		dial = d.DialContext
+	}
+
+	m, err := c.addrMapping()
+	if err != nil || m == nil {
+		return dial, err
+	}
+	return redisttl.MapAddrs(m, dial), nil
+}
+
+// addrMapping parses --addr-map, a list of announced=reachable addresses or
+// hosts, nil when empty.
+func (c *config) addrMapping() (map[string]string, error) {
+	entries := splitList(c.addrMap)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		from, to, found := strings.Cut(entry, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !found || from == "" || to == "" {
+			return nil, fmt.Errorf("addr-map entry %q isn't announced=reachable: %w", entry, errNodes)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
+
+// clientOptions returns the options of the client of the node at addr,
+// named name.
+func (c *config) clientOptions(addr, name string) *redis.Options {
+	// validated by Err
+	dialer, _ := c.dialer()
+	return &redis.Options{
+		Addr:                addr,
+		ClientName:          name,
+		Dialer:              dialer,
+		CredentialsProvider: c.credentials(),
+	}
+}
+
+// nodeAddrs returns the address receiving the commands modifying keys and
+// the scanned one of a single node run: --write-addr and --scan-addr when
+// set, --redis-addr for both otherwise.
+func (c *config) nodeAddrs() (write, scan string) {
+	if c.writeAddr != "" {
+		return c.writeAddr, c.scanAddr
+	}
+	return c.redisAddr, c.redisAddr
+}
+
+// clusterOptions returns the options of the client of the cluster seeded
+// by addrs.
+func (c *config) clusterOptions(addrs []string) *redis.ClusterOptions {
+	dialer, _ := c.dialer()
+	return &redis.ClusterOptions{
+		Addrs:               addrs,
+		ClientName:          "redis-ttl-cluster",
+		Dialer:              dialer,
+		CredentialsProvider: c.credentials(),
+	}
+}
+
+// ringOptions returns the options of the client of the ring of shards.
+func (c *config) ringOptions(shards map[string]string) *redis.RingOptions {
+	dialer, _ := c.dialer()
+	creds := c.credentials()
+	return &redis.RingOptions{
+		Addrs:      shards,
+		ClientName: "redis-ttl-ring",
+		Dialer:     dialer,
+		// the ring options have no CredentialsProvider of their own
+		NewClient: func(opt *redis.Options) *redis.Client {
+			opt.CredentialsProvider = creds
+			return redis.NewClient(opt)
+		},
+	}
+}
+
+// openDestination sets out.destination, the client of the instance or
+// cluster copy mode copies keys onto, from --copy-addr or
+// --copy-cluster-addrs. It uses the credentials and dialer of the source.
+// The returned func closes the client.
+func openDestination(cfg *config, out *collectors) func() {
+	var c redis.UniversalClient
+	switch {
+	case cfg.copyClusterAddrs != "":
+		opt := cfg.clusterOptions(strings.Split(cfg.copyClusterAddrs, ","))
+		opt.ClientName = "redis-ttl-copy"
+		c = redis.NewClusterClient(opt)
+	case cfg.copyAddr != "":
+		c = redis.NewClient(cfg.clientOptions(cfg.copyAddr, "redis-ttl-copy"))
+	default:
+		return func() {}
+	}
+	out.destination = c
+	return func() { _ = c.Close() }
+}
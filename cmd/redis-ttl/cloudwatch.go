@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// emfWriter writes the metrics of a run in the CloudWatch embedded metric
+// format, one JSON document per line which the CloudWatch agent, the
+// awslogs log driver or Lambda turn into metrics, for teams alarming on
+// CloudWatch rather than Prometheus. Every write reports the counts since
+// the previous one, so CloudWatch sums them per period.
+type emfWriter struct {
+	w         io.Writer
+	namespace string
+	cluster   string
+
+	prev      map[string]int64
+	prevNodes map[string]redisttl.NodeCommands
+}
+
+func newEMFWriter(w io.Writer, cfg *config) *emfWriter {
+	cluster := cfg.cloudwatchCluster
+	if cluster == "" {
+		cluster = cfg.clusterName()
+	}
+	return &emfWriter{
+		w:         w,
+		namespace: cfg.cloudwatchNamespace,
+		cluster:   cluster,
+		prev:      map[string]int64{},
+		prevNodes: map[string]redisttl.NodeCommands{},
+	}
+}
+
+// clusterName is the address the run targets, the CloudWatch Cluster
+// dimension when --cloudwatch-cluster is unset.
+func (c *config) clusterName() string {
+	for _, addr := range []string{c.clusterConfigEndpoint, c.redisClusterAddrs, c.redisRingAddrs} {
+		if addr != "" {
+			return addr
+		}
+	}
+	addr, _ := c.nodeAddrs()
+	return addr
+}
+
+// write writes a document with the counters of the summary with the Cluster
+// dimension, and one with the commands sent to every node with the Cluster
+// and Node dimensions.
+func (e *emfWriter) write(now time.Time, s *redisttl.Summary, nodes []redisttl.NodeCommands) error {
+	counters := map[string]int64{
+		"Scanned":      s.Scanned.Load(),
+		"Modified":     s.Modified.Load(),
+		"Errors":       s.Errors.Load(),
+		"Companions":   s.Companions.Load(),
+		"Tolerated":    s.Tolerated.Load(),
+		"BreakerTrips": s.BreakerTrips.Load(),
+		"Failovers":    s.Failovers.Load(),
+	}
+	doc := map[string]any{"Cluster": e.cluster}
+	for name, n := range counters {
+		doc[name] = n - e.prev[name]
+	}
+	e.prev = counters
+	metrics := []string{"Scanned", "Modified", "Errors", "Companions", "Tolerated", "BreakerTrips", "Failovers"}
+	if err := e.encode(now, doc, []string{"Cluster"}, metrics); err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		prev := e.prevNodes[n.Node]
+		doc := map[string]any{
+			"Cluster":       e.cluster,
+			"Node":          n.Node,
+			"ScanCommands":  n.Scan - prev.Scan,
+			"ReadCommands":  n.Read - prev.Read,
+			"WriteCommands": n.Write - prev.Write,
+			"OtherCommands": n.Other - prev.Other,
+		}
+		e.prevNodes[n.Node] = n
+		metrics = []string{"ScanCommands", "ReadCommands", "WriteCommands", "OtherCommands"}
+		if err := e.encode(now, doc, []string{"Cluster", "Node"}, metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode writes doc with the metadata declaring its metrics, all counts,
+// and dimensions.
+func (e *emfWriter) encode(now time.Time, doc map[string]any, dimensions, metrics []string) error {
+	definitions := make([]map[string]string, len(metrics))
+	for i, name := range metrics {
+		definitions[i] = map[string]string{"Name": name, "Unit": "Count"}
+	}
+	doc["_aws"] = map[string]any{
+		"Timestamp": now.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  e.namespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    definitions,
+		}},
+	}
+	return json.NewEncoder(e.w).Encode(doc)
+}
+
+// writeEMF appends the metrics of the run to path, stdout for "-", every
+// interval until the returned func is called, which writes them one last
+// time. Failures are logged, the run keeping on.
+func writeEMF(cfg *config, out *collectors) (func(), error) {
+	w := io.Writer(os.Stdout)
+	closeFile := func() {}
+	if cfg.cloudwatchEMF != "-" {
+		f, err := os.OpenFile(cfg.cloudwatchEMF, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("cloudwatch emf file: %w", err)
+		}
+		w, closeFile = f, func() { f.Close() }
+	}
+	e := newEMFWriter(w, cfg)
+	stop := every(cfg.cloudwatchInterval, func(bool) {
+		if err := e.write(time.Now(), out.summary, out.commands.Nodes()); err != nil {
+			log.Printf("cloudwatch emf error: %v\n", err)
+		}
+	})
+	return func() {
+		stop()
+		closeFile()
+	}, nil
+}
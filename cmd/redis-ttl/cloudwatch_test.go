@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+type emfDoc struct {
+	AWS struct {
+		Timestamp         int64
+		CloudWatchMetrics []struct {
+			Namespace  string
+			Dimensions [][]string
+			Metrics    []struct{ Name, Unit string }
+		}
+	} `json:"_aws"`
+	Cluster       string
+	Node          string
+	Scanned       int64
+	Modified      int64
+	Errors        int64
+	WriteCommands int64
+}
+
+func decodeEMF(t *testing.T, b *bytes.Buffer) []emfDoc {
+	t.Helper()
+	var docs []emfDoc
+	dec := json.NewDecoder(b)
+	for dec.More() {
+		var doc emfDoc
+		if err := dec.Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestEMFWriter(t *testing.T) {
+	var b bytes.Buffer
+	e := newEMFWriter(&b, &config{cloudwatchNamespace: "RedisTTL", redisAddr: "localhost:6379"})
+	s := &redisttl.Summary{}
+	s.Scanned.Add(10)
+	s.Modified.Add(4)
+	now := time.UnixMilli(1700000000000)
+
+	if err := e.write(now, s, []redisttl.NodeCommands{{Node: "a:6379", Write: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	docs := decodeEMF(t, &b)
+	if len(docs) != 2 {
+		t.Fatalf("want a document for the cluster and one for the node, got %+v", docs)
+	}
+	cluster, node := docs[0], docs[1]
+	if cluster.Cluster != "localhost:6379" || cluster.Scanned != 10 || cluster.Modified != 4 || cluster.AWS.Timestamp != now.UnixMilli() {
+		t.Fatalf("unexpected cluster document: %+v", cluster)
+	}
+	m := cluster.AWS.CloudWatchMetrics[0]
+	if m.Namespace != "RedisTTL" || !reflect.DeepEqual(m.Dimensions, [][]string{{"Cluster"}}) || len(m.Metrics) != 7 || m.Metrics[0].Unit != "Count" {
+		t.Fatalf("unexpected cluster metadata: %+v", m)
+	}
+	if node.Node != "a:6379" || node.WriteCommands != 4 || !reflect.DeepEqual(node.AWS.CloudWatchMetrics[0].Dimensions, [][]string{{"Cluster", "Node"}}) {
+		t.Fatalf("unexpected node document: %+v", node)
+	}
+
+	// counts are the ones since the previous write
+	s.Scanned.Add(5)
+	s.Errors.Add(1)
+	if err := e.write(now, s, []redisttl.NodeCommands{{Node: "a:6379", Write: 6}}); err != nil {
+		t.Fatal(err)
+	}
+	docs = decodeEMF(t, &b)
+	if docs[0].Scanned != 5 || docs[0].Modified != 0 || docs[0].Errors != 1 || docs[1].WriteCommands != 2 {
+		t.Fatalf("want deltas, got %+v", docs)
+	}
+}
+
+func TestClusterName(t *testing.T) {
+	testCases := map[string]config{
+		"localhost:6379": {redisAddr: "localhost:6379"},
+		"a:7000,b:7000":  {redisAddr: "localhost:6379", redisClusterAddrs: "a:7000,b:7000"},
+		"cfg.example:1":  {clusterConfigEndpoint: "cfg.example:1"},
+	}
+	for want, cfg := range testCases {
+		if got := cfg.clusterName(); got != want {
+			t.Fatalf("want %s, got %s", want, got)
+		}
+	}
+}
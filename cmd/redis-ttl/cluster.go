@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func runCluster(ctx context.Context, cfg *config, out *collectors) error {
+	if cfg.clusterConfigEndpoint != "" || cfg.preferHostnames {
+		return runDiscovered(ctx, cfg, out)
+	}
+
+	clusterClient := redis.NewClusterClient(cfg.clusterOptions(strings.Split(cfg.redisClusterAddrs, ",")))
+	clusterClient.OnNewNode(out.countCommands)
+	clusterClient.ReloadState(ctx)
+
+	ids, err := primaryIDs(ctx, cfg, clusterClient)
+	if err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	clients := map[string]*redis.Client{}
+	err = clusterClient.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		clients[client.Options().Addr] = client
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	addrs := make([]string, 0, len(clients))
+	for addr := range clients {
+		addrs = append(addrs, addr)
+	}
+	var failover *shardFailover
+	if cfg.failoverRetries > 0 {
+		seeds := strings.Split(cfg.redisClusterAddrs, ",")
+		if topology, err := discoverTopology(ctx, cfg, seeds); err != nil {
+			log.Printf("failovers won't be resumed, discover the slots of the primaries: %v\n", err)
+		} else {
+			failover = newShardFailover(cfg, seeds, topology)
+			defer failover.Close()
+		}
+	}
+
+	return runPrimaries(ctx, cfg, addrs, ids, func(ctx context.Context, addr string) error {
+		client := clients[addr]
+		r := newRunner(cfg, client, client, out)
+		failover.attach(r, addr, func(c *redis.Client) (redis.Cmdable, redis.Cmdable) {
+			out.countCommands(c)
+			return c, c
+		})
+		return runNode(ctx, out, addr, client, r)
+	})
+}
+
+// clusterSeeds returns the addresses of the nodes to discover the cluster
+// through: the ones its configuration endpoint resolves to, or
+// --redis-cluster-addrs.
+func clusterSeeds(ctx context.Context, cfg *config) ([]string, error) {
+	if cfg.clusterConfigEndpoint == "" {
+		return strings.Split(cfg.redisClusterAddrs, ","), nil
+	}
+	return redisttl.ResolveConfigEndpoint(ctx, cfg.clusterConfigEndpoint)
+}
+
+// runDiscovered discovers the primaries through the seeds of the cluster,
+// dialing them at their announced hostname with --prefer-hostnames. Each
+// primary is scanned with its own client while expire commands go through
+// the cluster client, so writes keep being routed to the right node after
+// a failover, the scan resuming on the promoted node.
+func runDiscovered(ctx context.Context, cfg *config, out *collectors) error {
+	seeds, err := clusterSeeds(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	topology, err := discoverTopology(ctx, cfg, seeds)
+	if err != nil {
+		return fmt.Errorf("discover primaries through %s: %w", strings.Join(seeds, ","), err)
+	}
+	var addrs []string
+	ids := map[string]string{}
+	for _, node := range topology.Primaries() {
+		addr := node.DialAddr(cfg.preferHostnames)
+		addrs = append(addrs, addr)
+		ids[addr] = node.ID
+	}
+
+	clusterClient := redis.NewClusterClient(cfg.clusterOptions(seeds))
+	clusterClient.OnNewNode(out.countCommands)
+	defer clusterClient.Close()
+	failover := newShardFailover(cfg, seeds, topology)
+	defer failover.Close()
+
+	return runPrimaries(ctx, cfg, addrs, ids, func(ctx context.Context, addr string) error {
+		scanClient := redis.NewClient(cfg.clientOptions(addr, "redis-ttl-scan"))
+		out.countCommands(scanClient)
+		defer scanClient.Close()
+		r := newRunner(cfg, clusterClient, scanClient, out)
+		failover.attach(r, addr, func(c *redis.Client) (redis.Cmdable, redis.Cmdable) {
+			out.countCommands(c)
+			return clusterClient, c
+		})
+		return runNode(ctx, out, addr, scanClient, r)
+	})
+}
+
+// runPrimaries runs scan on the primaries at addrs selected by cfg, in
+// address order and starting from --start-node. With --node-concurrency,
+// nodes start in that order as others finish, so after a failure, the
+// first node which didn't complete is the one to resume from.
+func runPrimaries(ctx context.Context, cfg *config, addrs []string, ids map[string]string, scan func(ctx context.Context, addr string) error) error {
+	addrs, err := nodeOrder(addrs, ids, cfg.startNode)
+	if err != nil {
+		return err
+	}
+	filter := newNodeFilter(cfg.nodesInclude, cfg.nodesExclude)
+	sem := newSemaphore(cfg.nodeConcurrency)
+	ctx, abort, cancel := failFast(ctx, cfg)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(addrs))
+	for i, addr := range addrs {
+		if !filter.allows(addr, ids[addr]) {
+			log.Printf("skipping node %s (%s)\n", addr, ids[addr])
+			continue
+		}
+		if err := sem.acquire(ctx); err != nil {
+			errs[i] = fmt.Errorf("node %s: %w", addr, err)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			defer sem.release()
+
+			log.Printf("scanning node %s\n", addr)
+			if err := scan(ctx, addr); err != nil {
+				errs[i] = fmt.Errorf("node %s: %w", addr, err)
+				abort(errs[i])
+			}
+		}(i, addr)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("node %s didn't complete, resume with --start-node=%s\n", addrs[i], addrs[i])
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runNode runs r against the node at addr, scanned through scanClient,
+// recording its DBSIZE in out.sizes, its key counts in out.counts and telling
+// out.nodes, when set, when it
+// starts and finishes.
+func runNode(ctx context.Context, out *collectors, addr string, scanClient redis.Cmdable, r runner) error {
+	out.sizes.start(ctx, addr, scanClient)
+	defer out.counts.before(ctx, addr, scanClient)(ctx)
+	out.active.add(addr, r)
+	defer out.active.remove(r)
+	if out.nodes != nil {
+		out.nodes.OnNodeStart(ctx, out.run, addr)
+	}
+	start := time.Now()
+	err := r.Run(ctx)
+	node := redisttl.NodeResult{Addr: addr, Duration: time.Since(start)}
+	if s, ok := r.(*redisttl.Scanner); ok {
+		node.Stats = s.Stats()
+	}
+	out.sizes.finish(addr, node.Stats, node.Duration)
+	if out.nodes != nil {
+		out.nodes.OnNodeFinish(ctx, out.run, node, err)
+	}
+	return err
+}
+
+// failFast returns a context canceled by abort when called with a non-nil
+// error and --fail-fast is set, so a failing node stops the others. The
+// returned cancel func must be called once done.
+func failFast(ctx context.Context, cfg *config) (_ context.Context, abort func(error), cancel func()) {
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	abort = func(err error) {
+		if err != nil && cfg.failFast {
+			cancelCause(err)
+		}
+	}
+	return ctx, abort, func() { cancelCause(nil) }
+}
+
+// discoverTopology asks each seed in turn for the cluster topology,
+// since any single node behind a configuration endpoint may be down.
+func discoverTopology(ctx context.Context, cfg *config, seeds []string) (redisttl.ClusterTopology, error) {
+	var lastErr error
+	for _, seed := range seeds {
+		c := redis.NewClient(cfg.clientOptions(seed, "redis-ttl-discovery"))
+		topology, err := redisttl.DiscoverTopology(ctx, c)
+		_ = c.Close()
+		if err == nil {
+			return topology, nil
+		}
+		lastErr = err
+	}
+	return redisttl.ClusterTopology{}, lastErr
+}
+
+// primaryIDs maps the address of each primary to its node ID, when cfg
+// selects nodes by ID.
+func primaryIDs(ctx context.Context, cfg *config, c *redis.ClusterClient) (map[string]string, error) {
+	if newNodeFilter(cfg.nodesInclude, cfg.nodesExclude).empty() && cfg.startNode == "" {
+		return nil, nil
+	}
+	return nodeIDs(ctx, c)
+}
+
+// semaphore bounds how many nodes are processed at once.
+// A nil semaphore doesn't limit anything.
+type semaphore chan struct{}
+
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
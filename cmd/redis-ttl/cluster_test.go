@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDiscoverTopology(t *testing.T) {
+	down := miniredis.RunT(t)
+	down.SetError("LOADING")
+	up := miniredis.RunT(t)
+
+	topology, err := discoverTopology(context.Background(), &config{}, []string{down.Addr(), up.Addr()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(topology.Primaries()) != 1 {
+		t.Fatalf("want one primary, got: %v", topology.Primaries())
+	}
+
+	if _, err := discoverTopology(context.Background(), &config{}, []string{down.Addr()}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	var unlimited semaphore
+	if err := unlimited.acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unlimited.release()
+
+	sem := newSemaphore(2)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sem.acquire(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			defer sem.release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Fatalf("want at most 2 concurrent holders, got: %d", peak.Load())
+	}
+
+	full := newSemaphore(1)
+	_ = full.acquire(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := full.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want: %v got: %v", context.Canceled, err)
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	errNode := errors.New("node failed")
+	for _, enabled := range []bool{false, true} {
+		ctx, abort, cancel := failFast(context.Background(), &config{failFast: enabled})
+		abort(nil)
+		if ctx.Err() != nil {
+			t.Fatalf("a successful node should not abort the run, got: %v", ctx.Err())
+		}
+		abort(errNode)
+		if canceled := ctx.Err() != nil; canceled != enabled {
+			t.Fatalf("fail-fast=%v: expected canceled=%v", enabled, enabled)
+		}
+		if enabled && !errors.Is(context.Cause(ctx), errNode) {
+			t.Fatalf("want: %v got: %v", errNode, context.Cause(ctx))
+		}
+		cancel()
+	}
+}
+
+type nodeEvents struct {
+	redisttl.Notifiers
+	started  []string
+	finished []redisttl.NodeResult
+}
+
+func (n *nodeEvents) OnNodeStart(_ context.Context, _ redisttl.RunInfo, addr string) {
+	n.started = append(n.started, addr)
+}
+
+func (n *nodeEvents) OnNodeFinish(_ context.Context, _ redisttl.RunInfo, node redisttl.NodeResult, _ error) {
+	n.finished = append(n.finished, node)
+}
+
+func TestRunNode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+
+	cfg, err := parseConfig([]string{"redis-ttl", "--mode=exp", "--desired-ttl=1h", "--scan-prefix=f*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := &nodeEvents{}
+	out := &collectors{summary: &redisttl.Summary{}, nodes: events, sizes: &nodeSizes{}}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	if err := runNode(context.Background(), out, s.Addr(), rdb, newRunner(&cfg, rdb, rdb, out)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events.started) != 1 || events.started[0] != s.Addr() || len(events.finished) != 1 {
+		t.Fatalf("unexpected events: %v %v", events.started, events.finished)
+	}
+	if node := events.finished[0]; node.Addr != s.Addr() || node.Duration <= 0 || node.Stats.Modified != 2 {
+		t.Fatalf("unexpected node result: %+v", node)
+	}
+	if size := out.sizes.nodes[s.Addr()]; size.keys != 2 || size.stats.Modified != 2 || !size.done {
+		t.Fatalf("unexpected node size: %+v", size)
+	}
+}
+
+func TestNewRunnerCluster(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{})
+	for args, cluster := range map[string]bool{
+		"--redis-addr=localhost:6379":              false,
+		"--redis-cluster-addrs=node1:6379":         true,
+		"--redis-cluster-config-endpoint=cfg:6379": true,
+	} {
+		cfg, err := parseConfig([]string{"redis-ttl", "--mode=exp", "--batch-script", args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newRunner(&cfg, rdb, rdb, &collectors{}).(*redisttl.Scanner)
+		if s.Cluster != cluster {
+			t.Fatalf("want cluster %v for %s, got %v", cluster, args, s.Cluster)
+		}
+	}
+}
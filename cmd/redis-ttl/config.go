@@ -3,57 +3,757 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+	"golang.org/x/time/rate"
 )
 
 var (
-	errTTL       = errors.New("invalid ttl")
-	errRPS       = errors.New("invalid rps")
-	errScanCount = errors.New("invalid scan count")
+	errMode       = errors.New("invalid mode")
+	errTTL        = errors.New("invalid ttl")
+	errRPS        = errors.New("invalid rps")
+	errScanCount  = errors.New("invalid scan count")
+	errMemory     = errors.New("invalid memory guard")
+	errNodes      = errors.New("invalid node selection")
+	errSampleSize = errors.New("invalid sample size")
+	errBigKeys    = errors.New("invalid big key thresholds")
+	errNotify     = errors.New("invalid notification settings")
+	errDaemon     = errors.New("invalid daemon settings")
+	errBenchmark  = errors.New("invalid benchmark settings")
+	errVerify     = errors.New("verification failed")
+	errConfig     = errors.New("invalid config file")
+	errConfirm    = errors.New("invalid confirmation settings")
+	errAborted    = errors.New("aborted")
+	errLogFormat  = errors.New("invalid log format")
+	errSample     = errors.New("invalid apply percent")
+	errQueue      = errors.New("invalid queue settings")
+	errPasses     = errors.New("invalid pass settings")
+	errConverge   = errors.New("did not converge")
+	errWindow     = errors.New("invalid window")
+	errPredicate  = errors.New("invalid predicate")
+	errSummary    = errors.New("invalid summary settings")
+	errAuth       = errors.New("invalid credential settings")
+	errProxy      = errors.New("invalid proxy settings")
+	errCoverage   = errors.New("slots not covered")
+	errBreaker    = errors.New("invalid circuit breaker")
+	errCloudWatch = errors.New("invalid cloudwatch settings")
+	errStream     = errors.New("invalid change stream settings")
+	errIntrospect = errors.New("invalid introspect sample rate")
+	errRetry      = errors.New("invalid retry policy")
 )
 
+// modesWithoutTTL lists the modes that don't use --desired-ttl.
+var modesWithoutTTL = map[string]bool{
+	"persist":   true,
+	"percent":   true,
+	"extend":    true,
+	"shrink":    true,
+	"inherit":   true,
+	"report":    true,
+	"audit":     true,
+	"discover":  true,
+	"benchmark": true,
+	"rules":     true,
+	"copy":      true,
+}
+
+// batchModes are the modes supporting --batch-script.
+var batchModes = []string{"exp", "gt", "lt", "nx", "xx"}
+
+// txModes are the modes supporting --transaction.
+var txModes = []string{"exp", "gt", "lt", "nx", "xx", "persist"}
+
+// followModes are the modes supporting --follow.
+var followModes = []string{"exp", "lt", "nx", "inherit"}
+
+// cliModes are the modes implemented by the command rather than by a
+// redisttl.Scanner.
+var cliModes = []string{"discover", "benchmark"}
+
+// modes returns every mode --mode accepts, including the ones registered
+// with redisttl.RegisterMode.
+func modes() []string {
+	return append(redisttl.Modes(), cliModes...)
+}
+
 var defaultConfig = config{
-	redisAddr:         ":6379",
-	mode:              "noop",
-	scanPrefix:        "not-found",
-	desiredTTL:        ttl{dur: 1 * time.Hour},
-	rps:               100,
-	redisClusterAddrs: "",
-	scanType:          "string",
-	scanCount:         0,
+	redisAddr:             ":6379",
+	mode:                  "noop",
+	scanPrefix:            "not-found",
+	desiredTTL:            ttl{dur: 1 * time.Hour},
+	rps:                   100,
+	redisClusterAddrs:     "",
+	clusterConfigEndpoint: "",
+	scanType:              "string",
+	scanCount:             0,
+	maxMemoryRatio:        0,
+	memoryCheckEvery:      1000,
+	memoryPause:           10 * time.Second,
+	nodesInclude:          "",
+	nodesExclude:          "",
+	metricsAddr:           "",
+	reportSeparator:       ":",
+	sampleSize:            1000,
+	top:                   20,
+	bigKeyBytes:           0,
+	bigKeyElements:        0,
+	notifyURL:             "",
+	notifyFormat:          "json",
+	ttlPercent:            0,
+	ttlDelta:              0,
+	ttlFloor:              0,
+	nodeConcurrency:       0,
+	dryRun:                false,
+	interval:              0,
+	healthTimeout:         5 * time.Minute,
+	benchKeys:             0,
+	benchDuration:         10 * time.Second,
+	estimate:              false,
+	pipelineSize:          100,
+	verifyWrites:          false,
+	failFast:              false,
+	sourceKey:             "",
+	companions:            "",
+	ttlRules:              nil,
+	configFile:            "",
+	validate:              false,
+	connect:               false,
+	yes:                   false,
+	confirmAbove:          1000,
+	preview:               0,
+	notifyNodes:           false,
+	redisRingAddrs:        "",
+	force:                 false,
+	maxMatchRatio:         0.9,
+	protected:             "",
+	preflight:             true,
+	noEmulation:           false,
+	logFormat:             "text",
+	applyPercent:          0,
+	tolerance:             0,
+	batchScript:           false,
+	scanPatterns:          "",
+	follow:                false,
+	followCheckpoint:      "",
+	enqueue:               false,
+	work:                  false,
+	queueAddr:             "",
+	queueStream:           "redis-ttl:jobs",
+	queueGroup:            "redis-ttl",
+	queueConsumer:         "",
+	keysFrom:              "",
+	queueBatchSize:        100,
+	queueBlock:            5 * time.Second,
+	targetConcurrency:     1,
+	target:                "",
+	targets:               nil,
+	startNode:             "",
+	untilConverged:        false,
+	maxPasses:             10,
+	convergePause:         0,
+	window:                "",
+	windowTimezone:        "",
+	cursorFile:            "",
+	killSwitchKey:         "",
+	killSwitchInterval:    time.Second,
+	valueMatch:            "",
+	valuePrefix:           "",
+	hashField:             "",
+	hashValue:             "",
+	typeTTLs:              nil,
+	summaryDepth:          0,
+	projectionDays:        0,
+	summaryFile:           "",
+	summaryInterval:       time.Minute,
+	rules:                 nil,
+	redisUsername:         "",
+	redisPasswordFile:     "",
+	redisPasswordEnv:      "",
+	redisAWSSecret:        "",
+	redisGCPSecret:        "",
+	credentialsRefresh:    5 * time.Minute,
+	socks5Proxy:           "",
+	preferHostnames:       false,
+	addrMap:               "",
+	topology:              false,
+	compareCounts:         false,
+	zscoreUnit:            time.Second,
+	expiryIndex:           "",
+	copyAddr:              "",
+	copyClusterAddrs:      "",
+	copyReplace:           false,
+	chunkSize:             0,
+	chunkPause:            0,
+	jitter:                0,
+	transaction:           false,
+	breakerThreshold:      0,
+	breakerWindow:         100,
+	breakerPause:          30 * time.Second,
+	breakerProbes:         3,
+	cloudwatchEMF:         "",
+	cloudwatchNamespace:   "RedisTTL",
+	cloudwatchCluster:     "",
+	cloudwatchInterval:    time.Minute,
+	changeStream:          "",
+	changeStreamAddr:      "",
+	changeStreamMaxLen:    0,
+	historyFile:           "",
+	statusFile:            "",
+	sentinelAddrs:         "",
+	sentinelMaster:        "",
+	sentinelScanReplica:   false,
+	scanAddr:              "",
+	writeAddr:             "",
+	failoverRetries:       3,
+	failoverDelay:         5 * time.Second,
+	keySizeEvery:          0,
+	keySizeStrlen:         false,
+	introspectSampleRate:  1,
+	retries:               0,
+	retryDelay:            100 * time.Millisecond,
+	retryMaxDelay:         2 * time.Second,
+	universalAddrs:        "",
+	universalMaster:       "",
+	statsdAddr:            "",
+	statsdPrefix:          "redis_ttl.",
+	progressInterval:      0,
 }
 
 type config struct {
-	redisAddr         string
-	scanPrefix        string
-	mode              string
-	desiredTTL        ttl
-	rps               int
-	redisClusterAddrs string
-	scanType          string
-	scanCount         int64
+	redisAddr             string
+	scanPrefix            string
+	mode                  string
+	desiredTTL            ttl
+	rps                   float64
+	redisClusterAddrs     string
+	clusterConfigEndpoint string
+	scanType              string
+	scanCount             int64
+	maxMemoryRatio        float64
+	memoryCheckEvery      int
+	memoryPause           time.Duration
+	nodesInclude          string
+	nodesExclude          string
+	metricsAddr           string
+	reportSeparator       string
+	sampleSize            int
+	top                   int
+	bigKeyBytes           int64
+	bigKeyElements        int64
+	notifyURL             string
+	notifyFormat          string
+	ttlPercent            float64
+	ttlDelta              time.Duration
+	ttlFloor              time.Duration
+	nodeConcurrency       int
+	dryRun                bool
+	interval              time.Duration
+	healthTimeout         time.Duration
+	benchKeys             int
+	benchDuration         time.Duration
+	estimate              bool
+	pipelineSize          int
+	verifyWrites          bool
+	failFast              bool
+	sourceKey             string
+	companions            string
+	ttlRules              ttlRules
+	configFile            string
+	validate              bool
+	connect               bool
+	yes                   bool
+	confirmAbove          int64
+	preview               int
+	notifyNodes           bool
+	redisRingAddrs        string
+	force                 bool
+	maxMatchRatio         float64
+	protected             string
+	preflight             bool
+	noEmulation           bool
+	logFormat             string
+	applyPercent          float64
+	tolerance             time.Duration
+	batchScript           bool
+	scanPatterns          string
+	follow                bool
+	followCheckpoint      string
+	enqueue               bool
+	work                  bool
+	queueAddr             string
+	queueStream           string
+	queueGroup            string
+	queueConsumer         string
+	keysFrom              string
+	queueBatchSize        int
+	queueBlock            time.Duration
+	targetConcurrency     int
+	target                string
+	targets               []config
+	startNode             string
+	untilConverged        bool
+	maxPasses             int
+	convergePause         time.Duration
+	window                string
+	windowTimezone        string
+	cursorFile            string
+	killSwitchKey         string
+	killSwitchInterval    time.Duration
+	valueMatch            string
+	valuePrefix           string
+	hashField             string
+	hashValue             string
+	typeTTLs              typeTTLs
+	summaryDepth          int
+	projectionDays        int
+	summaryFile           string
+	summaryInterval       time.Duration
+	rules                 modeRules
+	redisUsername         string
+	redisPasswordFile     string
+	redisPasswordEnv      string
+	redisAWSSecret        string
+	redisGCPSecret        string
+	credentialsRefresh    time.Duration
+	socks5Proxy           string
+	preferHostnames       bool
+	addrMap               string
+	topology              bool
+	compareCounts         bool
+	zscoreUnit            time.Duration
+	expiryIndex           string
+	copyAddr              string
+	copyClusterAddrs      string
+	copyReplace           bool
+	chunkSize             int
+	chunkPause            time.Duration
+	jitter                time.Duration
+	transaction           bool
+	breakerThreshold      float64
+	breakerWindow         int
+	breakerPause          time.Duration
+	breakerProbes         int
+	cloudwatchEMF         string
+	cloudwatchNamespace   string
+	cloudwatchCluster     string
+	cloudwatchInterval    time.Duration
+	changeStream          string
+	changeStreamAddr      string
+	changeStreamMaxLen    int64
+	historyFile           string
+	statusFile            string
+	sentinelAddrs         string
+	sentinelMaster        string
+	sentinelScanReplica   bool
+	scanAddr              string
+	writeAddr             string
+	failoverRetries       int
+	failoverDelay         time.Duration
+	keySizeEvery          int64
+	keySizeStrlen         bool
+	introspectSampleRate  float64
+	retries               int
+	retryDelay            time.Duration
+	retryMaxDelay         time.Duration
+	universalAddrs        string
+	universalMaster       string
+	statsdAddr            string
+	statsdPrefix          string
+	progressInterval      time.Duration
 }
 
 func (c *config) Err() error {
+	if len(c.targets) > 0 {
+		return c.targetsErr()
+	}
 	switch {
-	case c.desiredTTL.dur <= 0 && c.mode != "persist":
+	case !slices.Contains(modes(), c.mode):
+		return fmt.Errorf("unknown mode %s, want one of %s: %w", c.mode, strings.Join(modes(), "|"), errMode)
+	case c.batchScript && !slices.Contains(batchModes, c.mode):
+		return fmt.Errorf("batch-script requires one of the %s modes, got %s: %w", strings.Join(batchModes, "|"), c.mode, errMode)
+	case c.transaction && !slices.Contains(txModes, c.mode):
+		return fmt.Errorf("transaction requires one of the %s modes, got %s: %w", strings.Join(txModes, "|"), c.mode, errMode)
+	case c.transaction && c.batchScript:
+		return fmt.Errorf("transaction and batch-script are mutually exclusive: %w", errMode)
+	case c.transaction && (c.tolerance > 0 || c.verifyWrites):
+		return fmt.Errorf("transaction doesn't support tolerance and verify-writes: %w", errMode)
+	case c.batchScript && c.verifyWrites:
+		return fmt.Errorf("batch-script doesn't support verify-writes: %w", errMode)
+	case c.desiredTTL.infinite && !modesWithoutTTL[c.mode]:
+		return fmt.Errorf("desired-ttl %s removes the ttl, which only exp mode does, got %s: %w", &c.desiredTTL, c.mode, errTTL)
+	case c.desiredTTL.dur <= 0 && !modesWithoutTTL[c.mode] && len(c.ttlRules) == 0 && len(c.typeTTLs) == 0 && !c.enqueue && !c.topology:
 		return fmt.Errorf("invalid desired-ttl value (%s) for mode %s: %w", &c.desiredTTL, c.mode, errTTL)
-	case c.rps <= 0:
-		return fmt.Errorf("rps must be greater than 0, got %d: %w", &c.rps, errRPS)
-	case c.redisAddr == "" && c.redisClusterAddrs == "":
+	case c.mode == "percent" && c.ttlPercent <= 0:
+		return fmt.Errorf("ttl-percent must be greater than 0 in percent mode, got %v: %w", c.ttlPercent, errTTL)
+	case (c.mode == "extend" || c.mode == "shrink") && c.ttlDelta <= 0:
+		return fmt.Errorf("ttl-delta must be greater than 0 in %s mode, got %s: %w", c.mode, c.ttlDelta, errTTL)
+	case c.mode == "inherit" && !strings.Contains(c.sourceKey, "{key}"):
+		return fmt.Errorf("source-key must contain {key} in inherit mode, got %q: %w", c.sourceKey, errTTL)
+	case slices.ContainsFunc(splitList(c.companions), func(s string) bool { return !strings.Contains(s, "{key}") }):
+		return fmt.Errorf("every companion must contain {key}, got %q: %w", c.companions, errTTL)
+	case c.ttlFloor < 0:
+		return fmt.Errorf("ttl-floor can't be negative, got %s: %w", c.ttlFloor, errTTL)
+	case c.mode == "copy" && !c.dryRun && (c.copyAddr == "") == (c.copyClusterAddrs == ""):
+		return fmt.Errorf("copy mode requires one of --copy-addr and --copy-cluster-addrs: %w", errMode)
+	case c.mode != "copy" && c.mode != "rules" && (c.copyAddr != "" || c.copyClusterAddrs != "" || c.copyReplace):
+		return fmt.Errorf("the copy flags require copy mode: %w", errMode)
+	case c.mode == "ztrim" && c.scanType != "zset" && c.scanType != "":
+		return fmt.Errorf("ztrim mode trims sorted sets, use --scan-type=zset: %w", errMode)
+	case c.zscoreUnit < 0:
+		return fmt.Errorf("zscore-unit can't be negative, got %s: %w", c.zscoreUnit, errTTL)
+	case c.follow && !slices.Contains(followModes, c.mode):
+		return fmt.Errorf("follow requires one of the %s modes, got %s: %w", strings.Join(followModes, "|"), c.mode, errMode)
+	case (c.enqueue || c.work) && (c.queueStream == "" || c.queueGroup == ""):
+		return fmt.Errorf("queue-stream and queue-group can't be empty: %w", errQueue)
+	case c.work && c.queueBlock <= 0:
+		return fmt.Errorf("queue-block must be greater than 0, got %s: %w", c.queueBlock, errQueue)
+	case c.enqueue && c.queueBatchSize <= 0:
+		return fmt.Errorf("queue-batch-size must be greater than 0, got %d: %w", c.queueBatchSize, errQueue)
+	case c.work && (c.interval > 0 || c.follow):
+		return fmt.Errorf("work is exclusive with --interval and --follow: %w", errQueue)
+	case c.untilConverged && (readOnlyModes[c.mode] || c.dryRun):
+		return fmt.Errorf("until-converged requires a mode modifying keys, got %s: %w", c.mode, errPasses)
+	case c.untilConverged && (c.interval > 0 || c.follow || c.work || c.estimate || c.preview > 0):
+		return fmt.Errorf("until-converged is exclusive with --interval, --follow, work, estimate and --preview: %w", errPasses)
+	case c.maxPasses < 0 || c.convergePause < 0:
+		return fmt.Errorf("max-passes and converge-pause can't be negative: %w", errPasses)
+	case c.follow && c.interval > 0:
+		return fmt.Errorf("--follow and --interval are mutually exclusive: %w", errDaemon)
+	case c.tolerance < 0:
+		return fmt.Errorf("tolerance can't be negative, got %s: %w", c.tolerance, errTTL)
+	case c.rps < 0:
+		return fmt.Errorf("rps can't be negative, got %g: %w", c.rps, errRPS)
+	case c.jitter < 0:
+		return fmt.Errorf("jitter can't be negative, got %s: %w", c.jitter, errRPS)
+	case c.chunkSize < 0 || c.chunkPause < 0 || (c.chunkSize > 0) != (c.chunkPause > 0):
+		return fmt.Errorf("chunk-size and chunk-pause must both be greater than 0, got %d and %s: %w", c.chunkSize, c.chunkPause, errRPS)
+	case c.redisAddr == "" && c.redisClusterAddrs == "" && c.redisRingAddrs == "":
 		return fmt.Errorf("both --redis-addr and --redis-cluster-addrs cannot be empty")
+	case c.credentialSources() > 1:
+		return fmt.Errorf("--redis-password-file, --redis-password-env, --redis-aws-secret and --redis-gcp-secret are mutually exclusive: %w", errAuth)
+	case (c.redisAWSSecret != "" || c.redisGCPSecret != "") && c.credentialsRefresh <= 0:
+		return fmt.Errorf("credentials-refresh must be greater than 0, got %s: %w", c.credentialsRefresh, errAuth)
+	case c.summaryDepth < 0:
+		return fmt.Errorf("summary-depth can't be negative, got %d: %w", c.summaryDepth, errSummary)
+	case c.projectionDays < 0:
+		return fmt.Errorf("projection-days can't be negative, got %d: %w", c.projectionDays, errSummary)
+	case c.projectionDays > 0 && readOnlyModes[c.mode]:
+		return fmt.Errorf("projection-days requires a mode modifying keys, got %s: %w", c.mode, errSummary)
+	case c.summaryFile != "" && c.summaryInterval <= 0:
+		return fmt.Errorf("summary-interval must be greater than 0, got %s: %w", c.summaryInterval, errSummary)
+	case c.changeStream == "" && (c.changeStreamAddr != "" || c.changeStreamMaxLen != 0):
+		return fmt.Errorf("change-stream-addr and change-stream-maxlen require --change-stream: %w", errStream)
+	case c.changeStreamMaxLen < 0:
+		return fmt.Errorf("change-stream-maxlen can't be negative, got %d: %w", c.changeStreamMaxLen, errStream)
+	case c.cloudwatchEMF != "" && (c.cloudwatchInterval <= 0 || c.cloudwatchNamespace == ""):
+		return fmt.Errorf("cloudwatch-emf requires a cloudwatch-interval greater than 0 and a cloudwatch-namespace: %w", errCloudWatch)
+	case c.mode == "discover" && c.sampleSize <= 0:
+		return fmt.Errorf("sample-size must be greater than 0, got %d: %w", c.sampleSize, errSampleSize)
+	case c.bigKeyBytes < 0 || c.bigKeyElements < 0:
+		return fmt.Errorf("big-key-bytes and big-key-elements can't be negative: %w", errBigKeys)
+	case c.introspectSampleRate < 0 || c.introspectSampleRate > 1:
+		return fmt.Errorf("introspect-sample-rate must be between 0 and 1, got %v: %w", c.introspectSampleRate, errIntrospect)
+	case c.keySizeEvery < 0:
+		return fmt.Errorf("key-size-every can't be negative, got %d: %w", c.keySizeEvery, errBigKeys)
+	case c.notifyURL != "" && c.notifyFormat != redisttl.FormatJSON && c.notifyFormat != redisttl.FormatSlack:
+		return fmt.Errorf("notify-format must be json or slack, got %s: %w", c.notifyFormat, errNotify)
+	case c.mode == "benchmark" && (c.benchKeys < 0 || c.benchDuration <= 0):
+		return fmt.Errorf("bench-keys can't be negative and bench-duration must be greater than 0: %w", errBenchmark)
+	case c.interval < 0 || c.healthTimeout < 0:
+		return fmt.Errorf("interval and health-timeout can't be negative: %w", errDaemon)
+	case c.applyPercent < 0 || c.applyPercent > 100:
+		return fmt.Errorf("apply-percent must be between 0 and 100, got %v: %w", c.applyPercent, errSample)
+	case c.logFormat != "" && c.logFormat != "text" && c.logFormat != "json":
+		return fmt.Errorf("log-format must be text or json, got %s: %w", c.logFormat, errLogFormat)
+	case c.preview < 0:
+		return fmt.Errorf("preview can't be negative, got %d: %w", c.preview, errScanCount)
+	case c.confirmAbove < 0:
+		return fmt.Errorf("confirm-above can't be negative, got %d: %w", c.confirmAbove, errConfirm)
+	case c.maxMatchRatio < 0 || c.maxMatchRatio > 1:
+		return fmt.Errorf("max-match-ratio must be between 0 and 1, got %v: %w", c.maxMatchRatio, errConfirm)
 	case c.scanCount < 0:
 		return fmt.Errorf("scanCount must be greater than 0, got %d: %w", &c.scanCount, errScanCount)
+	case c.pipelineSize < 0:
+		return fmt.Errorf("pipeline-size can't be negative, got %d: %w", c.pipelineSize, errScanCount)
+	case c.maxMemoryRatio < 0 || c.maxMemoryRatio > 1:
+		return fmt.Errorf("max-memory-ratio must be between 0 and 1, got %v: %w", c.maxMemoryRatio, errMemory)
+	case c.maxMemoryRatio > 0 && (c.memoryCheckEvery <= 0 || c.memoryPause <= 0):
+		return fmt.Errorf("memory-check-every and memory-pause must be greater than 0: %w", errMemory)
+	case c.breakerThreshold < 0 || c.breakerThreshold > 1:
+		return fmt.Errorf("breaker-threshold must be between 0 and 1, got %v: %w", c.breakerThreshold, errBreaker)
+	case c.breakerThreshold > 0 && (c.breakerWindow <= 0 || c.breakerPause <= 0 || c.breakerProbes < 0):
+		return fmt.Errorf("breaker-window and breaker-pause must be greater than 0, and breaker-probes can't be negative: %w", errBreaker)
+	case c.progressInterval < 0:
+		return fmt.Errorf("progress-interval can't be negative, got %s: %w", c.progressInterval, errSummary)
+	case c.retries < 0 || c.retryDelay < 0 || c.retryMaxDelay < 0:
+		return fmt.Errorf("retries, retry-delay and retry-max-delay can't be negative: %w", errRetry)
+	case c.failoverRetries < 0 || c.failoverDelay < 0:
+		return fmt.Errorf("failover-retries and failover-delay can't be negative: %w", errNodes)
+	case c.nodeConcurrency < 0:
+		return fmt.Errorf("node-concurrency can't be negative, got %d: %w", c.nodeConcurrency, errNodes)
+	case c.redisClusterAddrs != "" && c.clusterConfigEndpoint != "":
+		return fmt.Errorf("--redis-cluster-addrs and --redis-cluster-config-endpoint are mutually exclusive: %w", errNodes)
+	case c.redisRingAddrs != "" && (c.redisClusterAddrs != "" || c.clusterConfigEndpoint != ""):
+		return fmt.Errorf("--redis-ring-addrs and the cluster flags are mutually exclusive: %w", errNodes)
+	case (c.scanAddr == "") != (c.writeAddr == ""):
+		return fmt.Errorf("--scan-addr and --write-addr go together: %w", errNodes)
+	case c.scanAddr != "" && (c.redisClusterAddrs != "" || c.clusterConfigEndpoint != "" || c.redisRingAddrs != "" || c.sentinelAddrs != ""):
+		return fmt.Errorf("--scan-addr and --write-addr are for a single node, not the cluster, ring and sentinel flags: %w", errNodes)
+	case (c.sentinelAddrs == "") != (c.sentinelMaster == ""):
+		return fmt.Errorf("--sentinel-addrs and --sentinel-master go together: %w", errNodes)
+	case c.sentinelAddrs != "" && (c.redisClusterAddrs != "" || c.clusterConfigEndpoint != "" || c.redisRingAddrs != ""):
+		return fmt.Errorf("--sentinel-addrs and the cluster and ring flags are mutually exclusive: %w", errNodes)
+	case c.universalMaster != "" && c.universalAddrs == "":
+		return fmt.Errorf("--universal-master requires --universal-addrs: %w", errNodes)
+	case c.universalAddrs != "" && (c.redisClusterAddrs != "" || c.clusterConfigEndpoint != "" || c.redisRingAddrs != "" || c.sentinelAddrs != "" || c.scanAddr != ""):
+		return fmt.Errorf("--universal-addrs and the other node flags are mutually exclusive: %w", errNodes)
+	case c.universalAddrs != "" && (c.preview > 0 || c.estimate || c.follow || c.mode == "discover" || c.mode == "benchmark"):
+		return fmt.Errorf("--universal-addrs doesn't support --preview, --estimate, --follow and the discover and benchmark modes: %w", errNodes)
+	case c.sentinelScanReplica && c.sentinelAddrs == "":
+		return fmt.Errorf("--sentinel-scan-replica requires --sentinel-addrs: %w", errNodes)
+	case (c.nodesInclude != "" || c.nodesExclude != "") && c.redisClusterAddrs == "" && c.clusterConfigEndpoint == "" && c.redisRingAddrs == "":
+		return fmt.Errorf("--nodes-include and --nodes-exclude require a cluster or a ring: %w", errNodes)
+	case c.topology && c.redisClusterAddrs == "" && c.clusterConfigEndpoint == "":
+		return fmt.Errorf("topology requires a cluster: %w", errNodes)
+	case c.preferHostnames && c.redisClusterAddrs == "" && c.clusterConfigEndpoint == "":
+		return fmt.Errorf("--prefer-hostnames requires a cluster: %w", errNodes)
+	case c.startNode != "" && c.redisClusterAddrs == "" && c.clusterConfigEndpoint == "":
+		return fmt.Errorf("--start-node requires a cluster: %w", errNodes)
+	case c.killSwitchKey != "" && c.killSwitchInterval <= 0:
+		return fmt.Errorf("kill-switch-interval must be greater than 0: %w", errDaemon)
+	case c.valueMatch != "" && c.valuePrefix != "":
+		return fmt.Errorf("--value-match and --value-prefix are mutually exclusive: %w", errPredicate)
+	case (c.mode == "rules") != (len(c.rules) > 0):
+		return fmt.Errorf("rules mode requires --rule, and --rule rules mode: %w", errMode)
+	case len(c.rules) > 0 && (len(c.ttlRules) > 0 || len(c.typeTTLs) > 0):
+		return fmt.Errorf("--rule picks the ttl, --ttl-rule and --type-ttls can't be set: %w", errTTL)
+	case len(c.typeTTLs) > 0 && len(c.ttlRules) > 0:
+		return fmt.Errorf("--type-ttls and --ttl-rule are mutually exclusive: %w", errTTL)
+	case len(c.typeTTLs) > 0 && c.scanType != "" && c.typeTTLs[c.scanType] == 0:
+		return fmt.Errorf("--type-ttls has no ttl for --scan-type=%s, pass --scan-type=any: %w", c.scanType, errTTL)
+	case c.hashValue != "" && c.hashField == "":
+		return fmt.Errorf("--hash-value requires --hash-field: %w", errPredicate)
+	case c.hashField != "" && c.scanType != "hash" && c.scanType != "":
+		return fmt.Errorf("--hash-field requires --scan-type=hash, got %s: %w", c.scanType, errPredicate)
+	case c.hashField != "" && (c.valueMatch != "" || c.valuePrefix != ""):
+		return fmt.Errorf("--hash-field only matches hashes, and --value-match and --value-prefix strings: %w", errPredicate)
+	case c.batchScript && (c.valueMatch != "" || c.valuePrefix != "" || c.hashField != ""):
+		return fmt.Errorf("batch-script doesn't support --value-match, --value-prefix and --hash-field: %w", errPredicate)
+	case c.transaction && (c.valueMatch != "" || c.valuePrefix != "" || c.hashField != ""):
+		return fmt.Errorf("transaction doesn't support --value-match, --value-prefix and --hash-field: %w", errPredicate)
+	case c.windowTimezone != "" && c.window == "":
+		return fmt.Errorf("--window-timezone requires --window: %w", errWindow)
+	case c.cursorFile != "" && !strings.Contains(c.cursorFile, "{node}") && (c.redisClusterAddrs != "" || c.clusterConfigEndpoint != "" || c.redisRingAddrs != "" || c.universalCluster()):
+		return fmt.Errorf("--cursor-file must contain {node} to record the cursor of every node: %w", errWindow)
+	}
+	if c.redisRingAddrs != "" {
+		if _, err := ringShards(c.redisRingAddrs); err != nil {
+			return err
+		}
+	}
+	if _, err := c.valuePredicate(); err != nil {
+		return fmt.Errorf("--value-match: %w: %w", errPredicate, err)
+	}
+	if _, err := c.scanWindow(); err != nil {
+		return fmt.Errorf("%w: %w", errWindow, err)
+	}
+	if _, err := c.addrMapping(); err != nil {
+		return err
+	}
+	if _, err := c.dialer(); err != nil {
+		return fmt.Errorf("--socks5-proxy: %w: %w", errProxy, err)
+	}
+	if err := redisttl.RuleSet(c.rules).Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errMode, err)
+	}
+
+	return nil
+}
+
+// targetsErr validates the targets of the config file, run on their own.
+func (c *config) targetsErr() error {
+	switch {
+	case c.targetConcurrency <= 0:
+		return fmt.Errorf("target-concurrency must be greater than 0, got %d: %w", c.targetConcurrency, errConfig)
+	case !c.validate && (c.estimate || c.preview > 0 || c.work || c.interval > 0 || c.untilConverged):
+		return fmt.Errorf("targets only support runs and validate: %w", errConfig)
+	case c.summaryFile != "":
+		return fmt.Errorf("--summary-file doesn't support targets: %w", errConfig)
+	case c.cloudwatchEMF != "":
+		return fmt.Errorf("--cloudwatch-emf doesn't support targets: %w", errConfig)
+	case c.historyFile != "":
+		return fmt.Errorf("--history-file doesn't support targets: %w", errConfig)
+	case c.statusFile != "":
+		return fmt.Errorf("--status-file doesn't support targets: %w", errConfig)
+	}
+	for _, t := range c.targets {
+		if err := t.Err(); err != nil {
+			return fmt.Errorf("target %s: %w", t.target, err)
+		}
+	}
+	return nil
+}
+
+// scanTarget describes the keys the run matches.
+func (c *config) scanTarget() string {
+	if c.scanPatterns != "" {
+		return c.scanPatterns
+	}
+	return c.scanPrefix
+}
+
+// limiter returns the limiter allowing --rps commands per second, bursting
+// to a single command below 1, and any number of them with --rps=0.
+func (c *config) limiter() *rate.Limiter {
+	if c.rps == 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(c.rps), max(1, int(c.rps)))
+}
+
+func (c *config) circuitBreaker() *redisttl.CircuitBreaker {
+	if c.breakerThreshold == 0 {
+		return nil
+	}
+	return &redisttl.CircuitBreaker{
+		Threshold: c.breakerThreshold,
+		Window:    c.breakerWindow,
+		Pause:     c.breakerPause,
+		Probes:    c.breakerProbes,
+	}
+}
+
+func (c *config) retryPolicy() redisttl.RetryPolicy {
+	if c.retries == 0 {
+		return nil
+	}
+	return redisttl.Backoff{
+		Retries:  c.retries,
+		Delay:    c.retryDelay,
+		MaxDelay: c.retryMaxDelay,
+	}
+}
+
+func (c *config) memoryGuard() *redisttl.MemoryGuard {
+	if c.maxMemoryRatio == 0 {
+		return nil
+	}
+	return &redisttl.MemoryGuard{
+		Threshold:  c.maxMemoryRatio,
+		CheckEvery: c.memoryCheckEvery,
+		Pause:      c.memoryPause,
+	}
+}
+
+// valuePredicate returns the regexp values must match, from --value-match
+// or --value-prefix, nil when both are unset.
+func (c *config) valuePredicate() (*regexp.Regexp, error) {
+	switch {
+	case c.valuePrefix != "":
+		return regexp.MustCompile("^" + regexp.QuoteMeta(c.valuePrefix)), nil
+	case c.valueMatch != "":
+		return regexp.Compile(c.valueMatch)
+	}
+	return nil, nil
+}
+
+// scanWindow returns the window of --window, nil when unset.
+func (c *config) scanWindow() (*redisttl.Window, error) {
+	if c.window == "" {
+		return nil, nil
+	}
+	loc := time.Local
+	if c.windowTimezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(c.windowTimezone); err != nil {
+			return nil, err
+		}
+	}
+	return redisttl.ParseWindow(c.window, loc)
+}
+
+func (c *config) namespaceSummary() *redisttl.NamespaceSummary {
+	if c.summaryDepth == 0 {
+		return nil
+	}
+	return &redisttl.NamespaceSummary{Depth: c.summaryDepth, Separator: c.reportSeparator}
+}
+
+func (c *config) bigKeyDetector() *redisttl.BigKeyDetector {
+	if c.bigKeyBytes == 0 && c.bigKeyElements == 0 {
+		return nil
+	}
+	return &redisttl.BigKeyDetector{
+		MaxBytes:    c.bigKeyBytes,
+		MaxElements: c.bigKeyElements,
+	}
+}
+
+// logger returns the logger receiving the modified keys, nil for the
+// default text lines.
+func (c *config) logger() *slog.Logger {
+	if c.logFormat != "json" {
+		return nil
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, nil))
+}
+
+// notifier returns the notifiers configured for the whole run.
+func (c *config) notifier() redisttl.Notifier {
+	var n redisttl.Notifiers
+	if c.notifyURL != "" {
+		n = append(n, &redisttl.WebhookNotifier{
+			URL:    c.notifyURL,
+			Format: c.notifyFormat,
+			Nodes:  c.notifyNodes,
+		})
 	}
+	return n
+}
 
+// scanType is the --scan-type flag, where "any" stands for every type,
+// which SCAN doesn't filter.
+type scanType struct {
+	typ *string
+}
+
+func (t scanType) String() string {
+	if t.typ == nil || *t.typ == "" {
+		return "any"
+	}
+	return *t.typ
+}
+
+func (t scanType) Set(s string) error {
+	if s == "any" {
+		s = ""
+	}
+	*t.typ = s
 	return nil
 }
 
-// ttl is a custom type to simplify parsing a TTL duration
+// ttl is a custom type to simplify parsing a TTL duration.
+// On top of time.ParseDuration, it accepts day (d), week (w) and month (mo,
+// 30 days) units, possibly compounded (1w2d12h), and the "none"/"infinite"
+// sentinels meaning no expiry at all, see persistInfinite.
 type ttl struct {
-	dur time.Duration
+	dur      time.Duration
+	infinite bool
+}
+
+// persistInfinite switches exp mode to persist when the desired TTL is
+// infinite, a key without any expiry being what it asks for.
+func (c *config) persistInfinite() {
+	if c.desiredTTL.infinite && c.mode == "exp" {
+		c.mode = "persist"
+	}
 }
 
 func newTTL(d time.Duration) ttl {
@@ -61,7 +761,25 @@ func newTTL(d time.Duration) ttl {
 }
 
 func (d *ttl) MarshalText() ([]byte, error) {
-	return []byte(d.dur.String()), nil
+	return []byte(d.String()), nil
+}
+
+// ttlUnits are matched in order, so longer units sharing a prefix with a
+// shorter one come first.
+var ttlUnits = []struct {
+	suffix string
+	dur    time.Duration
+}{
+	{"mo", 30 * 24 * time.Hour},
+	{"ms", time.Millisecond},
+	{"ns", time.Nanosecond},
+	{"us", time.Microsecond},
+	{"µs", time.Microsecond},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
 }
 
 func (d *ttl) UnmarshalText(text []byte) error {
@@ -70,39 +788,68 @@ func (d *ttl) UnmarshalText(text []byte) error {
 	}
 
 	s := string(text)
-	dur, err := time.ParseDuration(s)
-	if err == nil {
-		d.dur = dur
+	switch strings.ToLower(s) {
+	case "none", "infinite":
+		*d = ttl{infinite: true}
 		return nil
 	}
 
-	suffix := s[len(s)-1:]
-
-	extras := map[string]time.Duration{
-		"d": time.Hour * 24,
-		"w": time.Hour * 24 * 7,
+	dur, err := time.ParseDuration(s)
+	if err == nil {
+		*d = ttl{dur: dur}
+		return nil
 	}
 
-	mul, found := extras[suffix]
-	if !found {
-		return fmt.Errorf("unknown duration suffix %s: %w", suffix, errTTL)
-	}
-	n, err := strconv.ParseUint(strings.TrimSuffix(s, suffix), 10, 64)
+	dur, err = parseCompoundDuration(s)
 	switch {
 	case err != nil:
 		return err
-	case n == 0:
+	case dur <= 0:
 		return fmt.Errorf("duration has to be greater than 0: %w", errTTL)
 	}
 
-	d.dur = time.Duration(n) * mul
+	*d = ttl{dur: dur}
 	return nil
 }
 
+func parseCompoundDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %s: %w", s, errTTL)
+		}
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, err
+		}
+		s = s[i:]
+
+		unit, found := time.Duration(0), false
+		for _, u := range ttlUnits {
+			if strings.HasPrefix(s, u.suffix) {
+				unit, found = u.dur, true
+				s = s[len(u.suffix):]
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown duration suffix %s: %w", s, errTTL)
+		}
+		total += time.Duration(n * float64(unit))
+	}
+	return total, nil
+}
+
 func (d *ttl) AsDuration() time.Duration {
 	return d.dur
 }
 
 func (d *ttl) String() string {
+	if d.infinite {
+		return "none"
+	}
 	return d.dur.String()
 }
@@ -4,12 +4,20 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 func TestValidTTL(t *testing.T) {
 	m := map[string]time.Duration{
-		"1h": time.Hour,
-		"1w": time.Hour * 24 * 7,
+		"1h":       time.Hour,
+		"1w":       time.Hour * 24 * 7,
+		"1w2d12h":  time.Hour * (24*9 + 12),
+		"2mo":      time.Hour * 24 * 60,
+		"1d30m":    time.Hour*24 + 30*time.Minute,
+		"1.5d":     time.Hour * 36,
+		"1mo1ms":   time.Hour*24*30 + time.Millisecond,
+		"3d1h2m3s": time.Hour*73 + 2*time.Minute + 3*time.Second,
 	}
 	for k, v := range m {
 		dur := ttl{}
@@ -24,11 +32,13 @@ func TestValidTTL(t *testing.T) {
 
 func TestInvalidTTL(t *testing.T) {
 	input := []string{
-		"1w2h", // only simple suffixes are supported for days and weeks
-		"1y",   // year is not supported
+		"1y",  // year is not supported
+		"1w2", // missing unit
 		"",
 		"0w",   // a ttl can't be 0
 		"foow", // non numerical prefix
+		"w",    // missing value
+		"0d0h", // a ttl can't be 0
 	}
 	for _, s := range input {
 		dur := ttl{}
@@ -38,6 +48,34 @@ func TestInvalidTTL(t *testing.T) {
 	}
 }
 
+func TestInfiniteTTL(t *testing.T) {
+	for _, s := range []string{"none", "infinite", "NONE"} {
+		dur := ttl{}
+		if err := dur.UnmarshalText([]byte(s)); err != nil {
+			t.Fatalf("unexpected error for ttl %s: %v", s, err)
+		}
+		if !dur.infinite || dur.String() != "none" {
+			t.Fatalf("expected an infinite ttl for %s, got: %s", s, dur.String())
+		}
+	}
+
+	persist := config{mode: "persist", rps: 1, redisAddr: ":6379", desiredTTL: ttl{infinite: true}}
+	if err := persist.Err(); err != nil {
+		t.Fatalf("persist should accept an infinite ttl, got: %v", err)
+	}
+	exp, err := parseConfig([]string{"redis-ttl", "--mode=exp", "--desired-ttl=none"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exp.mode != "persist" {
+		t.Fatalf("want persist mode for an infinite ttl, got: %s", exp.mode)
+	}
+	lt := config{mode: "lt", rps: 1, redisAddr: ":6379", desiredTTL: ttl{infinite: true}}
+	if err := lt.Err(); !errors.Is(err, errTTL) {
+		t.Fatalf("want: %v got: %v", errTTL, err)
+	}
+}
+
 func TestRoundTrip(t *testing.T) {
 	input := []string{
 		"1h1m0s",
@@ -80,10 +118,414 @@ func TestConfig(t *testing.T) {
 			},
 			err: errTTL,
 		},
-		"can't set rps to 0": {
-			cfg: config{rps: 0, mode: "persist"},
+		"unknown mode": {
+			cfg: config{mode: "expire", rps: 1, redisAddr: ":6379", desiredTTL: newTTL(time.Hour)},
+			err: errMode,
+		},
+		"batch script needs a mode setting the desired ttl": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", batchScript: true},
+			err: errMode,
+		},
+		"transaction needs a mode sending a command per key": {
+			cfg: config{mode: "percent", rps: 1, redisAddr: ":6379", ttlPercent: 50, transaction: true},
+			err: errMode,
+		},
+		"transaction and batch script": {
+			cfg: config{mode: "exp", rps: 1, redisAddr: ":6379", desiredTTL: newTTL(time.Hour), transaction: true, batchScript: true},
+			err: errMode,
+		},
+		"transaction with a tolerance": {
+			cfg: config{mode: "exp", rps: 1, redisAddr: ":6379", desiredTTL: newTTL(time.Hour), transaction: true, tolerance: time.Minute},
+			err: errMode,
+		},
+		"batch script verifying writes": {
+			cfg: config{mode: "exp", rps: 1, redisAddr: ":6379", desiredTTL: newTTL(time.Hour), batchScript: true, verifyWrites: true},
+			err: errMode,
+		},
+		"follow needs a mode giving keys a ttl": {
+			cfg: config{mode: "extend", rps: 1, redisAddr: ":6379", ttlDelta: time.Hour, follow: true},
+			err: errMode,
+		},
+		"follow doesn't run in a loop": {
+			cfg: config{mode: "exp", rps: 1, redisAddr: ":6379", desiredTTL: newTTL(time.Hour), follow: true, interval: time.Hour},
+			err: errDaemon,
+		},
+		"enqueue needs a batch size": {
+			cfg: config{mode: "noop", rps: 1, redisAddr: ":6379", enqueue: true, queueStream: "jobs", queueGroup: "redis-ttl"},
+			err: errQueue,
+		},
+		"inherit mode needs a source key template": {
+			cfg: config{mode: "inherit", rps: 1, redisAddr: ":6379", sourceKey: "meta"},
+			err: errTTL,
+		},
+		"companions need a key template": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", companions: "{key}:idx,lock"},
+			err: errTTL,
+		},
+		"percent mode needs a percentage": {
+			cfg: config{mode: "percent", rps: 1, redisAddr: ":6379"},
+			err: errTTL,
+		},
+		"extend mode needs a delta": {
+			cfg: config{mode: "extend", rps: 1, redisAddr: ":6379"},
+			err: errTTL,
+		},
+		"tolerance can't be negative": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", tolerance: -time.Minute},
+			err: errTTL,
+		},
+		"fractional rps": {
+			cfg: config{rps: 0.5, mode: "persist", redisAddr: ":6379"},
+			err: nil,
+		},
+		"rps of 0 disables the limiter": {
+			cfg: config{rps: 0, mode: "persist", redisAddr: ":6379"},
+			err: nil,
+		},
+		"can't set a negative rps": {
+			cfg: config{rps: -1, mode: "persist"},
+			err: errRPS,
+		},
+		"breaker threshold can't exceed 1": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", breakerThreshold: 2, breakerWindow: 10, breakerPause: time.Second},
+			err: errBreaker,
+		},
+		"breaker needs a window": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", breakerThreshold: 0.5, breakerPause: time.Second},
+			err: errBreaker,
+		},
+		"change stream addr requires a change stream": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", changeStreamAddr: "ops:6379"},
+			err: errStream,
+		},
+		"failover retries can't be negative": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", failoverRetries: -1},
+			err: errNodes,
+		},
+		"key size sampling can't be negative": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", keySizeEvery: -1},
+			err: errBigKeys,
+		},
+		"introspect sample rate can't exceed 1": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", introspectSampleRate: 1.5},
+			err: errIntrospect,
+		},
+		"universal addrs exclude the cluster flags": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", universalAddrs: "a:6379,b:6379", redisClusterAddrs: "a:6379"},
+			err: errNodes,
+		},
+		"universal cluster cursor file needs a node placeholder": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", universalAddrs: "a:6379,b:6379", cursorFile: "cursor.json"},
+			err: errWindow,
+		},
+		"retries can't be negative": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", retries: -1},
+			err: errRetry,
+		},
+		"cloudwatch emf needs a namespace": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", cloudwatchEMF: "-", cloudwatchInterval: time.Minute},
+			err: errCloudWatch,
+		},
+		"benchmark needs a duration": {
+			cfg: config{mode: "benchmark", rps: 1, redisAddr: ":6379"},
+			err: errBenchmark,
+		},
+		"apply percent can't exceed 100": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", applyPercent: 150},
+			err: errSample,
+		},
+		"unknown log format": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", logFormat: "logfmt"},
+			err: errLogFormat,
+		},
+		"interval can't be negative": {
+			cfg: config{mode: "persist", rps: 1, redisAddr: ":6379", interval: -time.Second},
+			err: errDaemon,
+		},
+		"memory ratio can't exceed 1": {
+			cfg: config{
+				mode:           "persist",
+				rps:            1,
+				redisAddr:      ":6379",
+				maxMemoryRatio: 1.5,
+			},
+			err: errMemory,
+		},
+		"cluster addrs and config endpoint are exclusive": {
+			cfg: config{
+				mode:                  "persist",
+				rps:                   1,
+				redisClusterAddrs:     "node1:6379",
+				clusterConfigEndpoint: "cfg.example.com:6379",
+			},
+			err: errNodes,
+		},
+		"until converged requires a mode modifying keys": {
+			cfg: config{
+				mode:           "audit",
+				rps:            1,
+				redisAddr:      "localhost:6379",
+				untilConverged: true,
+			},
+			err: errPasses,
+		},
+		"until converged and interval are exclusive": {
+			cfg: config{
+				mode:           "persist",
+				rps:            1,
+				redisAddr:      "localhost:6379",
+				untilConverged: true,
+				interval:       time.Minute,
+			},
+			err: errPasses,
+		},
+		"invalid value regexp": {
+			cfg: config{
+				mode:       "persist",
+				rps:        1,
+				redisAddr:  "localhost:6379",
+				valueMatch: "^(",
+			},
+			err: errPredicate,
+		},
+		"value match and prefix are exclusive": {
+			cfg: config{
+				mode:        "persist",
+				rps:         1,
+				redisAddr:   "localhost:6379",
+				valueMatch:  "^$",
+				valuePrefix: "tombstone",
+			},
+			err: errPredicate,
+		},
+		"hash value requires a hash field": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				hashValue: "archived",
+			},
+			err: errPredicate,
+		},
+		"hash field requires hashes": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				scanType:  "string",
+				hashField: "status",
+			},
+			err: errPredicate,
+		},
+		"hash field and value match are exclusive": {
+			cfg: config{
+				mode:       "persist",
+				rps:        1,
+				redisAddr:  "localhost:6379",
+				scanType:   "hash",
+				hashField:  "status",
+				hashValue:  "archived",
+				valueMatch: "^archived$",
+			},
+			err: errPredicate,
+		},
+		"invalid window": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				window:    "01:00",
+			},
+			err: errWindow,
+		},
+		"unknown window timezone": {
+			cfg: config{
+				mode:           "persist",
+				rps:            1,
+				redisAddr:      "localhost:6379",
+				window:         "01:00-05:00",
+				windowTimezone: "Mars/Olympus_Mons",
+			},
+			err: errWindow,
+		},
+		"cluster cursor file without node": {
+			cfg: config{
+				mode:              "persist",
+				rps:               1,
+				redisClusterAddrs: "node1:6379",
+				cursorFile:        "/tmp/cursor.json",
+			},
+			err: errWindow,
+		},
+		"summary file without interval": {
+			cfg: config{
+				mode:        "persist",
+				rps:         1,
+				redisAddr:   "localhost:6379",
+				summaryFile: "/tmp/summary.json",
+			},
+			err: errSummary,
+		},
+		"several password sources": {
+			cfg: config{
+				mode:              "persist",
+				rps:               1,
+				redisAddr:         "localhost:6379",
+				redisPasswordFile: "/run/secrets/redis",
+				redisAWSSecret:    "prod/redis",
+			},
+			err: errAuth,
+		},
+		"invalid socks5 proxy": {
+			cfg: config{
+				mode:        "persist",
+				rps:         1,
+				redisAddr:   "localhost:6379",
+				socks5Proxy: "localhost:1080",
+			},
+			err: errProxy,
+		},
+		"addr map entries are announced=reachable": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				addrMap:   "172.18.0.2:6379=localhost:7000,172.18.0.3:6379",
+			},
+			err: errNodes,
+		},
+		"negative zscore unit": {
+			cfg: config{
+				mode:       "ztrim",
+				rps:        1,
+				redisAddr:  "localhost:6379",
+				desiredTTL: ttl{dur: time.Hour},
+				scanType:   "zset",
+				zscoreUnit: -time.Second,
+			},
+			err: errTTL,
+		},
+		"negative jitter": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				jitter:    -time.Millisecond,
+			},
 			err: errRPS,
 		},
+		"chunk size requires a pause": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				chunkSize: 100,
+			},
+			err: errRPS,
+		},
+		"copy mode requires a destination": {
+			cfg: config{
+				mode:      "copy",
+				rps:       1,
+				redisAddr: "localhost:6379",
+			},
+			err: errMode,
+		},
+		"copy flags require copy mode": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				copyAddr:  "localhost:6380",
+			},
+			err: errMode,
+		},
+		"ztrim mode scans sorted sets": {
+			cfg: config{
+				mode:       "ztrim",
+				rps:        1,
+				redisAddr:  "localhost:6379",
+				desiredTTL: ttl{dur: time.Hour},
+				scanType:   "string",
+			},
+			err: errMode,
+		},
+		"topology requires a cluster": {
+			cfg: config{
+				mode:      "noop",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				topology:  true,
+			},
+			err: errNodes,
+		},
+		"prefer hostnames requires a cluster": {
+			cfg: config{
+				mode:            "persist",
+				rps:             1,
+				redisAddr:       "localhost:6379",
+				preferHostnames: true,
+			},
+			err: errNodes,
+		},
+		"start node requires a cluster": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				startNode: "node1:6379",
+			},
+			err: errNodes,
+		},
+		"scan addr requires write addr": {
+			cfg: config{
+				mode:      "persist",
+				rps:       1,
+				redisAddr: "localhost:6379",
+				scanAddr:  "replica:6379",
+			},
+			err: errNodes,
+		},
+		"scan and write addrs exclude sentinel": {
+			cfg: config{
+				mode:           "persist",
+				rps:            1,
+				redisAddr:      "localhost:6379",
+				scanAddr:       "replica:6379",
+				writeAddr:      "primary:6379",
+				sentinelAddrs:  "sentinel1:26379",
+				sentinelMaster: "mymaster",
+			},
+			err: errNodes,
+		},
+		"sentinel master requires sentinel addrs": {
+			cfg: config{
+				mode:           "persist",
+				rps:            1,
+				redisAddr:      "localhost:6379",
+				sentinelMaster: "mymaster",
+			},
+			err: errNodes,
+		},
+		"sentinel excludes cluster": {
+			cfg: config{
+				mode:              "persist",
+				rps:               1,
+				redisClusterAddrs: "node1:6379",
+				sentinelAddrs:     "sentinel1:26379",
+				sentinelMaster:    "mymaster",
+			},
+			err: errNodes,
+		},
+		"sentinel scan replica requires sentinel addrs": {
+			cfg: config{
+				mode:                "persist",
+				rps:                 1,
+				redisAddr:           "localhost:6379",
+				sentinelScanReplica: true,
+			},
+			err: errNodes,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -95,3 +537,21 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLimiter(t *testing.T) {
+	testCases := map[float64]int{
+		0.5: 1,
+		1:   1,
+		2.5: 2,
+		100: 100,
+	}
+	if l := (&config{}).limiter(); l.Limit() != rate.Inf {
+		t.Fatalf("want no limit with an rps of 0, got %g", l.Limit())
+	}
+	for rps, burst := range testCases {
+		l := (&config{rps: rps}).limiter()
+		if float64(l.Limit()) != rps || l.Burst() != burst {
+			t.Fatalf("rps %g: want a limit of %g and a burst of %d, got %g and %d", rps, rps, burst, l.Limit(), l.Burst())
+		}
+	}
+}
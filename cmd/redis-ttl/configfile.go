@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// configFile is a --config file: settings, one name=value per line, e.g.
+// "mode=exp" or "--desired-ttl=1w". Blank lines and lines starting with #
+// are ignored, a boolean flag may be listed without a value, and a flag
+// listed several times, like --ttl-rule, is set once per line.
+//
+// A "[name]" line starts the section of a target, a Redis deployment run
+// with the settings before the first section overridden by its own.
+type configFile struct {
+	path     string
+	settings []setting
+	targets  []configTarget
+}
+
+type configTarget struct {
+	name     string
+	settings []setting
+}
+
+type setting struct {
+	line        int
+	name, value string
+}
+
+func readConfigFile(path string) (*configFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file := &configFile{path: path}
+	settings := &file.settings
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" || file.target(name) != nil {
+				return nil, fmt.Errorf("%s:%d: empty or duplicate target %q: %w", path, n, name, errConfig)
+			}
+			file.targets = append(file.targets, configTarget{name: name})
+			settings = &file.targets[len(file.targets)-1].settings
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimLeft(line, "-"), "=")
+		if !found {
+			value = "true"
+		}
+		*settings = append(*settings, setting{line: n, name: strings.TrimSpace(name), value: strings.TrimSpace(value)})
+	}
+	return file, sc.Err()
+}
+
+func (c *configFile) target(name string) *configTarget {
+	for i := range c.targets {
+		if c.targets[i].name == name {
+			return &c.targets[i]
+		}
+	}
+	return nil
+}
+
+// resetter is implemented by the repeatable flags.
+type resetter interface {
+	reset()
+}
+
+// apply sets the flags of fs listed in settings. Unless override is true,
+// flags set on the command line take precedence. With override, repeatable
+// flags listed in settings lose their previous values.
+func (c *configFile) apply(fs *flag.FlagSet, settings []setting, override bool) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	seen := map[string]bool{}
+	for _, s := range settings {
+		f := fs.Lookup(s.name)
+		if f == nil {
+			return fmt.Errorf("%s:%d: unknown setting %s: %w", c.path, s.line, s.name, errConfig)
+		}
+		if explicit[s.name] && !override {
+			continue
+		}
+		if r, ok := f.Value.(resetter); ok && override && !seen[s.name] {
+			r.reset()
+		}
+		seen[s.name] = true
+		if err := fs.Set(s.name, s.value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", c.path, s.line, s.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// confirmExamples is the number of matched keys shown before asking for
+// confirmation.
+const confirmExamples = 10
+
+// readOnlyModes lists the modes that never modify keys.
+var readOnlyModes = map[string]bool{
+	"noop":      true,
+	"report":    true,
+	"audit":     true,
+	"verify":    true,
+	"discover":  true,
+	"benchmark": true,
+}
+
+// confirm guards the runs modifying keys. Unless --force is set, it refuses
+// to run against every key, and estimates how many keys the run would modify
+// to refuse matching more than --max-match-ratio of the keyspace. Above
+// --confirm-above keys, it then shows a sample of them with the intended
+// action and waits for "yes" to be typed on in, unless --yes is set. Dry
+// runs, previews, estimates, queue jobs and read-only modes skip it.
+func confirm(ctx context.Context, cfg *config, in io.Reader, out io.Writer) error {
+	switch {
+	case cfg.dryRun || cfg.estimate || cfg.preview > 0 || cfg.enqueue || cfg.work || readOnlyModes[cfg.mode]:
+		return nil
+	case !cfg.force && matchesEverything(cfg):
+		return fmt.Errorf("refusing to run %s mode on every key with %q, pass --force to proceed: %w",
+			cfg.mode, cfg.scanTarget(), errAborted)
+	case cfg.force && cfg.yes:
+		return nil
+	}
+
+	m, err := estimateMatches(ctx, cfg)
+	switch {
+	case err != nil:
+		return err
+	case m.keys <= cfg.confirmAbove:
+		return nil
+	case !cfg.force && cfg.maxMatchRatio > 0 && m.dbSize > 0 && float64(m.keys)/float64(m.dbSize) > cfg.maxMatchRatio:
+		return fmt.Errorf("refusing to run %s mode on ~%d of %d keys matching %q, pass --force to proceed: %w",
+			cfg.mode, m.keys, m.dbSize, cfg.scanTarget(), errAborted)
+	case cfg.yes:
+		return nil
+	}
+
+	fmt.Fprintf(out, "%s on ~%d keys matching %q, e.g.:\n", describeAction(cfg), m.keys, cfg.scanTarget())
+	for _, key := range m.examples[:min(len(m.examples), confirmExamples)] {
+		fmt.Fprintf(out, "  %s\n", key)
+	}
+	fmt.Fprint(out, `Type "yes" to proceed: `)
+
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		return fmt.Errorf("not confirmed, pass --yes to skip the confirmation: %w", errAborted)
+	}
+	return nil
+}
+
+// matchesEverything reports whether the run matches any key.
+func matchesEverything(cfg *config) bool {
+	if patterns := splitList(cfg.scanPatterns); len(patterns) > 0 {
+		return slices.ContainsFunc(patterns, matchesAnything)
+	}
+	return matchesAnything(cfg.scanPrefix)
+}
+
+// matchesAnything reports whether the SCAN MATCH pattern matches any key.
+func matchesAnything(pattern string) bool {
+	return strings.Trim(pattern, "*") == ""
+}
+
+// matches is the estimated number of keys a run would process, over all
+// the nodes.
+type matches struct {
+	keys     int64
+	dbSize   int64
+	examples []string
+}
+
+func estimateMatches(ctx context.Context, cfg *config) (matches, error) {
+	est := *cfg
+	est.estimate = true
+	estimates := &redisttl.Estimates{}
+	if err := execute(ctx, &est, &collectors{estimates: estimates}); err != nil {
+		return matches{}, fmt.Errorf("estimate matched keys: %w", err)
+	}
+	var m matches
+	for _, x := range estimates.All() {
+		m.keys += x.Keys
+		m.dbSize += x.DBSize
+		m.examples = append(m.examples, x.Examples...)
+	}
+	return m, nil
+}
+
+// describeAction returns what the mode does to every matched key.
+func describeAction(cfg *config) string {
+	switch {
+	case cfg.mode == "persist":
+		return "remove the ttl"
+	case cfg.mode == "percent":
+		return fmt.Sprintf("set the ttl to %v%% of the current one", cfg.ttlPercent)
+	case cfg.mode == "extend" || cfg.mode == "shrink":
+		return fmt.Sprintf("%s the ttl by %s", cfg.mode, cfg.ttlDelta)
+	case cfg.mode == "inherit":
+		return "copy the ttl of " + cfg.sourceKey
+	case len(cfg.ttlRules) > 0:
+		return fmt.Sprintf("apply %s mode with the ttls of %d --ttl-rule", cfg.mode, len(cfg.ttlRules))
+	}
+	return fmt.Sprintf("apply %s mode with a ttl of %s", cfg.mode, &cfg.desiredTTL)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestConfirm(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 20; i++ {
+		_ = s.Set("foo:"+strconv.Itoa(i), "bar")
+		_ = s.Set("zoo:"+strconv.Itoa(i), "bar")
+	}
+
+	testCases := []struct {
+		args   []string
+		answer string
+		asked  bool
+		err    error
+	}{
+		{args: []string{"--confirm-above=100"}},
+		{args: []string{"--confirm-above=10"}, answer: "yes\n", asked: true},
+		{args: []string{"--confirm-above=10"}, answer: "y\n", asked: true, err: errAborted},
+		// no terminal attached
+		{args: []string{"--confirm-above=10"}, asked: true, err: errAborted},
+		{args: []string{"--confirm-above=10", "--yes"}},
+		{args: []string{"--confirm-above=10", "--dry-run"}},
+		{args: []string{"--confirm-above=10", "--mode=audit"}},
+		// safety interlock
+		{args: []string{"--confirm-above=10", "--scan-prefix=*", "--yes"}, err: errAborted},
+		{args: []string{"--confirm-above=10", "--scan-prefix=*", "--yes", "--force"}},
+		{args: []string{"--confirm-above=10", "--max-match-ratio=0.4", "--yes"}, err: errAborted},
+		{args: []string{"--confirm-above=10", "--max-match-ratio=0.4", "--force"}, answer: "yes\n", asked: true},
+		{args: []string{"--confirm-above=100", "--max-match-ratio=0.4"}},
+		{args: []string{"--confirm-above=10", "--scan-patterns=foo:*,*", "--yes"}, err: errAborted},
+		{args: []string{"--confirm-above=10", "--scan-patterns=foo:*,zoo:*", "--max-match-ratio=0.9", "--yes", "--force"}},
+		{args: []string{"--confirm-above=30", "--scan-patterns=foo:*,zoo:*", "--max-match-ratio=0.9", "--yes"}, err: errAborted},
+	}
+	for _, tc := range testCases {
+		args := append([]string{
+			"redis-ttl", "--mode=exp", "--desired-ttl=1h", "--scan-prefix=foo:*", "--redis-addr=" + s.Addr(),
+		}, tc.args...)
+		cfg, err := parseConfig(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		err = confirm(context.Background(), &cfg, strings.NewReader(tc.answer), &out)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%v: want error %v, got %v", tc.args, tc.err, err)
+		}
+		if asked := out.Len() > 0; asked != tc.asked {
+			t.Fatalf("%v: want asked=%v, got:\n%s", tc.args, tc.asked, out.String())
+		}
+		if tc.asked && (!strings.Contains(out.String(), "apply exp mode with a ttl of 1h0m0s on ~20 keys") ||
+			strings.Count(out.String(), "  foo:") != confirmExamples) {
+			t.Fatalf("%v: unexpected preview:\n%s", tc.args, out.String())
+		}
+	}
+	if ttl := s.TTL("foo:1"); ttl != 0 {
+		t.Fatalf("confirm should not modify keys, got ttl: %v", ttl)
+	}
+}
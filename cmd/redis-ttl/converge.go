@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// converge repeats the run, cfg.convergePause apart, until a pass modifies
+// no key, e.g. while the application keeps writing keys without a TTL after
+// a cutover. Running more than cfg.maxPasses passes, unless 0, is an error.
+func converge(ctx context.Context, cfg *config, out *collectors) error {
+	for pass := 1; ; pass++ {
+		before := out.summary.Modified.Load()
+		if err := runOnce(ctx, cfg, out); err != nil {
+			return fmt.Errorf("pass %d: %w", pass, err)
+		}
+		modified := out.summary.Modified.Load() - before
+		if modified == 0 {
+			log.Printf("converged after %d passes\n", pass)
+			return nil
+		}
+		log.Printf("pass %d modified %d keys\n", pass, modified)
+		if pass == cfg.maxPasses {
+			return fmt.Errorf("still modifying keys after %d passes: %w", pass, errConverge)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.convergePause):
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestConverge(t *testing.T) {
+	testCases := map[string]struct {
+		mode      string
+		maxPasses int
+		err       error
+		scanned   int64
+	}{
+		"stops after a pass modifying nothing": {
+			mode:      "nx",
+			maxPasses: 10,
+			scanned:   4,
+		},
+		"gives up after max passes": {
+			mode:      "extend",
+			maxPasses: 3,
+			err:       errConverge,
+			scanned:   6,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("foo", "bar")
+			_ = s.Set("far", "bar")
+			s.SetTTL("far", time.Minute)
+
+			cfg := defaultConfig
+			cfg.redisAddr = s.Addr()
+			cfg.mode = tc.mode
+			cfg.scanPrefix = "f*"
+			cfg.desiredTTL = newTTL(time.Hour)
+			cfg.ttlDelta = time.Hour
+			cfg.maxPasses = tc.maxPasses
+
+			out := &collectors{summary: &redisttl.Summary{}, health: &health{}}
+			if err := converge(context.Background(), &cfg, out); !errors.Is(err, tc.err) {
+				t.Fatalf("want: %v got: %v", tc.err, err)
+			}
+			if got := out.summary.Scanned.Load(); got != tc.scanned {
+				t.Fatalf("want %d keys scanned, got %d", tc.scanned, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyCounts records the keys counted on every node before and after a run
+// with --compare-counts, confirming its effect on the keyspace. It is safe
+// for concurrent use.
+type keyCounts struct {
+	cfg   *config
+	mu    sync.Mutex
+	nodes map[string]*nodeCounts
+}
+
+type nodeCounts struct {
+	before, after redisttl.KeyCount
+	// done is false until the keys were counted after the run.
+	done bool
+}
+
+func newKeyCounts(cfg *config) *keyCounts {
+	if !cfg.compareCounts {
+		return nil
+	}
+	return &keyCounts{cfg: cfg, nodes: map[string]*nodeCounts{}}
+}
+
+func (k *keyCounts) counter(c redis.Cmdable) *redisttl.KeyCounter {
+	return &redisttl.KeyCounter{
+		Client:       c,
+		ScanPrefix:   k.cfg.scanPrefix,
+		ScanPatterns: splitList(k.cfg.scanPatterns),
+		ScanType:     k.cfg.scanType,
+		ScanCount:    k.cfg.scanCount,
+		Limiter:      k.cfg.limiter(),
+	}
+}
+
+// before counts the keys of the node at addr through c, and returns the func
+// counting them again once the run is done. Failures are logged, the run
+// keeping on without the node's counts.
+func (k *keyCounts) before(ctx context.Context, addr string, c redis.Cmdable) (after func(context.Context)) {
+	if k == nil {
+		return func(context.Context) {}
+	}
+	before, err := k.counter(c).Count(ctx)
+	if err != nil {
+		log.Printf("node %s: count keys before the run: %v\n", addr, err)
+		return func(context.Context) {}
+	}
+	log.Printf("node %s: %d keys matched before the run, %d without ttl\n", addr, before.Matched, before.WithoutTTL)
+	k.mu.Lock()
+	k.nodes[addr] = &nodeCounts{before: before}
+	k.mu.Unlock()
+
+	return func(ctx context.Context) {
+		// count even when the run was interrupted, to see what it did
+		after, err := k.counter(c).Count(context.WithoutCancel(ctx))
+		if err != nil {
+			log.Printf("node %s: count keys after the run: %v\n", addr, err)
+			return
+		}
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		n := k.nodes[addr]
+		n.after, n.done = after, true
+	}
+}
+
+func (k *keyCounts) len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.nodes)
+}
+
+// print prints the counts of every node before and after the run, with
+// their total across nodes.
+func (k *keyCounts) print(w io.Writer) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	addrs := make([]string, 0, len(k.nodes))
+	for addr := range k.nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tDBSIZE\tMATCHED\tWITHOUT TTL")
+	var total nodeCounts
+	total.done = true
+	for _, addr := range addrs {
+		n := k.nodes[addr]
+		printCounts(tw, addr, *n)
+		total.before = total.before.Add(n.before)
+		total.after = total.after.Add(n.after)
+		total.done = total.done && n.done
+	}
+	if len(addrs) > 1 {
+		printCounts(tw, "total", total)
+	}
+	return tw.Flush()
+}
+
+// printCounts prints the counts of n as "before -> after (delta)", only the
+// ones before when they weren't counted after.
+func printCounts(w io.Writer, name string, n nodeCounts) {
+	cell := func(before, after, delta int64) string {
+		if !n.done {
+			return fmt.Sprintf("%d -> ?", before)
+		}
+		return fmt.Sprintf("%d -> %d (%+d)", before, after, delta)
+	}
+	delta := n.after.Sub(n.before)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name,
+		cell(n.before.DBSize, n.after.DBSize, delta.DBSize),
+		cell(n.before.Matched, n.after.Matched, delta.Matched),
+		cell(n.before.WithoutTTL, n.after.WithoutTTL, delta.WithoutTTL))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestKeyCounts(t *testing.T) {
+	if newKeyCounts(&config{}) != nil {
+		t.Fatal("want no counts without --compare-counts")
+	}
+
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:2", "bar")
+	_ = s.Set("zoo:1", "bar")
+
+	cfg := &config{compareCounts: true, scanPrefix: "foo:*", scanCount: 10, rps: 100}
+	counts := newKeyCounts(cfg)
+	c := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer c.Close()
+	after := counts.before(context.Background(), s.Addr(), c)
+	_ = c.Expire(context.Background(), "foo:1", time.Hour).Err()
+	_ = c.Del(context.Background(), "foo:2").Err()
+	after(context.Background())
+
+	n := counts.nodes[s.Addr()]
+	if want := (redisttl.KeyCount{DBSize: 3, Matched: 2, WithoutTTL: 2}); n.before != want {
+		t.Fatalf("want before: %+v got: %+v", want, n.before)
+	}
+	if want := (redisttl.KeyCount{DBSize: 2, Matched: 1, WithoutTTL: 0}); n.after != want || !n.done {
+		t.Fatalf("want after: %+v got: %+v", want, n.after)
+	}
+
+	var buf bytes.Buffer
+	if err := counts.print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "3 -> 2 (-1)  2 -> 1 (-1)  2 -> 0 (-2)"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("want %q in:\n%s", want, buf.String())
+	}
+}
+
+func TestRunCompareCounts(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--compare-counts",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+}
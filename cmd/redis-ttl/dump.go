@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+	"golang.org/x/time/rate"
+)
+
+// activeNodes tracks the nodes being processed, for dumpState. It is safe
+// for concurrent use.
+type activeNodes struct {
+	mu    sync.Mutex
+	nodes map[runner]activeNode
+	// cursors are the cursors of the scanners once removed, by node.
+	cursors map[string]uint64
+}
+
+type activeNode struct {
+	addr    string
+	started time.Time
+}
+
+func (a *activeNodes) add(addr string, r runner) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.nodes == nil {
+		a.nodes = map[runner]activeNode{}
+	}
+	a.nodes[r] = activeNode{addr: addr, started: time.Now()}
+}
+
+func (a *activeNodes) remove(r runner) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := r.(*redisttl.Scanner); ok {
+		if a.cursors == nil {
+			a.cursors = map[string]uint64{}
+		}
+		a.cursors[a.nodes[r].addr] = s.Stats().Cursor
+	}
+	delete(a.nodes, r)
+}
+
+// lastCursors returns the cursor of every node scanned, the latest one of
+// the scanners still running, 0 for the nodes scanned to the end.
+func (a *activeNodes) lastCursors() map[string]uint64 {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cursors := make(map[string]uint64, len(a.cursors)+len(a.nodes))
+	for addr, cursor := range a.cursors {
+		cursors[addr] = cursor
+	}
+	for r, n := range a.nodes {
+		if s, ok := r.(*redisttl.Scanner); ok {
+			cursors[n.addr] = s.Stats().Cursor
+		}
+	}
+	return cursors
+}
+
+// dumpState writes the state of the run to w: the summary, then the cursor,
+// counters, key in flight and limiter of every node being processed.
+func dumpState(w io.Writer, out *collectors) {
+	fmt.Fprintf(w, "redis-ttl state at %s\nsummary: %s\n", time.Now().Format(time.RFC3339), out.summary)
+
+	out.active.mu.Lock()
+	defer out.active.mu.Unlock()
+	lines := make([]string, 0, len(out.active.nodes))
+	for r, n := range out.active.nodes {
+		line := fmt.Sprintf("node=%s running=%s", n.addr, time.Since(n.started).Round(time.Second))
+		if s, ok := r.(*redisttl.Scanner); ok {
+			stats := s.Stats()
+			line += fmt.Sprintf(" cursor=%d scanned=%d modified=%d errors=%d key=%q",
+				stats.Cursor, stats.Scanned, stats.Modified, stats.Errors, stats.Key)
+			if l, ok := s.Limiter.(*rate.Limiter); ok {
+				line += fmt.Sprintf(" limit=%v/s tokens=%.1f", float64(l.Limit()), l.Tokens())
+			}
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// dumpOnSIGQUIT writes the state of the run to w on every SIGQUIT, instead
+// of the runtime's default of dumping the goroutines and exiting, until the
+// returned func is called.
+func dumpOnSIGQUIT(w io.Writer, out *collectors) func() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGQUIT)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-c:
+				dumpState(w, out)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
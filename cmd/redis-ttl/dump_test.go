@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+	"golang.org/x/time/rate"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDumpState(t *testing.T) {
+	out := &collectors{summary: &redisttl.Summary{}, active: &activeNodes{}}
+	s := &redisttl.Scanner{Limiter: rate.NewLimiter(10, 10)}
+	out.active.add("b:6379", s)
+	out.active.add("a:6379", &redisttl.Discoverer{})
+
+	var buf syncBuffer
+	stop := dumpOnSIGQUIT(&buf, out)
+	defer stop()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGQUIT); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "b:6379") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "summary: scanned=0") {
+		t.Fatalf("unexpected dump:\n%s", buf.String())
+	}
+	if lines[2] != "node=a:6379 running=0s" {
+		t.Fatalf("unexpected node line: %s", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], `node=b:6379 running=0s cursor=0 scanned=0 modified=0 errors=0 key="" limit=10/s tokens=10.0`) {
+		t.Fatalf("unexpected node line: %s", lines[3])
+	}
+
+	out.active.remove(s)
+	dumpState(&buf, out)
+	if strings.Count(buf.String(), "b:6379") != 1 {
+		t.Fatalf("finished nodes should not be dumped:\n%s", buf.String())
+	}
+}
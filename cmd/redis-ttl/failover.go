@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// shardFailover resumes the runs of the primaries of a cluster on the node
+// promoted when one fails over, finding it through the slots it served.
+type shardFailover struct {
+	cfg   *config
+	seeds []string
+	// slots maps the address of every primary to the first slot it served
+	// when the run started.
+	slots map[string]int
+
+	mu      sync.Mutex
+	clients []*redis.Client
+}
+
+// newShardFailover returns the shardFailover of the primaries of topology,
+// discovered again through seeds and every node of topology, nil with
+// --failover-retries=0.
+func newShardFailover(cfg *config, seeds []string, topology redisttl.ClusterTopology) *shardFailover {
+	if cfg.failoverRetries == 0 {
+		return nil
+	}
+	sf := &shardFailover{cfg: cfg, seeds: seeds, slots: map[string]int{}}
+	for _, n := range topology.Nodes {
+		addr := n.DialAddr(cfg.preferHostnames)
+		sf.seeds = append(sf.seeds, addr)
+		if n.Role == redisttl.RolePrimary && len(n.Slots) > 0 {
+			sf.slots[addr] = n.Slots[0].Start
+		}
+	}
+	return sf
+}
+
+// attach resumes r, the run of the primary at addr, on its new primary, with
+// connect returning the clients r then sends its commands through.
+func (sf *shardFailover) attach(r runner, addr string, connect func(c *redis.Client) (client, scanClient redis.Cmdable)) {
+	if sf == nil {
+		return
+	}
+	slot, found := sf.slots[addr]
+	if !found {
+		return
+	}
+	fo := &redisttl.Failover{
+		Retries: sf.cfg.failoverRetries,
+		Delay:   sf.cfg.failoverDelay,
+		Resolve: func(ctx context.Context) (redis.Cmdable, redis.Cmdable, error) {
+			topology, err := discoverTopology(ctx, sf.cfg, sf.seeds)
+			if err != nil {
+				return nil, nil, err
+			}
+			n, found := topology.SlotPrimary(slot)
+			if !found {
+				return nil, nil, fmt.Errorf("no healthy primary serves slot %d", slot)
+			}
+			primary := n.DialAddr(sf.cfg.preferHostnames)
+			log.Printf("resuming node %s on %s, now serving slot %d\n", addr, primary, slot)
+			c := redis.NewClient(sf.cfg.clientOptions(primary, "redis-ttl-scan"))
+			sf.mu.Lock()
+			sf.clients = append(sf.clients, c)
+			sf.mu.Unlock()
+			client, scanClient := connect(c)
+			return client, scanClient, nil
+		},
+	}
+	switch r := r.(type) {
+	case *redisttl.Scanner:
+		r.Failover = fo
+	case *redisttl.Follower:
+		r.Scanner.Failover = fo
+	}
+}
+
+// Close closes the clients of the new primaries.
+func (sf *shardFailover) Close() error {
+	if sf == nil {
+		return nil
+	}
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	for _, c := range sf.clients {
+		_ = c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestShardFailover(t *testing.T) {
+	topology := redisttl.ClusterTopology{Nodes: []redisttl.ClusterNode{
+		{Addr: "a:6379", Role: redisttl.RolePrimary, Healthy: true, Slots: []redisttl.SlotRange{{Start: 0, End: 8191}}},
+		{Addr: "b:6379", Role: redisttl.RoleReplica, Healthy: true, PrimaryID: "a"},
+	}}
+	connect := func(c *redis.Client) (redis.Cmdable, redis.Cmdable) { return c, c }
+
+	if sf := newShardFailover(&config{failoverRetries: 0}, nil, topology); sf != nil {
+		t.Fatalf("want no failover with 0 retries, got %+v", sf)
+	}
+	sf := newShardFailover(&config{failoverRetries: 2}, []string{"seed:6379"}, topology)
+	defer sf.Close()
+	if len(sf.seeds) != 3 || sf.slots["a:6379"] != 0 || len(sf.slots) != 1 {
+		t.Fatalf("unexpected seeds %v and slots %v", sf.seeds, sf.slots)
+	}
+
+	s := &redisttl.Scanner{}
+	sf.attach(s, "a:6379", connect)
+	if s.Failover == nil || s.Failover.Retries != 2 {
+		t.Fatalf("want the failover of a:6379, got %+v", s.Failover)
+	}
+	unknown := &redisttl.Scanner{}
+	sf.attach(unknown, "c:6379", connect)
+	if unknown.Failover != nil {
+		t.Fatal("want no failover for a node without slots")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// heartbeatInterval is how often scanners report progress to the health
+// checks.
+const heartbeatInterval = 5 * time.Second
+
+// health tracks the scanners of a long-lived process for the liveness and
+// readiness probes. It is a redisttl.Notifier so scanners report into it.
+type health struct {
+	// timeout is how long a scanner may go without progress before the
+	// process is considered wedged. 0 disables the liveness check.
+	timeout time.Duration
+
+	ready    atomic.Bool
+	active   atomic.Int64
+	lastBeat atomic.Int64
+}
+
+func (h *health) beat() {
+	h.lastBeat.Store(time.Now().UnixNano())
+}
+
+func (h *health) OnStart(context.Context, redisttl.RunInfo) {
+	// scanners only start once connected to Redis
+	h.ready.Store(true)
+	h.beat()
+	h.active.Add(1)
+}
+
+func (h *health) OnProgress(context.Context, redisttl.RunInfo, *redisttl.Summary) {
+	h.beat()
+}
+
+func (h *health) OnComplete(_ context.Context, _ redisttl.RunInfo, _ *redisttl.Summary, err error) {
+	h.active.Add(-1)
+	if redisttl.RunStatus(err) == redisttl.StatusFailed {
+		h.ready.Store(false)
+	}
+}
+
+// live reports whether every running scanner made progress recently.
+// An idle process, e.g. a daemon waiting for its next run, is live.
+func (h *health) live() bool {
+	if h.timeout == 0 || h.active.Load() == 0 {
+		return true
+	}
+	return time.Since(time.Unix(0, h.lastBeat.Load())) < h.timeout
+}
+
+func (h *health) healthz(w http.ResponseWriter, _ *http.Request) {
+	probe(w, h.live())
+}
+
+func (h *health) readyz(w http.ResponseWriter, _ *http.Request) {
+	probe(w, h.ready.Load())
+}
+
+func probe(w http.ResponseWriter, ok bool) {
+	if !ok {
+		http.Error(w, "not ok", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	h := &health{timeout: time.Minute}
+
+	status := func(handler http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Code
+	}
+
+	if got := status(h.readyz); got != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready before connecting, got: %d", got)
+	}
+	if got := status(h.healthz); got != http.StatusOK {
+		t.Fatalf("expected an idle process to be live, got: %d", got)
+	}
+
+	h.OnStart(ctx, redisttl.RunInfo{})
+	if got := status(h.readyz); got != http.StatusOK {
+		t.Fatalf("expected ready once a scanner started, got: %d", got)
+	}
+
+	// no progress for longer than the timeout
+	h.lastBeat.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	if got := status(h.healthz); got != http.StatusServiceUnavailable {
+		t.Fatalf("expected a stalled scanner to fail the liveness check, got: %d", got)
+	}
+	h.OnProgress(ctx, redisttl.RunInfo{}, nil)
+	if got := status(h.healthz); got != http.StatusOK {
+		t.Fatalf("expected live after progress, got: %d", got)
+	}
+
+	h.OnComplete(ctx, redisttl.RunInfo{}, nil, errors.New("connection refused"))
+	if got := status(h.readyz); got != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready after a failed run, got: %d", got)
+	}
+}
+
+func TestHealthOutsideWindow(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	// a window opening in an hour
+	now := time.Now().UTC()
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	closed := &redisttl.Window{
+		Start:    (offset + time.Hour) % (24 * time.Hour),
+		End:      (offset + 2*time.Hour) % (24 * time.Hour),
+		Location: time.UTC,
+	}
+	h := &health{timeout: 50 * time.Millisecond}
+	f := &redisttl.Scanner{
+		Mode:             "exp",
+		ScanPrefix:       "foo",
+		Client:           redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL:       time.Hour,
+		Window:           closed,
+		Notifier:         h,
+		ProgressInterval: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- f.Run(ctx) }()
+
+	// paused for several times the health timeout
+	time.Sleep(200 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	h.healthz(rec, httptest.NewRequest("GET", "/healthz", nil))
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if h.active.Load() != 0 {
+		t.Fatal("expected the run to be over")
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a run paused outside its window to be live, got: %d", rec.Code)
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	cfg := defaultConfig
+	cfg.redisAddr = s.Addr()
+	cfg.mode = "exp"
+	cfg.scanPrefix = "f*"
+	cfg.interval = 10 * time.Millisecond
+
+	out := &collectors{summary: &redisttl.Summary{}, health: &health{}}
+	if err := daemon(ctx, &cfg, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := out.summary.Scanned.Load(); got < 2 {
+		t.Fatalf("expected several runs, scanned %d keys", got)
+	}
+	if !out.health.ready.Load() {
+		t.Fatal("expected the daemon to be ready")
+	}
+}
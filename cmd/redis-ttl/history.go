@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// runRecord is a run as kept by --history-file, with the outcome of that
+// run alone even though the summary of a daemon accumulates across runs.
+type runRecord struct {
+	StartedAt     time.Time        `json:"started_at"`
+	EndedAt       time.Time        `json:"ended_at"`
+	Mode          string           `json:"mode"`
+	ScanPrefix    string           `json:"scan_prefix"`
+	Rules         []string         `json:"rules,omitempty"`
+	Scanned       int64            `json:"scanned"`
+	Modified      int64            `json:"modified"`
+	Errors        int64            `json:"errors"`
+	ErrorsByClass map[string]int64 `json:"errors_by_class,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// runHistory appends every run to a file, one JSON record per line, so
+// "when did the TTLs of this prefix last get fixed?" can be answered long
+// after the logs rotated. It is safe for concurrent use.
+type runHistory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// startRecord returns the func appending the run started now to h once done,
+// with the counts of s since now. Failures are logged, the run having
+// happened anyway.
+func (h *runHistory) startRecord(cfg *config, s *redisttl.Summary) func(err error) {
+	if h == nil {
+		return func(error) {}
+	}
+	rec := runRecord{
+		StartedAt:  time.Now(),
+		Mode:       cfg.mode,
+		ScanPrefix: cfg.scanTarget(),
+	}
+	for _, rule := range cfg.rules {
+		rec.Rules = append(rec.Rules, rule.String())
+	}
+	scanned, modified, errs := s.Scanned.Load(), s.Modified.Load(), s.Errors.Load()
+	classes := s.ErrorClasses.Snapshot()
+
+	return func(err error) {
+		rec.EndedAt = time.Now()
+		rec.Scanned = s.Scanned.Load() - scanned
+		rec.Modified = s.Modified.Load() - modified
+		rec.Errors = s.Errors.Load() - errs
+		for class, n := range s.ErrorClasses.Snapshot() {
+			if n -= classes[class]; n > 0 {
+				if rec.ErrorsByClass == nil {
+					rec.ErrorsByClass = map[string]int64{}
+				}
+				rec.ErrorsByClass[class] = n
+			}
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		if err := h.add(rec); err != nil {
+			log.Printf("history file error: %v\n", err)
+		}
+	}
+}
+
+func (h *runHistory) add(rec runRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	return errors.Join(err, f.Close())
+}
+
+// runs returns the runs of scanPrefix, all of them when empty, the latest
+// first, at most limit of them, all when limit is 0.
+func (h *runHistory) runs(scanPrefix string, limit int) ([]runRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []runRecord{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	runs := []runRecord{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var rec runRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		if scanPrefix == "" || rec.ScanPrefix == scanPrefix {
+			runs = append(runs, rec)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(runs)
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// handler serves the runs as a JSON array, the latest first, filtered by
+// the scan_prefix query parameter and capped by the limit one.
+func (h *runHistory) handler(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	runs, err := h.runs(r.URL.Query().Get("scan_prefix"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runs)
+}
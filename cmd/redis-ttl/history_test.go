@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestRunHistory(t *testing.T) {
+	h := &runHistory{path: filepath.Join(t.TempDir(), "runs.jsonl")}
+	s := &redisttl.Summary{}
+	s.Scanned.Add(10)
+
+	record := h.startRecord(&config{mode: "exp", scanPrefix: "session:*"}, s)
+	s.Scanned.Add(3)
+	s.Modified.Add(2)
+	record(nil)
+	record = h.startRecord(&config{mode: "exp", scanPrefix: "cache:*"}, s)
+	s.Errors.Add(1)
+	s.ErrorClasses.Add(errors.New("READONLY You can't write against a read only replica."))
+	record(errors.New("connection refused"))
+	record = h.startRecord(&config{mode: "persist", scanPrefix: "session:*"}, s)
+	record(nil)
+
+	runs, err := h.runs("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[0].Mode != "persist" || runs[2].Mode != "exp" {
+		t.Fatalf("want the latest run first, got %+v", runs)
+	}
+	if first := runs[2]; first.Scanned != 3 || first.Modified != 2 || first.Error != "" || first.EndedAt.Before(first.StartedAt) {
+		t.Fatalf("want the counts of the run alone, got %+v", first)
+	}
+	if failed := runs[1]; failed.Errors != 1 || failed.Error != "connection refused" || len(failed.ErrorsByClass) != 1 {
+		t.Fatalf("unexpected failed run: %+v", failed)
+	}
+
+	rec := httptest.NewRecorder()
+	h.handler(rec, httptest.NewRequest(http.MethodGet, "/runs?scan_prefix=session:*&limit=1", nil))
+	var served []runRecord
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil {
+		t.Fatal(err)
+	}
+	if len(served) != 1 || served[0].Mode != "persist" {
+		t.Fatalf("want the latest run of session:*, got %+v", served)
+	}
+
+	rec = httptest.NewRecorder()
+	h.handler(rec, httptest.NewRequest(http.MethodGet, "/runs?limit=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("want a bad request, got %d", rec.Code)
+	}
+}
+
+func TestRunHistoryMissingFile(t *testing.T) {
+	h := &runHistory{path: filepath.Join(t.TempDir(), "runs.jsonl")}
+	if runs, err := h.runs("", 0); err != nil || len(runs) != 0 {
+		t.Fatalf("want no run, got %v %v", runs, err)
+	}
+}
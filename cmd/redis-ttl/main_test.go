@@ -1,9 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	redisttl "github.com/pims/redis-ttl"
 )
 
 func TestRun(t *testing.T) {
@@ -30,7 +38,23 @@ func TestRun(t *testing.T) {
 		t.Fatal("expected error got nil")
 	}
 
+	_ = s.Set("foo", "bar")
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--dry-run",
+		"--scan-prefix=f*",
+		"--desired-ttl=1w",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("dry run should not modify keys, got ttl: %v", ttl)
+	}
+
 	s.SetError("fault-injected")
+
 	if err := run([]string{
 		"redis-ttl",
 		"--desired-ttl=1w",
@@ -39,3 +63,278 @@ func TestRun(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestRunInfiniteTTL(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	s.SetTTL("foo", time.Hour)
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=f*",
+		"--desired-ttl=none",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("expected the ttl to be removed, got: %v", ttl)
+	}
+}
+
+func TestRunNotifies(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	var p redisttl.WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&p)
+	}))
+	defer srv.Close()
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=f*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--notify-url=" + srv.URL,
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if p.Status != redisttl.StatusSucceeded || p.Modified != 1 {
+		t.Fatalf("unexpected notification: %+v", p)
+	}
+}
+
+func TestRunProtected(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("foo:lock", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--config=" + writeConfigFile(t, "protected=*:lock\n"),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != time.Hour {
+		t.Fatalf("want a ttl of 1h, got: %v", ttl)
+	}
+	if ttl := s.TTL("foo:lock"); ttl != 0 {
+		t.Fatalf("protected keys should not be modified, got ttl: %v", ttl)
+	}
+}
+
+func TestRunKillSwitch(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("ops:redis-ttl:stop", "1")
+
+	err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--kill-switch-key=ops:redis-ttl:stop",
+	})
+	if !errors.Is(err, redisttl.ErrKilled) {
+		t.Fatalf("want: %v got: %v", redisttl.ErrKilled, err)
+	}
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("no key should be modified once killed, got ttl: %v", ttl)
+	}
+}
+
+func TestRunValuePrefix(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "tombstone:2024-01-01")
+	_ = s.Set("foo:2", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--value-prefix=tombstone:",
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo:1"); ttl != time.Hour {
+		t.Fatalf("want a ttl of 1h, got: %v", ttl)
+	}
+	if ttl := s.TTL("foo:2"); ttl != 0 {
+		t.Fatalf("keys whose value doesn't match should not be modified, got ttl: %v", ttl)
+	}
+}
+
+func TestRunZTrim(t *testing.T) {
+	s := miniredis.RunT(t)
+	now := time.Now()
+	_, _ = s.ZAdd("events:1", float64(now.Add(-2*time.Hour).UnixMilli()), "old")
+	_, _ = s.ZAdd("events:1", float64(now.UnixMilli()), "recent")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=ztrim",
+		"--scan-prefix=events:*",
+		"--desired-ttl=1h",
+		"--zscore-unit=1ms",
+		"--scan-type=zset",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if members, _ := s.ZMembers("events:1"); len(members) != 1 || members[0] != "recent" {
+		t.Fatalf("want the members older than 1h removed, got: %v", members)
+	}
+}
+
+func TestRunExpiryIndex(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--expiry-index=ttl:index",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	score, err := s.ZScore("ttl:index", "foo:1")
+	if err != nil {
+		t.Fatalf("want foo:1 indexed, got: %v", err)
+	}
+	if at := time.UnixMilli(int64(score)); time.Until(at) < 59*time.Minute || time.Until(at) > time.Hour {
+		t.Fatalf("want foo:1 indexed as expiring in 1h, got: %v", at)
+	}
+}
+
+func TestRunCopy(t *testing.T) {
+	src, dst := miniredis.RunT(t), miniredis.RunT(t)
+	_ = src.Set("foo:1", "bar")
+	src.SetTTL("foo:1", time.Hour)
+	// miniredis has no DUMP and RESTORE
+	_ = src.Server().Register("DUMP", func(c *server.Peer, _ string, args []string) {
+		v, _ := src.Get(args[0])
+		c.WriteBulk(v)
+	})
+	_ = dst.Server().Register("RESTORE", func(c *server.Peer, _ string, args []string) {
+		_ = dst.Set(args[0], args[2])
+		ms, _ := strconv.Atoi(args[1])
+		dst.SetTTL(args[0], time.Duration(ms)*time.Millisecond)
+		c.WriteOK()
+	})
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=copy",
+		"--scan-prefix=foo*",
+		"--redis-addr=" + src.Addr(),
+		"--copy-addr=" + dst.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if v, _ := dst.Get("foo:1"); v != "bar" || dst.TTL("foo:1") != time.Hour {
+		t.Fatalf("want foo:1 copied with its ttl, got: %q %v", v, dst.TTL("foo:1"))
+	}
+}
+
+func TestRunPurge(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:2", "bar")
+	s.SetTTL("foo:1", time.Minute)
+	s.SetTTL("foo:2", time.Hour)
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=purge",
+		"--scan-prefix=foo*",
+		"--desired-ttl=5m",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if s.Exists("foo:1") || !s.Exists("foo:2") {
+		t.Fatalf("want only the key expiring within 5m purged, got: %v", s.Keys())
+	}
+}
+
+func TestRunTransaction(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:1:meta", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo:?",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--companions={key}:meta",
+		"--transaction",
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if s.TTL("foo:1") != time.Hour || s.TTL("foo:1:meta") != time.Hour {
+		t.Fatalf("want foo:1 and its companion expiring in 1h, got: %v %v", s.TTL("foo:1"), s.TTL("foo:1:meta"))
+	}
+}
+
+func TestRunChangeStream(t *testing.T) {
+	s := miniredis.RunT(t)
+	ops := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--change-stream=ttl-changes",
+		"--change-stream-addr=" + ops.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	entries, err := ops.Stream("ttl-changes")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("want an entry for foo:1, got: %v %v", entries, err)
+	}
+}
+
+func TestRunHashField(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.HSet("foo:1", "status", "archived")
+	s.HSet("foo:2", "status", "active")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--scan-type=hash",
+		"--hash-field=status",
+		"--hash-value=archived",
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo:1"); ttl != time.Hour {
+		t.Fatalf("want a ttl of 1h, got: %v", ttl)
+	}
+	if ttl := s.TTL("foo:2"); ttl != 0 {
+		t.Fatalf("hashes whose field doesn't match should not be modified, got ttl: %v", ttl)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// metricsHandler exposes a summary and the commands sent in the Prometheus
+// text format.
+func metricsHandler(s *redisttl.Summary, c *redisttl.CommandCounter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = s.WritePrometheus(w)
+		_ = c.WritePrometheus(w)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	s := &redisttl.Summary{}
+	s.Scanned.Add(3)
+	s.Modified.Add(2)
+	s.ExpireLatency.Observe(time.Millisecond)
+	s.ErrorClasses.Add(context.DeadlineExceeded)
+
+	m := miniredis.RunT(t)
+	c := &redisttl.CommandCounter{}
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	rdb.AddHook(c.Hook("a:6379"))
+	_ = rdb.Expire(context.Background(), "foo", time.Hour)
+
+	rec := httptest.NewRecorder()
+	metricsHandler(s, c).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"redis_ttl_keys_scanned_total 3\n",
+		"redis_ttl_keys_modified_total 2\n",
+		"redis_ttl_errors_total 0\n",
+		`redis_ttl_errors_by_class_total{class="timeout"} 1` + "\n",
+		`redis_ttl_expire_duration_seconds_bucket{le="0.0016"} 1` + "\n",
+		`redis_ttl_expire_duration_seconds_bucket{le="+Inf"} 1` + "\n",
+		"redis_ttl_expire_duration_seconds_count 1\n",
+		"redis_ttl_scan_duration_seconds_count 0\n",
+		`redis_ttl_commands_total{node="a:6379",kind="write"} 1` + "\n",
+		`redis_ttl_commands_total{node="a:6379",kind="scan"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestRunStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--statsd-addr=" + conn.LocalAddr().String(),
+		"--statsd-prefix=ttl.",
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 512)
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "ttl.keys.scanned:1|c|#node:" + s.Addr(); string(b[:n]) != expected {
+		t.Fatalf("want %s, got %s", expected, b[:n])
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// nodeFilter selects which cluster primaries a run targets.
+// Entries match either a node address or a node ID.
+type nodeFilter struct {
+	include []string
+	exclude []string
+}
+
+func newNodeFilter(include, exclude string) nodeFilter {
+	return nodeFilter{
+		include: splitList(include),
+		exclude: splitList(exclude),
+	}
+}
+
+func (f nodeFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+func (f nodeFilter) allows(addr, id string) bool {
+	if matchNode(f.exclude, addr, id) {
+		return false
+	}
+	return len(f.include) == 0 || matchNode(f.include, addr, id)
+}
+
+func matchNode(list []string, addr, id string) bool {
+	for _, n := range list {
+		if n == addr || (id != "" && n == id) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeOrder sorts the addresses of the primaries, so every run processes
+// them in the same order, and drops the ones before start, an address or a
+// node ID, when set: the nodes a previous run already finished.
+func nodeOrder(addrs []string, ids map[string]string, start string) ([]string, error) {
+	addrs = slices.Clone(addrs)
+	slices.Sort(addrs)
+	if start == "" {
+		return addrs, nil
+	}
+	for i, addr := range addrs {
+		if matchNode([]string{start}, addr, ids[addr]) {
+			return addrs[i:], nil
+		}
+	}
+	return nil, fmt.Errorf("start node %s isn't a primary of the cluster: %w", start, errNodes)
+}
+
+// nodeIDs maps each primary address to its cluster node ID.
+func nodeIDs(ctx context.Context, c *redis.ClusterClient) (map[string]string, error) {
+	slots, err := c.ClusterSlots(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, slot := range slots {
+		if len(slot.Nodes) == 0 {
+			continue
+		}
+		primary := slot.Nodes[0]
+		ids[primary.Addr] = primary.ID
+	}
+	return ids, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNodeFilter(t *testing.T) {
+	testCases := map[string]struct {
+		include string
+		exclude string
+		addr    string
+		id      string
+		allowed bool
+	}{
+		"empty filter allows everything": {
+			addr:    "node1:6379",
+			allowed: true,
+		},
+		"include by address": {
+			include: "node1:6379, node2:6379",
+			addr:    "node2:6379",
+			allowed: true,
+		},
+		"include by node id": {
+			include: "abc",
+			addr:    "node2:6379",
+			id:      "abc",
+			allowed: true,
+		},
+		"not in include list": {
+			include: "node1:6379",
+			addr:    "node2:6379",
+			allowed: false,
+		},
+		"exclude by node id": {
+			exclude: "abc",
+			addr:    "node1:6379",
+			id:      "abc",
+			allowed: false,
+		},
+		"exclude wins over include": {
+			include: "node1:6379",
+			exclude: "node1:6379",
+			addr:    "node1:6379",
+			allowed: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			f := newNodeFilter(tc.include, tc.exclude)
+			if got := f.allows(tc.addr, tc.id); got != tc.allowed {
+				t.Fatalf("want: %v got: %v", tc.allowed, got)
+			}
+		})
+	}
+}
+
+func TestNodeOrder(t *testing.T) {
+	addrs := []string{"node3:6379", "node1:6379", "node2:6379"}
+	ids := map[string]string{"node2:6379": "abc"}
+
+	testCases := map[string]struct {
+		start string
+		want  []string
+		err   error
+	}{
+		"sorted by address": {
+			want: []string{"node1:6379", "node2:6379", "node3:6379"},
+		},
+		"start by address": {
+			start: "node2:6379",
+			want:  []string{"node2:6379", "node3:6379"},
+		},
+		"start by node id": {
+			start: "abc",
+			want:  []string{"node2:6379", "node3:6379"},
+		},
+		"unknown start node": {
+			start: "node4:6379",
+			err:   errNodes,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, err := nodeOrder(addrs, ids, tc.start)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want: %v got: %v", tc.err, err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("want: %v got: %v", tc.want, got)
+			}
+		})
+	}
+	if addrs[0] != "node3:6379" {
+		t.Fatalf("nodeOrder modified its argument: %v", addrs)
+	}
+}
+
+func TestNodeIDs(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	c := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: []string{s.Addr()},
+	})
+	ids, err := nodeIDs(context.Background(), c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids[s.Addr()] == "" {
+		t.Fatalf("missing node id for %s: %v", s.Addr(), ids)
+	}
+}
+
+func TestRunClusterExcludedNode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=f*",
+		"--desired-ttl=1h",
+		"--redis-cluster-addrs=" + s.Addr(),
+		"--nodes-exclude=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if s.TTL("foo") != 0 {
+		t.Fatal("excluded node should not be scanned")
+	}
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=f*",
+		"--desired-ttl=1h",
+		"--redis-cluster-addrs=" + s.Addr(),
+		"--nodes-include=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if s.TTL("foo") != time.Hour {
+		t.Fatalf("included node should be scanned, got ttl: %v", s.TTL("foo"))
+	}
+}
+
+func TestRunClusterStartNode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	args := []string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=f*",
+		"--desired-ttl=1h",
+		"--redis-cluster-addrs=" + s.Addr(),
+	}
+	if err := run(append(args, "--start-node=unknown:6379")); !errors.Is(err, errNodes) {
+		t.Fatalf("expected %v, got: %v", errNodes, err)
+	}
+	if s.TTL("foo") != 0 {
+		t.Fatal("no node should be scanned with an unknown start node")
+	}
+
+	if err := run(append(args, "--start-node="+s.Addr())); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if s.TTL("foo") != time.Hour {
+		t.Fatalf("start node should be scanned, got ttl: %v", s.TTL("foo"))
+	}
+}
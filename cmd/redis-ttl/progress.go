@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// progressLog logs the progress of every node at most every interval, and
+// once its scan completes.
+type progressLog struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	logged map[string]time.Time
+}
+
+func (p *progressLog) Update(_ context.Context, s redisttl.ProgressSnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.logged == nil {
+		p.logged = map[string]time.Time{}
+	}
+	if !s.Done && time.Since(p.logged[s.Node]) < p.interval {
+		return
+	}
+	p.logged[s.Node] = time.Now()
+	log.Printf("progress %s: %s\n", s.Node, s)
+}
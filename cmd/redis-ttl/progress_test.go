@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestProgressLog(t *testing.T) {
+	var b bytes.Buffer
+	log.SetOutput(&b)
+	defer log.SetOutput(os.Stderr)
+
+	p := &progressLog{interval: time.Hour}
+	ctx := context.Background()
+	p.Update(ctx, redisttl.ProgressSnapshot{Node: "a", Processed: 10, Walked: 50, Total: 100, Elapsed: 10 * time.Second, Rate: 1, ETA: 10 * time.Second})
+	p.Update(ctx, redisttl.ProgressSnapshot{Node: "a", Processed: 20})
+	p.Update(ctx, redisttl.ProgressSnapshot{Node: "b", Processed: 5})
+	p.Update(ctx, redisttl.ProgressSnapshot{Node: "a", Processed: 30, Elapsed: 30 * time.Second, Rate: 1, Done: true})
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	expected := []string{
+		"progress a: 10 keys in 10s (1/s), 50.0% of ~100, eta 10s",
+		"progress b: 5 keys in 0s (0/s)",
+		"progress a: 30 keys in 30s (1/s), done",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("want %d lines, got %q", len(expected), lines)
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expected[i]) {
+			t.Fatalf("want %q, got %q", expected[i], line)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// newQueue returns the job queue of cfg, on --queue-addr or the node
+// targeted when it is empty.
+func newQueue(cfg *config) *redisttl.Queue {
+	addr := cfg.queueAddr
+	if addr == "" {
+		addr, _ = cfg.nodeAddrs()
+	}
+	return &redisttl.Queue{
+		Client: redis.NewClient(cfg.clientOptions(addr, "redis-ttl-queue")),
+		Stream: cfg.queueStream,
+		Group:  cfg.queueGroup,
+	}
+}
+
+// enqueue adds a job per --scan-patterns entry, or for --scan-prefix, or,
+// with --keys-from, a job per --queue-batch-size keys read one per line.
+func enqueue(ctx context.Context, cfg *config, stdin io.Reader) error {
+	q := newQueue(cfg)
+	defer closeClient(q.Client)
+	if cfg.keysFrom == "" {
+		patterns := splitList(cfg.scanPatterns)
+		if len(patterns) == 0 {
+			patterns = []string{cfg.scanPrefix}
+		}
+		for _, p := range patterns {
+			if err := enqueueJob(ctx, q, redisttl.Job{ScanPrefix: p}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	in := stdin
+	if cfg.keysFrom != "-" {
+		f, err := os.Open(cfg.keysFrom)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var keys []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			keys = append(keys, key)
+		}
+		if len(keys) == cfg.queueBatchSize {
+			if err := enqueueJob(ctx, q, redisttl.Job{Keys: keys}); err != nil {
+				return err
+			}
+			keys = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read keys: %w", err)
+	}
+	if len(keys) > 0 {
+		return enqueueJob(ctx, q, redisttl.Job{Keys: keys})
+	}
+	return nil
+}
+
+func enqueueJob(ctx context.Context, q *redisttl.Queue, job redisttl.Job) error {
+	id, err := q.Enqueue(ctx, job)
+	if err != nil {
+		return err
+	}
+	log.Printf("enqueued job %s\n", id)
+	return nil
+}
+
+// work processes the jobs of the queue with the mode of cfg until ctx is
+// done.
+func work(ctx context.Context, cfg *config, out *collectors) error {
+	client, err := jobClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(client)
+	consumer := cfg.queueConsumer
+	if consumer == "" {
+		host, _ := os.Hostname()
+		consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	q := newQueue(cfg)
+	defer closeClient(q.Client)
+	w := &redisttl.Worker{
+		Queue:    q,
+		Consumer: consumer,
+		Block:    cfg.queueBlock,
+		Process: func(ctx context.Context, job redisttl.Job) error {
+			return processJob(ctx, cfg, client, out, job)
+		},
+	}
+	return w.Run(ctx)
+}
+
+func processJob(ctx context.Context, cfg *config, client redis.Cmdable, out *collectors, job redisttl.Job) error {
+	if len(job.Keys) > 0 {
+		s, ok := newRunner(cfg, client, client, out).(*redisttl.Scanner)
+		if !ok {
+			return fmt.Errorf("mode %s can't process keys: %w", cfg.mode, errMode)
+		}
+		if err := s.ApplyKeys(ctx, job.Keys); err != nil {
+			return err
+		}
+		log.Printf("job %s: processed %d keys\n", job.ID, len(job.Keys))
+		return nil
+	}
+
+	if !cfg.force && matchesAnything(job.ScanPrefix) {
+		return fmt.Errorf("refusing to run %s mode on every key with %q, pass --force to proceed: %w",
+			cfg.mode, job.ScanPrefix, errAborted)
+	}
+	jobCfg := *cfg
+	jobCfg.scanPrefix, jobCfg.scanPatterns = job.ScanPrefix, ""
+	if err := execute(ctx, &jobCfg, out); err != nil {
+		return err
+	}
+	log.Printf("job %s: scanned %q\n", job.ID, job.ScanPrefix)
+	return nil
+}
+
+// jobClient returns the client processing the keys of jobs, routing every
+// key to its node.
+func jobClient(ctx context.Context, cfg *config) (redis.Cmdable, error) {
+	switch {
+	case cfg.redisClusterAddrs != "" || cfg.clusterConfigEndpoint != "":
+		seeds, err := clusterSeeds(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClusterClient(cfg.clusterOptions(seeds)), nil
+	case cfg.redisRingAddrs != "":
+		shards, err := ringShards(cfg.redisRingAddrs)
+		if err != nil {
+			return nil, err
+		}
+		return newRing(cfg, shards), nil
+	case cfg.sentinelAddrs != "":
+		return redis.NewFailoverClient(cfg.failoverOptions()), nil
+	case cfg.universalAddrs != "":
+		return redis.NewUniversalClient(cfg.universalOptions()), nil
+	}
+	addr, _ := cfg.nodeAddrs()
+	return redis.NewClient(cfg.clientOptions(addr, "redis-ttl")), nil
+}
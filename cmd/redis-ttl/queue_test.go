@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestQueue(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo:1", "foo:2", "a", "b", "c", "d"} {
+		_ = s.Set(k, "bar")
+	}
+
+	keys := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(keys, []byte("a\nb\n\nc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"redis-ttl", "enqueue", "--scan-prefix=foo:*", "--redis-addr=" + s.Addr()},
+		{"redis-ttl", "enqueue", "--keys-from=" + keys, "--queue-batch-size=2", "--redis-addr=" + s.Addr()},
+	} {
+		if err := run(args); err != nil {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+	}
+	if entries, _ := s.Stream("redis-ttl:jobs"); len(entries) != 3 {
+		t.Fatalf("want 3 jobs, got: %v", entries)
+	}
+
+	cfg, err := parseConfig([]string{
+		"redis-ttl", "work", "--mode=exp", "--desired-ttl=1h", "--queue-block=10ms", "--redis-addr=" + s.Addr(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- work(ctx, &cfg, &collectors{summary: &redisttl.Summary{}}) }()
+
+	for deadline := time.Now().Add(time.Second); s.TTL("c") == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("jobs not processed in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo:1": time.Hour,
+		"foo:2": time.Hour,
+		"a":     time.Hour,
+		"b":     time.Hour,
+		"c":     time.Hour,
+		"d":     0,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+}
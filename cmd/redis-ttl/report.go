@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// printReport prints the groups of r, limited to the first top ones when
+// top is greater than 0.
+func printReport(w io.Writer, r *redisttl.PrefixReport, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PREFIX\tKEYS\tWITH TTL\tCOVERAGE\tMIN TTL\tAVG TTL\tMAX TTL")
+	groups := r.Groups()
+	if top > 0 && len(groups) > top {
+		groups = groups[:top]
+	}
+	for _, g := range groups {
+		coverage := float64(g.WithTTL) / float64(g.Keys) * 100
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%s\t%s\t%s\n",
+			g.Prefix, g.Keys, g.WithTTL, coverage, g.MinTTL, g.AvgTTL(), g.MaxTTL)
+	}
+	return tw.Flush()
+}
+
+func printNamespaces(w io.Writer, s *redisttl.NamespaceSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tSCANNED\tMODIFIED\tSKIPPED\tERRORS")
+	for _, n := range s.Namespaces() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", n.Namespace, n.Scanned, n.Modified, n.Skipped(), n.Errors)
+	}
+	return tw.Flush()
+}
+
+// printInventory prints the keys of every node by TTL bucket, then by type.
+func printInventory(w io.Writer, inv *redisttl.Inventory) error {
+	nodes := inv.Nodes()
+	if len(nodes) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "NODE\tKEYS\tNO TTL")
+	for _, bound := range redisttl.InventoryBounds {
+		fmt.Fprintf(tw, "\t<=%s", formatBound(bound))
+	}
+	fmt.Fprintln(tw, "\tLONGER")
+	for _, n := range nodes {
+		fmt.Fprintf(tw, "%s\t%d\t%d", n.Node, n.Keys, n.WithoutTTL)
+		for _, keys := range n.TTLs {
+			fmt.Fprintf(tw, "\t%d", keys)
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "NODE\tTYPE\tKEYS")
+	for _, n := range nodes {
+		types := make([]string, 0, len(n.Types))
+		for typ := range n.Types {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		for _, typ := range types {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", n.Node, typ, n.Types[typ])
+		}
+	}
+	return tw.Flush()
+}
+
+// printProjection prints the keys expiring every hour until horizon, and
+// the ones expiring later.
+func printProjection(w io.Writer, p *redisttl.ExpiryProjection, horizon time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXPIRES\tKEYS")
+	var later int64
+	for _, b := range p.Buckets() {
+		if b.Start.After(horizon) {
+			later += b.Keys
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", b.Start.Local().Format("2006-01-02 15:04"), b.Keys)
+	}
+	if later > 0 {
+		fmt.Fprintf(tw, "later\t%d\n", later)
+	}
+	return tw.Flush()
+}
+
+func printBigKeys(w io.Writer, d *redisttl.BigKeyDetector) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BIG KEY\tTYPE\tBYTES\tELEMENTS")
+	for _, k := range d.Keys() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", k.Key, k.Type, k.Bytes, k.Elements)
+	}
+	return tw.Flush()
+}
+
+func printBenchmarks(w io.Writer, b *redisttl.BenchmarkResults) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tKEYS\tKEYS/S\tRTT\tSERVER CPU\tBOTTLENECK")
+	for _, r := range b.All() {
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t%s\t%.0f%%\t%s\n",
+			r.Node, r.Keys, r.Rate(), r.RTT, r.ServerCPU*100, r.Bottleneck)
+	}
+	return tw.Flush()
+}
+
+func printEstimates(w io.Writer, e *redisttl.Estimates) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tDBSIZE\tSAMPLED\tMATCHED\tEST. KEYS\tEST. DURATION")
+	for _, x := range e.All() {
+		keys := "~" + strconv.FormatInt(x.Keys, 10)
+		if x.Exact {
+			keys = keys[1:]
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\n",
+			x.Node, x.DBSize, x.Sampled, x.Matched, keys, x.Duration.Round(time.Second))
+	}
+	return tw.Flush()
+}
+
+// maxAuditFindings bounds the keys kept for printing in audit and verify
+// modes and with --verify-writes, all violations are counted regardless.
+const maxAuditFindings = 1000
+
+func printAudit(w io.Writer, a *redisttl.Audit) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tTTL\tREASON")
+	for _, f := range a.Findings() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Key, formatTTL(f.TTL), f.Reason)
+	}
+	fmt.Fprintf(tw, "checked=%d violations=%d\n", a.Checked(), a.Violations())
+	return tw.Flush()
+}
+
+func printPreview(w io.Writer, p *redisttl.PreviewResults) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tKEY\tTTL")
+	for _, k := range p.All() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", k.Node, k.Key, formatTTL(k.TTL))
+	}
+	return tw.Flush()
+}
+
+// formatBound formats a TTL bucket bound in its largest whole unit, days
+// included, e.g. 7d.
+func formatBound(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.String()
+}
+
+// formatTTL formats a ttl read with PTTL, which is -1 for keys without one
+// and -2 for deleted keys.
+func formatTTL(ttl time.Duration) string {
+	switch ttl {
+	case -1:
+		return "none"
+	case -2:
+		return "-"
+	}
+	return ttl.String()
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPrintReport(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:users:1", "v")
+	_ = s.Set("cache:users:2", "v")
+	s.SetTTL("cache:users:1", time.Hour)
+
+	report := &redisttl.PrefixReport{}
+	f := redisttl.Scanner{
+		Mode:       "report",
+		ScanPrefix: "cache:*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Report:     report,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printReport(&buf, report, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "PREFIX") {
+		t.Fatalf("unexpected report:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "cache:users:" || fields[1] != "2" || fields[3] != "50.0%" {
+		t.Fatalf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestPrintNamespaces(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:users:1", "v")
+	_ = s.Set("cache:users:2", "v")
+	_ = s.Set("cache:items:1", "v")
+
+	namespaces := &redisttl.NamespaceSummary{Depth: 2}
+	f := redisttl.Scanner{
+		Mode:       "exp",
+		ScanPrefix: "cache:*",
+		DesiredTTL: time.Hour,
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Namespaces: namespaces,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printNamespaces(&buf, namespaces); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "NAMESPACE") || !strings.HasPrefix(lines[1], "cache:users:") {
+		t.Fatalf("unexpected summary:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields[1:], " ") != "2 2 0 0" {
+		t.Fatalf("unexpected counts for cache:users: %v", fields)
+	}
+}
+
+func TestPrintProjection(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:1", "v")
+	_ = s.Set("cache:2", "v")
+	_ = s.Set("cache:3", "v")
+	s.SetTTL("cache:3", 30*24*time.Hour)
+
+	projection := &redisttl.ExpiryProjection{}
+	f := redisttl.Scanner{
+		Mode:       "extend",
+		ScanPrefix: "cache:*",
+		TTLDelta:   time.Hour,
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Projection: projection,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printProjection(&buf, projection, time.Now().AddDate(0, 0, 7)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "EXPIRES") || strings.Join(strings.Fields(lines[1]), " ") != "later 1" {
+		t.Fatalf("unexpected projection:\n%s", buf.String())
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:users:1", "v")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=report",
+		"--scan-prefix=cache:*",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+}
+
+func TestRunDiscover(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:users:1", "v")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=discover",
+		"--sample-size=5",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=discover",
+		"--sample-size=0",
+		"--redis-addr=" + s.Addr(),
+	}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestPrintReportTop(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("a:1", "v")
+	_ = s.Set("a:2", "v")
+	_ = s.Set("b:1", "v")
+
+	report := &redisttl.PrefixReport{}
+	f := redisttl.Scanner{
+		Mode:       "report",
+		ScanPrefix: "*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Report:     report,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printReport(&buf, report, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "a:") {
+		t.Fatalf("expected only the largest group, got:\n%s", buf.String())
+	}
+}
+
+func TestRunBigKeys(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", strings.Repeat("v", 4096))
+
+	if err := run([]string{
+		"redis-ttl",
+		"--scan-prefix=f*",
+		"--desired-ttl=1h",
+		"--big-key-bytes=1024",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	if err := run([]string{
+		"redis-ttl",
+		"--desired-ttl=1h",
+		"--big-key-elements=-1",
+		"--redis-addr=" + s.Addr(),
+	}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// cpuHook answers INFO, which miniredis only partially supports.
+type cpuHook struct{}
+
+func (cpuHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "info" {
+			return next(ctx, cmd)
+		}
+		cmd.(*redis.StringCmd).SetVal("used_cpu_sys:1\r\nused_cpu_user:1\r\n")
+		return nil
+	}
+}
+
+func (cpuHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (cpuHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestPrintBenchmarks(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(cpuHook{})
+
+	cfg := defaultConfig
+	cfg.mode = "benchmark"
+	cfg.benchKeys = 100
+	out := &collectors{benchmarks: &redisttl.BenchmarkResults{}}
+	if err := newRunner(&cfg, rdb, rdb, out).Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printBenchmarks(&buf, out.benchmarks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NODE") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != s.Addr() || fields[1] != "100" {
+		t.Fatalf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestRunEstimate(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("zoo", "bar")
+
+	if err := run([]string{
+		"redis-ttl",
+		"estimate",
+		"--mode=exp",
+		"--desired-ttl=1h",
+		"--scan-prefix=f*",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("estimate should not modify keys, got ttl: %v", ttl)
+	}
+
+	cfg, err := parseConfig([]string{"redis-ttl", "estimate", "--scan-prefix=f*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.estimate || cfg.scanPrefix != "f*" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	cfg.rps = 1
+	out := &collectors{estimates: &redisttl.Estimates{}}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	if err := newRunner(&cfg, rdb, rdb, out).Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printEstimates(&buf, out.estimates); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if fields := strings.Fields(lines[1]); len(lines) != 2 || fields[1] != "2" || fields[3] != "1" || fields[5] != "1s" {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	s.SetTTL("foo", time.Hour)
+	s.SetTTL("far", time.Hour)
+
+	args := []string{
+		"redis-ttl",
+		"--mode=verify",
+		"--scan-prefix=f*",
+		"--desired-ttl=2h",
+		"--redis-addr=" + s.Addr(),
+	}
+	if err := run(args); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	s.SetTTL("far", 3*time.Hour)
+	if err := run(args); !errors.Is(err, errVerify) {
+		t.Fatalf("expected %v, got: %v", errVerify, err)
+	}
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--verify-writes",
+		"--scan-prefix=f*",
+		"--desired-ttl=2h",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("far"); ttl != 2*time.Hour {
+		t.Fatalf("ttl don't match for: far, got:%v want: %v", ttl, 2*time.Hour)
+	}
+
+	// audit only flags keys without a ttl
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=audit",
+		"--scan-prefix=f*",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+}
+
+func TestPrintAudit(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	s.SetTTL("far", time.Hour)
+
+	cfg := defaultConfig
+	cfg.mode = "audit"
+	cfg.scanPrefix = "f*"
+	out := &collectors{summary: &redisttl.Summary{}, audit: &redisttl.Audit{}}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	if err := newRunner(&cfg, rdb, rdb, out).Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printAudit(&buf, out.audit); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || strings.Join(strings.Fields(lines[1]), " ") != "foo none no-ttl" || lines[2] != "checked=2 violations=1" {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestRunPreview(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	_ = s.Set("fiz", "bar")
+	s.SetTTL("far", time.Hour)
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--desired-ttl=1h",
+		"--preview=2",
+		"--scan-prefix=f*",
+		"--redis-addr=" + s.Addr(),
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("preview should not modify keys, got ttl: %v", ttl)
+	}
+
+	cfg, err := parseConfig([]string{"redis-ttl", "--preview=2", "--scan-prefix=f*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := &collectors{previews: &redisttl.PreviewResults{}}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	if err := newRunner(&cfg, rdb, rdb, out).Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printPreview(&buf, out.previews); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || strings.Fields(lines[1])[2] != "1h0m0s" || strings.Fields(lines[2])[2] != "none" {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestPrintInventory(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:1", "a")
+	s.SetTTL("cache:1", 10*24*time.Hour)
+	_ = s.Set("cache:2", "b")
+
+	inv := &redisttl.Inventory{}
+	f := redisttl.Scanner{
+		Mode:       "noop",
+		ScanPrefix: "cache:*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Node:       "a:6379",
+		Inventory:  inv,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printInventory(&buf, inv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || strings.Join(strings.Fields(lines[0]), " ") != "NODE KEYS NO TTL <=1m <=1h <=1d <=7d <=30d LONGER" {
+		t.Fatalf("unexpected inventory:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "a:6379 2 1 0 0 0 0 1 0" {
+		t.Fatalf("unexpected ttl counts: %v", fields)
+	}
+	if fields := strings.Fields(lines[4]); strings.Join(fields, " ") != "a:6379 string 2" {
+		t.Fatalf("unexpected type counts: %v", fields)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ringShards parses --redis-ring-addrs, a list of name=addr shards. A shard
+// listed by address only is named after it. The names have to be the ones
+// the application's ring uses, since they determine where keys hash to.
+func ringShards(s string) (map[string]string, error) {
+	shards := map[string]string{}
+	for _, entry := range splitList(s) {
+		name, addr, found := strings.Cut(entry, "=")
+		if !found {
+			addr = name
+		}
+		if name == "" || addr == "" {
+			return nil, fmt.Errorf("invalid ring shard %q, want name=addr: %w", entry, errNodes)
+		}
+		if _, dup := shards[name]; dup {
+			return nil, fmt.Errorf("duplicate ring shard %s: %w", name, errNodes)
+		}
+		shards[name] = addr
+	}
+	return shards, nil
+}
+
+func newRing(cfg *config, shards map[string]string) *redis.Ring {
+	return redis.NewRing(cfg.ringOptions(shards))
+}
+
+// runRing scans every shard of a client-side sharded deployment with the
+// shard's own client, while expire commands go through the ring, the way
+// the application routes them.
+func runRing(ctx context.Context, cfg *config, out *collectors) error {
+	shards, err := ringShards(cfg.redisRingAddrs)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(shards))
+	for name, addr := range shards {
+		names[addr] = name
+	}
+	ring := newRing(cfg, shards)
+	ring.OnNewNode(out.countCommands)
+	defer ring.Close()
+
+	filter := newNodeFilter(cfg.nodesInclude, cfg.nodesExclude)
+	sem := newSemaphore(cfg.nodeConcurrency)
+	ctx, abort, cancel := failFast(ctx, cfg)
+	defer cancel()
+
+	// ForEachShard only returns one of the errors
+	errc := make(chan error, len(shards))
+	err = ring.ForEachShard(ctx, func(ctx context.Context, client *redis.Client) error {
+		addr := client.Options().Addr
+		if !filter.allows(addr, names[addr]) {
+			log.Printf("skipping shard %s (%s)\n", addr, names[addr])
+			return nil
+		}
+		if err := sem.acquire(ctx); err != nil {
+			return err
+		}
+		defer sem.release()
+
+		log.Printf("scanning shard %s (%s)\n", addr, names[addr])
+		if err := runNode(ctx, out, addr, client, newRunner(cfg, ring, client, out)); err != nil {
+			err = fmt.Errorf("shard %s: %w", addr, err)
+			abort(err)
+			errc <- err
+		}
+		return nil
+	})
+	close(errc)
+	errs := []error{err}
+	for err := range errc {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestRingShards(t *testing.T) {
+	shards, err := ringShards("a=node1:6379, node2:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"a": "node1:6379", "node2:6379": "node2:6379"}; !reflect.DeepEqual(shards, want) {
+		t.Fatalf("want: %v got: %v", want, shards)
+	}
+	for _, s := range []string{"a=", "=node1:6379", "a=node1:6379,a=node2:6379"} {
+		if _, err := ringShards(s); !errors.Is(err, errNodes) {
+			t.Fatalf("expected errNodes for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestRunRing(t *testing.T) {
+	a, b := miniredis.RunT(t), miniredis.RunT(t)
+	addrs := fmt.Sprintf("a=%s,b=%s", a.Addr(), b.Addr())
+	shards, _ := ringShards(addrs)
+	ring := newRing(&config{}, shards)
+	defer ring.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		if err := ring.Set(ctx, fmt.Sprintf("foo:%d", i), "bar", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(a.Keys()) == 0 || len(b.Keys()) == 0 {
+		t.Fatalf("expected keys on both shards, got %d and %d", len(a.Keys()), len(b.Keys()))
+	}
+
+	cfg, err := parseConfig([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--desired-ttl=1h",
+		"--scan-prefix=foo:*",
+		"--redis-ring-addrs=" + addrs,
+		"--nodes-exclude=b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Err(); err != nil {
+		t.Fatal(err)
+	}
+	out := &collectors{summary: &redisttl.Summary{}}
+	if err := execute(ctx, &cfg, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, key := range a.Keys() {
+		if ttl := a.TTL(key); ttl != time.Hour {
+			t.Fatalf("want a ttl of 1h for %s, got %s", key, ttl)
+		}
+	}
+	for _, key := range b.Keys() {
+		if ttl := b.TTL(key); ttl != 0 {
+			t.Fatalf("excluded shard should not be modified, got ttl %s for %s", ttl, key)
+		}
+	}
+	if got := out.summary.Modified.Load(); got != int64(len(a.Keys())) {
+		t.Fatalf("want %d keys modified, got %d", len(a.Keys()), got)
+	}
+
+	cfg.redisClusterAddrs = a.Addr()
+	if err := cfg.Err(); !errors.Is(err, errNodes) {
+		t.Fatalf("ring and cluster flags should be mutually exclusive, got %v", err)
+	}
+}
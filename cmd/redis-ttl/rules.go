@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// ttlRules is a repeatable flag of rules of the form
+// "<regexp> <name>=<ttl>[,<name>=<ttl>...]", e.g.
+// `^cache:(\w+): billing=24h,search=1h,*=6h`, where the TTL is looked up by
+// the text captured by the first group of the regexp and * is the default.
+type ttlRules []redisttl.TTLRule
+
+func (r *ttlRules) String() string {
+	if r == nil {
+		return ""
+	}
+	parts := make([]string, len(*r))
+	for i, rule := range *r {
+		parts[i] = rule.Pattern.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r *ttlRules) Set(s string) error {
+	rule, err := parseTTLRule(s)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, rule)
+	return nil
+}
+
+// reset drops the rules set so far, so the rules of a target replace the
+// global ones.
+func (r *ttlRules) reset() {
+	*r = nil
+}
+
+func parseTTLRule(s string) (redisttl.TTLRule, error) {
+	pattern, ttls, found := strings.Cut(strings.TrimSpace(s), " ")
+	if !found {
+		return redisttl.TTLRule{}, fmt.Errorf("ttl rule %q must be of the form \"<regexp> <name>=<ttl>,...\": %w", s, errTTL)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return redisttl.TTLRule{}, fmt.Errorf("ttl rule %q: %w", s, err)
+	}
+
+	rule := redisttl.TTLRule{Pattern: re, TTLs: map[string]time.Duration{}}
+	for _, entry := range splitList(ttls) {
+		name, value, _ := strings.Cut(entry, "=")
+		var d ttl
+		if err := d.UnmarshalText([]byte(value)); err != nil || d.dur <= 0 {
+			return redisttl.TTLRule{}, fmt.Errorf("ttl rule %q: invalid ttl %q for %s: %w", s, value, name, errTTL)
+		}
+		if name == "*" {
+			rule.Default = d.AsDuration()
+			continue
+		}
+		rule.TTLs[name] = d.AsDuration()
+	}
+	if len(rule.TTLs) > 0 && re.NumSubexp() == 0 {
+		return redisttl.TTLRule{}, fmt.Errorf("ttl rule %q needs a capture group: %w", s, errTTL)
+	}
+	return rule, nil
+}
+
+// typeTTLs is a flag of the TTLs of every type, of the form
+// "<type>=<ttl>[,<type>=<ttl>...]", e.g. "string=1d,hash=7d,stream=30d".
+// Listing it several times adds to the TTLs.
+type typeTTLs map[string]time.Duration
+
+func (t *typeTTLs) String() string {
+	if t == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*t))
+	for typ, d := range *t {
+		parts = append(parts, typ+"="+d.String())
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+func (t *typeTTLs) Set(s string) error {
+	if *t == nil {
+		*t = typeTTLs{}
+	}
+	for _, entry := range splitList(s) {
+		typ, value, _ := strings.Cut(entry, "=")
+		var d ttl
+		if err := d.UnmarshalText([]byte(value)); err != nil || d.dur <= 0 {
+			return fmt.Errorf("type ttl %q: invalid ttl %q for %s: %w", s, value, typ, errTTL)
+		}
+		(*t)[strings.TrimSpace(typ)] = d.AsDuration()
+	}
+	return nil
+}
+
+// reset drops the TTLs set so far, so the TTLs of a target replace the
+// global ones.
+func (t *typeTTLs) reset() {
+	*t = nil
+}
+
+// modeRules is a repeatable flag of the rules of rules mode, written as
+// redisttl.ParseRule parses them, e.g. "session:* exp=1h" or
+// "session:admin:* noop priority=10".
+type modeRules redisttl.RuleSet
+
+func (r *modeRules) String() string {
+	if r == nil {
+		return ""
+	}
+	parts := make([]string, len(*r))
+	for i, rule := range *r {
+		parts[i] = rule.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *modeRules) Set(s string) error {
+	rule, err := redisttl.ParseRule(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errMode, err)
+	}
+	*r = append(*r, rule)
+	return nil
+}
+
+// reset drops the rules set so far, so the rules of a target replace the
+// global ones.
+func (r *modeRules) reset() {
+	*r = nil
+}
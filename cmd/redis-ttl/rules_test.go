@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestParseTTLRule(t *testing.T) {
+	rule, err := parseTTLRule(`^cache:(\w+): billing=1w,search=1h,*=6h`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Pattern.String() != `^cache:(\w+):` {
+		t.Fatalf("unexpected pattern %s", rule.Pattern)
+	}
+	if rule.TTLs["billing"] != 7*24*time.Hour || rule.TTLs["search"] != time.Hour || rule.Default != 6*time.Hour {
+		t.Fatalf("unexpected ttls %v default %s", rule.TTLs, rule.Default)
+	}
+
+	for _, s := range []string{
+		`^cache:(\w+):`,     // missing ttls
+		`^cache:(\w+ a=1h`,  // invalid regexp
+		`^cache:\w+: a=1h`,  // missing capture group
+		`^cache:(\w+): a=0`, // a ttl can't be 0
+		`^cache:(\w+): a=none`,
+	} {
+		if _, err := parseTTLRule(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestTTLRulesFlag(t *testing.T) {
+	cfg, err := parseConfig([]string{
+		"redis-ttl", "--mode=exp", "--desired-ttl=0s",
+		`--ttl-rule=^cache:(\w+): billing=24h`,
+		`--ttl-rule=^session: *=30m`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.ttlRules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(cfg.ttlRules))
+	}
+	if err := cfg.Err(); err != nil {
+		t.Fatalf("rules should replace --desired-ttl: %v", err)
+	}
+
+	cfg.ttlRules = nil
+	if err := cfg.Err(); !errors.Is(err, errTTL) {
+		t.Fatalf("expected errTTL without rules nor desired-ttl, got %v", err)
+	}
+}
+
+func TestTypeTTLsFlag(t *testing.T) {
+	cfg, err := parseConfig([]string{
+		"redis-ttl", "--mode=exp", "--scan-type=any",
+		"--type-ttls=string=1d,hash=1w",
+		"--type-ttls=stream=1mo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := typeTTLs{
+		"string": 24 * time.Hour,
+		"hash":   7 * 24 * time.Hour,
+		"stream": 30 * 24 * time.Hour,
+	}
+	if !maps.Equal(cfg.typeTTLs, want) {
+		t.Fatalf("want: %v got: %v", want, cfg.typeTTLs)
+	}
+	if cfg.scanType != "" {
+		t.Fatalf("any should scan every type, got %q", cfg.scanType)
+	}
+	if err := cfg.Err(); err != nil {
+		t.Fatalf("type ttls should replace --desired-ttl: %v", err)
+	}
+
+	cfg.scanType = "set"
+	if err := cfg.Err(); !errors.Is(err, errTTL) {
+		t.Fatalf("expected errTTL for a scanned type without ttl, got %v", err)
+	}
+
+	var invalid typeTTLs
+	if err := invalid.Set("hash=soon"); !errors.Is(err, errTTL) {
+		t.Fatalf("expected errTTL for an invalid ttl, got %v", err)
+	}
+}
+
+func TestModeRulesFlag(t *testing.T) {
+	cfg, err := parseConfig([]string{
+		"redis-ttl", "--mode=rules",
+		"--rule=session:* exp=30m",
+		"--rule=session:admin:* noop priority=1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(cfg.rules))
+	}
+	if err := cfg.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg.mode = "persist"
+	if err := cfg.Err(); !errors.Is(err, errMode) {
+		t.Fatalf("expected errMode for rules outside of rules mode, got %v", err)
+	}
+	cfg.mode, cfg.rules = "rules", nil
+	if err := cfg.Err(); !errors.Is(err, errMode) {
+		t.Fatalf("expected errMode for rules mode without rules, got %v", err)
+	}
+	if err := cfg.rules.Set("cache:*"); !errors.Is(err, errMode) {
+		t.Fatalf("expected errMode for a rule without mode, got %v", err)
+	}
+	if err := cfg.rules.Set("cache:* exp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Err(); !errors.Is(err, errMode) || !errors.Is(err, redisttl.ErrInvalidRule) {
+		t.Fatalf("expected an invalid rule without ttl, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// failoverOptions returns the options of the client of the master monitored
+// by the sentinels of --sentinel-addrs, following it across failovers.
+func (c *config) failoverOptions() *redis.FailoverOptions {
+	dialer, _ := c.dialer()
+	return &redis.FailoverOptions{
+		MasterName:    c.sentinelMaster,
+		SentinelAddrs: strings.Split(c.sentinelAddrs, ","),
+		ClientName:    "redis-ttl",
+		Dialer:        dialer,
+		// the failover options have no CredentialsProvider of their own
+		OnConnect: c.authenticate(),
+	}
+}
+
+// authenticate returns the OnConnect hook authenticating the connections of
+// the clients whose options have no CredentialsProvider.
+func (c *config) authenticate() func(ctx context.Context, cn *redis.Conn) error {
+	creds := c.credentials()
+	return func(ctx context.Context, cn *redis.Conn) error {
+		if creds == nil {
+			return nil
+		}
+		username, password := creds()
+		if password == "" {
+			return nil
+		}
+		return cn.AuthACL(ctx, username, password).Err()
+	}
+}
+
+// sentinelReplica asks the sentinels in turn for a healthy replica of
+// --sentinel-master, returning its address.
+func sentinelReplica(ctx context.Context, cfg *config) (string, error) {
+	dialer, _ := cfg.dialer()
+	var lastErr error
+	for _, addr := range strings.Split(cfg.sentinelAddrs, ",") {
+		s := redis.NewSentinelClient(&redis.Options{Addr: addr, ClientName: "redis-ttl-discovery", Dialer: dialer})
+		replicas, err := s.Replicas(ctx, cfg.sentinelMaster).Result()
+		_ = s.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("sentinel %s: %w", addr, err)
+			continue
+		}
+		if replica := healthyReplica(replicas); replica != "" {
+			return replica, nil
+		}
+		lastErr = fmt.Errorf("sentinel %s: no healthy replica of %s", addr, cfg.sentinelMaster)
+	}
+	return "", lastErr
+}
+
+// healthyReplica returns the address of the first replica neither down nor
+// disconnected from its master, as SENTINEL REPLICAS describes them, empty
+// when none is.
+func healthyReplica(replicas []map[string]string) string {
+	for _, r := range replicas {
+		healthy := r["master-link-status"] == "" || r["master-link-status"] == "ok"
+		for _, flag := range strings.Split(r["flags"], ",") {
+			switch flag {
+			case "s_down", "o_down", "disconnected":
+				healthy = false
+			}
+		}
+		if healthy && r["ip"] != "" && r["port"] != "" {
+			return net.JoinHostPort(r["ip"], r["port"])
+		}
+	}
+	return ""
+}
+
+// runSentinel runs against the master monitored by the sentinels, scanning
+// a replica with --sentinel-scan-replica while the commands modifying keys
+// go to the master.
+func runSentinel(ctx context.Context, cfg *config, out *collectors) error {
+	client := redis.NewFailoverClient(cfg.failoverOptions())
+	out.countCommands(client)
+	defer client.Close()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("master %s: %w", cfg.sentinelMaster, err)
+	}
+	if !cfg.sentinelScanReplica {
+		return runNode(ctx, out, cfg.sentinelMaster, client, newRunner(cfg, client, client, out))
+	}
+
+	addr, err := sentinelReplica(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("discover the replica to scan: %w", err)
+	}
+	log.Printf("scanning replica %s of %s\n", addr, cfg.sentinelMaster)
+	scanClient := redis.NewClient(cfg.clientOptions(addr, "redis-ttl-scan"))
+	out.countCommands(scanClient)
+	defer scanClient.Close()
+	return runNode(ctx, out, addr, scanClient, newRunner(cfg, client, scanClient, out))
+}
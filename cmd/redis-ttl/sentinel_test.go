@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+)
+
+// fakeSentinel registers on s the SENTINEL commands go-redis sends, for a
+// master named mymaster at master with the replicas described by replicas.
+func fakeSentinel(t *testing.T, s *miniredis.Miniredis, master string, replicas []map[string]string) {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(master)
+	err := s.Server().Register("SENTINEL", func(c *server.Peer, _ string, args []string) {
+		switch strings.ToLower(args[0]) {
+		case "get-master-addr-by-name":
+			c.WriteLen(2)
+			c.WriteBulk(host)
+			c.WriteBulk(port)
+		case "replicas":
+			c.WriteLen(len(replicas))
+			for _, r := range replicas {
+				c.WriteLen(2 * len(r))
+				for k, v := range r {
+					c.WriteBulk(k)
+					c.WriteBulk(v)
+				}
+			}
+		default:
+			c.WriteLen(0)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealthyReplica(t *testing.T) {
+	testCases := map[string]struct {
+		replicas []map[string]string
+		want     string
+	}{
+		"none": {},
+		"first healthy": {
+			replicas: []map[string]string{
+				{"ip": "10.0.0.1", "port": "6379", "flags": "slave,s_down"},
+				{"ip": "10.0.0.2", "port": "6379", "flags": "slave", "master-link-status": "err"},
+				{"ip": "10.0.0.3", "port": "6379", "flags": "slave,disconnected"},
+				{"ip": "10.0.0.4", "port": "6379", "flags": "slave", "master-link-status": "ok"},
+			},
+			want: "10.0.0.4:6379",
+		},
+		"ipv6": {
+			replicas: []map[string]string{{"ip": "::1", "port": "6379", "flags": "slave"}},
+			want:     "[::1]:6379",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := healthyReplica(tc.replicas); got != tc.want {
+				t.Fatalf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRunSentinelScanReplica(t *testing.T) {
+	master, replica, sentinel := miniredis.RunT(t), miniredis.RunT(t), miniredis.RunT(t)
+	for _, s := range []*miniredis.Miniredis{master, replica} {
+		_ = s.Set("foo:1", "bar")
+	}
+	host, port, _ := net.SplitHostPort(replica.Addr())
+	fakeSentinel(t, sentinel, master.Addr(), []map[string]string{
+		{"ip": host, "port": port, "flags": "slave", "master-link-status": "ok"},
+	})
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--sentinel-addrs=" + sentinel.Addr(),
+		"--sentinel-master=mymaster",
+		"--sentinel-scan-replica",
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	// the replica is scanned, the master modified
+	if master.TTL("foo:1") != time.Hour || replica.TTL("foo:1") != 0 {
+		t.Fatalf("want foo:1 modified on the master alone, got %v on the master and %v on the replica",
+			master.TTL("foo:1"), replica.TTL("foo:1"))
+	}
+}
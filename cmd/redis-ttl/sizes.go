@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// nodeSizes records the DBSIZE of every node of a cluster or ring run when
+// it starts, with its outcome, so the skew between shards shows and the
+// node taking the longest can be predicted. It is safe for concurrent use.
+type nodeSizes struct {
+	mu    sync.Mutex
+	nodes map[string]nodeSize
+}
+
+type nodeSize struct {
+	// keys is -1 when DBSIZE failed.
+	keys     int64
+	stats    redisttl.ScanStats
+	duration time.Duration
+	done     bool
+}
+
+// start asks c for the DBSIZE of the node at addr, logged and returned, -1
+// when it fails, which doesn't fail the run.
+func (n *nodeSizes) start(ctx context.Context, addr string, c redis.Cmdable) int64 {
+	keys, err := c.DBSize(ctx).Result()
+	if err != nil {
+		log.Printf("node %s: dbsize: %v\n", addr, err)
+		keys = -1
+	} else {
+		log.Printf("node %s: %d keys\n", addr, keys)
+	}
+	if n == nil {
+		return keys
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.nodes == nil {
+		n.nodes = map[string]nodeSize{}
+	}
+	n.nodes[addr] = nodeSize{keys: keys}
+	return keys
+}
+
+func (n *nodeSizes) finish(addr string, stats redisttl.ScanStats, d time.Duration) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	size := n.nodes[addr]
+	size.stats, size.duration, size.done = stats, d, true
+	n.nodes[addr] = size
+}
+
+func (n *nodeSizes) len() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.nodes)
+}
+
+// print prints every node, the largest first.
+func (n *nodeSizes) print(w io.Writer) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nodes := n.nodes
+	addrs := make([]string, 0, len(nodes))
+	for addr := range nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		if a, b := nodes[addrs[i]].keys, nodes[addrs[j]].keys; a != b {
+			return a > b
+		}
+		return addrs[i] < addrs[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tDBSIZE\tSCANNED\tMODIFIED\tERRORS\tDURATION")
+	for _, addr := range addrs {
+		size := nodes[addr]
+		keys, duration := "-", "-"
+		if size.keys >= 0 {
+			keys = fmt.Sprint(size.keys)
+		}
+		if size.done {
+			duration = size.duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\n",
+			addr, keys, size.stats.Scanned, size.stats.Modified, size.stats.Errors, duration)
+	}
+	return tw.Flush()
+}
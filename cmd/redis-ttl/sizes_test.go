@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNodeSizes(t *testing.T) {
+	small, large := miniredis.RunT(t), miniredis.RunT(t)
+	_ = small.Set("foo:1", "bar")
+	_ = large.Set("foo:1", "bar")
+	_ = large.Set("foo:2", "bar")
+	down := miniredis.RunT(t)
+	down.SetError("LOADING")
+
+	sizes := &nodeSizes{}
+	for _, s := range []*miniredis.Miniredis{small, large, down} {
+		c := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		defer c.Close()
+		sizes.start(context.Background(), s.Addr(), c)
+	}
+	sizes.finish(large.Addr(), redisttl.ScanStats{Scanned: 2, Modified: 1}, time.Second)
+	if size := sizes.nodes[down.Addr()]; size.keys != -1 {
+		t.Fatalf("want an unknown size for a failing node, got: %+v", size)
+	}
+
+	var buf bytes.Buffer
+	if err := sizes.print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("want a header and 3 nodes, got:\n%s", buf.String())
+	}
+	// the largest node first, the unknown sizes last
+	for i, want := range []string{large.Addr() + "  2  2  1  0  1s", small.Addr() + "  1", down.Addr() + "  -"} {
+		if fields := strings.Join(strings.Fields(lines[i+1]), "  "); !strings.HasPrefix(fields, want) {
+			t.Fatalf("line %d: want prefix %q, got %q", i+1, want, fields)
+		}
+	}
+
+	var none *nodeSizes
+	none.finish(small.Addr(), redisttl.ScanStats{}, 0)
+}
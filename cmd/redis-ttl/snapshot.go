@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshot is the content of --summary-file: the summary accumulated so far,
+// so partial results survive a crash and progress can be polled.
+type snapshot struct {
+	StartedAt     time.Time        `json:"started_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Done          bool             `json:"done"`
+	Scanned       int64            `json:"scanned"`
+	Modified      int64            `json:"modified"`
+	Errors        int64            `json:"errors"`
+	Companions    int64            `json:"companions,omitempty"`
+	Tolerated     int64            `json:"tolerated,omitempty"`
+	BreakerTrips  int64            `json:"breaker_trips,omitempty"`
+	Failovers     int64            `json:"failovers,omitempty"`
+	ErrorsByClass map[string]int64 `json:"errors_by_class,omitempty"`
+	// Nodes are the nodes being processed.
+	Nodes []string `json:"nodes"`
+}
+
+func newSnapshot(started time.Time, out *collectors, done bool) snapshot {
+	s := out.summary
+	snap := snapshot{
+		StartedAt:     started,
+		UpdatedAt:     time.Now(),
+		Done:          done,
+		Scanned:       s.Scanned.Load(),
+		Modified:      s.Modified.Load(),
+		Errors:        s.Errors.Load(),
+		Companions:    s.Companions.Load(),
+		Tolerated:     s.Tolerated.Load(),
+		BreakerTrips:  s.BreakerTrips.Load(),
+		Failovers:     s.Failovers.Load(),
+		ErrorsByClass: s.ErrorClasses.Snapshot(),
+		Nodes:         []string{},
+	}
+	if out.active != nil {
+		out.active.mu.Lock()
+		for _, n := range out.active.nodes {
+			snap.Nodes = append(snap.Nodes, n.addr)
+		}
+		out.active.mu.Unlock()
+		sort.Strings(snap.Nodes)
+	}
+	return snap
+}
+
+// writeJSONFile replaces path with v as JSON, through a temporary file so
+// pollers never read a partial one.
+func writeJSONFile(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeSnapshots writes the summary to path every interval until the
+// returned func is called, which writes it one last time. Failures are
+// logged, the run keeping on.
+func writeSnapshots(path string, interval time.Duration, out *collectors) func() {
+	started := time.Now()
+	return every(interval, func(done bool) {
+		if err := writeJSONFile(path, newSnapshot(started, out, done)); err != nil {
+			log.Printf("summary file error: %v\n", err)
+		}
+	})
+}
+
+// every calls fn with false every interval until the returned func is
+// called, which calls it one last time with true.
+func every(interval time.Duration, fn func(done bool)) func() {
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				fn(false)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		fn(true)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func readSnapshot(t *testing.T, path string) (snapshot, error) {
+	t.Helper()
+	var snap snapshot
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+	return snap, json.Unmarshal(b, &snap)
+}
+
+func TestWriteSnapshots(t *testing.T) {
+	out := &collectors{summary: &redisttl.Summary{}, active: &activeNodes{}}
+	out.summary.Scanned.Add(3)
+	out.summary.Modified.Add(2)
+	s := &redisttl.Scanner{}
+	out.active.add("a:6379", s)
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	stop := writeSnapshots(path, time.Millisecond, out)
+
+	deadline := time.Now().Add(time.Second)
+	snap, err := readSnapshot(t, path)
+	for err != nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		snap, err = readSnapshot(t, path)
+	}
+	if err != nil {
+		t.Fatalf("expected a snapshot while running: %v", err)
+	}
+	if snap.Done || snap.Scanned != 3 || snap.Modified != 2 || len(snap.Nodes) != 1 || snap.Nodes[0] != "a:6379" {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	out.summary.Scanned.Add(1)
+	out.active.remove(s)
+	stop()
+	if snap, err = readSnapshot(t, path); err != nil {
+		t.Fatal(err)
+	}
+	if !snap.Done || snap.Scanned != 4 || len(snap.Nodes) != 0 {
+		t.Fatalf("unexpected final snapshot: %+v", snap)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Outcomes of a run, as written to --status-file.
+const (
+	stateCompleted   = "completed"
+	stateInterrupted = "interrupted"
+	stateFailed      = "failed"
+)
+
+// exitStatus is the outcome of the run written to --status-file at exit, so
+// workflow orchestrators can tell an interrupted run, which can resume from
+// its cursors, from a failed one without parsing the logs.
+type exitStatus struct {
+	State   string    `json:"state"`
+	Error   string    `json:"error,omitempty"`
+	EndedAt time.Time `json:"ended_at"`
+	// Cursors are the cursors the scan of every node stopped at, 0 for the
+	// nodes scanned to the end.
+	Cursors map[string]uint64 `json:"cursors"`
+	snapshot
+}
+
+// newExitStatus returns the status of the run started at started, which
+// returned err, ctx being done when it was stopped by a signal.
+func newExitStatus(ctx context.Context, started time.Time, out *collectors, err error) exitStatus {
+	st := exitStatus{
+		State:    stateCompleted,
+		EndedAt:  time.Now(),
+		Cursors:  out.active.lastCursors(),
+		snapshot: newSnapshot(started, out, true),
+	}
+	switch {
+	case errors.Is(err, context.Canceled) || ctx.Err() != nil:
+		st.State = stateInterrupted
+	case err != nil:
+		st.State = stateFailed
+	}
+	if err != nil {
+		st.Error = err.Error()
+	}
+	if st.Cursors == nil {
+		st.Cursors = map[string]uint64{}
+	}
+	return st
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestNewExitStatus(t *testing.T) {
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	testCases := map[string]struct {
+		ctx   context.Context
+		err   error
+		state string
+	}{
+		"completed":           {ctx: ctx, state: stateCompleted},
+		"failed":              {ctx: ctx, err: errors.New("connection refused"), state: stateFailed},
+		"interrupted":         {ctx: ctx, err: context.Canceled, state: stateInterrupted},
+		"stopped by a signal": {ctx: canceled, state: stateInterrupted},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			out := &collectors{summary: &redisttl.Summary{}, active: &activeNodes{}}
+			st := newExitStatus(tc.ctx, time.Now(), out, tc.err)
+			if st.State != tc.state || (tc.err != nil) != (st.Error != "") || !st.Done {
+				t.Fatalf("unexpected status: %+v", st)
+			}
+		})
+	}
+}
+
+func TestRunStatusFile(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:2", "bar")
+	path := filepath.Join(t.TempDir(), "status.json")
+
+	if err := run([]string{
+		"redis-ttl",
+		"--mode=exp",
+		"--scan-prefix=foo*",
+		"--desired-ttl=1h",
+		"--redis-addr=" + s.Addr(),
+		"--status-file=" + path,
+	}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st exitStatus
+	if err := json.Unmarshal(b, &st); err != nil {
+		t.Fatal(err)
+	}
+	cursor, found := st.Cursors[s.Addr()]
+	if st.State != stateCompleted || st.Scanned != 2 || st.Modified != 2 || !found || cursor != 0 {
+		t.Fatalf("unexpected status: %s", b)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	redisttl "github.com/pims/redis-ttl"
+)
+
+// runTargets runs the targets of the config file, --target-concurrency at
+// once, each with its own collectors. The confirmations are all asked
+// first, and a target failing doesn't stop the others.
+func runTargets(ctx context.Context, cfg *config) error {
+	for i := range cfg.targets {
+		if err := confirm(ctx, &cfg.targets[i], os.Stdin, os.Stderr); err != nil {
+			return fmt.Errorf("target %s: %w", cfg.targets[i].target, err)
+		}
+	}
+
+	sem := newSemaphore(cfg.targetConcurrency)
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for i := range cfg.targets {
+		t := &cfg.targets[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := runTarget(ctx, t, sem)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("target %s: %w", t.target, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func runTarget(ctx context.Context, cfg *config, sem semaphore) error {
+	if err := sem.acquire(ctx); err != nil {
+		return err
+	}
+	defer sem.release()
+
+	log.Printf("target %s: starting\n", cfg.target)
+	out := &collectors{
+		summary:  &redisttl.Summary{},
+		commands: &redisttl.CommandCounter{},
+		active:   &activeNodes{},
+	}
+	closeKillSwitch, err := startKillSwitch(ctx, cfg, out)
+	if err != nil {
+		return err
+	}
+	defer closeKillSwitch()
+	defer openDestination(cfg, out)()
+	err = runOnce(ctx, cfg, out)
+	log.Printf("target %s: done, %s\n", cfg.target, out.summary)
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestTargets(t *testing.T) {
+	a := miniredis.RunT(t)
+	b := miniredis.RunT(t)
+	for _, s := range []*miniredis.Miniredis{a, b} {
+		_ = s.Set("cache:1", "bar")
+		_ = s.Set("session:1", "bar")
+	}
+
+	path := writeConfigFile(t, `
+mode=exp
+desired-ttl=1h
+ttl-rule=^cache: *=2h
+scan-prefix=cache:*
+
+[a]
+redis-addr=`+a.Addr()+`
+
+[b]
+redis-addr=`+b.Addr()+`
+scan-prefix=session:*
+ttl-rule=^session: *=3h
+`)
+	if err := run([]string{"redis-ttl", "--config=" + path, "--target-concurrency=2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[*miniredis.Miniredis]map[string]time.Duration{
+		a: {"cache:1": 2 * time.Hour, "session:1": 0},
+		b: {"cache:1": 0, "session:1": 3 * time.Hour},
+	}
+	for s, ttls := range expected {
+		for k, dur := range ttls {
+			if ttl := s.TTL(k); ttl != dur {
+				t.Fatalf("ttl don't match for: %s on %s, got:%v want: %v", k, s.Addr(), ttl, dur)
+			}
+		}
+	}
+}
+
+func TestTargetsErr(t *testing.T) {
+	testCases := map[string]string{
+		"invalid target":   "mode=exp\n[a]\ndesired-ttl=0s\n",
+		"duplicate target": "[a]\n[a]\n",
+		"unknown setting":  "[a]\nredis-adr=:6379\n",
+	}
+	for name, content := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := parseConfig([]string{"redis-ttl", "--config=" + writeConfigFile(t, content)})
+			if err == nil {
+				err = cfg.Err()
+			}
+			if !errors.Is(err, errConfig) && !errors.Is(err, errTTL) {
+				t.Fatalf("want a config error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// printTopology prints the primaries of the cluster, each followed by its
+// replicas, with their slots and key counts, and checks scanning every
+// primary covers all the slots, a sanity check before a cluster run.
+func printTopology(ctx context.Context, cfg *config, w io.Writer) error {
+	seeds, err := clusterSeeds(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	topology, err := discoverTopology(ctx, cfg, seeds)
+	if err != nil {
+		return fmt.Errorf("discover topology through %s: %w", strings.Join(seeds, ","), err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROLE\tADDR\tID\tSLOTS\tKEYS\tHEALTHY")
+	for _, n := range topology.Nodes {
+		if n.Role != redisttl.RolePrimary {
+			continue
+		}
+		printTopologyNode(ctx, cfg, tw, n)
+		for _, replica := range topology.Replicas(n.ID) {
+			printTopologyNode(ctx, cfg, tw, replica)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if uncovered := topology.UncoveredSlots(); len(uncovered) > 0 {
+		return fmt.Errorf("no healthy primary serves slots %s: %w", formatSlots(uncovered), errCoverage)
+	}
+	_, err = fmt.Fprintf(w, "# the primaries cover all %d slots\n", redisttl.ClusterSlots)
+	return err
+}
+
+func printTopologyNode(ctx context.Context, cfg *config, w io.Writer, n redisttl.ClusterNode) {
+	addr := n.DialAddr(cfg.preferHostnames)
+	keys := "-"
+	if n.Healthy {
+		c := redis.NewClient(cfg.clientOptions(addr, "redis-ttl-topology"))
+		if size, err := c.DBSize(ctx).Result(); err != nil {
+			log.Printf("node %s: dbsize: %v\n", addr, err)
+		} else {
+			keys = strconv.FormatInt(size, 10)
+		}
+		_ = c.Close()
+	}
+	slots := formatSlots(n.Slots)
+	if slots == "" {
+		slots = "-"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", n.Role, addr, n.ID, slots, keys, n.Healthy)
+}
+
+// formatSlots formats ranges the way CLUSTER NODES does, e.g. 0-5460,5461.
+func formatSlots(ranges []redisttl.SlotRange) string {
+	s := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Start == r.End {
+			s = append(s, strconv.Itoa(r.Start))
+		} else {
+			s = append(s, fmt.Sprintf("%d-%d", r.Start, r.End))
+		}
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	redisttl "github.com/pims/redis-ttl"
+)
+
+func TestPrintTopology(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:2", "bar")
+
+	// miniredis announces itself at 127.0.0.1:7000, serving every slot
+	cfg := config{redisClusterAddrs: s.Addr(), addrMap: "127.0.0.1:7000=" + s.Addr()}
+	var buf bytes.Buffer
+	if err := printTopology(context.Background(), &cfg, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"primary", "127.0.0.1:7000", "0-16383", "2", "cover all 16384 slots"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("want %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatSlots(t *testing.T) {
+	ranges := []redisttl.SlotRange{{Start: 0, End: 5460}, {Start: 5461, End: 5461}}
+	if got := formatSlots(ranges); got != "0-5460,5461" {
+		t.Fatalf("want: 0-5460,5461 got: %s", got)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// universalOptions returns the options of the client of --universal-addrs:
+// a standalone client for a single address, a sentinel client with
+// --universal-master, and a cluster client otherwise.
+func (c *config) universalOptions() *redis.UniversalOptions {
+	dialer, _ := c.dialer()
+	return &redis.UniversalOptions{
+		Addrs:      strings.Split(c.universalAddrs, ","),
+		MasterName: c.universalMaster,
+		ClientName: "redis-ttl",
+		Dialer:     dialer,
+		// the universal options have no CredentialsProvider of their own
+		OnConnect: c.authenticate(),
+	}
+}
+
+// universalCluster reports whether --universal-addrs makes a cluster client.
+func (c *config) universalCluster() bool {
+	return c.universalMaster == "" && strings.Contains(c.universalAddrs, ",")
+}
+
+// runUniversal runs a single Scanner against the client of --universal-addrs,
+// which scans every primary itself when it's a cluster client.
+func runUniversal(ctx context.Context, cfg *config, out *collectors) error {
+	client := redis.NewUniversalClient(cfg.universalOptions())
+	defer client.Close()
+	switch c := client.(type) {
+	case *redis.ClusterClient:
+		c.OnNewNode(out.countCommands)
+	case *redis.Client:
+		out.countCommands(c)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("universal client %s: %w", cfg.universalAddrs, err)
+	}
+	r := newRunner(cfg, client, client, out)
+	out.active.add(cfg.universalAddrs, r)
+	defer out.active.remove(r)
+	return r.Run(ctx)
+}
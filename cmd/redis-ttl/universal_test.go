@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRunUniversal(t *testing.T) {
+	testCases := map[string]func(addr string) string{
+		"standalone": func(addr string) string { return addr },
+		"cluster":    func(addr string) string { return addr + "," + addr },
+	}
+	for name, addrs := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("foo:1", "bar")
+			_ = s.Set("foo:2", "bar")
+
+			if err := run([]string{
+				"redis-ttl",
+				"--mode=exp",
+				"--scan-prefix=foo*",
+				"--desired-ttl=1h",
+				"--universal-addrs=" + addrs(s.Addr()),
+			}); err != nil {
+				t.Fatalf("expected nil, got: %v", err)
+			}
+			for _, key := range []string{"foo:1", "foo:2"} {
+				if ttl := s.TTL(key); ttl != time.Hour {
+					t.Fatalf("want %s expired, got %v", key, ttl)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	redisttl "github.com/pims/redis-ttl"
+	"github.com/redis/go-redis/v9"
+)
+
+// validate prints the effective settings of a run, already validated by
+// cfg.Err, without running it. With --connect, it also checks every node is
+// reachable. The settings are printed in the --config file format.
+func validate(ctx context.Context, cfg *config, fs *flag.FlagSet, w io.Writer) error {
+	if cfg.connect {
+		if err := checkConnectivity(ctx, cfg); err != nil {
+			return fmt.Errorf("connectivity check: %w", err)
+		}
+	}
+	printSettings(w, fs)
+	_, err := fmt.Fprintln(w, "# config is valid")
+	return err
+}
+
+func printSettings(w io.Writer, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case "config", "connect":
+			return
+		}
+		switch rules := f.Value.(type) {
+		case *ttlRules:
+			for _, rule := range *rules {
+				fmt.Fprintf(w, "%s=%s\n", f.Name, formatTTLRule(rule))
+			}
+		case *modeRules:
+			for _, rule := range *rules {
+				fmt.Fprintf(w, "%s=%s\n", f.Name, rule)
+			}
+		default:
+			fmt.Fprintf(w, "%s=%s\n", f.Name, f.Value)
+		}
+	})
+}
+
+// formatTTLRule formats rule the way --ttl-rule parses it.
+func formatTTLRule(rule redisttl.TTLRule) string {
+	ttls := make([]string, 0, len(rule.TTLs)+1)
+	for name, d := range rule.TTLs {
+		ttls = append(ttls, name+"="+d.String())
+	}
+	sort.Strings(ttls)
+	if rule.Default > 0 {
+		ttls = append(ttls, "*="+rule.Default.String())
+	}
+	return rule.Pattern.String() + " " + strings.Join(ttls, ",")
+}
+
+// checkConnectivity pings the node, or every primary of the cluster, the
+// run would process.
+func checkConnectivity(ctx context.Context, cfg *config) error {
+	switch {
+	case cfg.clusterConfigEndpoint != "":
+		seeds, err := redisttl.ResolveConfigEndpoint(ctx, cfg.clusterConfigEndpoint)
+		if err != nil {
+			return err
+		}
+		_, err = discoverTopology(ctx, cfg, seeds)
+		return err
+	case cfg.sentinelAddrs != "":
+		c := redis.NewFailoverClient(cfg.failoverOptions())
+		defer c.Close()
+		return c.Ping(ctx).Err()
+	case cfg.universalAddrs != "":
+		c := redis.NewUniversalClient(cfg.universalOptions())
+		defer c.Close()
+		return c.Ping(ctx).Err()
+	case cfg.redisRingAddrs != "":
+		shards, err := ringShards(cfg.redisRingAddrs)
+		if err != nil {
+			return err
+		}
+		ring := newRing(cfg, shards)
+		defer ring.Close()
+		return ring.ForEachShard(ctx, func(ctx context.Context, c *redis.Client) error {
+			if err := c.Ping(ctx).Err(); err != nil {
+				return fmt.Errorf("shard %s: %w", c.Options().Addr, err)
+			}
+			return nil
+		})
+	case cfg.redisClusterAddrs != "":
+		c := redis.NewClusterClient(cfg.clusterOptions(strings.Split(cfg.redisClusterAddrs, ",")))
+		defer c.Close()
+		return c.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error {
+			if err := c.Ping(ctx).Err(); err != nil {
+				return fmt.Errorf("node %s: %w", c.Options().Addr, err)
+			}
+			return nil
+		})
+	}
+
+	writeAddr, scanAddr := cfg.nodeAddrs()
+	for _, addr := range slices.Compact([]string{writeAddr, scanAddr}) {
+		c := redis.NewClient(cfg.clientOptions(addr, "redis-ttl"))
+		err := c.Ping(ctx).Err()
+		_ = c.Close()
+		if err != nil {
+			return fmt.Errorf("node %s: %w", addr, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "redis-ttl.conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFile(t *testing.T) {
+	path := writeConfigFile(t, `
+# nightly cleanup
+mode=exp
+--desired-ttl = 1w
+dry-run
+rps=10
+ttl-rule=^cache:(\w+): billing=24h
+ttl-rule=^session: *=30m
+`)
+	cfg, _, err := parseFlags([]string{"redis-ttl", "--config=" + path, "--rps=5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case cfg.mode != "exp" || cfg.desiredTTL.String() != "168h0m0s" || !cfg.dryRun:
+		t.Fatalf("settings not loaded from the config file: %+v", cfg)
+	case cfg.rps != 5:
+		t.Fatalf("flags should take precedence over the config file, got rps=%g", cfg.rps)
+	case len(cfg.ttlRules) != 2:
+		t.Fatalf("expected 2 ttl rules, got %d", len(cfg.ttlRules))
+	}
+
+	for content, want := range map[string]error{
+		"mode=exp\nttl-max=1h": errConfig,
+		"rps=ten":              nil,
+	} {
+		_, _, err := parseFlags([]string{"redis-ttl", "--config=" + writeConfigFile(t, content)})
+		if err == nil || (want != nil && !errors.Is(err, want)) {
+			t.Fatalf("expected an error for %q, got %v", content, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := miniredis.RunT(t)
+	path := writeConfigFile(t, "mode=exp\ndesired-ttl=1w\nredis-addr="+s.Addr()+"\nttl-rule=^cache:(\\w+): b=1h,a=2h,*=3h\n")
+
+	cfg, fs, err := parseFlags([]string{"redis-ttl", "validate", "--config=" + path, "--connect"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.validate || !cfg.connect {
+		t.Fatalf("expected the validate subcommand, got %+v", cfg)
+	}
+	var buf bytes.Buffer
+	if err := validate(context.Background(), &cfg, fs, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"mode=exp\n",
+		"desired-ttl=168h0m0s\n",
+		"rps=100\n",
+		`ttl-rule=^cache:(\w+): a=2h0m0s,b=1h0m0s,*=3h0m0s` + "\n",
+		"# config is valid\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "config=") {
+		t.Fatalf("--config should not be printed:\n%s", buf.String())
+	}
+
+	// the printed settings are a valid config file resolving to the same ones
+	_, again, err := parseFlags([]string{"redis-ttl", "--config=" + writeConfigFile(t, buf.String())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var round bytes.Buffer
+	printSettings(&round, again)
+	if !strings.HasPrefix(buf.String(), round.String()) {
+		t.Fatalf("settings differ after a round trip:\n%s\nvs\n%s", buf.String(), round.String())
+	}
+
+	s.Close()
+	if err := validate(context.Background(), &cfg, fs, &buf); err == nil {
+		t.Fatal("expected a connectivity error")
+	}
+	cfg.connect = false
+	if err := validate(context.Background(), &cfg, fs, &buf); err != nil {
+		t.Fatalf("connectivity should only be checked with --connect, got %v", err)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	path := writeConfigFile(t, "mode=exp\ndesired-ttl=0s\n")
+	if err := run([]string{"redis-ttl", "validate", "--config=" + path}); !errors.Is(err, errTTL) {
+		t.Fatalf("expected errTTL, got %v", err)
+	}
+}
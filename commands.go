@@ -0,0 +1,129 @@
+package redisttl
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Kinds of commands counted by a CommandCounter.
+const (
+	CommandScan  = "scan"
+	CommandRead  = "read"
+	CommandWrite = "write"
+	CommandOther = "other"
+)
+
+// commandKinds maps the commands sent by the modes to their kind.
+var commandKinds = map[string]string{
+	"scan":    CommandScan,
+	"pttl":    CommandRead,
+	"ttl":     CommandRead,
+	"type":    CommandRead,
+	"memory":  CommandRead,
+	"strlen":  CommandRead,
+	"llen":    CommandRead,
+	"scard":   CommandRead,
+	"zcard":   CommandRead,
+	"hlen":    CommandRead,
+	"expire":  CommandWrite,
+	"pexpire": CommandWrite,
+	"persist": CommandWrite,
+	"evalsha": CommandWrite,
+	"eval":    CommandWrite,
+}
+
+// NodeCommands are the commands sent to a node, by kind.
+type NodeCommands struct {
+	Node  string
+	Scan  int64
+	Read  int64
+	Write int64
+	Other int64
+}
+
+func (n NodeCommands) Total() int64 {
+	return n.Scan + n.Read + n.Write + n.Other
+}
+
+// CommandCounter counts the commands sent to every node, so the load of a
+// run can be compared to the traffic of the application. It is safe for
+// concurrent use.
+type CommandCounter struct {
+	mu    sync.Mutex
+	nodes map[string]*NodeCommands
+}
+
+// Hook returns a hook counting the commands sent by the client it is added
+// to, pipelined ones included, as sent to node.
+func (c *CommandCounter) Hook(node string) redis.Hook {
+	return commandHook{counter: c, node: node}
+}
+
+func (c *CommandCounter) count(node string, cmds ...redis.Cmder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes == nil {
+		c.nodes = map[string]*NodeCommands{}
+	}
+	n, found := c.nodes[node]
+	if !found {
+		n = &NodeCommands{Node: node}
+		c.nodes[node] = n
+	}
+	for _, cmd := range cmds {
+		switch commandKinds[cmd.Name()] {
+		case CommandScan:
+			n.Scan++
+		case CommandRead:
+			n.Read++
+		case CommandWrite:
+			n.Write++
+		default:
+			n.Other++
+		}
+	}
+}
+
+// Nodes returns the commands counted so far, sorted by node, none for a nil
+// CommandCounter.
+func (c *CommandCounter) Nodes() []NodeCommands {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodes := make([]NodeCommands, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		nodes = append(nodes, *n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Node < nodes[j].Node
+	})
+	return nodes
+}
+
+type commandHook struct {
+	counter *CommandCounter
+	node    string
+}
+
+func (h commandHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h commandHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.counter.count(h.node, cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (h commandHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.counter.count(h.node, cmds...)
+		return next(ctx, cmds)
+	}
+}
@@ -0,0 +1,47 @@
+package redisttl
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCommandCounter(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo", "far", "fiz"} {
+		_ = s.Set(k, "bar")
+	}
+
+	counter := &CommandCounter{}
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(counter.Hook("a"))
+
+	for _, mode := range []string{"exp", "report"} {
+		f := Scanner{
+			Mode:       mode,
+			ScanPrefix: "f*",
+			Client:     rdb,
+			DesiredTTL: time.Hour,
+			Report:     &PrefixReport{},
+		}
+		if err := f.Run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_ = rdb.Ping(context.Background())
+
+	// report reads the TTLs in a single pipeline
+	want := []NodeCommands{{Node: "a", Scan: 2, Read: 3, Write: 3, Other: 1}}
+	if got := counter.Nodes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %+v got: %+v", want, got)
+	}
+	if total := counter.Nodes()[0].Total(); total != 9 {
+		t.Fatalf("want 9 commands, got %d", total)
+	}
+}
@@ -0,0 +1,79 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// copyKey copies key onto Destination with DUMP and RESTORE, with its
+// remaining TTL, e.g. to move a single prefix to another instance with its
+// expirations intact. The source is left untouched, and so are the keys
+// which expired since they were scanned. A key already on the destination
+// fails with a BUSYKEY error unless CopyReplace is set.
+func (f *Scanner) copyKey(ctx context.Context, key string, _ time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx)
+	value, ttl, found, err := f.dump(ctx, key)
+	if err != nil || !found {
+		cmd.SetErr(err)
+		return cmd
+	}
+	restore := f.Destination.Restore
+	if f.CopyReplace {
+		restore = f.Destination.RestoreReplace
+	}
+	if err := restore(ctx, key, ttl, value).Err(); err != nil {
+		cmd.SetErr(fmt.Errorf("restore error: %w", err))
+		return cmd
+	}
+	cmd.SetVal(true)
+	return cmd
+}
+
+// dump returns the serialized value of key and its TTL as RESTORE expects
+// it, 0 for none, and false when the key doesn't exist.
+func (f *Scanner) dump(ctx context.Context, key string) (string, time.Duration, bool, error) {
+	pipe := f.Client.Pipeline()
+	pttl := pipe.PTTL(ctx, key)
+	dump := pipe.Dump(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return "", 0, false, fmt.Errorf("dump error: %w", err)
+	}
+	ttl := pttl.Val()
+	if ttl == -2 || dump.Err() == redis.Nil {
+		return "", 0, false, nil
+	}
+	if ttl == noTTL {
+		ttl = 0
+	}
+	// RESTORE with a TTL of 0 would never expire the key
+	if ttl > 0 && ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	return dump.Val(), ttl, true, nil
+}
+
+// copyDryRun is the dry-run counterpart of copyKey, printing the keys it
+// would copy with their TTL.
+func (f *Scanner) copyDryRun(ctx context.Context, key string, _ time.Duration) *redis.BoolCmd {
+	w := f.Diff
+	if w == nil {
+		w = os.Stdout
+	}
+	cmd := redis.NewBoolCmd(ctx)
+	ttl, err := f.Client.PTTL(ctx, key).Result()
+	if err != nil || ttl == -2 {
+		cmd.SetErr(err)
+		return cmd
+	}
+	diffMu.Lock()
+	_, err = fmt.Fprintf(w, "%s: %s [%s would apply]\n", key, formatTTL(ttl), f.Mode)
+	diffMu.Unlock()
+
+	cmd.SetVal(true)
+	cmd.SetErr(err)
+	return cmd
+}
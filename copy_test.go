@@ -0,0 +1,119 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeDumpRestore adds the DUMP and RESTORE commands miniredis lacks, for
+// string keys, the serialized value being the value itself.
+func fakeDumpRestore(t *testing.T, s *miniredis.Miniredis) {
+	t.Helper()
+	_ = s.Server().Register("DUMP", func(c *server.Peer, _ string, args []string) {
+		v, err := s.Get(args[0])
+		if err != nil {
+			c.WriteNull()
+			return
+		}
+		c.WriteBulk(v)
+	})
+	_ = s.Server().Register("RESTORE", func(c *server.Peer, _ string, args []string) {
+		key, value := args[0], args[2]
+		if s.Exists(key) && (len(args) < 4 || !strings.EqualFold(args[3], "replace")) {
+			c.WriteError("BUSYKEY Target key name already exists.")
+			return
+		}
+		_ = s.Set(key, value)
+		if ms, _ := strconv.Atoi(args[1]); ms > 0 {
+			s.SetTTL(key, time.Duration(ms)*time.Millisecond)
+		}
+		c.WriteOK()
+	})
+}
+
+func TestCopyMode(t *testing.T) {
+	tests := map[string]struct {
+		replace  bool
+		modified int64
+		errors   int64
+		want     string
+	}{
+		"copy": {
+			modified: 2,
+			errors:   1,
+			want:     "old",
+		},
+		"replace": {
+			replace:  true,
+			modified: 3,
+			want:     "bar",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			src, dst := miniredis.RunT(t), miniredis.RunT(t)
+			fakeDumpRestore(t, src)
+			fakeDumpRestore(t, dst)
+			for _, k := range []string{"user:1", "user:2", "user:3", "other"} {
+				_ = src.Set(k, "bar")
+			}
+			src.SetTTL("user:1", time.Hour)
+			_ = dst.Set("user:3", "old")
+
+			summary := &Summary{}
+			f := Scanner{
+				Mode:        "copy",
+				ScanPrefix:  "user:*",
+				Client:      redis.NewClient(&redis.Options{Addr: src.Addr()}),
+				Destination: redis.NewClient(&redis.Options{Addr: dst.Addr()}),
+				CopyReplace: tc.replace,
+				Summary:     summary,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if v, _ := dst.Get("user:1"); v != "bar" || dst.TTL("user:1") != time.Hour {
+				t.Fatalf("want user:1 copied with its ttl, got: %q %v", v, dst.TTL("user:1"))
+			}
+			if v, _ := dst.Get("user:2"); v != "bar" || dst.TTL("user:2") != 0 {
+				t.Fatalf("want user:2 copied without ttl, got: %q %v", v, dst.TTL("user:2"))
+			}
+			if v, _ := dst.Get("user:3"); v != tc.want {
+				t.Fatalf("want user:3 %q, got: %q", tc.want, v)
+			}
+			if dst.Exists("other") || !src.Exists("user:1") {
+				t.Fatal("want only the matched keys copied, the source untouched")
+			}
+			if summary.Modified.Load() != tc.modified || summary.Errors.Load() != tc.errors {
+				t.Fatalf("want %d keys copied and %d errors, got: %s", tc.modified, tc.errors, summary)
+			}
+		})
+	}
+}
+
+func TestCopyModeRequiresDestination(t *testing.T) {
+	s := miniredis.RunT(t)
+	f := Scanner{Mode: "copy", Client: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+	if err := f.Run(context.Background()); err == nil {
+		t.Fatal("expected error without destination, got nil")
+	}
+
+	_ = s.Set("user:1", "bar")
+	var diff bytes.Buffer
+	f.DryRun, f.Diff = true, &diff
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("dry-runs don't need a destination, got: %v", err)
+	}
+	if want := "user:1: no-ttl [copy would apply]"; !strings.Contains(diff.String(), want) {
+		t.Fatalf("want %q in:\n%s", want, diff.String())
+	}
+}
@@ -0,0 +1,122 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyCounter counts the keys of a node matching a run's selection, so the
+// counts taken before and after the run show its effect on the keyspace.
+type KeyCounter struct {
+	Client     redis.Cmdable
+	ScanPrefix string
+	// ScanPatterns, when set, replace ScanPrefix, see Scanner.ScanPatterns.
+	ScanPatterns []string
+	ScanType     string
+	ScanCount    int64
+	// Limiter, when set, is waited on before every SCAN batch.
+	Limiter limiter
+}
+
+// KeyCount is what a KeyCounter counted on a node.
+type KeyCount struct {
+	DBSize int64
+	// Matched keys match the scan prefix and type, WithoutTTL of them
+	// having no TTL.
+	Matched    int64
+	WithoutTTL int64
+}
+
+// Sub returns the difference between c and before, e.g. the count taken
+// after a run minus the one taken before.
+func (c KeyCount) Sub(before KeyCount) KeyCount {
+	return KeyCount{
+		DBSize:     c.DBSize - before.DBSize,
+		Matched:    c.Matched - before.Matched,
+		WithoutTTL: c.WithoutTTL - before.WithoutTTL,
+	}
+}
+
+// Add returns the sum of c and other, e.g. to total the counts of the
+// nodes of a cluster.
+func (c KeyCount) Add(other KeyCount) KeyCount {
+	return KeyCount{
+		DBSize:     c.DBSize + other.DBSize,
+		Matched:    c.Matched + other.Matched,
+		WithoutTTL: c.WithoutTTL + other.WithoutTTL,
+	}
+}
+
+// Count scans the whole keyspace of the node, reading the TTL of every
+// matched key with a pipeline per batch.
+func (k *KeyCounter) Count(ctx context.Context) (KeyCount, error) {
+	size, err := k.Client.DBSize(ctx).Result()
+	if err != nil {
+		return KeyCount{}, fmt.Errorf("dbsize error: %w", err)
+	}
+	count := KeyCount{DBSize: size}
+
+	match := k.ScanPrefix
+	if len(k.ScanPatterns) > 0 {
+		match = ""
+	}
+	var cursor uint64
+	for {
+		if k.Limiter != nil {
+			if err := k.Limiter.Wait(ctx); err != nil {
+				return KeyCount{}, err
+			}
+		}
+		var keys []string
+		var next uint64
+		if k.ScanType != "" {
+			keys, next, err = k.Client.ScanType(ctx, cursor, match, k.ScanCount, k.ScanType).Result()
+		} else {
+			keys, next, err = k.Client.Scan(ctx, cursor, match, k.ScanCount).Result()
+		}
+		if err != nil {
+			return KeyCount{}, fmt.Errorf("iter error: %w", err)
+		}
+		if len(k.ScanPatterns) > 0 {
+			keys = slices.DeleteFunc(keys, func(key string) bool {
+				return !slices.ContainsFunc(k.ScanPatterns, func(p string) bool { return matchGlob(p, key) })
+			})
+		}
+		if err := k.countBatch(ctx, keys, &count); err != nil {
+			return KeyCount{}, err
+		}
+		if next == 0 {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+func (k *KeyCounter) countBatch(ctx context.Context, keys []string, count *KeyCount) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	pipe := k.Client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("pttl error: %w", err)
+	}
+	for _, ttl := range ttls {
+		switch ttl.Val() {
+		case -2:
+			// expired since it was scanned
+		case noTTL:
+			count.Matched++
+			count.WithoutTTL++
+		default:
+			count.Matched++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package redisttl
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestKeyCounter(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 30; i++ {
+		_ = s.Set("foo:"+strconv.Itoa(i), "bar")
+	}
+	for i := 0; i < 10; i++ {
+		s.SetTTL("foo:"+strconv.Itoa(i), time.Hour)
+		_ = s.Set("zoo:"+strconv.Itoa(i), "bar")
+	}
+	_, _ = s.SAdd("foo:set", "a")
+
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	tests := map[string]struct {
+		counter KeyCounter
+		want    KeyCount
+	}{
+		"prefix": {
+			counter: KeyCounter{ScanPrefix: "foo:*", ScanCount: 7},
+			want:    KeyCount{DBSize: 41, Matched: 31, WithoutTTL: 21},
+		},
+		"type": {
+			counter: KeyCounter{ScanPrefix: "foo:*", ScanType: "set"},
+			want:    KeyCount{DBSize: 41, Matched: 1, WithoutTTL: 1},
+		},
+		"patterns": {
+			counter: KeyCounter{ScanPatterns: []string{"foo:1*", "zoo:*"}},
+			want:    KeyCount{DBSize: 41, Matched: 21, WithoutTTL: 20},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.counter.Client = rdb
+			got, err := tc.counter.Count(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("want: %+v got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestKeyCountSub(t *testing.T) {
+	before := KeyCount{DBSize: 10, Matched: 5, WithoutTTL: 4}
+	after := KeyCount{DBSize: 8, Matched: 3, WithoutTTL: 0}
+	if got, want := after.Sub(before), (KeyCount{DBSize: -2, Matched: -2, WithoutTTL: -4}); got != want {
+		t.Fatalf("want: %+v got: %+v", want, got)
+	}
+	if got, want := after.Add(before), (KeyCount{DBSize: 18, Matched: 8, WithoutTTL: 4}); got != want {
+		t.Fatalf("want: %+v got: %+v", want, got)
+	}
+}
@@ -0,0 +1,166 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNoCredentials = errors.New("no credentials")
+
+// Credentials authenticate a connection to Redis. An empty Username is the
+// default user.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialProvider returns the credentials to authenticate with. It is
+// called for every new connection, so credentials rotated while a run is in
+// progress are picked up as connections are replaced.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// FileCredentials reads the password from the file at Path, e.g. a mounted
+// Kubernetes secret, whitespace around it being trimmed.
+type FileCredentials struct {
+	Username string
+	Path     string
+}
+
+func (p *FileCredentials) Credentials(context.Context) (Credentials, error) {
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("read password file: %w", err)
+	}
+	return Credentials{Username: p.Username, Password: strings.TrimSpace(string(b))}, nil
+}
+
+// EnvCredentials reads the password from the environment variable Var.
+type EnvCredentials struct {
+	Username string
+	Var      string
+}
+
+func (p *EnvCredentials) Credentials(context.Context) (Credentials, error) {
+	password, found := os.LookupEnv(p.Var)
+	if !found {
+		return Credentials{}, fmt.Errorf("%s is not set: %w", p.Var, errNoCredentials)
+	}
+	return Credentials{Username: p.Username, Password: password}, nil
+}
+
+// CommandCredentials runs Command and reads the password from its output,
+// e.g. the CLI of a secret manager. An output holding a JSON object with
+// "username" and "password" fields, the way secrets are often stored, sets
+// both.
+type CommandCredentials struct {
+	Username string
+	Command  []string
+}
+
+// AWSSecretCredentials reads the secret id from AWS Secrets Manager, with
+// the aws CLI and its usual configuration.
+func AWSSecretCredentials(id string) *CommandCredentials {
+	return &CommandCredentials{Command: []string{
+		"aws", "secretsmanager", "get-secret-value", "--secret-id", id,
+		"--query", "SecretString", "--output", "text",
+	}}
+}
+
+// GCPSecretCredentials reads the latest version of the secret name from
+// Google Cloud Secret Manager, with the gcloud CLI and its usual
+// configuration. name may be qualified by its project, as
+// projects/<project>/secrets/<name>.
+func GCPSecretCredentials(name string) *CommandCredentials {
+	return &CommandCredentials{Command: []string{
+		"gcloud", "secrets", "versions", "access", "latest", "--secret=" + name,
+	}}
+}
+
+func (p *CommandCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	if len(p.Command) == 0 {
+		return Credentials{}, fmt.Errorf("empty credentials command: %w", errNoCredentials)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials command %s: %w: %s", p.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	creds := Credentials{Username: p.Username, Password: strings.TrimSpace(string(out))}
+	var secret struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if json.Unmarshal(out, &secret) == nil && secret.Password != "" {
+		creds.Password = secret.Password
+		if secret.Username != "" {
+			creds.Username = secret.Username
+		}
+	}
+	return creds, nil
+}
+
+// CachedCredentials caches the credentials of Provider for TTL, so that
+// opening many connections doesn't call a secret manager every time.
+type CachedCredentials struct {
+	Provider CredentialProvider
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	creds   Credentials
+	fetched time.Time
+}
+
+func (p *CachedCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.fetched.IsZero() && time.Since(p.fetched) < p.TTL {
+		return p.creds, nil
+	}
+	creds, err := p.Provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	p.creds, p.fetched = creds, time.Now()
+	return creds, nil
+}
+
+// credentialsTimeout bounds a call of CredentialsFunc.
+const credentialsTimeout = 10 * time.Second
+
+// CredentialsFunc adapts p to the CredentialsProvider of the go-redis
+// options. Since those can't fail, errors are logged and the last
+// credentials read are used, none before the first successful read.
+func CredentialsFunc(p CredentialProvider) func() (username, password string) {
+	var (
+		mu   sync.Mutex
+		last Credentials
+	)
+	return func() (string, string) {
+		ctx, cancel := context.WithTimeout(context.Background(), credentialsTimeout)
+		defer cancel()
+		creds, err := p.Credentials(ctx)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			log.Printf("credentials error: %v\n", err)
+			return last.Username, last.Password
+		}
+		last = creds
+		return creds.Username, creds.Password
+	}
+}
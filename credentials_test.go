@@ -0,0 +1,141 @@
+package redisttl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REDIS_TTL_TEST_PASSWORD", "from-env")
+
+	testCases := map[string]struct {
+		provider CredentialProvider
+		want     Credentials
+		err      bool
+	}{
+		"file": {
+			provider: &FileCredentials{Username: "ttl", Path: path},
+			want:     Credentials{Username: "ttl", Password: "from-file"},
+		},
+		"missing file": {
+			provider: &FileCredentials{Path: path + ".missing"},
+			err:      true,
+		},
+		"env": {
+			provider: &EnvCredentials{Var: "REDIS_TTL_TEST_PASSWORD"},
+			want:     Credentials{Password: "from-env"},
+		},
+		"missing env": {
+			provider: &EnvCredentials{Var: "REDIS_TTL_TEST_UNSET"},
+			err:      true,
+		},
+		"command": {
+			provider: &CommandCredentials{Username: "ttl", Command: []string{"echo", "from-command"}},
+			want:     Credentials{Username: "ttl", Password: "from-command"},
+		},
+		"json command": {
+			provider: &CommandCredentials{Command: []string{"echo", `{"username": "app", "password": "from-json"}`}},
+			want:     Credentials{Username: "app", Password: "from-json"},
+		},
+		"failed command": {
+			provider: &CommandCredentials{Command: []string{"false"}},
+			err:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			creds, err := tc.provider.Credentials(context.Background())
+			if (err != nil) != tc.err {
+				t.Fatalf("want error: %v got: %v", tc.err, err)
+			}
+			if creds != tc.want {
+				t.Fatalf("want: %+v got: %+v", tc.want, creds)
+			}
+		})
+	}
+}
+
+// countingCredentials counts its calls, failing after the first one when
+// fail is set.
+type countingCredentials struct {
+	calls int
+	fail  bool
+}
+
+func (p *countingCredentials) Credentials(context.Context) (Credentials, error) {
+	p.calls++
+	if p.fail && p.calls > 1 {
+		return Credentials{}, errNoCredentials
+	}
+	return Credentials{Password: "secret"}, nil
+}
+
+func TestCachedCredentials(t *testing.T) {
+	counting := &countingCredentials{}
+	p := &CachedCredentials{Provider: counting, TTL: time.Hour}
+	for i := 0; i < 3; i++ {
+		if _, err := p.Credentials(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counting.calls != 1 {
+		t.Fatalf("expected a single call within the ttl, got %d", counting.calls)
+	}
+
+	p.TTL = 0
+	if _, err := p.Credentials(context.Background()); err != nil || counting.calls != 2 {
+		t.Fatalf("expected a call once the ttl elapsed, got %d, %v", counting.calls, err)
+	}
+}
+
+func TestCredentialsFunc(t *testing.T) {
+	counting := &countingCredentials{fail: true}
+	fn := CredentialsFunc(counting)
+	if _, password := fn(); password != "secret" {
+		t.Fatalf("unexpected password %q", password)
+	}
+	// the last credentials are kept on errors
+	if _, password := fn(); password != "secret" {
+		t.Fatalf("unexpected password after an error %q", password)
+	}
+}
+
+func TestCredentialsRotation(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.RequireAuth("first")
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rdb := redis.NewClient(&redis.Options{
+		Addr:                s.Addr(),
+		CredentialsProvider: CredentialsFunc(&FileCredentials{Path: path}),
+	})
+	defer rdb.Close()
+	ctx := context.Background()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.RequireAuth("second")
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// replacing the connections picks up the new password
+	s.Restart()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Fatalf("expected the rotated password to be used: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package redisttl
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+var errSOCKS5 = errors.New("socks5 proxy error")
+
+// DialFunc dials addr on network. It has the signature of the Dialer of the
+// go-redis options, so clients can reach nodes through a bastion or proxy.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// MapAddrs returns a DialFunc dialing, with dial, the address m maps the
+// one asked for to, e.g. the port Docker publishes for the address a
+// cluster node announces. An address is looked up as host:port, then by
+// its host alone, the port being kept. Unmapped addresses are dialed as is.
+// dial is a net.Dialer with a 5s timeout when nil.
+func MapAddrs(m map[string]string, dial DialFunc) DialFunc {
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 5 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, network, mapAddr(m, addr))
+	}
+}
+
+func mapAddr(m map[string]string, addr string) string {
+	if mapped, found := m[addr]; found {
+		return mapped
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if mapped, found := m[host]; found {
+		return net.JoinHostPort(mapped, port)
+	}
+	return addr
+}
+
+// SOCKS5Dialer dials through the SOCKS5 proxy at Addr, e.g. the one opened
+// by ssh -D on a bastion. Only the CONNECT command is supported.
+type SOCKS5Dialer struct {
+	Addr string
+	// Username and Password, when set, authenticate to the proxy.
+	Username string
+	Password string
+	// Dial reaches the proxy, a net.Dialer with a 5s timeout when nil.
+	Dial DialFunc
+}
+
+// socks5 protocol constants, see RFC 1928 and RFC 1929.
+const (
+	socks5Version      = 5
+	socks5NoAuth       = 0
+	socks5PasswordAuth = 2
+	socks5NoMethod     = 0xff
+	socks5Connect      = 1
+	socks5IPv4         = 1
+	socks5Domain       = 3
+	socks5IPv6         = 4
+)
+
+// DialContext connects to addr through the proxy.
+func (d *SOCKS5Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := d.Dial
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 5 * time.Second}).DialContext
+	}
+	conn, err := dial(ctx, network, d.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial socks5 proxy %s: %w", d.Addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := d.connect(conn, addr); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connect to %s through %s: %w", addr, d.Addr, err)
+	}
+	return conn, nil
+}
+
+// connect negotiates the authentication and asks the proxy to connect to
+// addr.
+func (d *SOCKS5Dialer) connect(conn net.Conn, addr string) error {
+	method := byte(socks5NoAuth)
+	if d.Username != "" {
+		method = socks5PasswordAuth
+	}
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	switch {
+	case reply[0] != socks5Version:
+		return fmt.Errorf("unexpected version %d: %w", reply[0], errSOCKS5)
+	case reply[1] == socks5NoMethod || reply[1] != method:
+		return fmt.Errorf("authentication method refused: %w", errSOCKS5)
+	}
+	if method == socks5PasswordAuth {
+		if err := d.authenticate(conn); err != nil {
+			return err
+		}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q: %w", port, err)
+	}
+	req := []byte{socks5Version, socks5Connect, 0}
+	switch ip := net.ParseIP(host); {
+	case ip == nil:
+		if len(host) > 255 {
+			return fmt.Errorf("host name %s is too long: %w", host, errSOCKS5)
+		}
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	case ip.To4() != nil:
+		req = append(req, socks5IPv4)
+		req = append(req, ip.To4()...)
+	default:
+		req = append(req, socks5IPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(p))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	return readSOCKS5Reply(conn)
+}
+
+// authenticate sends the username and password, see RFC 1929.
+func (d *SOCKS5Dialer) authenticate(conn net.Conn) error {
+	if len(d.Username) > 255 || len(d.Password) > 255 {
+		return fmt.Errorf("username or password too long: %w", errSOCKS5)
+	}
+	req := []byte{1, byte(len(d.Username))}
+	req = append(req, d.Username...)
+	req = append(req, byte(len(d.Password)))
+	req = append(req, d.Password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return fmt.Errorf("authentication failed: %w", errSOCKS5)
+	}
+	return nil
+}
+
+// readSOCKS5Reply reads the reply to a CONNECT, skipping the bound address.
+func readSOCKS5Reply(conn net.Conn) error {
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return fmt.Errorf("connect refused with code %d: %w", reply[1], errSOCKS5)
+	}
+	var n int
+	switch reply[3] {
+	case socks5IPv4:
+		n = net.IPv4len
+	case socks5IPv6:
+		n = net.IPv6len
+	case socks5Domain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+		n = int(size[0])
+	default:
+		return fmt.Errorf("unexpected address type %d: %w", reply[3], errSOCKS5)
+	}
+	// the bound address and port
+	_, err := io.ReadFull(conn, make([]byte, n+2))
+	return err
+}
@@ -0,0 +1,194 @@
+package redisttl
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// socks5Proxy is a SOCKS5 proxy accepting username and password
+// authentication when username is set, recording the addresses asked for.
+func socks5Proxy(t *testing.T, username, password string) (string, chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	targets := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, username, password, targets)
+		}
+	}()
+	return l.Addr().String(), targets
+}
+
+func serveSOCKS5(conn net.Conn, username, password string, targets chan<- string) {
+	defer conn.Close()
+	greeting := make([]byte, 3)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return
+	}
+	method := byte(socks5NoAuth)
+	if username != "" {
+		method = socks5PasswordAuth
+	}
+	if greeting[2] != method {
+		_, _ = conn.Write([]byte{socks5Version, socks5NoMethod})
+		return
+	}
+	_, _ = conn.Write([]byte{socks5Version, method})
+	if method == socks5PasswordAuth {
+		header := make([]byte, 2)
+		_, _ = io.ReadFull(conn, header)
+		user := make([]byte, header[1])
+		_, _ = io.ReadFull(conn, user)
+		size := make([]byte, 1)
+		_, _ = io.ReadFull(conn, size)
+		pass := make([]byte, size[0])
+		_, _ = io.ReadFull(conn, pass)
+		if string(user) != username || string(pass) != password {
+			_, _ = conn.Write([]byte{1, 1})
+			return
+		}
+		_, _ = conn.Write([]byte{1, 0})
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case socks5IPv4:
+		ip := make([]byte, net.IPv4len)
+		_, _ = io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case socks5Domain:
+		size := make([]byte, 1)
+		_, _ = io.ReadFull(conn, size)
+		name := make([]byte, size[0])
+		_, _ = io.ReadFull(conn, name)
+		host = string(name)
+	}
+	port := make([]byte, 2)
+	_, _ = io.ReadFull(conn, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	targets <- addr
+
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		_, _ = conn.Write([]byte{socks5Version, 5, 0, socks5IPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	_, _ = conn.Write([]byte{socks5Version, 0, 0, socks5IPv4, 127, 0, 0, 1, 0, 0})
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	s := miniredis.RunT(t)
+	_, port, _ := net.SplitHostPort(s.Addr())
+
+	testCases := map[string]struct {
+		username, password string
+		dialer             SOCKS5Dialer
+		addr               string
+		err                bool
+	}{
+		"no authentication": {
+			addr: s.Addr(),
+		},
+		"host name": {
+			addr: net.JoinHostPort("localhost", port),
+		},
+		"password": {
+			username: "bastion",
+			password: "secret",
+			dialer:   SOCKS5Dialer{Username: "bastion", Password: "secret"},
+			addr:     s.Addr(),
+		},
+		"wrong password": {
+			username: "bastion",
+			password: "secret",
+			dialer:   SOCKS5Dialer{Username: "bastion", Password: "nope"},
+			addr:     s.Addr(),
+			err:      true,
+		},
+		"unreachable node": {
+			addr: "127.0.0.1:1",
+			err:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			proxy, targets := socks5Proxy(t, tc.username, tc.password)
+			d := tc.dialer
+			d.Addr = proxy
+			rdb := redis.NewClient(&redis.Options{
+				Addr:       tc.addr,
+				Dialer:     d.DialContext,
+				MaxRetries: -1,
+			})
+			defer rdb.Close()
+
+			err := rdb.Ping(context.Background()).Err()
+			if (err != nil) != tc.err {
+				t.Fatalf("want error: %v got: %v", tc.err, err)
+			}
+			if tc.err {
+				if _, err := d.DialContext(context.Background(), "tcp", tc.addr); !errors.Is(err, errSOCKS5) {
+					t.Fatalf("want: %v got: %v", errSOCKS5, err)
+				}
+				return
+			}
+			if got := <-targets; got != tc.addr {
+				t.Fatalf("want the proxy to connect to %s, got %s", tc.addr, got)
+			}
+		})
+	}
+}
+
+func TestMapAddrs(t *testing.T) {
+	s := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(s.Addr())
+
+	testCases := map[string]struct {
+		m    map[string]string
+		addr string
+	}{
+		"address":  {m: map[string]string{"172.18.0.2:6379": s.Addr()}, addr: "172.18.0.2:6379"},
+		"host":     {m: map[string]string{"redis-1": host}, addr: net.JoinHostPort("redis-1", port)},
+		"unmapped": {m: map[string]string{"172.18.0.2:6379": "127.0.0.1:1"}, addr: s.Addr()},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rdb := redis.NewClient(&redis.Options{
+				Addr:       tc.addr,
+				Dialer:     MapAddrs(tc.m, nil),
+				MaxRetries: -1,
+			})
+			defer rdb.Close()
+			if err := rdb.Ping(context.Background()).Err(); err != nil {
+				t.Fatalf("expected %s to be mapped to %s: %v", tc.addr, s.Addr(), err)
+			}
+		})
+	}
+}
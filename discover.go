@@ -0,0 +1,63 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Discoverer samples random keys to find which key prefixes exist and how
+// well they are covered by TTLs, without needing a scan pattern.
+type Discoverer struct {
+	Client  redis.Cmdable
+	Samples int
+	Limiter limiter
+	// Report receives one observation per sampled key, grouped by the key
+	// without its last path segment.
+	Report *PrefixReport
+}
+
+func (d *Discoverer) Run(ctx context.Context) error {
+	for i := 0; i < d.Samples; i++ {
+		if d.Limiter != nil {
+			if err := d.Limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		key, err := d.Client.RandomKey(ctx).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+			// empty database
+			return nil
+		case err != nil:
+			return fmt.Errorf("randomkey error: %w", err)
+		}
+
+		ttl, err := d.Client.PTTL(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("pttl error: %w", err)
+		}
+		if ttl == -2 {
+			continue
+		}
+		d.Report.observe(d.parent(key), key, ttl)
+	}
+	return nil
+}
+
+// parent returns the key up to, and including, its last separator.
+func (d *Discoverer) parent(key string) string {
+	sep := d.Report.Separator
+	if sep == "" {
+		sep = ":"
+	}
+	i := strings.LastIndex(key, sep)
+	if i < 0 {
+		return ""
+	}
+	return key[:i+len(sep)]
+}
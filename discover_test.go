@@ -0,0 +1,54 @@
+package redisttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDiscoverer(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	report := &PrefixReport{}
+	d := Discoverer{
+		Client:  rdb,
+		Samples: 10,
+		Report:  report,
+	}
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("empty database should not fail, got: %v", err)
+	}
+	if len(report.Groups()) != 0 {
+		t.Fatalf("expected no groups, got: %+v", report.Groups())
+	}
+
+	_ = s.Set("sessions:abc", "v")
+	s.SetTTL("sessions:abc", time.Hour)
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	groups := report.Groups()
+	if len(groups) != 1 || groups[0].Prefix != "sessions:" || groups[0].Keys != 10 || groups[0].WithTTL != 10 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+}
+
+func TestDiscovererParent(t *testing.T) {
+	d := Discoverer{Report: &PrefixReport{Separator: "/"}}
+	m := map[string]string{
+		"a/b/c": "a/b/",
+		"a":     "",
+		"a/":    "a/",
+	}
+	for key, want := range m {
+		if got := d.parent(key); got != want {
+			t.Fatalf("key %s want: %q got: %q", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// noTTL is how PTTL reports a key without expiry.
+const noTTL = time.Duration(-1)
+
+// plan computes the TTL a mode would set for a key given its current one
+// and the desired one, any of them being noTTL for no expiry, and whether
+// the mode would change anything.
+type plan func(cur, desired time.Duration) (time.Duration, bool)
+
+// keyPlan is a plan which also depends on the key itself.
+type keyPlan func(ctx context.Context, key string, cur, desired time.Duration) (time.Duration, bool)
+
+func (p plan) keyed() keyPlan {
+	return func(_ context.Context, _ string, cur, desired time.Duration) (time.Duration, bool) {
+		return p(cur, desired)
+	}
+}
+
+// plans mirrors the semantics of every mode modifying keys.
+func (f *Scanner) plans() map[string]keyPlan {
+	plans := map[string]plan{
+		"exp": func(_, d time.Duration) (time.Duration, bool) { return d, true },
+		"nx":  func(cur, d time.Duration) (time.Duration, bool) { return d, cur == noTTL },
+		"xx":  func(cur, d time.Duration) (time.Duration, bool) { return d, cur != noTTL },
+		// like Redis, GT and LT treat a key without expiry as an infinite TTL
+		"gt":      func(cur, d time.Duration) (time.Duration, bool) { return d, cur != noTTL && d > cur },
+		"lt":      func(cur, d time.Duration) (time.Duration, bool) { return d, cur == noTTL || d < cur },
+		"persist": func(cur, _ time.Duration) (time.Duration, bool) { return noTTL, cur != noTTL },
+		// purged keys are gone, as if expired
+		"purge":   func(cur, d time.Duration) (time.Duration, bool) { return 0, cur != noTTL && cur < d },
+		"percent": volatileOnly(f.percent),
+		"extend":  volatileOnly(f.extend),
+		"shrink":  volatileOnly(f.shrink),
+	}
+	keyed := map[string]keyPlan{
+		"inherit": f.inheritPlan,
+	}
+	for mode, p := range plans {
+		keyed[mode] = p.keyed()
+	}
+	return keyed
+}
+
+func volatileOnly(p func(cur time.Duration) (time.Duration, bool)) plan {
+	return func(cur, _ time.Duration) (time.Duration, bool) {
+		if cur == noTTL {
+			return noTTL, false
+		}
+		return p(cur)
+	}
+}
+
+// diffMu serializes diff lines of scanners sharing the same writer.
+var diffMu sync.Mutex
+
+// dryRun returns a ttlFunc printing what p would do to every key.
+func (f *Scanner) dryRun(p keyPlan) ttlFunc {
+	w := f.Diff
+	if w == nil {
+		w = os.Stdout
+	}
+	return func(ctx context.Context, key string, desired time.Duration) *redis.BoolCmd {
+		cmd := redis.NewBoolCmd(ctx)
+		cur, err := f.Client.PTTL(ctx, key).Result()
+		if err != nil || cur == -2 {
+			// -2 means the key expired since it was scanned
+			cmd.SetErr(err)
+			return cmd
+		}
+
+		next, apply := p(ctx, key, cur, desired)
+		verdict := "would skip"
+		if apply {
+			verdict = "would apply"
+			f.Projection.observe(next)
+		}
+		diffMu.Lock()
+		_, err = fmt.Fprintf(w, "%s: %s → %s [%s %s]\n", key, formatTTL(cur), formatTTL(next), f.Mode, verdict)
+		diffMu.Unlock()
+
+		cmd.SetVal(apply)
+		cmd.SetErr(err)
+		return cmd
+	}
+}
+
+// formatTTL prints d rounded to the second, without zero trailing units.
+func formatTTL(d time.Duration) string {
+	if d == noTTL {
+		return "no-ttl"
+	}
+	s := d.Round(time.Second).String()
+	if s == "0s" {
+		return s
+	}
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
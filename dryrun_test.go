@@ -0,0 +1,134 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDryRun(t *testing.T) {
+	testCases := map[string]struct {
+		mode     string
+		expected []string
+		modified int64
+	}{
+		"gt": {
+			mode: "gt",
+			expected: []string{
+				"far: 48h → 24h [gt would skip]",
+				"fiz: no-ttl → 24h [gt would skip]",
+				"foo: 12h → 24h [gt would apply]",
+			},
+			modified: 1,
+		},
+		"nx": {
+			mode: "nx",
+			expected: []string{
+				"far: 48h → 24h [nx would skip]",
+				"fiz: no-ttl → 24h [nx would apply]",
+				"foo: 12h → 24h [nx would skip]",
+			},
+			modified: 1,
+		},
+		"lt": {
+			mode: "lt",
+			expected: []string{
+				"far: 48h → 24h [lt would apply]",
+				"fiz: no-ttl → 24h [lt would apply]",
+				"foo: 12h → 24h [lt would skip]",
+			},
+			modified: 2,
+		},
+		"persist": {
+			mode: "persist",
+			expected: []string{
+				"far: 48h → no-ttl [persist would apply]",
+				"fiz: no-ttl → no-ttl [persist would skip]",
+				"foo: 12h → no-ttl [persist would apply]",
+			},
+			modified: 2,
+		},
+		"extend skips keys without a ttl": {
+			mode: "extend",
+			expected: []string{
+				"far: 48h → 50h30m [extend would apply]",
+				"fiz: no-ttl → no-ttl [extend would skip]",
+				"foo: 12h → 14h30m [extend would apply]",
+			},
+			modified: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, k := range []string{"foo", "far", "fiz"} {
+				_ = s.Set(k, "bar")
+			}
+			s.SetTTL("foo", 12*time.Hour)
+			s.SetTTL("far", 48*time.Hour)
+
+			rdb := redis.NewClient(&redis.Options{
+				Addr: s.Addr(),
+			})
+
+			var diff bytes.Buffer
+			summary := &Summary{}
+			f := Scanner{
+				Mode:       tc.mode,
+				ScanPrefix: "f*",
+				Client:     rdb,
+				DesiredTTL: 24 * time.Hour,
+				TTLDelta:   150 * time.Minute,
+				DryRun:     true,
+				Diff:       &diff,
+				Summary:    summary,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(diff.String()), "\n")
+			if len(lines) != len(tc.expected) {
+				t.Fatalf("expected %d lines, got: %q", len(tc.expected), lines)
+			}
+			for _, want := range tc.expected {
+				if !strings.Contains(diff.String(), want+"\n") {
+					t.Fatalf("missing line %q in:\n%s", want, diff.String())
+				}
+			}
+			if got := summary.Modified.Load(); got != tc.modified {
+				t.Fatalf("expected %d keys modified, got: %d", tc.modified, got)
+			}
+
+			// nothing was changed
+			if ttl := s.TTL("foo"); ttl != 12*time.Hour {
+				t.Fatalf("ttl don't match for: foo, got:%v want: %v", ttl, 12*time.Hour)
+			}
+			if ttl := s.TTL("fiz"); ttl != 0 {
+				t.Fatalf("ttl don't match for: fiz, got:%v want: 0", ttl)
+			}
+		})
+	}
+}
+
+func TestFormatTTL(t *testing.T) {
+	testCases := map[time.Duration]string{
+		-1:                                    "no-ttl",
+		0:                                     "0s",
+		1500 * time.Millisecond:               "2s",
+		12 * time.Hour:                        "12h",
+		90 * time.Minute:                      "1h30m",
+		time.Hour + time.Minute + time.Second: "1h1m1s",
+	}
+	for d, want := range testCases {
+		if got := formatTTL(d); got != want {
+			t.Errorf("formatTTL(%v) = %s, want %s", d, got, want)
+		}
+	}
+}
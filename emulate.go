@@ -0,0 +1,83 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// conditionalExpire implements EXPIRE with the NX, XX, GT and LT options,
+// added in Redis 7.0, for older servers. A key without a TTL counts as
+// having an infinite one, as it does for the native options.
+var conditionalExpire = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+local want = tonumber(ARGV[1])
+local opt = ARGV[2]
+if ttl == -2 then
+	return 0
+end
+if (opt == 'nx' and ttl ~= -1) or (opt == 'xx' and ttl == -1) then
+	return 0
+end
+if (opt == 'gt' and (ttl == -1 or want <= ttl)) or (opt == 'lt' and ttl ~= -1 and want >= ttl) then
+	return 0
+end
+return redis.call('PEXPIRE', KEYS[1], want)
+`)
+
+// emulatedModes are the modes emulated with conditionalExpire on servers
+// older than their modeVersions.
+var emulatedModes = map[string]bool{
+	"gt": true,
+	"lt": true,
+	"nx": true,
+	"xx": true,
+}
+
+// emulate switches r to conditionalExpire when the server is too old for
+// the mode. Servers whose version can't be read keep the native commands.
+// Transactions queue the native commands, so it returns ErrUnsupportedMode
+// for them instead.
+func (f *Scanner) emulate(ctx context.Context, r *scanRun) error {
+	if !emulatedModes[f.Mode] || f.NoEmulation || f.DryRun || r.custom || r.batch {
+		return nil
+	}
+	v, err := ReadServerVersion(ctx, f.Client)
+	if err != nil || !v.less(modeVersions[f.Mode]) {
+		return nil
+	}
+	if r.tx {
+		return fmt.Errorf("mode %s within transactions needs Redis %s or later, server runs %s: %w", f.Mode, modeVersions[f.Mode], v, ErrUnsupportedMode)
+	}
+	log.Printf("redis %s doesn't support mode %s, emulating it with a script\n", v, f.Mode)
+	r.fn, r.emulated = f.conditionalExpire(f.Mode), true
+	return nil
+}
+
+// inheritEmulation emulates the modes of gr, run by f, which r emulated,
+// gr resuming r against another node without asking its version again.
+func (f *Scanner) inheritEmulation(r, gr *scanRun) {
+	if r.emulated {
+		gr.fn, gr.emulated = f.conditionalExpire(f.Mode), true
+	}
+	for i, rr := range r.rules {
+		if g := gr.rules[i]; rr.r.emulated {
+			g.r.fn, g.r.emulated = g.f.conditionalExpire(g.f.Mode), true
+		}
+	}
+}
+
+func (f *Scanner) conditionalExpire(opt string) ttlFunc {
+	return func(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+		cmd := redis.NewBoolCmd(ctx)
+		// EXPIRE has a resolution of a second
+		ms := ttl.Truncate(time.Second).Milliseconds()
+		n, err := conditionalExpire.Run(ctx, f.Client, []string{key}, ms, opt).Int64()
+		cmd.SetVal(n == 1)
+		cmd.SetErr(err)
+		return cmd
+	}
+}
@@ -0,0 +1,86 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestEmulatedModes(t *testing.T) {
+	seed := func(s *miniredis.Miniredis) {
+		for _, k := range []string{"k:none", "k:short", "k:long"} {
+			_ = s.Set(k, "bar")
+		}
+		s.SetTTL("k:short", time.Minute)
+		s.SetTTL("k:long", 24*time.Hour)
+	}
+
+	for mode := range emulatedModes {
+		native, emulated := miniredis.RunT(t), miniredis.RunT(t)
+		seed(native)
+		seed(emulated)
+
+		old := redis.NewClient(&redis.Options{
+			Addr: emulated.Addr(),
+		})
+		old.AddHook(&infoHook{replies: []string{"redis_version:6.2.14\r\n"}})
+
+		nativeSummary, emulatedSummary := &Summary{}, &Summary{}
+		for _, f := range []Scanner{
+			{Client: redis.NewClient(&redis.Options{Addr: native.Addr()}), Summary: nativeSummary},
+			{Client: old, Summary: emulatedSummary},
+		} {
+			f.Mode = mode
+			f.ScanPrefix = "k:*"
+			f.DesiredTTL = time.Hour
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("mode %s: unexpected error: %v", mode, err)
+			}
+		}
+
+		if loaded, _ := conditionalExpire.Exists(context.Background(), old).Result(); len(loaded) != 1 || !loaded[0] {
+			t.Fatalf("mode %s: expected the script to be used", mode)
+		}
+		for _, k := range native.Keys() {
+			if want, got := native.TTL(k), emulated.TTL(k); want != got {
+				t.Fatalf("mode %s: ttl of %s differs, native: %s emulated: %s", mode, k, want, got)
+			}
+		}
+		if want, got := nativeSummary.Modified.Load(), emulatedSummary.Modified.Load(); want != got || got == 0 {
+			t.Fatalf("mode %s: want %d keys modified, got %d", mode, want, got)
+		}
+	}
+}
+
+func TestEmulatedModesTransaction(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("k:foo", "bar")
+	s.SetTTL("k:foo", time.Minute)
+
+	old := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	old.AddHook(&infoHook{replies: []string{"redis_version:6.2.14\r\n"}})
+	summary := &Summary{}
+	f := Scanner{
+		Client:      old,
+		Mode:        "gt",
+		ScanPrefix:  "k:*",
+		DesiredTTL:  time.Hour,
+		Transaction: true,
+		Summary:     summary,
+	}
+	if err := f.Run(context.Background()); !errors.Is(err, ErrUnsupportedMode) {
+		t.Fatalf("want error %v, got %v", ErrUnsupportedMode, err)
+	}
+	if got := summary.Errors.Load(); got != 0 {
+		t.Fatalf("want no key to fail, got %d", got)
+	}
+	if got := s.TTL("k:foo"); got != time.Minute {
+		t.Fatalf("want the ttl untouched, got %s", got)
+	}
+}
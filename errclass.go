@@ -0,0 +1,91 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Classes of per-key errors counted by a Summary.
+const (
+	ErrorTimeout    = "timeout"
+	ErrorConnection = "connection"
+	ErrorMoved      = "moved"
+	ErrorWrongType  = "wrongtype"
+	ErrorOOM        = "oom"
+	ErrorNoPerm     = "noperm"
+	ErrorOther      = "other"
+)
+
+// ClassifyError tells a transient network problem from a systematic one
+// reported by the server, such as missing permissions.
+func ClassifyError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case redis.HasErrorPrefix(err, "MOVED"), redis.HasErrorPrefix(err, "ASK"):
+		return ErrorMoved
+	case redis.HasErrorPrefix(err, "WRONGTYPE"):
+		return ErrorWrongType
+	case redis.HasErrorPrefix(err, "OOM"):
+		return ErrorOOM
+	case redis.HasErrorPrefix(err, "NOPERM"):
+		return ErrorNoPerm
+	case errors.Is(err, io.EOF), errors.Is(err, redis.ErrClosed), errors.As(err, &netErr):
+		return ErrorConnection
+	default:
+		return ErrorOther
+	}
+}
+
+// ErrorCounts counts errors per class. The zero value is ready to use and
+// safe for concurrent use.
+type ErrorCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// Add counts err under its class.
+func (c *ErrorCounts) Add(err error) {
+	class := ClassifyError(err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[string]int64{}
+	}
+	c.counts[class]++
+}
+
+// Snapshot returns a copy of the counts per class.
+func (c *ErrorCounts) Snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int64, len(c.counts))
+	for class, n := range c.counts {
+		counts[class] = n
+	}
+	return counts
+}
+
+func (c *ErrorCounts) String() string {
+	counts := c.Snapshot()
+	classes := make([]string, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	parts := make([]string, len(classes))
+	for i, class := range classes {
+		parts[i] = fmt.Sprintf("%s=%d", class, counts[class])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,87 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisError mimics an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (redisError) RedisError() {}
+
+func TestClassifyError(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected string
+	}{
+		"deadline":    {err: context.DeadlineExceeded, expected: ErrorTimeout},
+		"net timeout": {err: &net.OpError{Op: "read", Err: timeoutError{}}, expected: ErrorTimeout},
+		"eof":         {err: io.EOF, expected: ErrorConnection},
+		"refused":     {err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, expected: ErrorConnection},
+		"moved":       {err: redisError("MOVED 3999 127.0.0.1:6381"), expected: ErrorMoved},
+		"ask":         {err: redisError("ASK 3999 127.0.0.1:6381"), expected: ErrorMoved},
+		"wrongtype":   {err: redisError("WRONGTYPE Operation against a key holding the wrong kind of value"), expected: ErrorWrongType},
+		"oom":         {err: redisError("OOM command not allowed when used memory > 'maxmemory'."), expected: ErrorOOM},
+		"noperm":      {err: fmt.Errorf("wrapped: %w", redisError("NOPERM this user has no permissions to run the 'expire' command")), expected: ErrorNoPerm},
+		"other":       {err: redisError("ERR syntax error"), expected: ErrorOther},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := ClassifyError(tc.err); got != tc.expected {
+				t.Fatalf("want: %s got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClassesSummary(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(&hook{
+		cmdName: "expire",
+		err:     redisError("NOPERM this user has no permissions to run the 'expire' command"),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := summary.ErrorClasses.Snapshot(); len(got) != 1 || got[ErrorNoPerm] != 2 {
+		t.Fatalf("unexpected error classes: %v", got)
+	}
+	if !strings.HasSuffix(summary.String(), " errors_by_class[noperm=2]") {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
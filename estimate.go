@@ -0,0 +1,226 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Estimator predicts how many keys a run would process on a node, and how
+// long it would take at the configured rate, from a short sampling scan.
+type Estimator struct {
+	Client     redis.Cmdable
+	Node       string
+	ScanPrefix string
+	// ScanPatterns, when set, replace ScanPrefix, see Scanner.ScanPatterns.
+	ScanPatterns []string
+	ScanType     string
+	ScanCount    int64
+	// Samples is the number of keys examined. The whole keyspace is
+	// examined when it holds fewer keys.
+	Samples int
+	// Examples is the number of matched keys kept in Estimate.Examples.
+	Examples int
+	// RPS is the rate at which the run would process keys.
+	RPS float64
+	// Limiter, when set, is waited for before the TYPE of every sampled key
+	// read with ScanType, as the run would.
+	Limiter   limiter
+	Estimates *Estimates
+}
+
+// Estimate is the prediction for a node.
+type Estimate struct {
+	Node   string
+	DBSize int64
+	// Sampled keys were examined, Matched of them match the scan prefix and
+	// type.
+	Sampled int64
+	Matched int64
+	// Exact is true when the whole keyspace was examined.
+	Exact    bool
+	Keys     int64
+	Duration time.Duration
+	// Examples are the first matched keys sampled.
+	Examples []string
+}
+
+// Estimates collects the estimates of a run. It is safe for concurrent use.
+type Estimates struct {
+	mu        sync.Mutex
+	estimates []Estimate
+}
+
+func (e *Estimates) add(x Estimate) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.estimates = append(e.estimates, x)
+}
+
+// All returns the estimates collected so far.
+func (e *Estimates) All() []Estimate {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Estimate(nil), e.estimates...)
+}
+
+func (e *Estimator) Run(ctx context.Context) error {
+	size, err := e.Client.DBSize(ctx).Result()
+	if err != nil {
+		return fmt.Errorf("dbsize error: %w", err)
+	}
+	x := Estimate{Node: e.Node, DBSize: size}
+	if err := e.sample(ctx, &x); err != nil {
+		return err
+	}
+
+	x.Keys = x.Matched
+	if !x.Exact && x.Sampled > 0 {
+		x.Keys = int64(math.Round(float64(x.Matched) / float64(x.Sampled) * float64(size)))
+	}
+	if e.RPS > 0 {
+		x.Duration = time.Duration(float64(x.Keys) / e.RPS * float64(time.Second))
+	}
+	e.Estimates.add(x)
+	return nil
+}
+
+// sample scans without a pattern, matching keys client side, so the share
+// of matching keys can be extrapolated to the whole keyspace.
+func (e *Estimator) sample(ctx context.Context, x *Estimate) error {
+	var cursor uint64
+	for {
+		keys, next, err := e.Client.Scan(ctx, cursor, "", e.ScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("iter error: %w", err)
+		}
+		for _, key := range keys {
+			ok, err := e.matches(ctx, key)
+			if err != nil {
+				return err
+			}
+			x.Sampled++
+			if !ok {
+				continue
+			}
+			x.Matched++
+			if len(x.Examples) < e.Examples {
+				x.Examples = append(x.Examples, key)
+			}
+		}
+		if next == 0 {
+			x.Exact = true
+			return nil
+		}
+		if x.Sampled >= int64(e.Samples) {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (e *Estimator) matches(ctx context.Context, key string) (bool, error) {
+	if len(e.ScanPatterns) > 0 {
+		if !slices.ContainsFunc(e.ScanPatterns, func(p string) bool { return matchGlob(p, key) }) {
+			return false, nil
+		}
+	} else if e.ScanPrefix != "" && !matchGlob(e.ScanPrefix, key) {
+		return false, nil
+	}
+	if e.ScanType == "" {
+		return true, nil
+	}
+	if e.Limiter != nil {
+		if err := e.Limiter.Wait(ctx); err != nil {
+			return false, err
+		}
+	}
+	t, err := e.Client.Type(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("type error: %w", err)
+	}
+	return t == e.ScanType, nil
+}
+
+// matchGlob reports whether s matches the Redis glob-style pattern, as SCAN
+// MATCH does: * and ? wildcards, [...] classes with ranges and ^ negation,
+// and \ escapes.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			rest, ok := matchClass(pattern[1:], s)
+			if !ok {
+				return false
+			}
+			pattern = rest
+			s = s[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchClass matches the first byte of s against the class at the start of
+// pattern, right after its opening bracket, and returns what follows the class.
+func matchClass(pattern, s string) (string, bool) {
+	if len(s) == 0 {
+		return "", false
+	}
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	found := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			found = found || pattern[1] == s[0]
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := min(pattern[0], pattern[2]), max(pattern[0], pattern[2])
+			found = found || (s[0] >= lo && s[0] <= hi)
+			pattern = pattern[3:]
+		default:
+			found = found || pattern[0] == s[0]
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		// closing bracket
+		pattern = pattern[1:]
+	}
+	return pattern, found != negate
+}
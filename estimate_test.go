@@ -0,0 +1,145 @@
+package redisttl
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestEstimator(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 30; i++ {
+		_ = s.Set("foo:"+strconv.Itoa(i), "bar")
+	}
+	for i := 0; i < 10; i++ {
+		_ = s.Set("zoo:"+strconv.Itoa(i), "bar")
+	}
+	_, _ = s.SAdd("foo:set", "a")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	estimates := &Estimates{}
+	e := Estimator{
+		Client:     rdb,
+		Node:       s.Addr(),
+		ScanPrefix: "foo:*",
+		ScanType:   "string",
+		Samples:    1000,
+		Examples:   2,
+		RPS:        10,
+		Estimates:  estimates,
+	}
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	x := estimates.All()[0]
+	expected := Estimate{
+		Node:     s.Addr(),
+		DBSize:   41,
+		Sampled:  41,
+		Matched:  30,
+		Exact:    true,
+		Keys:     30,
+		Duration: 3 * time.Second,
+		Examples: []string{"foo:0", "foo:1"},
+	}
+	if !reflect.DeepEqual(x, expected) {
+		t.Fatalf("want: %+v got: %+v", expected, x)
+	}
+}
+
+// countingLimiter counts the waits of a run.
+type countingLimiter struct {
+	waits int
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return ctx.Err()
+}
+
+func TestEstimatorLimiter(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 5; i++ {
+		_ = s.Set("foo:"+strconv.Itoa(i), "bar")
+	}
+	_ = s.Set("zoo", "bar")
+
+	l := &countingLimiter{}
+	e := Estimator{
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		ScanPrefix: "foo:*",
+		ScanType:   "string",
+		Samples:    1000,
+		Limiter:    l,
+		Estimates:  &Estimates{},
+	}
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a TYPE for every key matching the prefix
+	if l.waits != 5 {
+		t.Fatalf("want a wait per TYPE sent, got %d", l.waits)
+	}
+}
+
+func TestEstimatorScanPatterns(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo:1", "zoo:1", "bar:1", "bar:2"} {
+		_ = s.Set(k, "bar")
+	}
+
+	estimates := &Estimates{}
+	e := Estimator{
+		Client:       redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		ScanPrefix:   "bar:*",
+		ScanPatterns: []string{"foo:*", "zoo:*"},
+		Samples:      1000,
+		Estimates:    estimates,
+	}
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if x := estimates.All()[0]; x.Matched != 2 || x.Keys != 2 {
+		t.Fatalf("want the keys matching any pattern, got: %+v", x)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "", true},
+		{"*", "a/b:c", true},
+		{"foo*", "foo:1", true},
+		{"foo*", "fo", false},
+		{"*:1", "foo:1", true},
+		{"*:1", "foo:12", false},
+		{"f?o", "foo", true},
+		{"f?o", "fo", false},
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"a**b", "axxb", true},
+	}
+	for _, tc := range testCases {
+		if got := matchGlob(tc.pattern, tc.s); got != tc.match {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.match)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Failover resumes a run whose node failed over midway, instead of failing
+// it: once the scan or a command sent for a key fails with a connection
+// error or a READONLY reply, the run waits for Delay, asks Resolve for the
+// clients of the new primary and resumes the scan from the cursor of the
+// batch it stopped at. The keys of that batch are processed again.
+type Failover struct {
+	// Resolve returns the client receiving the commands modifying keys and
+	// the one scanning the new primary of the node, which may be the same.
+	Resolve func(ctx context.Context) (client, scanClient redis.Cmdable, err error)
+	// Retries is the number of failovers a run survives.
+	Retries int
+	// Delay is how long the run waits before calling Resolve, giving the
+	// cluster time to promote a replica.
+	Delay time.Duration
+}
+
+// failedOver reports whether err tells the node is gone or was demoted to
+// a replica.
+func failedOver(err error) bool {
+	return redis.HasErrorPrefix(err, "READONLY") || ClassifyError(err) == ErrorConnection
+}
+
+// resume scans again from the cursor of r against the new primary as long
+// as the run stops on a failover, returning the error of the last scan. The
+// checks and emulation of the run aren't repeated, nor is its DBSIZE read.
+func (f *Scanner) resume(ctx context.Context, r *scanRun, err error) error {
+	for retry := 0; f.Failover != nil && retry < f.Failover.Retries && failedOver(err); retry++ {
+		cursor := atomic.LoadUint64(&r.live.cursor)
+		log.Printf("node %s failed over at cursor %d: %v\n", f.Node, cursor, err)
+		r.summary.observeFailover()
+		if err := f.clock().Sleep(ctx, f.Failover.Delay); err != nil {
+			return err
+		}
+		client, scanClient, rerr := f.Failover.Resolve(ctx)
+		if rerr != nil {
+			return errors.Join(err, fmt.Errorf("resolve the new primary: %w", rerr))
+		}
+
+		// the copy sends the commands of the run to the new primary
+		g := *f
+		g.Client, g.ScanClient, g.Summary = client, scanClient, r.summary
+		gr, nerr := g.newRun()
+		if nerr != nil {
+			return nerr
+		}
+		gr.info, gr.live, gr.cursor = r.info, r.live, cursor
+		gr.total, gr.batches = r.total, r.batches
+		gr.results, gr.done = r.results, r.done
+		g.inheritEmulation(r, gr)
+		err = g.scanLoop(ctx, gr)
+	}
+	return err
+}
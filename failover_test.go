@@ -0,0 +1,232 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// readOnlyError is the reply of a primary demoted to a replica.
+type readOnlyError string
+
+func (e readOnlyError) Error() string { return string(e) }
+
+func (readOnlyError) RedisError() {}
+
+// keyHook fails the expire commands of key with err, and records the keys
+// of the ones it lets through.
+type keyHook struct {
+	key     string
+	err     error
+	expired []string
+}
+
+func (h *keyHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "expire" {
+			return next(ctx, cmd)
+		}
+		key, _ := cmd.Args()[1].(string)
+		if key == h.key {
+			return h.err
+		}
+		h.expired = append(h.expired, key)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *keyHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *keyHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestFailover(t *testing.T) {
+	errReadOnly := readOnlyError("READONLY You can't write against a read only replica.")
+	testCases := map[string]struct {
+		err      error
+		retries  int
+		resolve  error
+		failed   bool
+		resumed  []string
+		expired  []string
+		failover int64
+	}{
+		"resumes from the cursor": {
+			err:      errReadOnly,
+			retries:  1,
+			expired:  []string{"foo:1"},
+			resumed:  []string{"foo:2", "foo:3"},
+			failover: 1,
+		},
+		"no retry left": {
+			err:      errReadOnly,
+			failed:   true,
+			expired:  []string{"foo:1"},
+			failover: 0,
+		},
+		"resolve failure": {
+			err:      errReadOnly,
+			retries:  1,
+			resolve:  errors.New("no primary"),
+			failed:   true,
+			expired:  []string{"foo:1"},
+			failover: 1,
+		},
+		"other errors are logged": {
+			err:     readOnlyError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+			retries: 1,
+			expired: []string{"foo:1", "foo:3"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+				_ = s.Set(key, "bar")
+			}
+			old := &keyHook{key: "foo:2", err: tc.err}
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			rdb.AddHook(old)
+			promoted := &keyHook{}
+			newRdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			newRdb.AddHook(promoted)
+
+			summary := &Summary{}
+			f := Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo:*",
+				ScanCount:  1,
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+				Summary:    summary,
+				Failover: &Failover{
+					Retries: tc.retries,
+					Resolve: func(ctx context.Context) (redis.Cmdable, redis.Cmdable, error) {
+						return newRdb, newRdb, tc.resolve
+					},
+				},
+			}
+			if err := f.Run(context.Background()); (err != nil) != tc.failed {
+				t.Fatalf("want failed %v, got %v", tc.failed, err)
+			}
+			if !slices.Equal(old.expired, tc.expired) || !slices.Equal(promoted.expired, tc.resumed) {
+				t.Fatalf("want %v then %v expired, got %v then %v", tc.expired, tc.resumed, old.expired, promoted.expired)
+			}
+			if n := summary.Failovers.Load(); n != tc.failover {
+				t.Fatalf("want %d failovers, got %d: %s", tc.failover, n, summary)
+			}
+		})
+	}
+}
+
+func TestFailoverScan(t *testing.T) {
+	gone, promoted := miniredis.RunT(t), miniredis.RunT(t)
+	_ = promoted.Set("foo:1", "bar")
+	rdb := redis.NewClient(&redis.Options{Addr: gone.Addr(), MaxRetries: -1})
+	gone.Close()
+	newRdb := redis.NewClient(&redis.Options{Addr: promoted.Addr()})
+
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Failover: &Failover{
+			Retries: 1,
+			Resolve: func(ctx context.Context) (redis.Cmdable, redis.Cmdable, error) {
+				return newRdb, newRdb, nil
+			},
+		},
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := promoted.TTL("foo:1"); ttl != time.Hour {
+		t.Fatalf("want foo:1 expired on the promoted node, got %v", ttl)
+	}
+	if st := f.Stats(); st.Modified != 1 {
+		t.Fatalf("want the stats of the resumed run, got %+v", st)
+	}
+}
+
+// namesHook records the names of the commands sent, failing the scripts
+// sent for key with err.
+type namesHook struct {
+	key   string
+	err   error
+	names []string
+}
+
+func (h *namesHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.names = append(h.names, cmd.Name())
+		if name := cmd.Name(); (name == "evalsha" || name == "eval") && cmd.Args()[3] == h.key {
+			return h.err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *namesHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *namesHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestFailoverResumesScan(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+		_ = s.Set(key, "bar")
+		s.SetTTL(key, time.Minute)
+	}
+	old := &namesHook{key: "foo:2", err: readOnlyError("READONLY You can't write against a read only replica.")}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(&infoHook{replies: []string{"redis_version:6.2.14\r\n"}})
+	rdb.AddHook(old)
+	promoted := &namesHook{}
+	newRdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	newRdb.AddHook(promoted)
+
+	f := Scanner{
+		Mode:            "gt",
+		ScanPrefix:      "foo:*",
+		ScanCount:       1,
+		Client:          rdb,
+		DesiredTTL:      time.Hour,
+		PreflightChecks: true,
+		Progress:        &progressRecorder{},
+		Failover: &Failover{
+			Retries: 1,
+			Resolve: func(ctx context.Context) (redis.Cmdable, redis.Cmdable, error) {
+				return newRdb, newRdb, nil
+			},
+		},
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, key := range s.Keys() {
+		if ttl := s.TTL(key); ttl != time.Hour {
+			t.Fatalf("want %s expired, got %v", key, ttl)
+		}
+	}
+	// the run only scans again, still emulating the mode
+	for _, name := range promoted.names {
+		if name != "scan" && name != "evalsha" && name != "eval" {
+			t.Fatalf("want the resumed run to only scan and emulate, got %v", promoted.names)
+		}
+	}
+	if !slices.Contains(promoted.names, "evalsha") {
+		t.Fatalf("want the mode still emulated, got %v", promoted.names)
+	}
+}
@@ -0,0 +1,137 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyFilter selects the keys a Scanner modifies. Match may read the key
+// through c; a key deleted since it was scanned doesn't match.
+type KeyFilter interface {
+	Match(ctx context.Context, c redis.Cmdable, key string) (bool, error)
+}
+
+// KeyFilterFunc adapts a function to a KeyFilter.
+type KeyFilterFunc func(ctx context.Context, c redis.Cmdable, key string) (bool, error)
+
+// Match implements KeyFilter.
+func (fn KeyFilterFunc) Match(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+	return fn(ctx, c, key)
+}
+
+// And matches the keys every filter matches, trying them in order until
+// one doesn't, so the ones sending no command are best first. It matches
+// every key without filters.
+func And(filters ...KeyFilter) KeyFilter {
+	return KeyFilterFunc(func(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+		for _, filter := range filters {
+			if ok, err := filter.Match(ctx, c, key); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Or matches the keys any filter matches, trying them in order until one
+// does. It matches no key without filters.
+func Or(filters ...KeyFilter) KeyFilter {
+	return KeyFilterFunc(func(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+		for _, filter := range filters {
+			if ok, err := filter.Match(ctx, c, key); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	})
+}
+
+// Not matches the keys filter doesn't. Keys deleted since they were scanned
+// match neither filter nor Not(filter).
+func Not(filter KeyFilter) KeyFilter {
+	return KeyFilterFunc(func(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+		ok, err := filter.Match(ctx, c, key)
+		if err != nil {
+			return false, err
+		}
+		return !ok, nil
+	})
+}
+
+// errKeyGone fails the filters reading a key deleted since it was scanned,
+// for Not to tell it from a key which doesn't match. Scanner treats it as a
+// key which doesn't match.
+var errKeyGone = errors.New("key deleted since it was scanned")
+
+// Prefix matches the keys starting with prefix.
+func Prefix(prefix string) KeyFilter {
+	return KeyFilterFunc(func(_ context.Context, _ redis.Cmdable, key string) (bool, error) {
+		return strings.HasPrefix(key, prefix), nil
+	})
+}
+
+// Regexp matches the keys re matches.
+func Regexp(re *regexp.Regexp) KeyFilter {
+	return KeyFilterFunc(func(_ context.Context, _ redis.Cmdable, key string) (bool, error) {
+		return re.MatchString(key), nil
+	})
+}
+
+// TTLBetween matches the keys whose TTL is between lo and hi inclusive, at
+// the cost of a PTTL per key. A hi of 0 sets no upper bound, matching the
+// keys without a TTL as well.
+func TTLBetween(lo, hi time.Duration) KeyFilter {
+	return KeyFilterFunc(func(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+		ttl, err := c.PTTL(ctx, key).Result()
+		switch {
+		case err != nil:
+			return false, err
+		case ttl == -2:
+			return false, errKeyGone
+		case ttl < 0:
+			return hi == 0, nil
+		}
+		return ttl >= lo && (hi == 0 || ttl <= hi), nil
+	})
+}
+
+// IdleFor matches the keys not accessed for at least d, at the cost of an
+// OBJECT IDLETIME per key, which servers evicting with an LFU policy reject.
+func IdleFor(d time.Duration) KeyFilter {
+	return KeyFilterFunc(func(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+		idle, err := c.ObjectIdleTime(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return false, errKeyGone
+		}
+		return err == nil && idle >= d, err
+	})
+}
+
+// SizeBetween matches the keys whose MEMORY USAGE, in bytes, is between lo
+// and hi inclusive, at the cost of a MEMORY USAGE per key. A hi of 0 sets
+// no upper bound.
+func SizeBetween(lo, hi int64) KeyFilter {
+	return KeyFilterFunc(func(ctx context.Context, c redis.Cmdable, key string) (bool, error) {
+		n, err := c.MemoryUsage(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return false, errKeyGone
+		}
+		return err == nil && n >= lo && (hi == 0 || n <= hi), err
+	})
+}
+
+func (f *Scanner) matchFilter(ctx context.Context, key string) (bool, error) {
+	if f.Filter == nil {
+		return true, nil
+	}
+	ok, err := f.Filter.Match(ctx, f.Client, key)
+	if errors.Is(err, errKeyGone) {
+		return false, nil
+	}
+	return ok, err
+}
@@ -0,0 +1,93 @@
+package redisttl
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// filterKeys returns a client of keys last written 2h ago for session:1,
+// and now for the users.
+func filterKeys(t *testing.T) redis.Cmdable {
+	s := miniredis.RunT(t)
+	now := time.Now()
+	s.SetTime(now.Add(-2 * time.Hour))
+	_ = s.Set("session:1", strings.Repeat("v", 4096))
+	s.SetTTL("session:1", 2*time.Hour)
+	s.SetTime(now)
+	_ = s.Set("user:1", "bar")
+	_ = s.Set("user:2", "bar")
+	s.SetTTL("user:1", 30*time.Minute)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(memoryUsageHook{})
+	return rdb
+}
+
+func TestKeyFilters(t *testing.T) {
+	keys := []string{"gone", "session:1", "user:1", "user:2"}
+	testCases := map[string]struct {
+		filter  KeyFilter
+		matched []string
+	}{
+		"prefix":             {filter: Prefix("user:"), matched: []string{"user:1", "user:2"}},
+		"regexp":             {filter: Regexp(regexp.MustCompile(`:1$`)), matched: []string{"session:1", "user:1"}},
+		"ttl range":          {filter: TTLBetween(time.Minute, time.Hour), matched: []string{"user:1"}},
+		"ttl lower bound":    {filter: TTLBetween(time.Hour, 0), matched: []string{"session:1", "user:2"}},
+		"idle":               {filter: IdleFor(time.Hour), matched: []string{"session:1"}},
+		"size":               {filter: SizeBetween(1024, 0), matched: []string{"session:1"}},
+		"and":                {filter: And(Prefix("user:"), TTLBetween(0, time.Hour)), matched: []string{"user:1"}},
+		"or":                 {filter: Or(Prefix("session:"), TTLBetween(0, time.Hour)), matched: []string{"session:1", "user:1"}},
+		"not":                {filter: Not(Prefix("user:")), matched: []string{"gone", "session:1"}},
+		"not a reading one":  {filter: Not(IdleFor(time.Hour)), matched: []string{"user:1", "user:2"}},
+		"and without filter": {filter: And(), matched: keys},
+		"or without filter":  {filter: Or()},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f := Scanner{Client: filterKeys(t), Filter: tc.filter}
+			var matched []string
+			for _, key := range keys {
+				ok, err := f.matchFilter(context.Background(), key)
+				if err != nil {
+					t.Fatalf("unexpected error for %s: %v", key, err)
+				}
+				if ok {
+					matched = append(matched, key)
+				}
+			}
+			if !slices.Equal(matched, tc.matched) {
+				t.Fatalf("want %v, got %v", tc.matched, matched)
+			}
+		})
+	}
+}
+
+func TestScannerFilter(t *testing.T) {
+	s := miniredis.RunT(t)
+	for key, ttl := range map[string]time.Duration{"user:1": time.Minute, "user:2": 2 * time.Hour, "session:1": time.Minute} {
+		_ = s.Set(key, "bar")
+		s.SetTTL(key, ttl)
+	}
+	f := Scanner{
+		Mode:   "persist",
+		Client: redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Filter: And(Prefix("user:"), TTLBetween(0, time.Hour)),
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for key, ttl := range map[string]time.Duration{"user:1": 0, "user:2": 2 * time.Hour, "session:1": time.Minute} {
+		if got := s.TTL(key); got != ttl {
+			t.Fatalf("want %s with a ttl of %v, got %v", key, ttl, got)
+		}
+	}
+	if st := f.Stats(); st.Scanned != 3 || st.Modified != 1 {
+		t.Fatalf("want the keys filtered out left untouched, got %+v", st)
+	}
+}
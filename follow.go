@@ -0,0 +1,281 @@
+package redisttl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// followModes are the modes a Follower supports: the ones giving a TTL to
+// keys without one, which applying twice doesn't change.
+var followModes = map[string]bool{
+	"exp":     true,
+	"lt":      true,
+	"nx":      true,
+	"inherit": true,
+}
+
+// ignoredEvents are the keyspace events which don't leave a key without a
+// TTL, including the ones the follower's own commands trigger.
+var ignoredEvents = map[string]bool{
+	"del":         true,
+	"expire":      true,
+	"expired":     true,
+	"evicted":     true,
+	"rename_from": true,
+	"move_from":   true,
+}
+
+// checkpointInterval is how often, on the Clock of its Scanner, a Follower
+// saves its checkpoint while catching up.
+const checkpointInterval = 10 * time.Second
+
+type subscriber interface {
+	PSubscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// Follower applies the mode of a Scanner to the whole keyspace, then keeps
+// applying it to the matched keys written without a TTL afterwards, as
+// keyspace notifications report them. Notifications must be enabled, e.g.
+// with notify-keyspace-events set to KA.
+//
+// The follower subscribes before scanning, so the keys written while it
+// catches up aren't missed. Redis doesn't keep notifications for absent
+// subscribers though: a follower restarting after its scan completed scans
+// again for the keys written while it was down, while one restarting midway
+// resumes the scan from its Checkpoint.
+type Follower struct {
+	// Scanner can't scan a cluster client, whose notifications are sent by
+	// every primary: a Follower follows a single node.
+	Scanner *Scanner
+	// Subscriber receives the keyspace notifications of the node scanned,
+	// Scanner.ScanClient, or Scanner.Client, when nil.
+	Subscriber subscriber
+	// DB is the database whose notifications are followed.
+	DB int
+	// Checkpoint, when set, is the file recording the progress of the scan
+	// across restarts.
+	Checkpoint string
+}
+
+// FollowCheckpoint is the progress a Follower records in its checkpoint.
+type FollowCheckpoint struct {
+	// Cursor is the cursor the scan resumes from.
+	Cursor uint64 `json:"cursor"`
+	// CaughtUp is true once the scan completed, and the follower only
+	// followed notifications since.
+	CaughtUp  bool      `json:"caught_up"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (f *Follower) Run(ctx context.Context) error {
+	if !followModes[f.Scanner.Mode] {
+		return fmt.Errorf("mode %s can't follow notifications: %w", f.Scanner.Mode, errInvalidMode)
+	}
+	if _, ok := f.Scanner.scanClient().(*redis.ClusterClient); ok {
+		return fmt.Errorf("a follower can't follow a cluster client, follow each primary instead: %w", errInvalidMode)
+	}
+	sub := f.Subscriber
+	if sub == nil {
+		c, ok := f.Scanner.scanClient().(subscriber)
+		if !ok {
+			return fmt.Errorf("client can't subscribe to notifications: %w", errInvalidMode)
+		}
+		sub = c
+	}
+	cp, err := f.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	ps := sub.PSubscribe(ctx, f.channels()...)
+	defer ps.Close()
+	if _, err := ps.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe error: %w", err)
+	}
+	f.checkNotifications(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- f.follow(ctx, ps.Channel())
+		cancel()
+	}()
+
+	caughtUp := f.catchUp(ctx, cp)
+	if caughtUp != nil {
+		cancel()
+	}
+	followed := <-done
+	if followed != nil && !errors.Is(followed, context.Canceled) {
+		return followed
+	}
+	if caughtUp != nil {
+		return caughtUp
+	}
+	return followed
+}
+
+// channels are the keyspace channels of the matched keys.
+func (f *Follower) channels() []string {
+	patterns := f.Scanner.ScanPatterns
+	if len(patterns) == 0 {
+		patterns = []string{f.Scanner.ScanPrefix}
+	}
+	channels := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		channels = append(channels, fmt.Sprintf("__keyspace@%d__:%s", f.DB, p))
+	}
+	return channels
+}
+
+// checkNotifications warns when the server doesn't send keyspace
+// notifications. Servers which don't allow reading the setting are trusted.
+func (f *Follower) checkNotifications(ctx context.Context) {
+	cfg, err := f.Scanner.Client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return
+	}
+	if events, found := cfg["notify-keyspace-events"]; found && !strings.Contains(events, "K") {
+		log.Printf("keyspace notifications are disabled (notify-keyspace-events=%q), new keys won't be followed\n", events)
+	}
+}
+
+// catchUp runs the Scanner from the checkpoint, saving it every
+// checkpointInterval as it goes.
+func (f *Follower) catchUp(ctx context.Context, cp FollowCheckpoint) error {
+	s := f.Scanner
+	var cursor uint64
+	if cp.CaughtUp {
+		log.Printf("caught up at %s, scanning again for the keys written since\n", cp.UpdatedAt.Format(time.RFC3339))
+	} else {
+		cursor = cp.Cursor
+	}
+
+	// the copy shares the liveStats and pauseGate of s
+	s.stats()
+	s.gate()
+	g := *s
+	saved := s.clock().Now()
+	g.onBatch = func(cursor uint64) {
+		if now := s.clock().Now(); now.Sub(saved) >= checkpointInterval {
+			f.saveCheckpoint(FollowCheckpoint{Cursor: cursor})
+			saved = now
+		}
+	}
+	err := g.start(ctx, nil, cursor)
+	if err != nil {
+		f.saveCheckpoint(FollowCheckpoint{Cursor: s.Stats().Cursor})
+		return err
+	}
+	f.saveCheckpoint(FollowCheckpoint{CaughtUp: true})
+	return nil
+}
+
+// follow applies the mode to the keys notifications report, until ctx is
+// done.
+func (f *Follower) follow(ctx context.Context, messages <-chan *redis.Message) error {
+	s := f.Scanner
+	r, err := s.newRun()
+	if err != nil {
+		return err
+	}
+	if err := s.emulate(ctx, r); err != nil {
+		return err
+	}
+	prefix := fmt.Sprintf("__keyspace@%d__:", f.DB)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("subscription closed")
+			}
+			key, found := strings.CutPrefix(msg.Channel, prefix)
+			if !found || ignoredEvents[msg.Payload] {
+				continue
+			}
+			if err := s.checkKillSwitch(ctx, r); err != nil {
+				return err
+			}
+			if err := f.apply(ctx, r, key); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apply applies the mode to key when it has no TTL.
+func (f *Follower) apply(ctx context.Context, r *scanRun, key string) error {
+	s := f.Scanner
+	ttl, err := s.Client.PTTL(ctx, key).Result()
+	if err != nil || ttl != noTTL {
+		return nil
+	}
+	if s.ScanType != "" {
+		typ, err := s.Client.Type(ctx, key).Result()
+		if err != nil || typ != s.ScanType {
+			return nil
+		}
+	}
+	return s.applyBatch(ctx, r, []string{key})
+}
+
+func (f *Follower) loadCheckpoint() (FollowCheckpoint, error) {
+	var cp FollowCheckpoint
+	if f.Checkpoint == "" {
+		return cp, nil
+	}
+	b, err := os.ReadFile(f.Checkpoint)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return cp, nil
+	case err != nil:
+		return cp, fmt.Errorf("read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return cp, fmt.Errorf("parse checkpoint %s: %w", f.Checkpoint, err)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint replaces the checkpoint with cp. Failures are logged, the
+// follower keeping on.
+func (f *Follower) saveCheckpoint(cp FollowCheckpoint) {
+	if f.Checkpoint == "" {
+		return
+	}
+	cp.UpdatedAt = f.Scanner.clock().Now()
+	b, _ := json.Marshal(cp)
+	if err := writeFile(f.Checkpoint, b); err != nil {
+		log.Printf("save checkpoint error: %v\n", err)
+	}
+}
+
+// writeFile replaces the file at path with b, so readers never see it
+// partially written.
+func writeFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,211 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// eventually fails t unless cond becomes true within a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	f := Follower{
+		Scanner: &Scanner{
+			Mode:       "exp",
+			ScanPrefix: "foo:*",
+			Client:     rdb,
+			DesiredTTL: time.Hour,
+		},
+		Subscriber: rdb,
+		Checkpoint: checkpoint,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx) }()
+
+	eventually(t, func() bool { return s.TTL("foo:1") == time.Hour })
+	eventually(t, func() bool {
+		cp, err := f.loadCheckpoint()
+		return err == nil && cp.CaughtUp
+	})
+
+	// the follower's own writes and deletions are ignored
+	_ = s.Set("foo:2", "bar")
+	s.Publish("__keyspace@0__:foo:2", "expire")
+	_ = s.Set("foo:3", "bar")
+	s.Publish("__keyspace@0__:foo:3", "set")
+	_ = s.Set("zoo:1", "bar")
+	s.Publish("__keyspace@0__:zoo:1", "set")
+
+	eventually(t, func() bool { return s.TTL("foo:3") == time.Hour })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got: %v", err)
+	}
+	if ttl := s.TTL("foo:2"); ttl != 0 {
+		t.Fatalf("foo:2 should be left untouched, got ttl: %v", ttl)
+	}
+	if ttl := s.TTL("zoo:1"); ttl != 0 {
+		t.Fatalf("zoo:1 doesn't match, got ttl: %v", ttl)
+	}
+}
+
+func TestFollowerResume(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo:1", "foo:2", "foo:3", "foo:4"} {
+		_ = s.Set(k, "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := os.WriteFile(checkpoint, []byte(`{"cursor": 2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := Follower{
+		Scanner: &Scanner{
+			Mode:       "exp",
+			ScanPrefix: "foo:*",
+			ScanCount:  2,
+			Client:     rdb,
+			DesiredTTL: time.Hour,
+		},
+		Checkpoint: checkpoint,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx) }()
+
+	eventually(t, func() bool { return s.TTL("foo:4") == time.Hour })
+	cancel()
+	<-done
+
+	expected := map[string]time.Duration{
+		"foo:1": 0,
+		"foo:2": 0,
+		"foo:3": time.Hour,
+		"foo:4": time.Hour,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+}
+
+func TestFollowerUnsupportedMode(t *testing.T) {
+	f := Follower{Scanner: &Scanner{Mode: "extend"}}
+	if err := f.Run(context.Background()); !errors.Is(err, errInvalidMode) {
+		t.Fatalf("want errInvalidMode, got: %v", err)
+	}
+}
+
+func TestFollowerCluster(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.Addr()}})
+	defer rdb.Close()
+
+	f := Follower{Scanner: &Scanner{Mode: "exp", Client: rdb, DesiredTTL: time.Hour}}
+	if err := f.Run(context.Background()); !errors.Is(err, errInvalidMode) {
+		t.Fatalf("want errInvalidMode, got: %v", err)
+	}
+}
+
+// checkpointHook advances clock by checkpointInterval before every SCAN,
+// recording the cursors of the checkpoints of f saved while catching up.
+type checkpointHook struct {
+	clock   *FakeClock
+	f       *Follower
+	mu      sync.Mutex
+	cursors []uint64
+}
+
+func (h *checkpointHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "scan" {
+			if cp, err := h.f.loadCheckpoint(); err == nil && !cp.CaughtUp && cp.Cursor != 0 {
+				h.mu.Lock()
+				h.cursors = append(h.cursors, cp.Cursor)
+				h.mu.Unlock()
+			}
+			h.clock.Advance(checkpointInterval)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *checkpointHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *checkpointHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestFollowerCheckpointClock(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo:1", "foo:2", "foo:3", "foo:4"} {
+		_ = s.Set(k, "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	f := &Follower{
+		Scanner: &Scanner{
+			Mode:       "exp",
+			ScanPrefix: "foo:*",
+			ScanCount:  1,
+			Client:     rdb,
+			DesiredTTL: time.Hour,
+			Clock:      NewFakeClock(time.Now()),
+		},
+		Checkpoint: filepath.Join(t.TempDir(), "checkpoint.json"),
+	}
+	h := &checkpointHook{clock: f.Scanner.Clock.(*FakeClock), f: f}
+	rdb.AddHook(h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx) }()
+	eventually(t, func() bool {
+		cp, err := f.loadCheckpoint()
+		return err == nil && cp.CaughtUp
+	})
+	cancel()
+	<-done
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.cursors) == 0 {
+		t.Fatal("want the checkpoint saved as the clock moves while catching up")
+	}
+}
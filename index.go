@@ -0,0 +1,47 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// indexExpiry records in ExpiryIndex, when set, when key expires given the
+// TTL applied to it, removing it from the index when it no longer expires or
+// was purged.
+// Failures are logged, the key being modified anyway.
+func (f *Scanner) indexExpiry(ctx context.Context, key string, applied time.Duration) {
+	if f.ExpiryIndex == "" || f.DryRun {
+		return
+	}
+	var err error
+	if applied < 0 || f.Mode == "purge" {
+		err = f.Client.ZRem(ctx, f.ExpiryIndex, key).Err()
+	} else {
+		at := f.clock().Now().Add(applied).UnixMilli()
+		err = f.Client.ZAdd(ctx, f.ExpiryIndex, redis.Z{Score: float64(at), Member: key}).Err()
+	}
+	if err != nil {
+		log.Printf("expiry index error for %s: %v\n", key, err)
+	}
+}
+
+// ExpiringBefore returns the keys the expiry index at index records as
+// expiring before t, soonest first, at most limit of them, all when limit is
+// 0. Expired keys are returned until TrimExpiryIndex removes them.
+func ExpiringBefore(ctx context.Context, c redis.Cmdable, index string, t time.Time, limit int64) ([]string, error) {
+	return c.ZRangeByScore(ctx, index, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + strconv.FormatInt(t.UnixMilli(), 10),
+		Count: limit,
+	}).Result()
+}
+
+// TrimExpiryIndex removes the keys the expiry index at index records as
+// expired, returning how many were removed.
+func TrimExpiryIndex(ctx context.Context, c redis.Cmdable, index string) (int64, error) {
+	return c.ZRemRangeByScore(ctx, index, "-inf", "("+strconv.FormatInt(time.Now().UnixMilli(), 10)).Result()
+}
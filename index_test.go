@@ -0,0 +1,77 @@
+package redisttl
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestExpiryIndex(t *testing.T) {
+	tests := map[string]struct {
+		scanner Scanner
+		want    []string
+	}{
+		"exp": {
+			scanner: Scanner{Mode: "exp", DesiredTTL: time.Hour},
+			want:    []string{"other", "user:1", "user:2", "user:3"},
+		},
+		"batch script": {
+			scanner: Scanner{Mode: "exp", DesiredTTL: time.Hour, BatchScript: true},
+			want:    []string{"other", "user:1", "user:2", "user:3"},
+		},
+		"persist": {
+			scanner: Scanner{Mode: "persist"},
+			want:    []string{"other"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, k := range []string{"user:1", "user:2", "user:3"} {
+				_ = s.Set(k, "bar")
+				s.SetTTL(k, 24*time.Hour)
+			}
+			// matched by the scan, the index is left alone
+			_, _ = s.ZAdd("user:index", float64(time.Now().Add(time.Minute).UnixMilli()), "other")
+			_, _ = s.ZAdd("user:index", float64(time.Now().Add(24*time.Hour).UnixMilli()), "user:1")
+
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			f := tc.scanner
+			f.Client, f.ScanPrefix, f.ExpiryIndex = rdb, "user:*", "user:index"
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx := context.Background()
+			keys, err := ExpiringBefore(ctx, rdb, "user:index", time.Now().Add(48*time.Hour), 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(keys, tc.want) {
+				t.Fatalf("want: %v got: %v", tc.want, keys)
+			}
+		})
+	}
+}
+
+func TestTrimExpiryIndex(t *testing.T) {
+	s := miniredis.RunT(t)
+	now := time.Now()
+	_, _ = s.ZAdd("index", float64(now.Add(-time.Minute).UnixMilli()), "expired")
+	_, _ = s.ZAdd("index", float64(now.Add(time.Minute).UnixMilli()), "soon")
+	_, _ = s.ZAdd("index", float64(now.Add(time.Hour).UnixMilli()), "later")
+
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	ctx := context.Background()
+	if n, err := TrimExpiryIndex(ctx, rdb, "index"); err != nil || n != 1 {
+		t.Fatalf("want 1 key trimmed, got: %d, %v", n, err)
+	}
+	keys, err := ExpiringBefore(ctx, rdb, "index", now.Add(2*time.Hour), 1)
+	if err != nil || !reflect.DeepEqual(keys, []string{"soon"}) {
+		t.Fatalf("want the soonest key, got: %v, %v", keys, err)
+	}
+}
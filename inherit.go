@@ -0,0 +1,49 @@
+package redisttl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sourceKey returns the key whose TTL key inherits.
+func (f *Scanner) sourceKey(key string) string {
+	return strings.ReplaceAll(f.SourceKey, keyPlaceholder, key)
+}
+
+// sourceTTL returns the TTL of the source key of key, and false when the
+// source doesn't exist.
+func (f *Scanner) sourceTTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	ttl, err := f.Client.PTTL(ctx, f.sourceKey(key)).Result()
+	if err != nil || ttl == -2 {
+		return 0, false, err
+	}
+	return ttl, true, nil
+}
+
+// inherit copies the TTL of the source key of every key, no TTL included.
+// Keys whose source doesn't exist are left untouched.
+func (f *Scanner) inherit(ctx context.Context, key string, _ time.Duration) *redis.BoolCmd {
+	ttl, found, err := f.sourceTTL(ctx, key)
+	if err != nil || !found {
+		cmd := redis.NewBoolCmd(ctx)
+		cmd.SetErr(err)
+		return cmd
+	}
+	if ttl == noTTL {
+		return f.Client.Persist(ctx, key)
+	}
+	// PEXPIRE with a value of 0 would delete the key
+	return f.Client.PExpire(ctx, key, max(ttl, time.Millisecond))
+}
+
+// inheritPlan is the dry-run counterpart of inherit.
+func (f *Scanner) inheritPlan(ctx context.Context, key string, _, _ time.Duration) (time.Duration, bool) {
+	ttl, found, err := f.sourceTTL(ctx, key)
+	if err != nil || !found {
+		return noTTL, false
+	}
+	return ttl, true
+}
@@ -0,0 +1,90 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestInheritMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"user:1", "user:1:meta", "user:2", "user:2:meta", "user:3"} {
+		_ = s.Set(k, "bar")
+	}
+	s.SetTTL("user:1", time.Hour)
+	s.SetTTL("user:1:meta", 24*time.Hour)
+	s.SetTTL("user:2", time.Hour) // its meta key never expires
+	s.SetTTL("user:3", time.Hour) // no meta key
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "inherit",
+		ScanPrefix: "user:*",
+		Client:     rdb,
+		SourceKey:  "{key}:meta",
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"user:1":      24 * time.Hour,
+		"user:1:meta": 24 * time.Hour,
+		"user:2":      0,
+		"user:3":      time.Hour,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if summary.Modified.Load() != 2 {
+		t.Fatalf("expected 2 keys modified, got: %s", summary)
+	}
+}
+
+func TestInheritDryRun(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("user:1", "bar")
+	_ = s.Set("meta:user:1", "bar")
+	_ = s.Set("user:2", "bar")
+	s.SetTTL("meta:user:1", 12*time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	var diff bytes.Buffer
+	f := Scanner{
+		Mode:       "inherit",
+		ScanPrefix: "user:*",
+		Client:     rdb,
+		SourceKey:  "meta:{key}",
+		DryRun:     true,
+		Diff:       &diff,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"user:1: no-ttl → 12h [inherit would apply]\n",
+		"user:2: no-ttl → no-ttl [inherit would skip]\n",
+	} {
+		if !strings.Contains(diff.String(), want) {
+			t.Fatalf("missing line %q in:\n%s", want, diff.String())
+		}
+	}
+	if ttl := s.TTL("user:1"); ttl != 0 {
+		t.Fatalf("dry run should not modify keys, got ttl: %v", ttl)
+	}
+}
@@ -0,0 +1,92 @@
+package redisttl
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// InventoryBounds are the upper bounds of the TTL buckets of an Inventory.
+var InventoryBounds = []time.Duration{
+	time.Minute,
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+// NodeInventory counts the keys scanned on a node.
+type NodeInventory struct {
+	Node string
+	Keys int64
+	// Types counts the keys by type, e.g. "hash".
+	Types      map[string]int64
+	WithoutTTL int64
+	// TTLs counts the keys with a TTL by bucket: TTLs[i] the ones expiring
+	// within InventoryBounds[i], but not the previous bound, and the last one
+	// the ones expiring later.
+	TTLs []int64
+}
+
+// WithTTL returns the number of keys with a TTL.
+func (n NodeInventory) WithTTL() int64 {
+	return n.Keys - n.WithoutTTL
+}
+
+// Inventory counts the keys scanned in "noop" mode by node, type and TTL,
+// turning a run modifying nothing into an inventory of the keyspace. Like
+// Summary, it can be shared by several scanners and is safe for concurrent
+// use.
+type Inventory struct {
+	mu    sync.Mutex
+	nodes map[string]*NodeInventory
+}
+
+// observe records a key of node of type typ, empty when unknown, whose TTL
+// is ttl, noTTL for none.
+func (inv *Inventory) observe(node, typ string, ttl time.Duration) {
+	if inv == nil {
+		return
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if inv.nodes == nil {
+		inv.nodes = map[string]*NodeInventory{}
+	}
+	n, found := inv.nodes[node]
+	if !found {
+		n = &NodeInventory{
+			Node:  node,
+			Types: map[string]int64{},
+			TTLs:  make([]int64, len(InventoryBounds)+1),
+		}
+		inv.nodes[node] = n
+	}
+	n.Keys++
+	if typ != "" {
+		n.Types[typ]++
+	}
+	if ttl < 0 {
+		n.WithoutTTL++
+		return
+	}
+	n.TTLs[sort.Search(len(InventoryBounds), func(i int) bool { return ttl <= InventoryBounds[i] })]++
+}
+
+// Nodes returns the counts of every node, in node order.
+func (inv *Inventory) Nodes() []NodeInventory {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	nodes := make([]NodeInventory, 0, len(inv.nodes))
+	for _, n := range inv.nodes {
+		c := *n
+		c.Types = make(map[string]int64, len(n.Types))
+		for typ, keys := range n.Types {
+			c.Types[typ] = keys
+		}
+		c.TTLs = append([]int64(nil), n.TTLs...)
+		nodes = append(nodes, c)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+	return nodes
+}
@@ -0,0 +1,71 @@
+package redisttl
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestInventory(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+	_ = s.Set("foo:2", "bar")
+	s.SetTTL("foo:2", 30*time.Second)
+	s.HSet("foo:3", "field", "value")
+	s.SetTTL("foo:3", 2*time.Hour)
+	_, _ = s.Lpush("foo:4", "a")
+	s.SetTTL("foo:4", 90*24*time.Hour)
+
+	inv := &Inventory{}
+	f := Scanner{
+		Mode:       "noop",
+		ScanPrefix: "foo:*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Node:       s.Addr(),
+		Inventory:  inv,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nodes := inv.Nodes()
+	if len(nodes) != 1 {
+		t.Fatalf("want a single node, got %+v", nodes)
+	}
+	n := nodes[0]
+	if n.Node != s.Addr() || n.Keys != 4 || n.WithoutTTL != 1 || n.WithTTL() != 3 {
+		t.Fatalf("unexpected counts: %+v", n)
+	}
+	if want := map[string]int64{"string": 2, "hash": 1, "list": 1}; !reflect.DeepEqual(n.Types, want) {
+		t.Fatalf("want types %v, got %v", want, n.Types)
+	}
+	if want := []int64{1, 0, 1, 0, 0, 1}; !reflect.DeepEqual(n.TTLs, want) {
+		t.Fatalf("want ttl buckets %v, got %v", want, n.TTLs)
+	}
+	if ttl := s.TTL("foo:1"); ttl != 0 {
+		t.Fatalf("noop mode modified foo:1: %v", ttl)
+	}
+}
+
+func TestInventoryScanType(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "bar")
+
+	inv := &Inventory{}
+	f := Scanner{
+		Mode:       "noop",
+		ScanPrefix: "foo:*",
+		ScanType:   "string",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Inventory:  inv,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nodes := inv.Nodes(); len(nodes) != 1 || nodes[0].Types["string"] != 1 {
+		t.Fatalf("want the scan type counted, got %+v", nodes)
+	}
+}
@@ -0,0 +1,131 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const sizeBuckets = 24
+
+// sizeBounds are the upper bounds of the size histogram buckets, in bytes,
+// from 64B doubling up to 512MiB. Larger keys land in an overflow bucket.
+var sizeBounds = func() []int64 {
+	bounds := make([]int64, sizeBuckets)
+	n := int64(64)
+	for i := range bounds {
+		bounds[i] = n
+		n *= 2
+	}
+	return bounds
+}()
+
+// SizeHistogram is a histogram of key sizes in bytes with exponentially
+// sized buckets. The zero value is ready to use and safe for concurrent use.
+type SizeHistogram struct {
+	mu     sync.Mutex
+	counts [sizeBuckets + 1]int64
+	count  int64
+	sum    int64
+	max    int64
+}
+
+// Observe records the size of a single key.
+func (h *SizeHistogram) Observe(n int64) {
+	i := 0
+	for i < len(sizeBounds) && n > sizeBounds[i] {
+		i++
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += n
+	h.max = max(h.max, n)
+}
+
+// SizeHistogramSnapshot is a point in time copy of a SizeHistogram.
+type SizeHistogramSnapshot struct {
+	// Bounds are the bucket upper bounds; Counts has one extra trailing
+	// entry for sizes greater than the last bound.
+	Bounds []int64
+	Counts []int64
+	Count  int64
+	Sum    int64
+	Max    int64
+}
+
+// Snapshot returns a copy of the current state of the histogram.
+func (h *SizeHistogram) Snapshot() SizeHistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return SizeHistogramSnapshot{
+		Bounds: sizeBounds,
+		Counts: append([]int64(nil), h.counts[:]...),
+		Count:  h.count,
+		Sum:    h.sum,
+		Max:    h.max,
+	}
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) by interpolating
+// linearly within the bucket it falls in.
+func (s SizeHistogramSnapshot) Quantile(q float64) int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(s.Count)))
+	var seen int64
+	for i, c := range s.Counts {
+		if seen+c < rank {
+			seen += c
+			continue
+		}
+		if i == len(s.Bounds) {
+			return s.Max
+		}
+		lower := int64(0)
+		if i > 0 {
+			lower = s.Bounds[i-1]
+		}
+		frac := float64(rank-seen) / float64(c)
+		return min(lower+int64(frac*float64(s.Bounds[i]-lower)), s.Max)
+	}
+	return s.Max
+}
+
+func (h *SizeHistogram) String() string {
+	s := h.Snapshot()
+	return fmt.Sprintf("count=%d p50=%dB p95=%dB p99=%dB max=%dB",
+		s.Count, s.Quantile(0.50), s.Quantile(0.95), s.Quantile(0.99), s.Max)
+}
+
+// sampleSize records the size of key in the KeySizes of the summary of r
+// when it is one of the KeySizeEvery-th keys scanned.
+func (f *Scanner) sampleSize(ctx context.Context, r *scanRun, key string) {
+	if f.KeySizeEvery <= 0 || atomic.LoadInt64(&r.live.scanned)%f.KeySizeEvery != 0 {
+		return
+	}
+	var n int64
+	var err error
+	if f.KeySizeStrlen {
+		n, err = f.scanClient().StrLen(ctx, key).Result()
+	} else {
+		n, err = f.scanClient().MemoryUsage(ctx, key).Result()
+	}
+	switch {
+	case errors.Is(err, redis.Nil), redis.HasErrorPrefix(err, "WRONGTYPE"):
+		// the key expired since it was scanned, or isn't a string
+	case err != nil:
+		log.Printf("key size error for %s: %v\n", key, err)
+	default:
+		r.summary.KeySizes.Observe(n)
+	}
+}
@@ -0,0 +1,102 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	var h SizeHistogram
+	for _, n := range []int64{10, 100, 100, 5000} {
+		h.Observe(n)
+	}
+	s := h.Snapshot()
+	if s.Count != 4 || s.Sum != 5210 || s.Max != 5000 {
+		t.Fatalf("unexpected snapshot: %+v", s)
+	}
+	if p50 := s.Quantile(0.5); p50 <= 64 || p50 > 128 {
+		t.Fatalf("want a median within the 64B-128B bucket, got %d", p50)
+	}
+	if p99 := s.Quantile(0.99); p99 != 5000 {
+		t.Fatalf("want the p99 capped by the max, got %d", p99)
+	}
+	if got := h.String(); !strings.HasPrefix(got, "count=4 ") {
+		t.Fatalf("unexpected string: %s", got)
+	}
+}
+
+func TestKeySizes(t *testing.T) {
+	testCases := map[string]struct {
+		prefix  string
+		every   int64
+		strlen  bool
+		sampled int64
+		max     int64
+	}{
+		"disabled": {},
+		"every key": {
+			every:   1,
+			strlen:  true,
+			sampled: 4,
+			max:     400,
+		},
+		"every other key": {
+			every:   2,
+			strlen:  true,
+			sampled: 2,
+			max:     400,
+		},
+		"memory usage": {
+			every:   4,
+			sampled: 1,
+		},
+		"strlen skips other types": {
+			prefix:  "foo:*",
+			every:   1,
+			strlen:  true,
+			sampled: 4,
+			max:     400,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for i := 1; i <= 4; i++ {
+				_ = s.Set(fmt.Sprintf("foo:%d", i), strings.Repeat("v", 100*i))
+			}
+			_, _ = s.SetAdd("foo:set", "a")
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			rdb.AddHook(memoryUsageHook{})
+
+			if tc.prefix == "" {
+				tc.prefix = "foo:[0-9]"
+			}
+			summary := &Summary{}
+			f := Scanner{
+				Mode:          "exp",
+				ScanPrefix:    tc.prefix,
+				Client:        rdb,
+				DesiredTTL:    time.Hour,
+				Summary:       summary,
+				KeySizeEvery:  tc.every,
+				KeySizeStrlen: tc.strlen,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sizes := summary.KeySizes.Snapshot()
+			if sizes.Count != tc.sampled || (tc.max > 0 && sizes.Max != tc.max) {
+				t.Fatalf("want %d sizes up to %d, got %+v", tc.sampled, tc.max, sizes)
+			}
+			if tc.sampled > 0 && !strings.Contains(summary.String(), "key_sizes[count=") {
+				t.Fatalf("want the sizes in the summary, got %s", summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrKilled is returned by the runs stopped by their KillSwitch.
+var ErrKilled = errors.New("stopped by kill switch")
+
+// killSwitchPause is the value of a kill switch key pausing the run rather
+// than stopping it.
+const killSwitchPause = "pause"
+
+// KillSwitch stops a run once its key exists, so an operator with access
+// to the server, e.g. through redis-cli, can stop it without access to the
+// host running it. A key holding "pause" pauses the run instead, until it
+// is deleted or set to another value.
+type KillSwitch struct {
+	// Client reads the key, Scanner.Client when nil.
+	Client redis.Cmdable
+	Key    string
+	// Interval is how often the key is read, 1s when 0.
+	Interval time.Duration
+}
+
+func (k *KillSwitch) interval() time.Duration {
+	if k.Interval == 0 {
+		return time.Second
+	}
+	return k.Interval
+}
+
+// check returns ErrKilled once the key exists, blocking on clock while it
+// pauses the run. Failing to read the key is logged rather than stopping the
+// run.
+func (k *KillSwitch) check(ctx context.Context, clock Clock, c redis.Cmdable) error {
+	if k.Client != nil {
+		c = k.Client
+	}
+	paused := false
+	for {
+		v, err := c.Get(ctx, k.Key).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+			if paused {
+				log.Printf("kill switch %s deleted, resuming\n", k.Key)
+			}
+			return nil
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			log.Printf("kill switch %s: %v\n", k.Key, err)
+			return nil
+		case v != killSwitchPause:
+			return fmt.Errorf("%s set to %q: %w", k.Key, v, ErrKilled)
+		case !paused:
+			log.Printf("kill switch %s set to %q, pausing\n", k.Key, v)
+			paused = true
+		}
+
+		if err := clock.Sleep(ctx, k.interval()); err != nil {
+			return err
+		}
+	}
+}
+
+// checkKillSwitch checks the KillSwitch when it hasn't been in the last
+// interval.
+func (f *Scanner) checkKillSwitch(ctx context.Context, r *scanRun) error {
+	k := f.KillSwitch
+	if k == nil || f.clock().Now().Sub(r.killSwitchRead) < k.interval() {
+		return nil
+	}
+	err := k.check(ctx, f.clock(), f.Client)
+	r.killSwitchRead = f.clock().Now()
+	return err
+}
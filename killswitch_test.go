@@ -0,0 +1,141 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestKillSwitch(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		err      error
+		modified time.Duration
+	}{
+		"unset": {
+			modified: time.Hour,
+		},
+		"stop": {
+			value: "stop",
+			err:   ErrKilled,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("foo", "bar")
+			if tc.value != "" {
+				_ = s.Set("ops:stop", tc.value)
+			}
+			rdb := redis.NewClient(&redis.Options{
+				Addr: s.Addr(),
+			})
+
+			f := &Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo",
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+				KillSwitch: &KillSwitch{Key: "ops:stop"},
+			}
+			if err := f.Run(context.Background()); !errors.Is(err, tc.err) {
+				t.Fatalf("want: %v got: %v", tc.err, err)
+			}
+			if ttl := s.TTL("foo"); ttl != tc.modified {
+				t.Fatalf("want ttl: %v got: %v", tc.modified, ttl)
+			}
+		})
+	}
+}
+
+func TestKillSwitchPause(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("ops:stop", "pause")
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		KillSwitch: &KillSwitch{Key: "ops:stop", Interval: time.Millisecond},
+	}
+	done := make(chan error, 1)
+	go func() { done <- f.Run(context.Background()) }()
+
+	time.Sleep(20 * time.Millisecond)
+	if ttl := s.TTL("foo"); ttl != 0 {
+		t.Fatalf("no key should be modified while paused, got ttl: %v", ttl)
+	}
+	s.Del("ops:stop")
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != time.Hour {
+		t.Fatalf("want ttl: %v got: %v", time.Hour, ttl)
+	}
+}
+
+// deleteHook deletes key from s before the GET of key numbered after.
+type deleteHook struct {
+	s     *miniredis.Miniredis
+	key   string
+	after int
+	gets  int
+}
+
+func (h *deleteHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *deleteHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "get" && cmd.Args()[1] == h.key {
+			if h.gets++; h.gets == h.after {
+				h.s.Del(h.key)
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *deleteHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestKillSwitchPauseClock(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("ops:stop", "pause")
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(&deleteHook{s: s, key: "ops:stop", after: 3})
+
+	clock := NewFakeClock(time.Now())
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Clock:      clock,
+		KillSwitch: &KillSwitch{Key: "ops:stop", Interval: time.Minute},
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// paused for the two reads before the key was deleted
+	if clock.Slept() != 2*time.Minute {
+		t.Fatalf("want the pauses on the clock, slept %v", clock.Slept())
+	}
+	if ttl := s.TTL("foo"); ttl != time.Hour {
+		t.Fatalf("want ttl: %v got: %v", time.Hour, ttl)
+	}
+}
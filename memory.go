@@ -0,0 +1,92 @@
+package redisttl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryGuard pauses a run while the server is close to maxmemory.
+// Both the scan and the expiry metadata consume memory, so backing off
+// before the server starts evicting is safer than pushing through.
+type MemoryGuard struct {
+	// Threshold is the used_memory/maxmemory ratio at which the run pauses.
+	Threshold float64
+	// CheckEvery is the number of keys processed between two memory polls.
+	CheckEvery int
+	// Pause is how long to wait before polling again once over Threshold.
+	Pause time.Duration
+}
+
+type memoryInfo struct {
+	used int64
+	max  int64
+}
+
+func (m memoryInfo) ratio() float64 {
+	if m.max <= 0 {
+		return 0
+	}
+	return float64(m.used) / float64(m.max)
+}
+
+// wait blocks on clock until the server memory usage drops below the
+// threshold. A server without maxmemory configured never pauses the run.
+func (g *MemoryGuard) wait(ctx context.Context, clock Clock, c redis.Cmdable) error {
+	for {
+		info, err := readMemoryInfo(ctx, c)
+		if err != nil {
+			return fmt.Errorf("memory guard: %w", err)
+		}
+		if info.ratio() < g.Threshold {
+			return nil
+		}
+		log.Printf("memory guard: used_memory at %.2f of maxmemory, pausing for %s\n", info.ratio(), g.Pause)
+
+		if err := clock.Sleep(ctx, g.Pause); err != nil {
+			return err
+		}
+	}
+}
+
+func readMemoryInfo(ctx context.Context, c redis.Cmdable) (memoryInfo, error) {
+	raw, err := c.Info(ctx, "memory").Result()
+	if err != nil {
+		return memoryInfo{}, err
+	}
+	return parseMemoryInfo(raw)
+}
+
+func parseMemoryInfo(raw string) (memoryInfo, error) {
+	info := memoryInfo{}
+	sc := bufio.NewScanner(strings.NewReader(raw))
+	for sc.Scan() {
+		name, value, found := strings.Cut(strings.TrimSpace(sc.Text()), ":")
+		if !found {
+			continue
+		}
+
+		var dst *int64
+		switch name {
+		case "used_memory":
+			dst = &info.used
+		case "maxmemory":
+			dst = &info.max
+		default:
+			continue
+		}
+
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return memoryInfo{}, fmt.Errorf("invalid %s value %q: %w", name, value, err)
+		}
+		*dst = n
+	}
+	return info, sc.Err()
+}
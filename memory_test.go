@@ -0,0 +1,140 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseMemoryInfo(t *testing.T) {
+	raw := "# Memory\r\nused_memory:900\r\nused_memory_human:900B\r\nmaxmemory:1000\r\n"
+	info, err := parseMemoryInfo(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.used != 900 || info.max != 1000 {
+		t.Fatalf("got: %+v", info)
+	}
+	if info.ratio() != 0.9 {
+		t.Fatalf("want: 0.9 got: %v", info.ratio())
+	}
+
+	if _, err := parseMemoryInfo("used_memory:lots\r\n"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	info, err = parseMemoryInfo("used_memory:900\r\nmaxmemory:0\r\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.ratio() != 0 {
+		t.Fatalf("no maxmemory should never pause, got ratio: %v", info.ratio())
+	}
+}
+
+// infoHook answers INFO commands with the next canned reply.
+type infoHook struct {
+	replies []string
+	calls   int
+}
+
+func (h *infoHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "info" {
+			return next(ctx, cmd)
+		}
+		reply := h.replies[len(h.replies)-1]
+		if h.calls < len(h.replies) {
+			reply = h.replies[h.calls]
+		}
+		h.calls++
+		cmd.(*redis.StringCmd).SetVal(reply)
+		return nil
+	}
+}
+
+func (h *infoHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *infoHook) DialHook(hook redis.DialHook) redis.DialHook {
+	return hook
+}
+
+func TestMemoryGuardPauses(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	h := &infoHook{replies: []string{
+		"used_memory:990\r\nmaxmemory:1000\r\n",
+		"used_memory:500\r\nmaxmemory:1000\r\n",
+	}}
+	rdb.AddHook(h)
+
+	clock := NewFakeClock(time.Now())
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Clock:      clock,
+		MemoryGuard: &MemoryGuard{
+			Threshold:  0.9,
+			CheckEvery: 1,
+			Pause:      time.Hour,
+		},
+	}
+
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clock.Slept() != time.Hour {
+		t.Fatalf("want a single pause on the clock, slept %v", clock.Slept())
+	}
+	// one poll over the threshold, one under it, then one for the second key
+	if h.calls != 3 {
+		t.Fatalf("want: 3 INFO calls got: %d", h.calls)
+	}
+	if s.TTL("foo") != time.Hour || s.TTL("far") != time.Hour {
+		t.Fatal("expected both keys to be expired once memory recovered")
+	}
+}
+
+func TestMemoryGuardHonorsContext(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(&infoHook{replies: []string{"used_memory:1000\r\nmaxmemory:1000\r\n"}})
+
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		MemoryGuard: &MemoryGuard{
+			Threshold:  0.9,
+			CheckEvery: 1,
+			Pause:      time.Hour,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := f.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want: %v got: %v", context.DeadlineExceeded, err)
+	}
+	if s.TTL("foo") != 0 {
+		t.Fatal("key should not be modified while memory is over the threshold")
+	}
+}
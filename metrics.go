@@ -0,0 +1,290 @@
+package redisttl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives the outcome of every key a Scanner processes as it
+// happens, node being the Node of the Scanner. Implementations must be safe
+// for concurrent use when shared by several scanners.
+type Metrics interface {
+	// IncScanned counts a key processed.
+	IncScanned(node string)
+	// IncModified counts a key modified.
+	IncModified(node string)
+	// IncError counts a key whose command failed with err.
+	IncError(node string, err error)
+	// ObserveLatency records how long the command sent for a key took.
+	ObserveLatency(node string, d time.Duration)
+}
+
+// NopMetrics discards every metric, the Metrics of a Scanner without any.
+type NopMetrics struct{}
+
+func (NopMetrics) IncScanned(string)                    {}
+func (NopMetrics) IncModified(string)                   {}
+func (NopMetrics) IncError(string, error)               {}
+func (NopMetrics) ObserveLatency(string, time.Duration) {}
+
+func (f *Scanner) metrics() Metrics {
+	if f.Metrics != nil {
+		return f.Metrics
+	}
+	return NopMetrics{}
+}
+
+// observeMetrics sends the outcome of the command sent for a key to the
+// Metrics of the run. A zero duration means no command was sent.
+func (r *scanRun) observeMetrics(d time.Duration, modified bool, err error) {
+	r.metrics.IncScanned(r.node)
+	if d > 0 {
+		r.metrics.ObserveLatency(r.node, d)
+	}
+	switch {
+	case err != nil:
+		r.metrics.IncError(r.node, err)
+	case modified:
+		r.metrics.IncModified(r.node)
+	}
+}
+
+// PrometheusMetrics counts the keys of every node, served in the Prometheus
+// text format by ServeHTTP. The zero value is ready to use.
+type PrometheusMetrics struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeMetrics
+}
+
+type nodeMetrics struct {
+	scanned  int64
+	modified int64
+	errors   map[string]int64
+	latency  Histogram
+}
+
+func (p *PrometheusMetrics) node(node string) *nodeMetrics {
+	if p.nodes == nil {
+		p.nodes = map[string]*nodeMetrics{}
+	}
+	n, found := p.nodes[node]
+	if !found {
+		n = &nodeMetrics{errors: map[string]int64{}}
+		p.nodes[node] = n
+	}
+	return n
+}
+
+func (p *PrometheusMetrics) IncScanned(node string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.node(node).scanned++
+}
+
+func (p *PrometheusMetrics) IncModified(node string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.node(node).modified++
+}
+
+// IncError counts err under its class, see ClassifyError.
+func (p *PrometheusMetrics) IncError(node string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.node(node).errors[ClassifyError(err)]++
+}
+
+func (p *PrometheusMetrics) ObserveLatency(node string, d time.Duration) {
+	p.mu.Lock()
+	n := p.node(node)
+	p.mu.Unlock()
+	n.latency.Observe(d)
+}
+
+// ServeHTTP writes the metrics of every node in the Prometheus text format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = p.WriteTo(w)
+}
+
+// WriteTo writes the metrics of every node in the Prometheus text format.
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	p.mu.Lock()
+	names := make([]string, 0, len(p.nodes))
+	for name := range p.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	nodes := make([]*nodeMetrics, len(names))
+	for i, name := range names {
+		nodes[i] = p.nodes[name]
+	}
+
+	writeFamily(&b, "redis_ttl_node_keys_scanned_total", "counter", "Keys processed by node.")
+	for i, n := range nodes {
+		fmt.Fprintf(&b, "redis_ttl_node_keys_scanned_total{node=%q} %d\n", names[i], n.scanned)
+	}
+	writeFamily(&b, "redis_ttl_node_keys_modified_total", "counter", "Keys modified by node.")
+	for i, n := range nodes {
+		fmt.Fprintf(&b, "redis_ttl_node_keys_modified_total{node=%q} %d\n", names[i], n.modified)
+	}
+	writeFamily(&b, "redis_ttl_node_errors_total", "counter", "Per-key command errors by node and class.")
+	for i, n := range nodes {
+		for _, class := range sortedKeys(n.errors) {
+			fmt.Fprintf(&b, "redis_ttl_node_errors_total{node=%q,class=%q} %d\n", names[i], class, n.errors[class])
+		}
+	}
+	p.mu.Unlock()
+
+	const name = "redis_ttl_node_command_duration_seconds"
+	writeFamily(&b, name, "histogram", "Latency of the commands sent for keys by node.")
+	for i, n := range nodes {
+		writeHistogram(&b, name, fmt.Sprintf("node=%q", names[i]), n.latency.Snapshot())
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// WritePrometheus writes the counters and latencies of s in the Prometheus
+// text format.
+func (s *Summary) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	for _, c := range []struct {
+		name, help string
+		v          int64
+	}{
+		{"redis_ttl_keys_scanned_total", "Keys returned by SCAN.", s.Scanned.Load()},
+		{"redis_ttl_keys_modified_total", "Keys whose TTL was changed.", s.Modified.Load()},
+		{"redis_ttl_companions_modified_total", "Companion keys whose TTL was changed.", s.Companions.Load()},
+		{"redis_ttl_keys_tolerated_total", "Keys skipped because their TTL was within the tolerance.", s.Tolerated.Load()},
+		{"redis_ttl_errors_total", "Per-key command errors.", s.Errors.Load()},
+		{"redis_ttl_breaker_trips_total", "Times the circuit breaker paused the run.", s.BreakerTrips.Load()},
+		{"redis_ttl_failovers_total", "Times the run resumed on the new primary of a node which failed over.", s.Failovers.Load()},
+	} {
+		writeFamily(&b, c.name, "counter", c.help)
+		fmt.Fprintf(&b, "%s %d\n", c.name, c.v)
+	}
+
+	const classes = "redis_ttl_errors_by_class_total"
+	writeFamily(&b, classes, "counter", "Per-key command errors by class.")
+	counts := s.ErrorClasses.Snapshot()
+	for _, class := range sortedKeys(counts) {
+		fmt.Fprintf(&b, "%s{class=%q} %d\n", classes, class, counts[class])
+	}
+
+	for _, h := range []struct {
+		name, help string
+		h          *Histogram
+	}{
+		{"redis_ttl_expire_duration_seconds", "Latency of expire commands.", &s.ExpireLatency},
+		{"redis_ttl_scan_duration_seconds", "Latency of SCAN batches.", &s.ScanLatency},
+	} {
+		writeFamily(&b, h.name, "histogram", h.help)
+		writeHistogram(&b, h.name, "", h.h.Snapshot())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePrometheus writes the commands sent to every node, by kind, in the
+// Prometheus text format.
+func (c *CommandCounter) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	const name = "redis_ttl_commands_total"
+	writeFamily(&b, name, "counter", "Commands sent by node and kind.")
+	for _, n := range c.Nodes() {
+		for _, k := range []struct {
+			kind  string
+			count int64
+		}{
+			{CommandScan, n.Scan},
+			{CommandRead, n.Read},
+			{CommandWrite, n.Write},
+			{CommandOther, n.Other},
+		} {
+			fmt.Fprintf(&b, "%s{node=%q,kind=%q} %d\n", name, n.Node, k.kind, k.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFamily writes the HELP and TYPE lines of the metric name.
+func writeFamily(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeHistogram writes the samples of the histogram name, labels being
+// the labels of every sample, like node="a", when not empty.
+func writeHistogram(w io.Writer, name, labels string, h HistogramSnapshot) {
+	le, set := "", ""
+	if labels != "" {
+		le, set = labels+",", "{"+labels+"}"
+	}
+	var cumulative int64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, le, seconds(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, le, h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, set, seconds(h.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, set, h.Count)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StatsDMetrics sends every metric as a StatsD datagram to Conn, such as
+// the one of net.Dial("udp", "127.0.0.1:8125"), its name starting with
+// Prefix and tagged with the node in the DogStatsD format. Write errors are
+// ignored, metrics being best effort.
+type StatsDMetrics struct {
+	Conn   io.Writer
+	Prefix string
+}
+
+func (s StatsDMetrics) send(name, value, typ, node string, tags ...string) {
+	if node != "" {
+		tags = append(tags, "node:"+node)
+	}
+	line := s.Prefix + name + ":" + value + "|" + typ
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	_, _ = io.WriteString(s.Conn, line)
+}
+
+func (s StatsDMetrics) IncScanned(node string) {
+	s.send("keys.scanned", "1", "c", node)
+}
+
+func (s StatsDMetrics) IncModified(node string) {
+	s.send("keys.modified", "1", "c", node)
+}
+
+// IncError counts err tagged with its class, see ClassifyError.
+func (s StatsDMetrics) IncError(node string, err error) {
+	s.send("errors", "1", "c", node, "class:"+ClassifyError(err))
+}
+
+func (s StatsDMetrics) ObserveLatency(node string, d time.Duration) {
+	s.send("command.duration", strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", node)
+}
@@ -0,0 +1,114 @@
+package redisttl
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// datagrams records every write as a datagram.
+type datagrams []string
+
+func (d *datagrams) Write(b []byte) (int, error) {
+	*d = append(*d, string(b))
+	return len(b), nil
+}
+
+func runMetrics(t *testing.T, m Metrics) {
+	t.Helper()
+	s := miniredis.RunT(t)
+	for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+		_ = s.Set(key, "bar")
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(&keyHook{key: "foo:2", err: readOnlyError("NOPERM this user has no permissions to run the 'expire' command")})
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Node:       "node1",
+		Metrics:    m,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	var m PrometheusMetrics
+	runMetrics(t, &m)
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`redis_ttl_node_keys_scanned_total{node="node1"} 3`,
+		`redis_ttl_node_keys_modified_total{node="node1"} 2`,
+		`redis_ttl_node_errors_total{node="node1",class="noperm"} 1`,
+		`redis_ttl_node_command_duration_seconds_count{node="node1"} 3`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Fatalf("want %s in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestSummaryWritePrometheus(t *testing.T) {
+	s := &Summary{}
+	s.Scanned.Add(3)
+	s.ScanLatency.Observe(time.Millisecond)
+
+	var b strings.Builder
+	if err := s.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	// the histograms of a summary are written like the ones of every node,
+	// without labels
+	for _, line := range []string{
+		"redis_ttl_keys_scanned_total 3",
+		`redis_ttl_scan_duration_seconds_bucket{le="0.0016"} 1`,
+		`redis_ttl_scan_duration_seconds_bucket{le="+Inf"} 1`,
+		"redis_ttl_scan_duration_seconds_sum 0.001",
+		"redis_ttl_scan_duration_seconds_count 1",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Fatalf("want %s in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestStatsDMetrics(t *testing.T) {
+	var d datagrams
+	runMetrics(t, StatsDMetrics{Conn: &d, Prefix: "redis_ttl."})
+
+	var counters []string
+	for _, line := range d {
+		if !strings.HasPrefix(line, "redis_ttl.command.duration:") {
+			counters = append(counters, line)
+		} else if !strings.HasSuffix(line, "|ms|#node:node1") {
+			t.Fatalf("unexpected timing %s", line)
+		}
+	}
+	slices.Sort(counters)
+	expected := []string{
+		"redis_ttl.errors:1|c|#class:noperm,node:node1",
+		"redis_ttl.keys.modified:1|c|#node:node1",
+		"redis_ttl.keys.modified:1|c|#node:node1",
+		"redis_ttl.keys.scanned:1|c|#node:node1",
+		"redis_ttl.keys.scanned:1|c|#node:node1",
+		"redis_ttl.keys.scanned:1|c|#node:node1",
+	}
+	if !slices.Equal(counters, expected) {
+		t.Fatalf("want %v, got %v", expected, counters)
+	}
+	if len(d) != len(expected)+3 {
+		t.Fatalf("want a timing per key, got %v", d)
+	}
+}
@@ -0,0 +1,76 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ModeFunc applies a custom mode to key through c, ttl being the Scanner
+// DesiredTTL. The returned command reports whether key was modified.
+type ModeFunc func(ctx context.Context, c redis.Cmdable, key string, ttl time.Duration) *redis.BoolCmd
+
+var (
+	modesMu sync.RWMutex
+	modes   = map[string]ModeFunc{}
+)
+
+// builtinModes are the modes implemented by the Scanner itself.
+var builtinModes = []string{
+	"exp", "gt", "lt", "nx", "xx", "noop", "persist",
+	"percent", "extend", "shrink", "inherit", "report", "audit", "verify",
+	"rules", "ztrim", "copy", "purge",
+}
+
+// RegisterMode makes fn selectable as Scanner.Mode under name. It is meant
+// to be called from an init function and panics if name is already taken.
+func RegisterMode(name string, fn ModeFunc) {
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	if fn == nil {
+		panic("redisttl: RegisterMode fn is nil")
+	}
+	if _, dup := modes[name]; dup || isBuiltinMode(name) {
+		panic(fmt.Sprintf("redisttl: RegisterMode called twice for mode %s", name))
+	}
+	modes[name] = fn
+}
+
+// Modes returns the names of the builtin and registered modes, sorted.
+func Modes() []string {
+	modesMu.RLock()
+	defer modesMu.RUnlock()
+	names := append([]string(nil), builtinModes...)
+	for name := range modes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isBuiltinMode(name string) bool {
+	for _, m := range builtinModes {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// registeredMode returns the ttlFunc of a registered mode.
+func (f *Scanner) registeredMode(name string) (ttlFunc, bool) {
+	modesMu.RLock()
+	fn, found := modes[name]
+	modesMu.RUnlock()
+	if !found {
+		return nil, false
+	}
+	c := f.Client
+	return func(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+		return fn(ctx, c, key, ttl)
+	}, true
+}
@@ -0,0 +1,81 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	// caps the TTL of keys at 30 days, whatever the desired TTL
+	RegisterMode("test-compliance", func(ctx context.Context, c redis.Cmdable, key string, ttl time.Duration) *redis.BoolCmd {
+		return c.ExpireLT(ctx, key, min(ttl, 30*24*time.Hour))
+	})
+}
+
+func TestRegisteredMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	s.SetTTL("far", time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "test-compliance",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		DesiredTTL: 365 * 24 * time.Hour,
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo": 30 * 24 * time.Hour,
+		"far": time.Hour,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if summary.Modified.Load() != 1 {
+		t.Fatalf("expected 1 key modified, got: %s", summary)
+	}
+
+	f.DryRun = true
+	if err := f.Run(context.Background()); !errors.Is(err, errInvalidMode) {
+		t.Fatalf("expected error %v, got: %v", errInvalidMode, err)
+	}
+}
+
+func TestRegisterModeConflicts(t *testing.T) {
+	for _, name := range []string{"exp", "test-compliance"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), name) {
+					t.Fatalf("expected a panic registering %s, got: %v", name, r)
+				}
+			}()
+			RegisterMode(name, func(ctx context.Context, c redis.Cmdable, key string, ttl time.Duration) *redis.BoolCmd {
+				return redis.NewBoolCmd(ctx)
+			})
+		}()
+	}
+
+	names := Modes()
+	if !slices.Contains(names, "test-compliance") || !slices.Contains(names, "exp") || !slices.IsSorted(names) {
+		t.Fatalf("unexpected modes: %v", names)
+	}
+}
@@ -0,0 +1,109 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// multiMatch runs SCAN with the cursor, count and type in ARGV[1..3], a
+// count of 0 and an empty type being left out, and returns the next cursor
+// and the scanned keys matching any of the Lua patterns in the rest of ARGV.
+var multiMatch = redis.NewScript(`
+local args = {'SCAN', ARGV[1]}
+if ARGV[2] ~= '0' then
+	table.insert(args, 'COUNT')
+	table.insert(args, ARGV[2])
+end
+if ARGV[3] ~= '' then
+	table.insert(args, 'TYPE')
+	table.insert(args, ARGV[3])
+end
+local res = redis.call(unpack(args))
+local matched = {}
+for _, key in ipairs(res[2]) do
+	for i = 4, #ARGV do
+		if string.find(key, ARGV[i]) then
+			table.insert(matched, key)
+			break
+		end
+	end
+end
+return {res[1], matched}
+`)
+
+// scanPatterns is SCAN matching any of the glob-style patterns, filtered
+// server side so the keyspace is scanned once whatever their number.
+func scanPatterns(ctx context.Context, c redis.Scripter, cursor uint64, patterns []string, count int64, typ string) ([]string, uint64, error) {
+	args := []any{cursor, count, typ}
+	for _, p := range patterns {
+		args = append(args, luaPattern(p))
+	}
+	res, err := multiMatch.Run(ctx, c, nil, args...).Slice()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(res) != 2 {
+		return nil, 0, fmt.Errorf("unexpected scan script reply %v", res)
+	}
+	next, err := strconv.ParseUint(fmt.Sprint(res[0]), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected scan script cursor: %w", err)
+	}
+	matched, _ := res[1].([]any)
+	keys := make([]string, 0, len(matched))
+	for _, key := range matched {
+		keys = append(keys, fmt.Sprint(key))
+	}
+	return keys, next, nil
+}
+
+// luaMagic are the characters with a meaning in Lua patterns.
+const luaMagic = "^$()%.[]*+-?"
+
+// luaPattern translates a glob-style pattern, as matchGlob implements it,
+// to an anchored Lua pattern.
+func luaPattern(glob string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	inClass := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			writeLuaLiteral(&b, glob[i])
+		case inClass:
+			switch c {
+			case ']':
+				inClass = false
+				b.WriteByte(c)
+			case '^', '-':
+				b.WriteByte(c)
+			default:
+				writeLuaLiteral(&b, c)
+			}
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteByte('.')
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+		default:
+			writeLuaLiteral(&b, c)
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+func writeLuaLiteral(b *strings.Builder, c byte) {
+	if strings.IndexByte(luaMagic, c) >= 0 {
+		b.WriteByte('%')
+	}
+	b.WriteByte(c)
+}
@@ -0,0 +1,85 @@
+package redisttl
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLuaPattern(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	testCases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"foo*", "foo:1", true},
+		{"foo*", "fo", false},
+		{"*:1", "foo:12", false},
+		{"f?o", "foo", true},
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"a.b*", "axb", false},
+		{"a.b*", "a.b", true},
+		{"50%*", "50%off", true},
+		{"(x)+", "(x)+", true},
+		{"(x)+", "xx", false},
+	}
+	for _, tc := range testCases {
+		s.FlushAll()
+		_ = s.Set(tc.s, "bar")
+		keys, _, err := scanPatterns(context.Background(), rdb, 0, []string{tc.pattern}, 0, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := len(keys) == 1; got != tc.match || got != matchGlob(tc.pattern, tc.s) {
+			t.Errorf("pattern %q (%s) on %q = %v, want %v", tc.pattern, luaPattern(tc.pattern), tc.s, got, tc.match)
+		}
+	}
+}
+
+func TestScanPatterns(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"session:1", "cache:1", "cache:2", "user:1"} {
+		_ = s.Set(k, "bar")
+	}
+	_, _ = s.SAdd("cache:set", "a")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	f := Scanner{
+		Mode:         "exp",
+		ScanPatterns: []string{"session:*", "cache:*"},
+		ScanType:     "string",
+		ScanCount:    2,
+		Client:       rdb,
+		DesiredTTL:   time.Hour,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var modified []string
+	for _, k := range s.Keys() {
+		if s.TTL(k) > 0 {
+			modified = append(modified, k)
+		}
+	}
+	if want := []string{"cache:1", "cache:2", "session:1"}; !slices.Equal(modified, want) {
+		t.Fatalf("want %v modified, got %v", want, modified)
+	}
+}
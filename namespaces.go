@@ -0,0 +1,97 @@
+package redisttl
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NamespaceCounts are the outcomes of the keys of a namespace.
+type NamespaceCounts struct {
+	Namespace string
+	Scanned   int64
+	Modified  int64
+	Errors    int64
+}
+
+// Skipped is the number of keys left untouched.
+func (c NamespaceCounts) Skipped() int64 {
+	return c.Scanned - c.Modified - c.Errors
+}
+
+// NamespaceSummary breaks the outcome of runs down by namespace, the first
+// Depth path segments of the keys, e.g. "cache:users:" at depth 2 for
+// "cache:users:1", so a run over a whole cluster shows which namespaces it
+// affected most. Like Summary, it can be shared by several scanners and is
+// safe for concurrent use.
+type NamespaceSummary struct {
+	// Depth is the number of segments of a namespace, 1 when 0.
+	Depth int
+	// Separator delimits path segments, ":" when empty.
+	Separator string
+
+	mu     sync.Mutex
+	counts map[string]*NamespaceCounts
+}
+
+func (s *NamespaceSummary) observe(key string, modified bool, err error) {
+	if s == nil {
+		return
+	}
+	ns := s.namespace(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = map[string]*NamespaceCounts{}
+	}
+	c, found := s.counts[ns]
+	if !found {
+		c = &NamespaceCounts{Namespace: ns}
+		s.counts[ns] = c
+	}
+	c.Scanned++
+	switch {
+	case err != nil:
+		c.Errors++
+	case modified:
+		c.Modified++
+	}
+}
+
+// namespace returns the first Depth segments of key, with their trailing
+// separator, or key itself when it has fewer.
+func (s *NamespaceSummary) namespace(key string) string {
+	sep := s.Separator
+	if sep == "" {
+		sep = ":"
+	}
+	depth := max(s.Depth, 1)
+	end := 0
+	for i := 0; i < depth; i++ {
+		next := strings.Index(key[end:], sep)
+		if next < 0 {
+			return key
+		}
+		end += next + len(sep)
+	}
+	return key[:end]
+}
+
+// Namespaces returns the counts of every namespace, the ones with the most
+// keys modified first.
+func (s *NamespaceSummary) Namespaces() []NamespaceCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]NamespaceCounts, 0, len(s.counts))
+	for _, c := range s.counts {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Modified != out[j].Modified {
+			return out[i].Modified > out[j].Modified
+		}
+		return out[i].Namespace < out[j].Namespace
+	})
+	return out
+}
@@ -0,0 +1,105 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNamespace(t *testing.T) {
+	testCases := map[string]struct {
+		depth     int
+		separator string
+		key       string
+		want      string
+	}{
+		"default depth": {
+			key:  "cache:users:1",
+			want: "cache:",
+		},
+		"depth 2": {
+			depth: 2,
+			key:   "cache:users:1",
+			want:  "cache:users:",
+		},
+		"fewer segments": {
+			depth: 3,
+			key:   "cache:users",
+			want:  "cache:users",
+		},
+		"custom separator": {
+			depth:     2,
+			separator: "/",
+			key:       "cache/users/1",
+			want:      "cache/users/",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			s := &NamespaceSummary{Depth: tc.depth, Separator: tc.separator}
+			if got := s.namespace(tc.key); got != tc.want {
+				t.Fatalf("want: %q got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNamespaceSummary(t *testing.T) {
+	s := &NamespaceSummary{}
+	s.observe("cache:1", true, nil)
+	s.observe("cache:2", false, nil)
+	s.observe("session:1", true, nil)
+	s.observe("session:2", true, nil)
+	s.observe("session:3", false, errors.New("boom"))
+
+	want := []NamespaceCounts{
+		{Namespace: "session:", Scanned: 3, Modified: 2, Errors: 1},
+		{Namespace: "cache:", Scanned: 2, Modified: 1},
+	}
+	if got := s.Namespaces(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %+v got: %+v", want, got)
+	}
+	if skipped := want[1].Skipped(); skipped != 1 {
+		t.Fatalf("want 1 key skipped, got %d", skipped)
+	}
+}
+
+func TestScannerNamespaces(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:a:1", "bar")
+	_ = s.Set("foo:a:2", "bar")
+	_ = s.Set("foo:b:1", "bar")
+	_ = s.Set("foo:lock", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	namespaces := &NamespaceSummary{Depth: 2}
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Protected:  []string{"foo:lock"},
+		Namespaces: namespaces,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []NamespaceCounts{
+		{Namespace: "foo:a:", Scanned: 2, Modified: 2},
+		{Namespace: "foo:b:", Scanned: 1, Modified: 1},
+		{Namespace: "foo:lock", Scanned: 1},
+	}
+	if got := namespaces.Namespaces(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %+v got: %+v", want, got)
+	}
+}
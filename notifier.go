@@ -0,0 +1,99 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Run statuses reported to a Notifier.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusAborted   = "aborted"
+)
+
+// RunStatus maps the error returned by a run to its status.
+func RunStatus(err error) string {
+	switch {
+	case err == nil:
+		return StatusSucceeded
+	case errors.Is(err, context.Canceled):
+		return StatusAborted
+	default:
+		return StatusFailed
+	}
+}
+
+// RunInfo describes the run a Notifier is told about.
+type RunInfo struct {
+	Mode       string
+	ScanPrefix string
+	StartedAt  time.Time
+}
+
+// Notifier receives the lifecycle events of a run. Implementations must be
+// safe for concurrent use when shared by several scanners.
+type Notifier interface {
+	OnStart(ctx context.Context, run RunInfo)
+	// OnProgress is called periodically with the summary accumulated so far.
+	OnProgress(ctx context.Context, run RunInfo, s *Summary)
+	// OnComplete is called once the run returns, err being its result.
+	OnComplete(ctx context.Context, run RunInfo, s *Summary, err error)
+}
+
+// NodeResult describes how a node of a cluster run went.
+type NodeResult struct {
+	Addr     string
+	Duration time.Duration
+	// Stats are the ones of the Scanner that processed the node, zero for
+	// the other runners.
+	Stats ScanStats
+}
+
+// NodeNotifier may be implemented by a Notifier to also be told when each
+// node of a cluster run starts and finishes.
+type NodeNotifier interface {
+	OnNodeStart(ctx context.Context, run RunInfo, addr string)
+	// OnNodeFinish is called once the node is done, err being its result.
+	OnNodeFinish(ctx context.Context, run RunInfo, node NodeResult, err error)
+}
+
+// Notifiers fans events out to several notifiers.
+type Notifiers []Notifier
+
+func (n Notifiers) OnStart(ctx context.Context, run RunInfo) {
+	for _, x := range n {
+		x.OnStart(ctx, run)
+	}
+}
+
+func (n Notifiers) OnProgress(ctx context.Context, run RunInfo, s *Summary) {
+	for _, x := range n {
+		x.OnProgress(ctx, run, s)
+	}
+}
+
+func (n Notifiers) OnComplete(ctx context.Context, run RunInfo, s *Summary, err error) {
+	for _, x := range n {
+		x.OnComplete(ctx, run, s, err)
+	}
+}
+
+// OnNodeStart fans the event out to the notifiers implementing NodeNotifier.
+func (n Notifiers) OnNodeStart(ctx context.Context, run RunInfo, addr string) {
+	for _, x := range n {
+		if x, ok := x.(NodeNotifier); ok {
+			x.OnNodeStart(ctx, run, addr)
+		}
+	}
+}
+
+// OnNodeFinish fans the event out to the notifiers implementing NodeNotifier.
+func (n Notifiers) OnNodeFinish(ctx context.Context, run RunInfo, node NodeResult, err error) {
+	for _, x := range n {
+		if x, ok := x.(NodeNotifier); ok {
+			x.OnNodeFinish(ctx, run, node, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRunStatus(t *testing.T) {
+	m := map[error]string{
+		nil:                                   StatusSucceeded,
+		errors.New("boom"):                    StatusFailed,
+		fmt.Errorf("x: %w", context.Canceled): StatusAborted,
+	}
+	for err, want := range m {
+		if got := RunStatus(err); got != want {
+			t.Fatalf("err %v want: %s got: %s", err, want, got)
+		}
+	}
+}
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []string
+	err    error
+}
+
+func (n *recordingNotifier) record(e string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, e)
+}
+
+func (n *recordingNotifier) OnStart(context.Context, RunInfo) { n.record("start") }
+
+func (n *recordingNotifier) OnProgress(context.Context, RunInfo, *Summary) { n.record("progress") }
+
+func (n *recordingNotifier) OnComplete(_ context.Context, _ RunInfo, _ *Summary, err error) {
+	n.err = err
+	n.record("complete")
+}
+
+func TestScannerNotifier(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	a, b := &recordingNotifier{}, &recordingNotifier{}
+	f := Scanner{
+		Mode:             "exp",
+		ScanPrefix:       "f*",
+		Client:           rdb,
+		DesiredTTL:       time.Hour,
+		Notifier:         Notifiers{a, b},
+		ProgressInterval: time.Nanosecond,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, n := range []*recordingNotifier{a, b} {
+		if len(n.events) != 3 || n.events[0] != "start" || n.events[1] != "progress" || n.events[2] != "complete" {
+			t.Fatalf("unexpected events: %v", n.events)
+		}
+	}
+
+	s.SetError("fault-injected")
+	n := &recordingNotifier{}
+	f.Notifier = n
+	if err := f.Run(context.Background()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if n.err == nil {
+		t.Fatal("OnComplete should receive the run error")
+	}
+}
+
+type nodeRecordingNotifier struct {
+	recordingNotifier
+	nodes []NodeResult
+}
+
+func (n *nodeRecordingNotifier) OnNodeStart(_ context.Context, _ RunInfo, addr string) {
+	n.record("node start " + addr)
+}
+
+func (n *nodeRecordingNotifier) OnNodeFinish(_ context.Context, _ RunInfo, node NodeResult, _ error) {
+	n.nodes = append(n.nodes, node)
+	n.record("node finish " + node.Addr)
+}
+
+func TestNotifiersNodeEvents(t *testing.T) {
+	plain, nodes := &recordingNotifier{}, &nodeRecordingNotifier{}
+	n := Notifiers{plain, nodes}
+
+	n.OnNodeStart(context.Background(), RunInfo{}, "a:6379")
+	n.OnNodeFinish(context.Background(), RunInfo{}, NodeResult{Addr: "a:6379", Stats: ScanStats{Scanned: 2}}, nil)
+	if len(plain.events) != 0 {
+		t.Fatalf("node events should only reach node notifiers, got %v", plain.events)
+	}
+	if len(nodes.events) != 2 || nodes.events[0] != "node start a:6379" || nodes.nodes[0].Stats.Scanned != 2 {
+		t.Fatalf("unexpected events: %v %v", nodes.events, nodes.nodes)
+	}
+}
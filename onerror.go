@@ -0,0 +1,81 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Action is what a run does after a command sent for a key failed, as
+// decided by Scanner.OnError.
+type Action int
+
+const (
+	// ActionContinue logs and counts the error, and moves on to the next key.
+	ActionContinue Action = iota
+	// ActionRetry sends the command again once the Limiter allows it.
+	ActionRetry
+	// ActionAbort stops the run, returning the error.
+	ActionAbort
+)
+
+// onError returns what the run does after the command sent for key failed
+// with err: abort on a failover for the run to resume on the new primary,
+// what OnError decides when set, and abort with FailFast. It continues
+// otherwise, and when err is nil.
+func (f *Scanner) onError(key string, err error) Action {
+	switch {
+	case err == nil:
+		return ActionContinue
+	case f.Failover != nil && failedOver(err):
+		return ActionAbort
+	case f.OnError != nil:
+		return f.OnError(key, err)
+	case f.FailFast:
+		return ActionAbort
+	}
+	return ActionContinue
+}
+
+// retry calls send, sending a command for key, again as long as it fails and
+// Retry, then OnError, ask to retry it, waiting for the Limiter in between.
+// It returns the error of the last attempt and the action decided for it.
+func (f *Scanner) retry(ctx context.Context, key string, send func() error) (Action, error) {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		retried, werr := f.retryAfter(ctx, key, err, attempt)
+		if werr != nil {
+			return ActionAbort, werr
+		}
+		if retried {
+			if err := f.wait(ctx); err != nil {
+				return ActionAbort, err
+			}
+			continue
+		}
+		action := f.onError(key, err)
+		if action != ActionRetry {
+			return action, err
+		}
+		log.Printf("retrying %s: %v\n", key, err)
+		if err := f.wait(ctx); err != nil {
+			return ActionAbort, err
+		}
+	}
+}
+
+// stop returns the error stopping the run when action aborts it, nil
+// otherwise.
+func stop(action Action, key string, err error) error {
+	if action != ActionAbort {
+		return nil
+	}
+	return fmt.Errorf("key %s: %w", key, err)
+}
+
+// failFast returns the error stopping the run after the command sent for
+// key failed with err, nil when it goes on. The commands of a batch or a
+// pipeline aren't retried, so ActionRetry moves on to the next key.
+func (f *Scanner) failFast(key string, err error) error {
+	return stop(f.onError(key, err), key, err)
+}
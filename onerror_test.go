@@ -0,0 +1,133 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// flakyHook fails the first fails commands called name with err, only the
+// ones of key when set.
+type flakyHook struct {
+	name  string
+	key   string
+	err   error
+	fails int
+}
+
+func (h *flakyHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		key, _ := cmd.Args()[1].(string)
+		if cmd.Name() == h.name && (h.key == "" || key == h.key) && h.fails > 0 {
+			h.fails--
+			return h.err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *flakyHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *flakyHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestOnError(t *testing.T) {
+	errNoPerm := readOnlyError("NOPERM this user has no permissions to run the 'expire' command")
+	errLoading := readOnlyError("LOADING Redis is loading the dataset in memory")
+	policy := func(key string, err error) Action {
+		switch {
+		case redis.HasErrorPrefix(err, "NOPERM"):
+			return ActionAbort
+		case redis.HasErrorPrefix(err, "LOADING"):
+			return ActionRetry
+		}
+		return ActionContinue
+	}
+	testCases := map[string]struct {
+		err      error
+		fails    int
+		failFast bool
+		failed   bool
+		calls    int
+		expired  []string
+		errors   int64
+	}{
+		"continue": {
+			err:      readOnlyError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+			fails:    1,
+			failFast: true,
+			calls:    1,
+			expired:  []string{"foo:1", "foo:3"},
+			errors:   1,
+		},
+		"retry": {
+			err:     errLoading,
+			fails:   2,
+			calls:   2,
+			expired: []string{"foo:1", "foo:2", "foo:3"},
+		},
+		"abort": {
+			err:     errNoPerm,
+			fails:   1,
+			failed:  true,
+			calls:   1,
+			expired: []string{"foo:1"},
+			errors:  1,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+				_ = s.Set(key, "bar")
+			}
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			rdb.AddHook(&flakyHook{name: "expire", key: "foo:2", err: tc.err, fails: tc.fails})
+
+			calls := 0
+			summary := &Summary{}
+			f := Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo:*",
+				ScanCount:  1,
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+				Summary:    summary,
+				FailFast:   tc.failFast,
+				OnError: func(key string, err error) Action {
+					calls++
+					if key != "foo:2" || !errors.Is(err, tc.err) {
+						t.Errorf("unexpected error for %s: %v", key, err)
+					}
+					return policy(key, err)
+				},
+			}
+			if err := f.Run(context.Background()); (err != nil) != tc.failed {
+				t.Fatalf("want failed %v, got %v", tc.failed, err)
+			}
+			if calls != tc.calls {
+				t.Fatalf("want OnError called %d times, got %d", tc.calls, calls)
+			}
+			var expired []string
+			for _, key := range s.Keys() {
+				if s.TTL(key) == time.Hour {
+					expired = append(expired, key)
+				}
+			}
+			if !slices.Equal(expired, tc.expired) {
+				t.Fatalf("want %v expired, got %v", tc.expired, expired)
+			}
+			if n := summary.Errors.Load(); n != tc.errors {
+				t.Fatalf("want %d errors, got %d: %s", tc.errors, n, summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// pauseGate holds the runs of a Scanner paused by Pause until Resume.
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is closed by Resume, nil when not paused.
+	resumed chan struct{}
+}
+
+// Pause holds the runs of f before their next key or SCAN batch, without
+// cancelling them, until Resume is called. The keys being processed are
+// processed first. The pause state is created by the first run of f or call
+// to Pause, Resume or Paused, and shared with the copies of f made after it:
+// pausing f holds every primary of a cluster client, and those copies.
+func (f *Scanner) Pause() {
+	g := f.gate()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+		log.Printf("node %s paused\n", f.Node)
+	}
+}
+
+// Resume resumes the runs of f held by Pause.
+func (f *Scanner) Resume() {
+	g := f.gate()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+		log.Printf("node %s resumed\n", f.Node)
+	}
+}
+
+// Paused reports whether f is paused.
+func (f *Scanner) Paused() bool {
+	g := f.gate()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// gate returns the pauseGate of f, created on first use.
+func (f *Scanner) gate() *pauseGate {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if f.pause == nil {
+		f.pause = &pauseGate{}
+	}
+	return f.pause
+}
+
+// waitResumed blocks while f is paused, or until ctx is done.
+func (f *Scanner) waitResumed(ctx context.Context) error {
+	g := f.gate()
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPause(t *testing.T) {
+	testCases := map[string]struct {
+		addrs  int
+		cancel bool
+	}{
+		"resumed":         {addrs: 1},
+		"cluster resumed": {addrs: 2},
+		"canceled":        {addrs: 1, cancel: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+				_ = s.Set(key, "bar")
+			}
+			addrs := make([]string, tc.addrs)
+			for i := range addrs {
+				addrs[i] = s.Addr()
+			}
+			rdb := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: addrs})
+			defer rdb.Close()
+
+			f := Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo:*",
+				ScanCount:  1,
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+			}
+			f.Pause()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- f.Run(ctx) }()
+
+			select {
+			case err := <-done:
+				t.Fatalf("want the run held, got %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			if !f.Paused() {
+				t.Fatal("want the scanner paused")
+			}
+			for _, key := range s.Keys() {
+				if ttl := s.TTL(key); ttl != 0 {
+					t.Fatalf("want %s untouched while paused, got %v", key, ttl)
+				}
+			}
+
+			if tc.cancel {
+				cancel()
+				if err := <-done; !errors.Is(err, context.Canceled) {
+					t.Fatalf("want the run canceled, got %v", err)
+				}
+				return
+			}
+			f.Resume()
+			if err := <-done; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, key := range s.Keys() {
+				if ttl := s.TTL(key); ttl != time.Hour {
+					t.Fatalf("want %s expired once resumed, got %v", key, ttl)
+				}
+			}
+		})
+	}
+}
+
+func TestPauseCopies(t *testing.T) {
+	f := &Scanner{}
+	before := *f
+	f.Pause()
+	after := *f
+	if before.Paused() || !after.Paused() {
+		t.Fatalf("want only the copy made after Pause paused, got %v before and %v after", before.Paused(), after.Paused())
+	}
+	after.Resume()
+	if f.Paused() {
+		t.Fatal("want f resumed with its copy")
+	}
+}
@@ -0,0 +1,75 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hasPredicate reports whether the keys are read before being modified to
+// decide whether they are.
+func (f *Scanner) hasPredicate() bool {
+	return f.ValueMatch != nil || f.HashField != "" || f.Filter != nil
+}
+
+// matches reports whether key satisfies the predicates, Filter, ValueMatch
+// and HashField, and records the keys which don't as left untouched. Keys
+// deleted since they were scanned, or of another type, don't, and neither
+// do the ones left out by IntrospectRate. Errors are logged and counted, and
+// only returned when OnError or FailFast abort the run.
+func (f *Scanner) matches(ctx context.Context, r *scanRun, key string) (bool, error) {
+	if f.hasPredicate() && !f.introspected() {
+		r.observeKey(key, 0, false, nil)
+		return false, nil
+	}
+	var ok bool
+	action, err := f.retry(ctx, key, func() (err error) {
+		ok, err = f.matchFilter(ctx, key)
+		if ok && err == nil {
+			ok, err = f.matchValue(ctx, key)
+		}
+		if ok && err == nil {
+			ok, err = f.matchHashField(ctx, key)
+		}
+		return err
+	})
+	if err != nil {
+		r.observeKey(key, 0, false, err)
+		log.Printf("predicate error: %v\n", err)
+		return false, stop(action, key, err)
+	}
+	if !ok {
+		r.observeKey(key, 0, false, nil)
+	}
+	return ok, nil
+}
+
+func (f *Scanner) matchValue(ctx context.Context, key string) (bool, error) {
+	if f.ValueMatch == nil {
+		return true, nil
+	}
+	v, err := f.Client.Get(ctx, key).Result()
+	switch {
+	case errors.Is(err, redis.Nil), redis.HasErrorPrefix(err, "WRONGTYPE"):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return f.ValueMatch.MatchString(v), nil
+}
+
+func (f *Scanner) matchHashField(ctx context.Context, key string) (bool, error) {
+	if f.HashField == "" {
+		return true, nil
+	}
+	v, err := f.Client.HGet(ctx, key, f.HashField).Result()
+	switch {
+	case errors.Is(err, redis.Nil), redis.HasErrorPrefix(err, "WRONGTYPE"):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return v == f.HashValue, nil
+}
@@ -0,0 +1,154 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestValueMatch(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:1", "{}")
+	_ = s.Set("foo:2", `{"name":"bar"}`)
+	_ = s.Set("foo:3", "tombstone:2024-01-01")
+	s.HSet("foo:4", "f", "{}")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	summary := &Summary{}
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		ValueMatch: regexp.MustCompile(`^(\{\}|tombstone:)`),
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo:1": time.Hour,
+		"foo:2": 0,
+		"foo:3": time.Hour,
+		"foo:4": 0,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if scanned, modified := summary.Scanned.Load(), summary.Modified.Load(); scanned != 4 || modified != 2 {
+		t.Fatalf("want 4 keys scanned and 2 modified, got %d and %d", scanned, modified)
+	}
+}
+
+func TestPredicateBatchScript(t *testing.T) {
+	f := &Scanner{
+		Client:      redis.NewClient(&redis.Options{}),
+		Mode:        "exp",
+		BatchScript: true,
+		ValueMatch:  regexp.MustCompile(`^$`),
+	}
+	if err := f.Run(context.Background()); !errors.Is(err, errInvalidMode) {
+		t.Fatalf("want: %v got: %v", errInvalidMode, err)
+	}
+}
+
+func TestHashField(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.HSet("foo:1", "status", "archived")
+	s.HSet("foo:2", "status", "active")
+	s.HSet("foo:3", "name", "bar")
+	_ = s.Set("foo:4", "archived")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		HashField:  "status",
+		HashValue:  "archived",
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo:1": time.Hour,
+		"foo:2": 0,
+		"foo:3": 0,
+		"foo:4": 0,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+}
+
+func TestIntrospectRate(t *testing.T) {
+	testCases := map[string]struct {
+		rate     float64
+		modified int64
+		reads    int64
+	}{
+		"every key": {
+			rate:     1,
+			modified: 20,
+			reads:    40,
+		},
+		"almost none": {
+			rate: 1e-9,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for i := range 20 {
+				_ = s.Set(fmt.Sprintf("foo:%d", i), "{}")
+			}
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			commands := &CommandCounter{}
+			rdb.AddHook(commands.Hook(s.Addr()))
+			rdb.AddHook(memoryUsageHook{})
+
+			summary := &Summary{}
+			f := &Scanner{
+				Mode:           "exp",
+				ScanPrefix:     "foo:*",
+				Client:         rdb,
+				DesiredTTL:     time.Hour,
+				ValueMatch:     regexp.MustCompile(`^\{\}$`),
+				BigKeys:        &BigKeyDetector{MaxBytes: 1 << 20},
+				KeySizeEvery:   1,
+				IntrospectRate: tc.rate,
+				Summary:        summary,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n := summary.Modified.Load(); n != tc.modified || summary.Scanned.Load() != 20 {
+				t.Fatalf("want 20 keys scanned and %d modified, got %s", tc.modified, summary)
+			}
+			// a MEMORY USAGE per key for its size and another for BigKeys
+			if n := commands.Nodes()[0].Read; n != tc.reads {
+				t.Fatalf("want %d reads, got %d", tc.reads, n)
+			}
+			if n := summary.KeySizes.Snapshot().Count; n != tc.modified {
+				t.Fatalf("want %d sizes sampled, got %d", tc.modified, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoPermission is returned by Scanner.Preflight when the connection isn't
+// allowed to run a command the mode needs.
+var ErrNoPermission = errors.New("missing permission")
+
+// modeCommands lists the commands the builtin modes send for every key, on
+// top of SCAN, with the arguments used to try them out.
+var modeCommands = map[string][][]any{
+	"exp":     {{"expire", 1}},
+	"gt":      {{"expire", 1}},
+	"lt":      {{"expire", 1}},
+	"nx":      {{"expire", 1}},
+	"xx":      {{"expire", 1}},
+	"persist": {{"persist"}},
+	"percent": {{"pttl"}, {"pexpire", 1}},
+	"extend":  {{"pttl"}, {"pexpire", 1}},
+	"shrink":  {{"pttl"}, {"pexpire", 1}},
+	"inherit": {{"pttl"}, {"pexpire", 1}, {"persist"}},
+	"ztrim":   {{"zremrangebyscore", "-inf", "(0"}},
+	"copy":    {{"pttl"}, {"dump"}},
+	"purge":   {{"pttl"}, {"unlink"}},
+	"report":  {{"pttl"}},
+	"audit":   {{"pttl"}},
+	"verify":  {{"pttl"}},
+}
+
+// Preflight checks the server supports the mode, failing with an
+// ErrUnsupportedMode otherwise when it can't be emulated or NoEmulation is
+// set, and the connection is allowed to run SCAN and the commands the mode
+// sends, including MULTI and EXEC with Transaction, EVALSHA with
+// BatchScript, and RESTORE on the Destination in "copy" mode. A run missing
+// a permission then fails upfront with an ErrNoPermission telling which
+// one, rather than with an error per key.
+// Permissions are checked with ACL DRYRUN, so commands other than SCAN are
+// only checked against Redis 7 and later.
+func (f *Scanner) Preflight(ctx context.Context) error {
+	if err := f.checkVersion(ctx); err != nil {
+		return err
+	}
+	// the probe is sent for the first pattern, the permissions of a user
+	// usually covering every key of a run
+	pattern := f.ScanPrefix
+	if len(f.ScanPatterns) > 0 {
+		pattern = f.ScanPatterns[0]
+	}
+	keys, _, err := f.scanClient().ScanType(ctx, 0, pattern, 1, f.ScanType).Result()
+	switch {
+	case redis.HasErrorPrefix(err, "NOPERM"):
+		return fmt.Errorf("can't run scan, grant +scan: %v: %w", err, ErrNoPermission)
+	case err != nil:
+		return fmt.Errorf("preflight scan: %w", err)
+	}
+
+	key := literalPrefix(pattern)
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	if err := checkPermissions(ctx, f.Client, key, f.preflightCommands(key)); err != nil {
+		return err
+	}
+	if f.Mode == "copy" && !f.DryRun && f.Destination != nil {
+		restore := []any{"restore", key, 0, ""}
+		if f.CopyReplace {
+			restore = append(restore, "replace")
+		}
+		if err := checkPermissions(ctx, f.Destination, key, [][]any{restore}); err != nil {
+			return fmt.Errorf("destination: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkPermissions checks the user of c is allowed to run commands, sent
+// for key, with ACL DRYRUN.
+func checkPermissions(ctx context.Context, c redis.Cmdable, key string, commands [][]any) error {
+	info, err := c.ClientInfo(ctx).Result()
+	if err != nil || info.User == "" {
+		// no ACL support, or no permission to check permissions
+		return nil
+	}
+	user := info.User
+	for _, args := range commands {
+		res, err := c.ACLDryRun(ctx, user, args...).Result()
+		switch {
+		case err != nil:
+			// ACL DRYRUN was added in Redis 7
+			return nil
+		case res != "OK":
+			return fmt.Errorf("user %s can't run %s on %s: %s, grant it with ACL SETUSER %s +%s: %w",
+				user, args[0], key, strings.TrimSpace(res), user, args[0], ErrNoPermission)
+		}
+	}
+	return nil
+}
+
+// preflightCommands returns the commands, with their arguments, the run
+// sends for key: the ones of the mode, sent within a MULTI/EXEC with
+// Transaction, or the script and the commands it runs with BatchScript.
+func (f *Scanner) preflightCommands(key string) [][]any {
+	if f.DryRun {
+		return [][]any{{"pttl", key}}
+	}
+	if f.BatchScript {
+		return [][]any{{"evalsha", batchExpire.Hash(), 1, key}, {"pttl", key}, {"pexpire", key, 1}}
+	}
+	var commands [][]any
+	for _, cmd := range modeCommands[f.Mode] {
+		commands = append(commands, append([]any{cmd[0], key}, cmd[1:]...))
+	}
+	if f.Transaction {
+		commands = append(commands, []any{"multi"}, []any{"exec"})
+	}
+	return commands
+}
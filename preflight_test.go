@@ -0,0 +1,186 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// aclHook answers CLIENT INFO and ACL DRYRUN, which miniredis doesn't
+// support, denying the commands in denied and recording the keys checked.
+type aclHook struct {
+	user   string
+	denied map[string]bool
+	scan   error
+	keys   []string
+}
+
+func (h *aclHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		switch {
+		case cmd.Name() == "client" && args[1] == "info":
+			cmd.(*redis.ClientInfoCmd).SetVal(&redis.ClientInfo{User: h.user})
+			return nil
+		case cmd.Name() == "acl" && args[1] == "dryrun":
+			res := "OK"
+			if len(args) > 4 {
+				key, _ := args[4].(string)
+				h.keys = append(h.keys, key)
+			}
+			if name, _ := args[3].(string); h.denied[name] {
+				res = "User " + h.user + " has no permissions to run the '" + name + "' command"
+			}
+			cmd.(*redis.StringCmd).SetVal(res)
+			return nil
+		case cmd.Name() == "scan" && h.scan != nil:
+			return h.scan
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *aclHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *aclHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestPreflight(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	testCases := []struct {
+		mode        string
+		dryRun      bool
+		batchScript bool
+		transaction bool
+		hook        *aclHook
+		err         error
+	}{
+		// miniredis doesn't support CLIENT INFO
+		{mode: "exp"},
+		{mode: "exp", hook: &aclHook{user: "app"}},
+		{mode: "exp", hook: &aclHook{user: "app", denied: map[string]bool{"expire": true}}, err: ErrNoPermission},
+		{mode: "exp", dryRun: true, hook: &aclHook{user: "app", denied: map[string]bool{"expire": true}}},
+		{mode: "shrink", hook: &aclHook{user: "app", denied: map[string]bool{"pexpire": true}}, err: ErrNoPermission},
+		{mode: "report", hook: &aclHook{user: "app", denied: map[string]bool{"pttl": true}}, err: ErrNoPermission},
+		{mode: "exp", batchScript: true, hook: &aclHook{user: "app", denied: map[string]bool{"evalsha": true}}, err: ErrNoPermission},
+		{mode: "exp", batchScript: true, hook: &aclHook{user: "app", denied: map[string]bool{"pexpire": true}}, err: ErrNoPermission},
+		{mode: "exp", batchScript: true, hook: &aclHook{user: "app", denied: map[string]bool{"expire": true}}},
+		{mode: "exp", transaction: true, hook: &aclHook{user: "app", denied: map[string]bool{"multi": true}}, err: ErrNoPermission},
+		{mode: "exp", transaction: true, hook: &aclHook{user: "app", denied: map[string]bool{"exec": true}}, err: ErrNoPermission},
+		{mode: "exp", hook: &aclHook{user: "app", denied: map[string]bool{"multi": true}}},
+		{mode: "exp", hook: &aclHook{user: "app", scan: redisError("NOPERM this user has no permissions to run the 'scan' command")}, err: ErrNoPermission},
+	}
+	for _, tc := range testCases {
+		_ = s.Set("foo", "bar")
+		rdb := redis.NewClient(&redis.Options{
+			Addr: s.Addr(),
+		})
+		if tc.hook != nil {
+			rdb.AddHook(tc.hook)
+		}
+		f := Scanner{
+			Mode:            tc.mode,
+			ScanPrefix:      "f*",
+			Client:          rdb,
+			DesiredTTL:      time.Hour,
+			TTLDelta:        time.Minute,
+			DryRun:          tc.dryRun,
+			BatchScript:     tc.batchScript,
+			Transaction:     tc.transaction,
+			PreflightChecks: true,
+		}
+		if err := f.Run(context.Background()); !errors.Is(err, tc.err) {
+			t.Fatalf("mode %s: want error %v, got %v", tc.mode, tc.err, err)
+		}
+		if tc.err == nil {
+			continue
+		}
+		if ttl := s.TTL("foo"); ttl != 0 {
+			t.Fatalf("mode %s: a failed preflight should not modify keys, got ttl %s", tc.mode, ttl)
+		}
+	}
+}
+
+func TestPreflightScanPatterns(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("zoo:1", "bar")
+
+	testCases := map[string]struct {
+		patterns []string
+		key      string
+	}{
+		"matched key":    {patterns: []string{"zoo:*", "foo"}, key: "zoo:1"},
+		"literal prefix": {patterns: []string{"bar:*", "foo"}, key: "bar:"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			h := &aclHook{user: "app"}
+			rdb := redis.NewClient(&redis.Options{
+				Addr: s.Addr(),
+			})
+			rdb.AddHook(h)
+			f := Scanner{
+				Mode:         "exp",
+				ScanPatterns: tc.patterns,
+				Client:       rdb,
+				DesiredTTL:   time.Hour,
+			}
+			if err := f.Preflight(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(h.keys) == 0 || h.keys[0] != tc.key {
+				t.Fatalf("want %s checked, got %v", tc.key, h.keys)
+			}
+		})
+	}
+}
+
+func TestPreflightDestination(t *testing.T) {
+	src := miniredis.RunT(t)
+	fakeDumpRestore(t, src)
+	_ = src.Set("foo", "bar")
+
+	testCases := map[string]struct {
+		hook *aclHook
+		err  error
+	}{
+		"no acl":     {},
+		"allowed":    {hook: &aclHook{user: "copier"}},
+		"no restore": {hook: &aclHook{user: "copier", denied: map[string]bool{"restore": true}}, err: ErrNoPermission},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dst := miniredis.RunT(t)
+			fakeDumpRestore(t, dst)
+			dest := redis.NewClient(&redis.Options{
+				Addr: dst.Addr(),
+			})
+			if tc.hook != nil {
+				dest.AddHook(tc.hook)
+			}
+			f := Scanner{
+				Mode:            "copy",
+				ScanPrefix:      "f*",
+				Client:          redis.NewClient(&redis.Options{Addr: src.Addr()}),
+				Destination:     dest,
+				PreflightChecks: true,
+			}
+			if err := f.Run(context.Background()); !errors.Is(err, tc.err) {
+				t.Fatalf("want error %v, got %v", tc.err, err)
+			}
+			if copied := dst.Exists("foo"); copied != (tc.err == nil) {
+				t.Fatalf("want foo copied %v, got %v", tc.err == nil, copied)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Preview lists the first keys a run would process on a node along with
+// their current TTL, without modifying anything.
+type Preview struct {
+	Client     redis.Cmdable
+	Node       string
+	ScanPrefix string
+	// ScanPatterns, when set, replace ScanPrefix, see Scanner.ScanPatterns.
+	ScanPatterns []string
+	ScanType     string
+	ScanCount    int64
+	// Keys is the number of matched keys listed.
+	Keys    int
+	Results *PreviewResults
+}
+
+// PreviewKey is a matched key and its TTL, -1 when it has none.
+type PreviewKey struct {
+	Node string
+	Key  string
+	TTL  time.Duration
+}
+
+// PreviewResults collects the keys listed by previews. It is safe for
+// concurrent use.
+type PreviewResults struct {
+	mu   sync.Mutex
+	keys []PreviewKey
+}
+
+func (p *PreviewResults) add(k PreviewKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, k)
+}
+
+// All returns the keys listed so far.
+func (p *PreviewResults) All() []PreviewKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PreviewKey(nil), p.keys...)
+}
+
+func (p *Preview) Run(ctx context.Context) error {
+	var cursor uint64
+	listed := 0
+	for listed < p.Keys {
+		keys, next, err := p.scan(ctx, cursor)
+		if err != nil {
+			return fmt.Errorf("iter error: %w", err)
+		}
+		for _, key := range keys[:min(len(keys), p.Keys-listed)] {
+			ttl, err := p.Client.PTTL(ctx, key).Result()
+			if err != nil {
+				return fmt.Errorf("pttl error: %w", err)
+			}
+			if ttl == -2 {
+				// deleted since it was scanned
+				continue
+			}
+			p.Results.add(PreviewKey{Node: p.Node, Key: key, TTL: ttl})
+			listed++
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+	return nil
+}
+
+func (p *Preview) scan(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+	if len(p.ScanPatterns) > 0 {
+		return scanPatterns(ctx, p.Client, cursor, p.ScanPatterns, p.ScanCount, p.ScanType)
+	}
+	return p.Client.ScanType(ctx, cursor, p.ScanPrefix, p.ScanCount, p.ScanType).Result()
+}
@@ -0,0 +1,60 @@
+package redisttl
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPreview(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 30; i++ {
+		_ = s.Set("foo:"+strconv.Itoa(i), "bar")
+	}
+	s.SetTTL("foo:0", time.Hour)
+	_ = s.Set("zoo:0", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	testCases := []struct {
+		keys      int
+		scanCount int64
+		listed    int
+	}{
+		{keys: 5, listed: 5},
+		{keys: 5, scanCount: 2, listed: 5},
+		{keys: 100, listed: 30},
+	}
+	for _, tc := range testCases {
+		results := &PreviewResults{}
+		p := Preview{
+			Client:     rdb,
+			Node:       s.Addr(),
+			ScanPrefix: "foo:*",
+			ScanType:   "string",
+			ScanCount:  tc.scanCount,
+			Keys:       tc.keys,
+			Results:    results,
+		}
+		if err := p.Run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		keys := results.All()
+		if len(keys) != tc.listed {
+			t.Fatalf("want %d keys, got %d", tc.listed, len(keys))
+		}
+		if k := keys[0]; k.Key != "foo:0" || k.TTL != time.Hour || k.Node != s.Addr() {
+			t.Fatalf("unexpected first key: %+v", k)
+		}
+		if k := keys[1]; k.TTL != -1 {
+			t.Fatalf("want no ttl for %s, got %s", k.Key, k.TTL)
+		}
+	}
+}
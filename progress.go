@@ -0,0 +1,100 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressSnapshot is the progress of a run at a point in time.
+type ProgressSnapshot struct {
+	Node string
+	// Processed is the number of keys processed so far.
+	Processed int64
+	// Walked estimates the keys SCAN went through so far, matched or not,
+	// from the batches scanned and ScanCount.
+	Walked int64
+	// Total estimates the keys to walk through, the DBSIZE of the node when
+	// the run started, 0 when unknown.
+	Total   int64
+	Elapsed time.Duration
+	// Rate is the number of keys processed per second.
+	Rate float64
+	// ETA estimates the time left from the share of Total walked, 0 when
+	// Total is unknown.
+	ETA time.Duration
+	// Done is true for the snapshot of a completed scan.
+	Done bool
+}
+
+// Percent returns the share of Total walked, from 0 to 100, 0 when Total
+// is unknown.
+func (p ProgressSnapshot) Percent() float64 {
+	if p.Done {
+		return 100
+	}
+	if p.Total <= 0 {
+		return 0
+	}
+	return 100 * min(float64(p.Walked)/float64(p.Total), 1)
+}
+
+func (p ProgressSnapshot) String() string {
+	s := fmt.Sprintf("%d keys in %s (%.0f/s)", p.Processed, p.Elapsed.Round(time.Second), p.Rate)
+	switch {
+	case p.Done:
+		return s + ", done"
+	case p.Total > 0:
+		return fmt.Sprintf("%s, %.1f%% of ~%d, eta %s", s, p.Percent(), p.Total, p.ETA.Round(time.Second))
+	}
+	return s
+}
+
+// Progress receives a ProgressSnapshot of a run every ProgressInterval, and
+// once its scan completes. Implementations must be safe for concurrent use
+// when shared by several scanners.
+type Progress interface {
+	Update(ctx context.Context, p ProgressSnapshot)
+}
+
+// newProgressSnapshot computes the rate and ETA of processed keys out of
+// walked ones, in elapsed, the node holding total keys.
+func newProgressSnapshot(node string, processed, walked, total int64, elapsed time.Duration) ProgressSnapshot {
+	p := ProgressSnapshot{Node: node, Processed: processed, Walked: walked, Total: total, Elapsed: elapsed}
+	if elapsed > 0 {
+		p.Rate = float64(processed) / elapsed.Seconds()
+	}
+	if total > 0 && walked > 0 {
+		done := min(float64(walked)/float64(total), 1)
+		p.ETA = time.Duration(float64(elapsed) * (1 - done) / done)
+	}
+	return p
+}
+
+// startProgress reads the DBSIZE of the node the ETA of Progress is
+// computed from. Failures leave it unknown.
+func (f *Scanner) startProgress(ctx context.Context, r *scanRun) {
+	if f.Progress != nil && r.total == 0 {
+		r.total, _ = f.scanClient().DBSize(ctx).Result()
+	}
+}
+
+// snapshot returns the progress of r.
+func (f *Scanner) snapshot(r *scanRun, done bool) ProgressSnapshot {
+	count := f.ScanCount
+	if count <= 0 {
+		// the default COUNT of SCAN
+		count = 10
+	}
+	walked := r.batches * count
+	if done {
+		walked = max(walked, r.total)
+	}
+	p := newProgressSnapshot(f.Node, atomic.LoadInt64(&r.live.scanned), walked, r.total, f.clock().Now().Sub(r.info.StartedAt))
+	p.Done = done
+	if done {
+		p.ETA = 0
+	}
+	return p
+}
@@ -0,0 +1,92 @@
+package redisttl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewProgressSnapshot(t *testing.T) {
+	testCases := map[string]struct {
+		processed, walked, total int64
+		elapsed                  time.Duration
+		rate                     float64
+		eta                      time.Duration
+		percent                  float64
+	}{
+		"halfway": {
+			processed: 50, walked: 500, total: 1000, elapsed: 10 * time.Second,
+			rate: 5, eta: 10 * time.Second, percent: 50,
+		},
+		"unknown total": {
+			processed: 50, walked: 500, elapsed: 10 * time.Second,
+			rate: 5,
+		},
+		"walked past the estimate": {
+			processed: 10, walked: 1200, total: 1000, elapsed: 10 * time.Second,
+			rate: 1, percent: 100,
+		},
+		"not started": {
+			total: 1000,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			p := newProgressSnapshot("node1", tc.processed, tc.walked, tc.total, tc.elapsed)
+			if p.Rate != tc.rate || p.ETA != tc.eta || p.Percent() != tc.percent {
+				t.Fatalf("want rate %v, eta %v and %v%%, got %+v and %v%%", tc.rate, tc.eta, tc.percent, p, p.Percent())
+			}
+		})
+	}
+}
+
+// progressRecorder records every snapshot.
+type progressRecorder struct {
+	mu        sync.Mutex
+	snapshots []ProgressSnapshot
+}
+
+func (p *progressRecorder) Update(_ context.Context, s ProgressSnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots = append(p.snapshots, s)
+}
+
+func TestProgress(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, key := range []string{"foo:1", "foo:2", "foo:3", "bar:1"} {
+		_ = s.Set(key, "bar")
+	}
+	progress := &progressRecorder{}
+	f := Scanner{
+		Mode:             "exp",
+		ScanPrefix:       "foo:*",
+		ScanCount:        1,
+		Client:           redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL:       time.Hour,
+		Node:             "node1",
+		Clock:            NewFakeClock(time.Now()),
+		ChunkSize:        1,
+		ChunkPause:       time.Second,
+		ProgressInterval: time.Second,
+		Progress:         progress,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(progress.snapshots) < 2 {
+		t.Fatalf("want periodic snapshots, got %+v", progress.snapshots)
+	}
+	first := progress.snapshots[0]
+	if first.Total != 4 || first.Done || first.ETA <= 0 {
+		t.Fatalf("want an eta from the dbsize, got %+v", first)
+	}
+	last := progress.snapshots[len(progress.snapshots)-1]
+	if !last.Done || last.Processed != 3 || last.Percent() != 100 || last.ETA != 0 || last.Node != "node1" {
+		t.Fatalf("want a final snapshot, got %+v", last)
+	}
+}
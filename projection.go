@@ -0,0 +1,59 @@
+package redisttl
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExpiryBucket is the number of keys expiring within a Bucket from Start.
+type ExpiryBucket struct {
+	Start time.Time
+	Keys  int64
+}
+
+// ExpiryProjection records when the keys given a TTL by a run, or which
+// would be by a dry-run, expire, to anticipate the deletion workload. Like
+// Summary, it can be shared by several scanners and is safe for concurrent
+// use.
+type ExpiryProjection struct {
+	// Bucket is the width of the buckets keys are counted in, 1h when 0.
+	Bucket time.Duration
+
+	mu      sync.Mutex
+	buckets map[time.Time]int64
+}
+
+func (p *ExpiryProjection) bucket() time.Duration {
+	if p.Bucket == 0 {
+		return time.Hour
+	}
+	return p.Bucket
+}
+
+// observe records a key expiring in ttl, noTTL for never.
+func (p *ExpiryProjection) observe(ttl time.Duration) {
+	if p == nil || ttl < 0 {
+		return
+	}
+	start := time.Now().Add(ttl).Truncate(p.bucket())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.buckets == nil {
+		p.buckets = map[time.Time]int64{}
+	}
+	p.buckets[start]++
+}
+
+// Buckets returns the buckets with keys expiring in them, in time order.
+func (p *ExpiryProjection) Buckets() []ExpiryBucket {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]ExpiryBucket, 0, len(p.buckets))
+	for start, keys := range p.buckets {
+		out = append(out, ExpiryBucket{Start: start, Keys: keys})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
@@ -0,0 +1,86 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestExpiryProjection(t *testing.T) {
+	p := &ExpiryProjection{}
+	p.observe(noTTL)
+	p.observe(time.Hour)
+	p.observe(time.Hour)
+	p.observe(48 * time.Hour)
+
+	buckets := p.Buckets()
+	if len(buckets) != 2 {
+		t.Fatalf("want 2 buckets, got: %+v", buckets)
+	}
+	if buckets[0].Keys != 2 || buckets[1].Keys != 1 {
+		t.Fatalf("unexpected counts: %+v", buckets)
+	}
+	if d := buckets[1].Start.Sub(buckets[0].Start); d != 47*time.Hour {
+		t.Fatalf("want buckets 47h apart, got %v", d)
+	}
+}
+
+func TestScannerProjection(t *testing.T) {
+	testCases := map[string]struct {
+		mode   string
+		dryRun bool
+		want   []int64
+	}{
+		"exp": {
+			mode: "exp",
+			want: []int64{3},
+		},
+		"extend depends on the current ttl": {
+			mode: "extend",
+			want: []int64{1},
+		},
+		"dry-run": {
+			mode:   "exp",
+			dryRun: true,
+			want:   []int64{3},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("foo:1", "bar")
+			_ = s.Set("foo:2", "bar")
+			_ = s.Set("foo:3", "bar")
+			s.SetTTL("foo:3", 24*time.Hour)
+
+			projection := &ExpiryProjection{Bucket: 24 * time.Hour}
+			f := &Scanner{
+				Mode:       tc.mode,
+				ScanPrefix: "foo:*",
+				Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+				DesiredTTL: 48 * time.Hour,
+				TTLDelta:   48 * time.Hour,
+				DryRun:     tc.dryRun,
+				Diff:       &bytes.Buffer{},
+				Projection: projection,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []int64
+			for _, b := range projection.Buckets() {
+				got = append(got, b.Keys)
+			}
+			if len(got) != len(tc.want) || got[0] != tc.want[0] {
+				t.Fatalf("want: %v got: %v", tc.want, got)
+			}
+		})
+	}
+}
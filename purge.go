@@ -0,0 +1,30 @@
+package redisttl
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// purgeExpiring unlinks KEYS[1] when its TTL, in milliseconds, is below
+// ARGV[1], reading the TTL and unlinking atomically so a key whose TTL was
+// extended meanwhile is kept.
+var purgeExpiring = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl >= 0 and ttl < tonumber(ARGV[1]) then
+	return redis.call('UNLINK', KEYS[1])
+end
+return 0
+`)
+
+// purge unlinks the keys expiring within threshold, reclaiming their memory
+// ahead of the lazy and active expiry of Redis, e.g. while the server is
+// under memory pressure. Keys without TTL are left untouched.
+func (f *Scanner) purge(ctx context.Context, key string, threshold time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx)
+	n, err := purgeExpiring.Run(ctx, f.Client, []string{key}, threshold.Milliseconds()).Int64()
+	cmd.SetVal(n == 1)
+	cmd.SetErr(err)
+	return cmd
+}
@@ -0,0 +1,71 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPurgeMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"session:1", "session:2", "session:3", "other"} {
+		_ = s.Set(k, "bar")
+	}
+	s.SetTTL("session:1", time.Minute)
+	s.SetTTL("session:2", time.Hour)
+	s.SetTTL("other", time.Minute)
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "purge",
+		ScanPrefix: "session:*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL: 5 * time.Minute,
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.Exists("session:1") {
+		t.Fatal("want the key expiring within 5m purged")
+	}
+	for _, k := range []string{"session:2", "session:3", "other"} {
+		if !s.Exists(k) {
+			t.Fatalf("want %s kept", k)
+		}
+	}
+	if summary.Modified.Load() != 1 {
+		t.Fatalf("expected 1 key purged, got: %s", summary)
+	}
+}
+
+func TestPurgeDryRun(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("session:1", "bar")
+	s.SetTTL("session:1", time.Minute)
+
+	var diff bytes.Buffer
+	f := Scanner{
+		Mode:       "purge",
+		ScanPrefix: "session:*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL: 5 * time.Minute,
+		DryRun:     true,
+		Diff:       &diff,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.Exists("session:1") {
+		t.Fatal("dry-run purged session:1")
+	}
+	if want := "session:1: 1m → 0s [purge would apply]"; !strings.Contains(diff.String(), want) {
+		t.Fatalf("want %q in:\n%s", want, diff.String())
+	}
+}
@@ -0,0 +1,193 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Fields of the stream entries holding jobs.
+const (
+	jobPrefixField = "prefix"
+	jobKeysField   = "keys"
+)
+
+// Job is a unit of work shared through a Queue: a pattern to scan, or a
+// batch of keys to process.
+type Job struct {
+	// ID is the ID of the stream entry, set when the job is read.
+	ID         string
+	ScanPrefix string
+	Keys       []string
+}
+
+// Queue is a Redis Stream of jobs read by a consumer group, so the workers
+// of the group share them.
+type Queue struct {
+	Client redis.Cmdable
+	Stream string
+	Group  string
+}
+
+// Enqueue appends job to the queue and returns its ID.
+func (q *Queue) Enqueue(ctx context.Context, job Job) (string, error) {
+	values := map[string]any{jobPrefixField: job.ScanPrefix}
+	if len(job.Keys) > 0 {
+		values = map[string]any{jobKeysField: strings.Join(job.Keys, "\n")}
+	}
+	id, err := q.Client.XAdd(ctx, &redis.XAddArgs{Stream: q.Stream, Values: values}).Result()
+	if err != nil {
+		return "", fmt.Errorf("enqueue error: %w", err)
+	}
+	return id, nil
+}
+
+// createGroup creates the consumer group, reading the jobs enqueued before
+// any worker started, unless it exists.
+func (q *Queue) createGroup(ctx context.Context) error {
+	err := q.Client.XGroupCreateMkStream(ctx, q.Stream, q.Group, "0").Err()
+	if err != nil && !redis.HasErrorPrefix(err, "BUSYGROUP") {
+		return fmt.Errorf("create group error: %w", err)
+	}
+	return nil
+}
+
+func parseJob(msg redis.XMessage) Job {
+	job := Job{ID: msg.ID}
+	if keys, ok := msg.Values[jobKeysField].(string); ok && keys != "" {
+		job.Keys = strings.Split(keys, "\n")
+	}
+	job.ScanPrefix, _ = msg.Values[jobPrefixField].(string)
+	return job
+}
+
+// Worker processes the jobs of a Queue until its context is done. A job is
+// acknowledged once processed, so the jobs of a worker dying midway, or
+// failing to process them, are claimed by a worker of the group once they
+// have been pending for MinIdle: every job is processed at least once.
+type Worker struct {
+	Queue *Queue
+	// Consumer names the worker within the group, and must be unique.
+	Consumer string
+	Process  func(ctx context.Context, job Job) error
+	// Block is how long a worker waits for new jobs before checking for
+	// abandoned ones, 5s when 0.
+	Block time.Duration
+	// MinIdle is how long a job stays pending before another worker claims
+	// it, 5m when 0.
+	MinIdle time.Duration
+}
+
+// Run creates the group of the Queue when missing, then processes its jobs
+// one at a time. A job Process fails is left pending for a retry. Run
+// returns once ctx is done, or on the first error reading or acknowledging
+// the jobs.
+func (w *Worker) Run(ctx context.Context) error {
+	if err := w.Queue.createGroup(ctx); err != nil {
+		return err
+	}
+	for {
+		job, found, err := w.next(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			return err
+		case !found:
+			continue
+		}
+
+		if err := w.Process(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("job %s failed, leaving it for a retry: %v\n", job.ID, err)
+			continue
+		}
+		if err := w.Queue.Client.XAck(ctx, w.Queue.Stream, w.Queue.Group, job.ID).Err(); err != nil {
+			return fmt.Errorf("ack error: %w", err)
+		}
+	}
+}
+
+// next claims a job abandoned by another worker, or reads a new one.
+func (w *Worker) next(ctx context.Context) (Job, bool, error) {
+	q := w.Queue
+	claimed, _, err := q.Client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.Stream,
+		Group:    q.Group,
+		Consumer: w.Consumer,
+		MinIdle:  w.minIdle(),
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err != nil {
+		return Job{}, false, fmt.Errorf("claim error: %w", err)
+	}
+	if len(claimed) > 0 {
+		return parseJob(claimed[0]), true, nil
+	}
+
+	streams, err := q.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.Group,
+		Consumer: w.Consumer,
+		Streams:  []string{q.Stream, ">"},
+		Count:    1,
+		Block:    w.block(),
+	}).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return Job{}, false, nil
+	case err != nil:
+		return Job{}, false, fmt.Errorf("read error: %w", err)
+	}
+	if len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return Job{}, false, nil
+	}
+	return parseJob(streams[0].Messages[0]), true, nil
+}
+
+func (w *Worker) block() time.Duration {
+	if w.Block == 0 {
+		return 5 * time.Second
+	}
+	return w.Block
+}
+
+func (w *Worker) minIdle() time.Duration {
+	if w.MinIdle == 0 {
+		return 5 * time.Minute
+	}
+	return w.MinIdle
+}
+
+// ApplyKeys applies the mode to keys as if a scan had returned them, e.g.
+// for the keys of a Job. Only the modes modifying keys are supported.
+func (f *Scanner) ApplyKeys(ctx context.Context, keys []string) error {
+	r, err := f.newRun()
+	if err != nil {
+		return err
+	}
+	if r.observe != nil {
+		return fmt.Errorf("mode %s doesn't modify keys: %w", f.Mode, errInvalidMode)
+	}
+	if err := f.emulate(ctx, r); err != nil {
+		return err
+	}
+	if err := r.emulateRules(ctx); err != nil {
+		return err
+	}
+	defer r.live.key.Store("")
+	switch {
+	case r.batch:
+		return f.applyScript(ctx, r, keys)
+	case r.tx:
+		return f.applyTx(ctx, r, keys)
+	}
+	return f.applyBatch(ctx, r, keys)
+}
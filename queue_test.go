@@ -0,0 +1,140 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestWorker(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	ctx := context.Background()
+	q := &Queue{Client: rdb, Stream: "jobs", Group: "redis-ttl"}
+	for _, job := range []Job{{ScanPrefix: "foo:*"}, {Keys: []string{"a", "b"}}, {ScanPrefix: "fail"}} {
+		if _, err := q.Enqueue(ctx, job); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var mu sync.Mutex
+	var processed []Job
+	attempts := 0
+	w := Worker{
+		Queue:    q,
+		Consumer: "worker-1",
+		Block:    10 * time.Millisecond,
+		MinIdle:  time.Millisecond,
+		Process: func(ctx context.Context, job Job) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if job.ScanPrefix == "fail" {
+				// the job is claimed again once it failed
+				if attempts++; attempts == 1 {
+					return errors.New("failed")
+				}
+				cancel()
+			}
+			job.ID = ""
+			processed = append(processed, job)
+			return nil
+		},
+	}
+	if err := w.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got: %v", err)
+	}
+
+	expected := []Job{{ScanPrefix: "foo:*"}, {Keys: []string{"a", "b"}}, {ScanPrefix: "fail"}}
+	if !reflect.DeepEqual(processed, expected) {
+		t.Fatalf("want: %+v got: %+v", expected, processed)
+	}
+	pending, err := rdb.XPending(context.Background(), "jobs", "redis-ttl").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the last job was canceled before being acknowledged
+	if pending.Count != 1 {
+		t.Fatalf("want a single pending job, got: %+v", pending)
+	}
+}
+
+func TestApplyKeys(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("zoo", "bar")
+
+	f := Scanner{
+		Mode:       "exp",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL: time.Hour,
+	}
+	if err := f.ApplyKeys(context.Background(), []string{"foo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.TTL("foo") != time.Hour || s.TTL("zoo") != 0 {
+		t.Fatalf("only foo should be modified, got: %v %v", s.TTL("foo"), s.TTL("zoo"))
+	}
+
+	f.Mode = "audit"
+	if err := f.ApplyKeys(context.Background(), []string{"foo"}); !errors.Is(err, errInvalidMode) {
+		t.Fatalf("want errInvalidMode, got: %v", err)
+	}
+}
+
+func TestApplyKeysTransaction(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("zoo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	h := &execCounter{}
+	rdb.AddHook(h)
+	f := Scanner{
+		Mode:        "exp",
+		Client:      rdb,
+		DesiredTTL:  time.Hour,
+		Transaction: true,
+	}
+	if err := f.ApplyKeys(context.Background(), []string{"foo", "zoo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.TTL("foo") != time.Hour || s.TTL("zoo") != time.Hour {
+		t.Fatalf("want both keys modified, got: %v %v", s.TTL("foo"), s.TTL("zoo"))
+	}
+	if h.execs != 1 {
+		t.Fatalf("want the keys sent within a single transaction, got %d", h.execs)
+	}
+}
+
+func TestApplyKeysRules(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	s.SetTTL("foo", time.Minute)
+
+	old := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	old.AddHook(&infoHook{replies: []string{"redis_version:6.2.14\r\n"}})
+	f := Scanner{
+		Mode:   "rules",
+		Client: old,
+		Rules:  RuleSet{{Pattern: "*", Mode: "gt", TTL: time.Hour}},
+	}
+	if err := f.ApplyKeys(context.Background(), []string{"foo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.TTL("foo") != time.Hour {
+		t.Fatalf("want foo modified, got: %v", s.TTL("foo"))
+	}
+	if loaded, _ := conditionalExpire.Exists(context.Background(), old).Result(); len(loaded) != 1 || !loaded[0] {
+		t.Fatal("want the mode of the rule emulated")
+	}
+}
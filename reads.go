@@ -0,0 +1,93 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultPipelineSize = 100
+
+// readers maps the read-only modes to what they do with the TTL of each
+// scanned key. These modes read TTLs in pipelines rather than one key at a
+// time, and so does "noop" mode with an Inventory.
+func (f *Scanner) readers(r *scanRun) map[string]func(key string, ttl time.Duration) {
+	readers := map[string]func(key string, ttl time.Duration){
+		"report": func(key string, ttl time.Duration) {
+			f.Report.observe(f.keyPrefix(key), key, ttl)
+		},
+		"audit": func(key string, ttl time.Duration) {
+			f.Audit.observe(key, ttl, 0)
+		},
+		"verify": func(key string, ttl time.Duration) {
+			if desired, covered := f.desiredTTL(key, r.types[key]); covered {
+				f.Audit.observe(key, ttl, desired)
+			}
+		},
+	}
+	if f.Inventory != nil {
+		readers["noop"] = func(key string, ttl time.Duration) {
+			f.Inventory.observe(f.Node, r.types[key], ttl)
+		}
+	}
+	return readers
+}
+
+// readBatch reads the TTL of keys, PipelineSize keys at a time, and hands
+// them to r.observe.
+func (f *Scanner) readBatch(ctx context.Context, r *scanRun, keys []string) error {
+	size := f.PipelineSize
+	if size <= 0 {
+		size = defaultPipelineSize
+	}
+	if f.Mode == "verify" || f.Mode == "noop" {
+		if err := f.loadTypes(ctx, r, keys); err != nil {
+			return err
+		}
+	}
+	for len(keys) > 0 {
+		chunk := keys[:min(size, len(keys))]
+		keys = keys[len(chunk):]
+		for range chunk {
+			if err := f.throttle(ctx, r); err != nil {
+				return err
+			}
+		}
+		if err := f.readChunk(ctx, r, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Scanner) readChunk(ctx context.Context, r *scanRun, keys []string) error {
+	r.live.key.Store(keys[0])
+	start := time.Now()
+	// per-key errors are reported through each command
+	cmds, _ := f.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range keys {
+			p.PTTL(ctx, key)
+		}
+		return nil
+	})
+	elapsed := time.Since(start) / time.Duration(len(keys))
+
+	for i, key := range keys {
+		ttl, err := cmds[i].(*redis.DurationCmd).Result()
+		r.observeKey(key, elapsed, false, err)
+		switch {
+		case err != nil:
+			log.Printf("pttl error for %s: %v\n", key, err)
+			if err := f.failFast(key, err); err != nil {
+				return err
+			}
+		case ttl != -2:
+			// -2 means the key expired since it was scanned
+			r.observe(key, ttl)
+		}
+		f.inspect(ctx, r, key)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// pipelineCounter counts the pipelines sent by a client, failing all of
+// their commands with err when set.
+type pipelineCounter struct {
+	pipelines int
+	err       error
+}
+
+func (h *pipelineCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *pipelineCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.pipelines++
+		if h.err == nil {
+			return next(ctx, cmds)
+		}
+		for _, cmd := range cmds {
+			cmd.SetErr(h.err)
+		}
+		return h.err
+	}
+}
+
+func (h *pipelineCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func TestReadModes(t *testing.T) {
+	testCases := map[string]struct {
+		mode       string
+		violations int64
+		findings   []AuditFinding
+	}{
+		"audit flags keys without a ttl": {
+			mode:       "audit",
+			violations: 1,
+			findings:   []AuditFinding{{Key: "foo:none", TTL: -1, Reason: FindingNoTTL}},
+		},
+		"verify also flags keys above the desired ttl": {
+			mode:       "verify",
+			violations: 2,
+			findings: []AuditFinding{
+				{Key: "foo:long", TTL: 48 * time.Hour, Reason: FindingTTLTooLong},
+				{Key: "foo:none", TTL: -1, Reason: FindingNoTTL},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for i := 0; i < 5; i++ {
+				key := "foo:" + strconv.Itoa(i)
+				_ = s.Set(key, "bar")
+				s.SetTTL(key, time.Hour)
+			}
+			_ = s.Set("foo:none", "bar")
+			_ = s.Set("foo:long", "bar")
+			s.SetTTL("foo:long", 48*time.Hour)
+
+			rdb := redis.NewClient(&redis.Options{
+				Addr: s.Addr(),
+			})
+			h := &pipelineCounter{}
+			rdb.AddHook(h)
+
+			audit := &Audit{}
+			summary := &Summary{}
+			f := Scanner{
+				Mode:         tc.mode,
+				ScanPrefix:   "foo:*",
+				Client:       rdb,
+				DesiredTTL:   24 * time.Hour,
+				Audit:        audit,
+				Summary:      summary,
+				PipelineSize: 3,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// 7 keys read 3 at a time
+			if h.pipelines != 3 {
+				t.Fatalf("expected 3 pipelines, got: %d", h.pipelines)
+			}
+			if audit.Checked() != 7 || audit.Violations() != tc.violations {
+				t.Fatalf("unexpected counts: checked=%d violations=%d", audit.Checked(), audit.Violations())
+			}
+			findings := audit.Findings()
+			if len(findings) != len(tc.findings) {
+				t.Fatalf("want: %v got: %v", tc.findings, findings)
+			}
+			for i := range tc.findings {
+				if findings[i] != tc.findings[i] {
+					t.Fatalf("want: %v got: %v", tc.findings, findings)
+				}
+			}
+			if summary.Scanned.Load() != 7 || summary.Modified.Load() != 0 {
+				t.Fatalf("unexpected summary: %s", summary)
+			}
+		})
+	}
+}
+
+func TestReadModeErrors(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	rdb.AddHook(&pipelineCounter{err: errors.New("pttl failed")})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "audit",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		Audit:      &Audit{},
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("per-key errors should not abort the run, got: %v", err)
+	}
+	if summary.Errors.Load() != 1 || f.Audit.Checked() != 0 {
+		t.Fatalf("expected the error to be counted, got: %s", summary)
+	}
+}
+
+func TestReadModeFailFast(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	errPTTL := errors.New("pttl failed")
+	rdb.AddHook(&pipelineCounter{err: errPTTL})
+
+	f := Scanner{
+		Mode:       "audit",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		FailFast:   true,
+	}
+	if err := f.Run(context.Background()); !errors.Is(err, errPTTL) {
+		t.Fatalf("expected error %v, got: %v", errPTTL, err)
+	}
+}
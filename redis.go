@@ -4,7 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,27 +20,485 @@ import (
 
 var errInvalidMode = errors.New("invalid mode")
 
+// keyPlaceholder stands for the matched key in SourceKey and Companions.
+const keyPlaceholder = "{key}"
+
+type ttlFunc func(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd
+
 type limiter interface {
 	Wait(ctx context.Context) (err error)
 }
 
 type Scanner struct {
-	Client     redis.Cmdable
+	// Client is any redis.UniversalClient: a standalone, sentinel or cluster
+	// client, whose primaries Run scans separately, see Run.
+	Client redis.Cmdable
+	// ScanClient, when set, receives the SCAN commands while Client receives
+	// the expire commands. This lets cluster runs scan a single primary and
+	// still route writes through a cluster-aware client.
+	ScanClient redis.Cmdable
 	Mode       string
 	ScanPrefix string
-	DesiredTTL time.Duration
-	Limiter    limiter
-	ScanType   string
-	ScanCount  int64
+	// ScanPatterns, when set, replace ScanPrefix with several glob-style
+	// patterns. The keys matching any of them are filtered by a script in a
+	// single scan of the keyspace.
+	ScanPatterns []string
+	DesiredTTL   time.Duration
+	Limiter      limiter
+	// Node is the address of the node scanned, as reported by Logger.
+	Node string
+	// Logger, when set, receives a structured record for every key modified,
+	// with its TTL before and after, instead of the default log line. Reading
+	// the TTL before costs a command per key.
+	Logger    *slog.Logger
+	ScanType  string
+	ScanCount int64
+	// MemoryGuard, when set, pauses the run while the server is close to maxmemory.
+	MemoryGuard *MemoryGuard
+	// CircuitBreaker, when set, pauses the run while too many keys fail.
+	CircuitBreaker *CircuitBreaker
+	// Failover, when set, resumes the run on the new primary when the node
+	// fails over midway.
+	Failover *Failover
+	// ChangeStream, when set, receives an entry for every key modified,
+	// itself being protected.
+	ChangeStream *ChangeStream
+	// Window, when set, pauses the run outside its hours, between two SCAN
+	// batches.
+	Window *Window
+	// ChunkSize, when greater than 0, pauses the run for ChunkPause every
+	// ChunkSize keys processed, on top of the Limiter, leaving the server
+	// idle between chunks.
+	ChunkSize  int
+	ChunkPause time.Duration
+	// Jitter, when greater than 0, delays every key by a random duration up
+	// to Jitter on top of the Limiter, so the commands sent don't form
+	// periodic bursts beating against the traffic of the applications.
+	Jitter time.Duration
+	// CursorFile, when set, records the cursor of the scan when it pauses
+	// outside Window or stops on an error, and Run resumes from it, so a scan
+	// can span several windows or processes. It is removed once the keyspace
+	// has been scanned, and is only valid for the same patterns and node.
+	CursorFile string
+	// KillSwitch, when set, is checked between two SCAN batches to stop or
+	// pause the run.
+	KillSwitch *KillSwitch
+	// Summary, when set, accumulates the outcome of the run. A Summary can be
+	// shared by several scanners, e.g. one per cluster node.
+	Summary *Summary
+	// Namespaces, when set, breaks the outcome of the run down by namespace.
+	Namespaces *NamespaceSummary
+	// Projection, when set, records when the keys modified expire. Like
+	// Logger, it costs a TTL read per key for the modes whose TTL depends on
+	// the current one.
+	Projection *ExpiryProjection
+	// Inventory, when set, counts the keys scanned in "noop" mode by type and
+	// TTL, at the cost of a PTTL per key, and a TYPE unless ScanType is set,
+	// sent in pipelines like the read-only modes.
+	Inventory *Inventory
+	// Report collects per-prefix TTL statistics in "report" mode.
+	Report *PrefixReport
+	// BigKeys, when set, flags scanned keys exceeding its size thresholds.
+	BigKeys *BigKeyDetector
+	// KeySizeEvery, when greater than 0, samples the size of every
+	// KeySizeEvery-th scanned key into Summary.KeySizes with MEMORY USAGE, or
+	// with STRLEN when KeySizeStrlen is set, which only measures strings.
+	KeySizeEvery  int64
+	KeySizeStrlen bool
+	// TTLPercent is the percentage of its current TTL a key is set to in
+	// "percent" mode, e.g. 50 halves the remaining lifetime.
+	TTLPercent float64
+	// TTLDelta is the duration added to the current TTL in "extend" mode,
+	// and subtracted from it in "shrink" mode.
+	TTLDelta time.Duration
+	// TTLFloor is the lowest TTL "shrink" mode sets. Keys already below it
+	// are left untouched.
+	TTLFloor time.Duration
+	// TypeTTLs, when set, pick the TTL of every key by its type, e.g. "hash",
+	// instead of DesiredTTL, leaving the keys of other types untouched. The
+	// type of every key is read, unless ScanType restricts them to one.
+	TypeTTLs map[string]time.Duration
+	// TTLRules, when set, pick the TTL of every key instead of DesiredTTL
+	// and TypeTTLs. The first rule matching a key wins, and keys no rule
+	// covers are left untouched.
+	TTLRules []TTLRule
+	// Rules pick the mode and TTL of every key in "rules" mode, replacing
+	// Mode and DesiredTTL. The first rule matching a key, by priority, wins,
+	// and keys no rule matches are left untouched.
+	Rules RuleSet
+	// ZScoreUnit is the unit of the timestamps scoring the members of the
+	// sorted sets in "ztrim" mode, which removes the members older than
+	// DesiredTTL, time.Second when 0, e.g. time.Millisecond.
+	ZScoreUnit time.Duration
+	// Destination receives the keys copied in "copy" mode, which DUMPs every
+	// matched key and RESTOREs it there with its remaining TTL. CopyReplace
+	// replaces the keys already on Destination, which otherwise fail.
+	Destination redis.Cmdable
+	CopyReplace bool
+	// SourceKey is the key whose TTL every matched key gets in "inherit"
+	// mode, with {key} standing for the matched key, e.g. "{key}:meta".
+	SourceKey string
+	// ExpiryIndex, when set, is the key of a sorted set recording when every
+	// key modified expires, scored by its expiry as a Unix timestamp in
+	// milliseconds, so what expires next can be queried without scanning,
+	// see ExpiringBefore. Keys left without TTL are removed from it. It costs
+	// a command per key modified, and a TTL read per key for the modes whose
+	// TTL depends on the current one. The index itself is never modified by
+	// the mode.
+	ExpiryIndex string
+	// Companions are templates of keys the mode is also applied to for every
+	// matched key, with {key} standing for the matched key, e.g. "{key}:idx".
+	// A companion key matching ScanPrefix is processed twice.
+	Companions []string
+	// Protected are glob-style patterns, like ScanPrefix, of keys never
+	// modified whatever ScanPrefix matches, companions included.
+	Protected []string
+	// ApplyPercent, when between 0 and 100 exclusive, restricts the keys
+	// modified to a sample of this percentage of the matched ones. A key is
+	// sampled by hashing it, so it stays in or out of the sample across runs.
+	ApplyPercent float64
+	// IntrospectRate, when between 0 and 1 exclusive, restricts the commands
+	// inspecting keys, Filter, the GET and HGET of ValueMatch and HashField
+	// and the MEMORY USAGE and element counts of BigKeys and KeySizeEvery, to
+	// this fraction of the keys, picked at random, keeping the commands sent
+	// within the budget of the Limiter. The keys whose predicates aren't read
+	// are left untouched, as if they didn't match.
+	IntrospectRate float64
+	// ValueMatch, when set, restricts the keys modified to the string keys
+	// whose value it matches, e.g. ^\{\}$ for empty JSON objects, at the cost
+	// of a GET per key, sent once the Limiter allows the key to be processed.
+	ValueMatch *regexp.Regexp
+	// HashField, when set, restricts the keys modified to the hashes whose
+	// HashField field holds HashValue, e.g. status=archived, at the cost of
+	// an HGET per key, sent like the GET of ValueMatch. No key is both a
+	// string and a hash, so setting both matches nothing.
+	HashField string
+	HashValue string
+	// Filter, when set, restricts the keys modified to the ones it matches,
+	// checked like ValueMatch, before it. See And, Or and Not to combine
+	// filters.
+	Filter KeyFilter
+	// Tolerance, when greater than 0, skips the keys whose TTL is already
+	// within Tolerance of the desired one in exp, gt, lt and xx modes, at the
+	// cost of reading the TTL of every key.
+	Tolerance time.Duration
+	// DryRun reads the TTL of every key and writes what the mode would do to
+	// Diff instead of modifying anything.
+	DryRun bool
+	// Diff receives one line per key in dry-run mode, os.Stdout when nil.
+	Diff io.Writer
+	// Audit collects the keys flagged in "audit" mode, which flags keys
+	// without a TTL, and "verify" mode, which also flags keys whose TTL
+	// exceeds DesiredTTL.
+	Audit *Audit
+	// VerifyWrites re-reads the TTL of every key modified and flags in Audit
+	// the ones deleted or whose TTL was changed by someone else since.
+	VerifyWrites bool
+	// PreflightChecks runs Preflight before scanning.
+	PreflightChecks bool
+	// NoEmulation fails the modes the server is too old for instead of
+	// emulating them with a script, see Preflight.
+	NoEmulation bool
+	// FailFast aborts the run on the first per-key error instead of logging
+	// it and moving on to the next key.
+	FailFast bool
+	// OnError, when set, decides instead of FailFast what the run does after
+	// a command sent for a key failed: move on to the next key, retry the
+	// command, calling OnError again should it fail again, or abort. Only the
+	// commands sent one key at a time are retried; the keys of a batch script,
+	// a transaction or a pipeline move on instead.
+	OnError func(key string, err error) Action
+	// Retry, when set, retries the SCAN batches and the commands sent one key
+	// at a time which failed, before OnError or FailFast decide what to do
+	// with their error. See DefaultRetryPolicy.
+	Retry RetryPolicy
+	// Clock, when set, replaces the SystemClock, e.g. with a FakeClock.
+	Clock Clock
+	// Metrics, when set, receives the outcome of every key as it happens.
+	Metrics Metrics
+	// Cluster tells Client is a node of a Redis Cluster, e.g. the one of the
+	// primary a run scans, so BatchScript and Transaction split the keys of
+	// every batch by hash slot. It is implied by a cluster client.
+	Cluster bool
+	// BatchScript applies the mode to the keys of every SCAN batch with a
+	// single script call, one per hash slot on a cluster, instead of a
+	// command per key. Only exp, gt, lt, nx and xx modes support it, without
+	// VerifyWrites. Dry-runs process keys one by one.
+	BatchScript bool
+	// Transaction applies the mode to the keys of every SCAN batch, and to
+	// their companions, within a single MULTI/EXEC, so either all of them
+	// are modified or none are. On a cluster, where a transaction may only
+	// access the keys of a single slot, there is one per hash slot: a key
+	// and its companions are then only modified atomically when they share a
+	// hash tag, such as {user:1}:profile and {user:1}:session. Only exp, gt,
+	// lt, nx, xx and persist modes support it, without Tolerance or
+	// VerifyWrites, and gt, lt, nx and xx aren't emulated within a
+	// transaction: the run fails with ErrUnsupportedMode on servers older
+	// than 7.0. Dry-runs process keys one by one.
+	Transaction bool
+	// PipelineSize is the number of TTL reads sent at once by the read-only
+	// modes, 100 when 0.
+	PipelineSize int
+	// Notifier, when set, is told when the run starts, every
+	// ProgressInterval while it runs, waiting for Window included, and when
+	// it completes.
+	Notifier         Notifier
+	ProgressInterval time.Duration
+	// Progress, when set, receives a snapshot of the run every
+	// ProgressInterval, and once its scan completes.
+	Progress Progress
+
+	// live and pause are created on first use by stats and gate, and shared
+	// with the copies of f made after them.
+	live  *liveStats
+	pause *pauseGate
+	// onBatch, when set, is called with the cursor of the next batch once
+	// one is processed, see Follower.
+	onBatch func(cursor uint64)
+}
+
+// ScanStats are the counters of the runs of a Scanner, see Scanner.Stats.
+type ScanStats struct {
+	Scanned  int64
+	Modified int64
+	Errors   int64
+	// Cursor is the cursor of the next SCAN once the current batch is
+	// processed, so a scan stopped midway can resume from it. It is 0 before
+	// the first batch and once the keyspace has been scanned.
+	Cursor uint64
+	// Key is the key being processed, or the first of the batch being read
+	// by the read-only modes, empty when none is.
+	Key string
+}
+
+// liveStats are the counters of Scanner.Stats. A Scanner holds them by
+// pointer, for its copies not to copy them while they are updated.
+type liveStats struct {
+	scanned  int64
+	modified int64
+	errors   int64
+	cursor   uint64
+	key      atomic.Value
+}
+
+// sharedMu guards the creation of the liveStats and pauseGate of every
+// Scanner.
+var sharedMu sync.Mutex
+
+// stats returns the liveStats of f, created on first use.
+func (f *Scanner) stats() *liveStats {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if f.live == nil {
+		f.live = &liveStats{}
+	}
+	return f.live
+}
+
+func (l *liveStats) observe(modified bool, err error) {
+	atomic.AddInt64(&l.scanned, 1)
+	switch {
+	case err != nil:
+		atomic.AddInt64(&l.errors, 1)
+	case modified:
+		atomic.AddInt64(&l.modified, 1)
+	}
+}
+
+// Stats returns the counters of the runs of f, including the ones in
+// progress. It is safe to call while f runs in another goroutine. They are
+// created by the first run of f or call to Stats, and shared with the copies
+// of f made after it, accumulating across every run, with Cursor being the
+// one of the latest SCAN.
+func (f *Scanner) Stats() ScanStats {
+	live := f.stats()
+	key, _ := live.key.Load().(string)
+	return ScanStats{
+		Scanned:  atomic.LoadInt64(&live.scanned),
+		Modified: atomic.LoadInt64(&live.modified),
+		Errors:   atomic.LoadInt64(&live.errors),
+		Cursor:   atomic.LoadUint64(&live.cursor),
+		Key:      key,
+	}
 }
 
+// Run scans the keyspace and applies the mode to every matched key. Run
+// doesn't modify f besides creating the counters returned by Stats, so a
+// Scanner can be Run several times, including concurrently. Copying a
+// Scanner is cheap, e.g. to run it against another ScanPrefix, and the
+// copies share the Summary, Report and other collectors, as well as the
+// counters of Stats once created.
+//
+// When the scanned client is a cluster client, e.g. the redis.UniversalClient
+// of several addresses, Run scans every primary concurrently, counting their
+// keys in Stats as they go.
 func (f *Scanner) Run(ctx context.Context) error {
-	c := f.Client
-	iter := c.ScanType(ctx, 0, f.ScanPrefix, f.ScanCount, f.ScanType).Iterator()
+	return f.start(ctx, nil, 0)
+}
 
-	type ttlFunc func(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd
+// start runs f, sending the outcome of every key to results when set. The
+// scan starts from the cursor saved in CursorFile when set, from cursor
+// otherwise.
+func (f *Scanner) start(ctx context.Context, results chan<- Result, cursor uint64) error {
+	// the copies of f share its liveStats and pauseGate
+	f.stats()
+	f.gate()
+	if c, ok := f.scanClient().(*redis.ClusterClient); ok {
+		return f.runPrimaries(ctx, c, results)
+	}
+	r, err := f.newRun()
+	if err != nil {
+		return err
+	}
+	r.results, r.done, r.cursor = results, ctx.Done(), cursor
+	if f.CursorFile != "" {
+		if r.cursor, err = f.loadCursor(); err != nil {
+			return err
+		}
+	}
+	if f.Notifier == nil {
+		err = f.resume(ctx, r, f.run(ctx, r))
+		f.finishCursor(err)
+		return err
+	}
+
+	f.Notifier.OnStart(ctx, r.info)
+	err = f.resume(ctx, r, f.run(ctx, r))
+	f.finishCursor(err)
+	f.Notifier.OnComplete(ctx, r.info, r.summary, err)
+	return err
+}
+
+// scanRun is the state of a single Run.
+type scanRun struct {
+	info    RunInfo
+	summary *Summary
+	fn      ttlFunc
+	// custom is true for the modes registered with RegisterMode.
+	custom bool
+	// emulated is true when fn emulates the mode, see emulate.
+	emulated bool
+	// batch is true when keys are sent to batchExpire, see BatchScript.
+	batch bool
+	// tx is true when keys are sent within a MULTI/EXEC, see Transaction.
+	tx bool
+	// cursor is the cursor the scan starts from.
+	cursor uint64
+	// types are the types of the keys of the batch, when TypeTTLs or Rules
+	// need them.
+	types map[string]string
+	// observe is set instead of fn for the read-only modes.
+	observe      func(key string, ttl time.Duration)
+	processed    int
+	lastProgress time.Time
+	live         *liveStats
+
+	// killSwitchRead is when the KillSwitch was last read.
+	killSwitchRead time.Time
+	namespaces     *NamespaceSummary
+	// breaker is the outcome of the latest keys, see CircuitBreaker.
+	breaker *breakerState
+	// rules are the runs of Rules in "rules" mode.
+	rules []ruleRun
+	// total is the DBSIZE of the node, for Progress, and batches the number
+	// of SCAN batches processed.
+	total   int64
+	batches int64
+	// results, when set, receives the outcome of every key until done is
+	// closed, see Stream.
+	results chan<- Result
+	done    <-chan struct{}
+	// metrics receives the outcome of every key of node.
+	metrics Metrics
+	node    string
+}
+
+// observeKey records the outcome of the command sent for a key in the
+// summary and the live stats of the run.
+func (r *scanRun) observeKey(key string, d time.Duration, modified bool, err error) {
+	r.summary.observeKey(d, modified, err)
+	r.observeMetrics(d, modified, err)
+	r.sendResult(Result{Node: r.node, Key: key, Modified: modified, Err: err, Elapsed: d})
+	r.namespaces.observe(key, modified, err)
+	r.live.observe(modified, err)
+	r.breaker.observe(err)
+}
+
+func (f *Scanner) newRun() (*scanRun, error) {
+	r := &scanRun{
+		info: RunInfo{
+			Mode:       f.Mode,
+			ScanPrefix: f.scanPattern(),
+			StartedAt:  f.clock().Now(),
+		},
+		summary:      f.Summary,
+		namespaces:   f.Namespaces,
+		breaker:      newBreakerState(f.CircuitBreaker),
+		lastProgress: f.clock().Now(),
+		live:         f.stats(),
+		metrics:      f.metrics(),
+		node:         f.Node,
+	}
+	if r.summary == nil {
+		r.summary = &Summary{}
+	}
+	if f.Mode == "rules" || len(f.Rules) > 0 {
+		if f.Mode != "rules" || len(f.Rules) == 0 {
+			return nil, fmt.Errorf("rules require rules mode, and rules mode rules: %w", errInvalidMode)
+		}
+		if f.BatchScript || f.Transaction {
+			return nil, fmt.Errorf("rules mode doesn't support batch scripts and transactions: %w", errInvalidMode)
+		}
+		rules, err := f.ruleRuns(r)
+		if err != nil {
+			return nil, err
+		}
+		r.rules = rules
+		return r, nil
+	}
+
+	fn, found := f.ttlFuncs()[f.Mode]
+	observe, reads := f.readers(r)[f.Mode]
+	custom, registered := f.registeredMode(f.Mode)
+	switch {
+	case registered && f.DryRun:
+		// there is no telling what a custom mode would do without running it
+		return nil, fmt.Errorf("mode %s doesn't support dry-run: %w", f.Mode, errInvalidMode)
+	case registered:
+		fn = custom
+	case !found && !reads:
+		return nil, fmt.Errorf("mode %s is not supported: %w", f.Mode, errInvalidMode)
+	case f.BatchScript && (!batchModes[f.Mode] || registered):
+		return nil, fmt.Errorf("mode %s doesn't support batch scripts: %w", f.Mode, errInvalidMode)
+	case f.BatchScript && f.hasPredicate():
+		return nil, fmt.Errorf("batch scripts don't support predicates: %w", errInvalidMode)
+	case f.BatchScript && f.VerifyWrites:
+		return nil, fmt.Errorf("batch scripts don't support verifying writes: %w", errInvalidMode)
+	case f.Transaction && (!txModes[f.Mode] || registered):
+		return nil, fmt.Errorf("mode %s doesn't support transactions: %w", f.Mode, errInvalidMode)
+	case f.Transaction && (f.BatchScript || f.hasPredicate()):
+		return nil, fmt.Errorf("transactions don't support batch scripts and predicates: %w", errInvalidMode)
+	case f.Transaction && (f.Tolerance > 0 || f.VerifyWrites):
+		return nil, fmt.Errorf("transactions don't support a tolerance and verifying writes: %w", errInvalidMode)
+	}
+	if p, found := f.plans()[f.Mode]; found && f.DryRun {
+		fn = f.dryRun(p)
+	}
+	if f.Mode == "copy" && f.Destination == nil && !f.DryRun {
+		return nil, fmt.Errorf("copy mode requires a destination: %w", errInvalidMode)
+	}
+	if dry, found := f.dryRuns()[f.Mode]; found && f.DryRun {
+		fn = dry
+	}
+	r.fn, r.observe, r.custom = fn, observe, registered
+	r.batch = f.BatchScript && !f.DryRun
+	r.tx = f.Transaction && !f.DryRun
+	return r, nil
+}
 
-	ttlFuncs := map[string]ttlFunc{
+func (f *Scanner) ttlFuncs() map[string]ttlFunc {
+	c := f.Client
+	return map[string]ttlFunc{
 		"exp": c.Expire,
 		"gt":  c.ExpireGT,
 		"lt":  c.ExpireLT,
@@ -44,40 +510,451 @@ func (f *Scanner) Run(ctx context.Context) error {
 		"persist": func(ctx context.Context, key string, _ time.Duration) *redis.BoolCmd {
 			return c.Persist(ctx, key)
 		},
+		"percent": f.adjustTTL(f.percent),
+		"extend":  f.adjustTTL(f.extend),
+		"shrink":  f.adjustTTL(f.shrink),
+		"inherit": f.inherit,
+		"ztrim":   f.ztrim,
+		"copy":    f.copyKey,
+		"purge":   f.purge,
 	}
+}
 
-	fn, found := ttlFuncs[f.Mode]
-	if !found {
-		return fmt.Errorf("mode %s is not supported: %w", f.Mode, errInvalidMode)
+func (f *Scanner) run(ctx context.Context, r *scanRun) error {
+	if f.PreflightChecks {
+		if err := f.Preflight(ctx); err != nil {
+			return err
+		}
 	}
+	if err := f.emulate(ctx, r); err != nil {
+		return err
+	}
+	if err := r.emulateRules(ctx); err != nil {
+		return err
+	}
+	f.startProgress(ctx, r)
+	return f.scanLoop(ctx, r)
+}
 
-	for iter.Next(ctx) {
-
-		if err := f.wait(ctx); err != nil {
+// scanLoop scans the keyspace from r.cursor, applying the mode to the keys
+// of every batch, until the scan completes.
+func (f *Scanner) scanLoop(ctx context.Context, r *scanRun) error {
+	defer r.live.key.Store("")
+	cursor := r.cursor
+	atomic.StoreUint64(&r.live.cursor, cursor)
+	for {
+		if err := f.waitWindow(ctx, r, cursor); err != nil {
+			return err
+		}
+		if err := f.checkKillSwitch(ctx, r); err != nil {
+			return err
+		}
+		if err := f.waitResumed(ctx); err != nil {
 			return err
 		}
+		start := time.Now()
+		keys, next, err := f.scanRetrying(ctx, cursor)
+		r.summary.observeScan(time.Since(start))
+		if err != nil {
+			return fmt.Errorf("iter error: %w", err)
+		}
 
-		key := iter.Val()
-		ok, err := fn(ctx, key, f.DesiredTTL).Result()
+		switch {
+		case r.observe != nil:
+			err = f.readBatch(ctx, r, keys)
+		case r.batch:
+			err = f.applyScript(ctx, r, keys)
+		case r.tx:
+			err = f.applyTx(ctx, r, keys)
+		default:
+			err = f.applyBatch(ctx, r, keys)
+		}
 		if err != nil {
-			log.Printf("expFn error: %v\n", err)
+			return err
+		}
+		atomic.StoreUint64(&r.live.cursor, next)
+		r.batches++
+		f.progress(ctx, r)
+		if f.onBatch != nil {
+			f.onBatch(next)
+		}
+
+		if next == 0 {
+			if f.Progress != nil {
+				f.Progress.Update(ctx, f.snapshot(r, true))
+			}
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// scan returns the keys of the batch at cursor matching ScanPrefix, or
+// ScanPatterns when set, and the next cursor.
+func (f *Scanner) scan(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+	if len(f.ScanPatterns) > 0 {
+		return scanPatterns(ctx, f.scanClient(), cursor, f.ScanPatterns, f.ScanCount, f.ScanType)
+	}
+	return f.scanClient().ScanType(ctx, cursor, f.ScanPrefix, f.ScanCount, f.ScanType).Result()
+}
+
+// scanPattern describes what the run scans, for notifications.
+func (f *Scanner) scanPattern() string {
+	if len(f.ScanPatterns) > 0 {
+		return strings.Join(f.ScanPatterns, ",")
+	}
+	return f.ScanPrefix
+}
+
+func (f *Scanner) progress(ctx context.Context, r *scanRun) {
+	if now := f.clock().Now(); (f.Notifier != nil || f.Progress != nil) && f.ProgressInterval > 0 && now.Sub(r.lastProgress) >= f.ProgressInterval {
+		if f.Notifier != nil {
+			f.Notifier.OnProgress(ctx, r.info, r.summary)
+		}
+		if f.Progress != nil {
+			f.Progress.Update(ctx, f.snapshot(r, false))
+		}
+		r.lastProgress = now
+	}
+}
+
+func (f *Scanner) applyBatch(ctx context.Context, r *scanRun, keys []string) error {
+	if err := f.loadTypes(ctx, r, keys); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		// g applies the mode to key within gr, the rule matching it in
+		// rules mode
+		g, gr := f, r
+		if r.rules != nil {
+			g, gr = r.rule(key, r.types[key])
+		}
+		if g == nil {
+			r.observeKey(key, 0, false, nil)
+			continue
+		}
+		desired, covered := g.desiredTTL(key, r.types[key])
+		if !covered || f.protected(key) || !f.sampled(key) {
+			r.observeKey(key, 0, false, nil)
 			continue
 		}
-		if ok {
-			log.Println(key, ok)
+		if err := f.throttle(ctx, r); err != nil {
+			return err
+		}
+		r.live.key.Store(key)
+		if ok, err := f.matches(ctx, r, key); !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := g.apply(ctx, gr, key, desired); err != nil {
+			return err
+		}
+		if err := g.applyCompanions(ctx, gr, key, desired); err != nil {
+			return err
+		}
+		f.inspect(ctx, r, key)
+	}
+	return nil
+}
+
+// throttle waits for Resume, the circuit breaker, the limiter, the jitter
+// and the memory guard before a key is processed, and pauses between chunks
+// after it.
+func (f *Scanner) throttle(ctx context.Context, r *scanRun) error {
+	if err := f.waitResumed(ctx); err != nil {
+		return err
+	}
+	if err := f.checkBreaker(ctx, r); err != nil {
+		return err
+	}
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+	if f.Jitter > 0 {
+		if err := f.clock().Sleep(ctx, rand.N(f.Jitter)); err != nil {
+			return err
 		}
 	}
+	if err := f.checkMemory(ctx, r.processed); err != nil {
+		return err
+	}
+	r.processed++
+	return f.pauseChunk(ctx, r.processed)
+}
+
+// pauseChunk pauses for ChunkPause once a chunk of ChunkSize keys has been
+// processed.
+func (f *Scanner) pauseChunk(ctx context.Context, processed int) error {
+	if f.ChunkSize <= 0 || f.ChunkPause <= 0 || processed%f.ChunkSize != 0 {
+		return nil
+	}
+	return f.clock().Sleep(ctx, f.ChunkPause)
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// apply runs fn against key. Errors are logged and counted, and only
+// returned when OnError or FailFast abort the run.
+func (f *Scanner) apply(ctx context.Context, r *scanRun, key string, desired time.Duration) error {
+	var expected time.Duration
+	verify := f.VerifyWrites && !f.DryRun
+	if verify {
+		expected, verify = f.expectedTTL(ctx, key, desired)
+	}
+
+	previous := time.Duration(noTTL)
+	if (f.Logger != nil || f.Projection != nil || f.ExpiryIndex != "" || f.ChangeStream != nil || f.tolerates()) && !f.DryRun {
+		previous, _ = f.Client.PTTL(ctx, key).Result()
+	}
+	if f.tolerates() && !f.DryRun && withinTolerance(previous, desired, f.Tolerance) {
+		r.observeKey(key, 0, false, nil)
+		r.summary.observeTolerated()
+		return nil
+	}
+
+	start := time.Now()
+	var ok bool
+	action, err := f.retry(ctx, key, func() (err error) {
+		ok, err = r.fn(ctx, key, desired).Result()
+		return err
+	})
+	elapsed := time.Since(start)
+	if f.Mode == "noop" {
+		// no command was sent
+		elapsed = 0
+	}
+	r.observeKey(key, elapsed, ok, err)
+
+	if err != nil {
+		log.Printf("expFn error: %v\n", err)
+		return stop(action, key, err)
+	}
+	if ok {
+		applied := f.appliedTTL(ctx, key, previous, desired)
+		if !f.DryRun {
+			// dry-runs project the keys themselves
+			f.Projection.observe(applied)
+		}
+		f.indexExpiry(ctx, key, applied)
+		f.streamChange(ctx, key, previous, applied)
+		f.logModified(ctx, key, previous, applied, elapsed)
+		if verify {
+			f.verifyWrite(ctx, key, expected, start)
+		}
+	}
+	return nil
+}
+
+// toleranceModes are the modes setting the desired TTL, which Tolerance
+// applies to.
+var toleranceModes = map[string]bool{"exp": true, "gt": true, "lt": true, "xx": true}
+
+func (f *Scanner) tolerates() bool {
+	return f.Tolerance > 0 && toleranceModes[f.Mode]
+}
+
+// withinTolerance reports whether a key whose TTL is cur, noTTL for none,
+// already is within tolerance of the desired TTL.
+func withinTolerance(cur, desired, tolerance time.Duration) bool {
+	if cur < 0 || desired <= 0 {
+		return false
+	}
+	return max(cur-desired, desired-cur) <= tolerance
+}
+
+// ttlKeepingModes are the modes modifying keys without changing their TTL:
+// ztrim trims their members and copy copies them elsewhere.
+var ttlKeepingModes = map[string]bool{"ztrim": true, "copy": true}
+
+// dryRuns are the dry-run counterparts of the modes without a keyPlan.
+func (f *Scanner) dryRuns() map[string]ttlFunc {
+	return map[string]ttlFunc{
+		"ztrim": f.ztrimDryRun,
+		"copy":  f.copyDryRun,
+	}
+}
+
+// appliedTTL returns the TTL the mode set on key, from its previous TTL and
+// the desired one.
+func (f *Scanner) appliedTTL(ctx context.Context, key string, previous, desired time.Duration) time.Duration {
+	if ttlKeepingModes[f.Mode] {
+		return previous
+	}
+	if p, found := f.plans()[f.Mode]; found {
+		applied, _ := p(ctx, key, previous, desired)
+		return applied
+	}
+	return desired
+}
+
+// logModified logs that key was modified in elapsed, from the previous TTL
+// to the applied one.
+func (f *Scanner) logModified(ctx context.Context, key string, previous, applied, elapsed time.Duration) {
+	if f.Logger == nil {
+		log.Println(key, true)
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("node", f.Node),
+		slog.String("key", key),
+		slog.String("mode", f.Mode),
+	}
+	// BatchScript and Transaction don't read the previous TTL
+	if previous != unknownTTL {
+		attrs = append(attrs, slog.Duration("previous_ttl", previous))
+	}
+	attrs = append(attrs, slog.Duration("applied_ttl", applied), slog.Duration("elapsed", elapsed))
+	f.Logger.LogAttrs(ctx, slog.LevelInfo, "ttl modified", attrs...)
+}
 
-	iterErr := iter.Err()
-	if iterErr != nil {
-		return fmt.Errorf("iter error: %w", iterErr)
+// applyCompanions applies the mode to the companion keys of key, with the
+// same desired TTL.
+func (f *Scanner) applyCompanions(ctx context.Context, r *scanRun, key string, desired time.Duration) error {
+	for _, companion := range f.companionKeys(key) {
+		if err := f.wait(ctx); err != nil {
+			return err
+		}
+		var ok bool
+		action, err := f.retry(ctx, companion, func() (err error) {
+			ok, err = r.fn(ctx, companion, desired).Result()
+			return err
+		})
+		r.summary.observeCompanion(ok, err)
+		if err != nil {
+			log.Printf("companion %s error: %v\n", companion, err)
+			if err := stop(action, companion, err); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// companionKeys returns the companion keys of key, but the protected ones.
+func (f *Scanner) companionKeys(key string) []string {
+	var keys []string
+	for _, tmpl := range f.Companions {
+		companion := strings.ReplaceAll(tmpl, keyPlaceholder, key)
+		if !f.protected(companion) {
+			keys = append(keys, companion)
+		}
+	}
+	return keys
+}
+
+func (f *Scanner) protected(key string) bool {
+	if f.ExpiryIndex != "" && key == f.ExpiryIndex {
+		return true
+	}
+	if f.ChangeStream != nil && key == f.ChangeStream.Stream {
+		return true
+	}
+	for _, pattern := range f.Protected {
+		if matchGlob(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampled reports whether key is part of the ApplyPercent sample.
+func (f *Scanner) sampled(key string) bool {
+	if f.ApplyPercent <= 0 || f.ApplyPercent >= 100 {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return float64(h.Sum64()%10000) < f.ApplyPercent*100
+}
+
+// introspected reports whether the commands inspecting a key are sent for
+// it, see IntrospectRate.
+func (f *Scanner) introspected() bool {
+	if f.IntrospectRate <= 0 || f.IntrospectRate >= 1 {
+		return true
+	}
+	return rand.Float64() < f.IntrospectRate
+}
+
+// inspect measures key for BigKeys and KeySizeEvery.
+func (f *Scanner) inspect(ctx context.Context, r *scanRun, key string) {
+	if (f.BigKeys == nil && f.KeySizeEvery <= 0) || !f.introspected() {
+		return
+	}
+	f.sampleSize(ctx, r, key)
+	if f.BigKeys == nil {
+		return
+	}
+	if err := f.BigKeys.inspect(ctx, f.scanClient(), key, f.ScanType); err != nil {
+		log.Printf("big key detection error for %s: %v\n", key, err)
+	}
+}
+
+// adjustTTL sets the TTL of keys that have one to the value computed from
+// their current TTL. Keys without a TTL, or for which next returns false,
+// are left untouched.
+func (f *Scanner) adjustTTL(next func(cur time.Duration) (time.Duration, bool)) ttlFunc {
+	return func(ctx context.Context, key string, _ time.Duration) *redis.BoolCmd {
+		cmd := redis.NewBoolCmd(ctx)
+		cur, err := f.Client.PTTL(ctx, key).Result()
+		if err != nil || cur <= 0 {
+			cmd.SetErr(err)
+			return cmd
+		}
+		ttl, ok := next(cur)
+		if !ok {
+			return cmd
+		}
+		// PEXPIRE with a value of 0 would delete the key
+		return f.Client.PExpire(ctx, key, max(ttl, time.Millisecond))
+	}
+}
+
+func (f *Scanner) percent(cur time.Duration) (time.Duration, bool) {
+	return time.Duration(float64(cur) * f.TTLPercent / 100), true
+}
+
+func (f *Scanner) extend(cur time.Duration) (time.Duration, bool) {
+	return cur + f.TTLDelta, true
+}
+
+func (f *Scanner) shrink(cur time.Duration) (time.Duration, bool) {
+	if cur <= f.TTLFloor {
+		return 0, false
+	}
+	return max(cur-f.TTLDelta, f.TTLFloor), true
+}
+
 func (s *Scanner) wait(ctx context.Context) error {
 	if s.Limiter != nil {
 		return s.Limiter.Wait(ctx)
 	}
 	return nil
 }
+
+func (s *Scanner) checkMemory(ctx context.Context, processed int) error {
+	g := s.MemoryGuard
+	if g == nil || g.CheckEvery <= 0 || processed%g.CheckEvery != 0 {
+		return nil
+	}
+	return g.wait(ctx, s.clock(), s.scanClient())
+}
+
+func (s *Scanner) scanClient() redis.Cmdable {
+	if s.ScanClient != nil {
+		return s.ScanClient
+	}
+	return s.Client
+}
@@ -1,9 +1,13 @@
 package redisttl
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -261,3 +265,540 @@ func TestLimitError(t *testing.T) {
 		t.Fatalf("expected error %v, got: %v", errLimit, err)
 	}
 }
+
+func TestPercentMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	_ = s.Set("fiz", "bar")
+	s.SetTTL("foo", time.Hour)
+	s.SetTTL("far", 2*time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	f := Scanner{
+		Mode:       "percent",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		TTLPercent: 50,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo": 30 * time.Minute,
+		"far": time.Hour,
+		"fiz": 0, // keys without a ttl are skipped
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+
+	f.TTLPercent = 300
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != 90*time.Minute {
+		t.Fatalf("ttl don't match for: foo, got:%v want: %v", ttl, 90*time.Minute)
+	}
+}
+
+func TestExtendMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("fiz", "bar")
+	s.SetTTL("foo", time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	f := Scanner{
+		Mode:       "extend",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		TTLDelta:   3 * time.Hour,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := s.TTL("foo"); ttl != 4*time.Hour {
+		t.Fatalf("ttl don't match for: foo, got:%v want: %v", ttl, 4*time.Hour)
+	}
+	if ttl := s.TTL("fiz"); ttl != 0 {
+		t.Fatalf("keys without a ttl should be skipped, got: %v", ttl)
+	}
+}
+
+func TestShrinkMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	_ = s.Set("fat", "bar")
+	_ = s.Set("fiz", "bar")
+	s.SetTTL("foo", 5*time.Hour)
+	s.SetTTL("far", 90*time.Minute)
+	s.SetTTL("fat", 30*time.Minute)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	f := Scanner{
+		Mode:       "shrink",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		TTLDelta:   2 * time.Hour,
+		TTLFloor:   time.Hour,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo": 3 * time.Hour,
+		"far": time.Hour,        // clamped to the floor
+		"fat": 30 * time.Minute, // already below the floor
+		"fiz": 0,                // keys without a ttl are skipped
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	errExpire := errors.New("expire call failed")
+	rdb.AddHook(&hook{
+		cmdName: "expire",
+		err:     errExpire,
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Summary:    summary,
+		FailFast:   true,
+	}
+	if err := f.Run(context.Background()); !errors.Is(err, errExpire) {
+		t.Fatalf("expected error %v, got: %v", errExpire, err)
+	}
+	if summary.Errors.Load() != 1 {
+		t.Fatalf("expected the run to stop at the first error, got: %s", summary)
+	}
+
+	f.FailFast = false
+	summary.Errors.Store(0)
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Errors.Load() != 2 {
+		t.Fatalf("expected every key to be attempted, got: %s", summary)
+	}
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 20; i++ {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+		_ = s.Set(fmt.Sprintf("zoo:%d", i), "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Summary:    summary,
+		Notifier:   Notifiers{},
+	}
+	zoo := *f
+	zoo.ScanPrefix = "zoo:*"
+	zoo.DesiredTTL = 2 * time.Hour
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for _, sc := range []*Scanner{f, f, &zoo, &zoo} {
+		wg.Add(1)
+		go func(sc *Scanner) {
+			defer wg.Done()
+			errs <- sc.Run(context.Background())
+		}(sc)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if f.ScanPrefix != "foo:*" || f.DesiredTTL != time.Hour {
+		t.Fatalf("Run should not modify the scanner: %+v", f)
+	}
+	if got := summary.Scanned.Load(); got != 80 {
+		t.Fatalf("expected 80 keys scanned, got: %d", got)
+	}
+	for i := 0; i < 20; i++ {
+		if ttl := s.TTL(fmt.Sprintf("foo:%d", i)); ttl != time.Hour {
+			t.Fatalf("ttl don't match for foo:%d, got: %v", i, ttl)
+		}
+		if ttl := s.TTL(fmt.Sprintf("zoo:%d", i)); ttl != 2*time.Hour {
+			t.Fatalf("ttl don't match for zoo:%d, got: %v", i, ttl)
+		}
+	}
+}
+
+func TestCompanions(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"job:1", "job:1:idx", "job:1:lock", "job:2", "other"} {
+		_ = s.Set(k, "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "nx",
+		ScanPrefix: "job:?",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Companions: []string{"{key}:idx", "{key}:lock"},
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"job:1":      time.Hour,
+		"job:1:idx":  time.Hour,
+		"job:1:lock": time.Hour,
+		"job:2":      time.Hour,
+		"other":      0,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	// job:2 has no companion keys
+	if summary.Scanned.Load() != 2 || summary.Modified.Load() != 2 || summary.Companions.Load() != 2 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+func TestProtected(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"job:1", "job:1:lock", "job:2", "locks:1", "schema:v1"} {
+		_ = s.Set(k, "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Companions: []string{"{key}:lock"},
+		Protected:  []string{"locks:*", "schema:*", "*:lock", "job:2"},
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"job:1":      time.Hour,
+		"job:1:lock": 0,
+		"job:2":      0,
+		"locks:1":    0,
+		"schema:v1":  0,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if summary.Scanned.Load() != 5 || summary.Modified.Load() != 1 || summary.Companions.Load() != 0 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+func TestApplyPercent(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 1000; i++ {
+		_ = s.Set(fmt.Sprintf("key:%d", i), "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	f := Scanner{
+		Mode:         "exp",
+		ScanPrefix:   "key:*",
+		Client:       rdb,
+		DesiredTTL:   time.Hour,
+		ApplyPercent: 10,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var modified []string
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if s.TTL(key) > 0 {
+			modified = append(modified, key)
+		}
+		if f.sampled(key) != (s.TTL(key) > 0) {
+			t.Fatalf("sampled(%s) doesn't match its ttl %v", key, s.TTL(key))
+		}
+	}
+	if len(modified) < 50 || len(modified) > 150 {
+		t.Fatalf("want about 100 keys modified, got %d", len(modified))
+	}
+
+	// a larger sample includes the smaller one
+	f.ApplyPercent = 20
+	for _, key := range modified {
+		if !f.sampled(key) {
+			t.Fatalf("%s sampled at 10%% but not at 20%%", key)
+		}
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("close", "bar")
+	s.SetTTL("close", 58*time.Minute)
+	_ = s.Set("far", "bar")
+	s.SetTTL("far", 10*time.Minute)
+	_ = s.Set("none", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Tolerance:  5 * time.Minute,
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"close": 58 * time.Minute,
+		"far":   time.Hour,
+		"none":  time.Hour,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if summary.Modified.Load() != 2 || summary.Tolerated.Load() != 1 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+// gate lets a key through for every value received.
+type gate chan struct{}
+
+func (g gate) Wait(ctx context.Context) error {
+	<-g
+	return nil
+}
+
+func TestStats(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 10; i++ {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	g := make(gate)
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Limiter:    g,
+	}
+	if stats := f.Stats(); stats != (ScanStats{}) {
+		t.Fatalf("expected no stats before the run, got %+v", stats)
+	}
+
+	done := make(chan error)
+	go func() { done <- f.Run(context.Background()) }()
+	for i := 0; i < 5; i++ {
+		g <- struct{}{}
+	}
+	deadline := time.Now().Add(time.Second)
+	for f.Stats().Scanned < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := f.Stats(); stats.Scanned != 5 || stats.Modified != 5 || stats.Errors != 0 || stats.Key == "" {
+		t.Fatalf("unexpected stats while running: %+v", stats)
+	}
+
+	close(g)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats := f.Stats(); stats != (ScanStats{Scanned: 10, Modified: 10}) {
+		t.Fatalf("unexpected stats once done: %+v", stats)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	s.SetTTL("far", time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	var buf bytes.Buffer
+	f := Scanner{
+		Mode:       "extend",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		TTLDelta:   time.Hour,
+		Node:       s.Addr(),
+		Logger:     slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// foo has no TTL to extend
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":          "ttl modified",
+		"node":         s.Addr(),
+		"key":          "far",
+		"mode":         "extend",
+		"previous_ttl": float64(time.Hour),
+		"applied_ttl":  float64(2 * time.Hour),
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Fatalf("want %s=%v, got %v in %v", k, v, record[k], record)
+		}
+	}
+	if _, found := record["elapsed"]; !found {
+		t.Fatalf("missing elapsed in %v", record)
+	}
+}
+
+func TestChunkPause(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 10; i++ {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		ChunkSize:  4,
+		ChunkPause: 20 * time.Millisecond,
+	}
+	start := time.Now()
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// after the 4th and the 8th key
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("want 2 pauses of 20ms, the run took %s", elapsed)
+	}
+	if stats := f.Stats(); stats.Modified != 10 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	f.ChunkPause = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := f.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want the pause interrupted by the context, got: %v", err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 5; i++ {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Jitter:     time.Hour,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// 5 delays up to an hour are all but certain to outlast the context
+	if err := f.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want the jitter interrupted by the context, got: %v", err)
+	}
+
+	f.Jitter = time.Millisecond
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := s.TTL("foo:4"); ttl != time.Hour {
+		t.Fatalf("want a ttl of 1h, got: %v", ttl)
+	}
+}
@@ -0,0 +1,122 @@
+package redisttl
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrefixStats holds the TTL statistics of the keys sharing a prefix.
+type PrefixStats struct {
+	Prefix  string
+	Keys    int64
+	WithTTL int64
+	MinTTL  time.Duration
+	MaxTTL  time.Duration
+	sumTTL  time.Duration
+}
+
+// AvgTTL returns the average TTL of the keys that have one.
+func (p PrefixStats) AvgTTL() time.Duration {
+	if p.WithTTL == 0 {
+		return 0
+	}
+	return p.sumTTL / time.Duration(p.WithTTL)
+}
+
+func (p *PrefixStats) observe(ttl time.Duration) {
+	p.Keys++
+	if ttl < 0 {
+		return
+	}
+	if p.WithTTL == 0 || ttl < p.MinTTL {
+		p.MinTTL = ttl
+	}
+	if ttl > p.MaxTTL {
+		p.MaxTTL = ttl
+	}
+	p.WithTTL++
+	p.sumTTL += ttl
+}
+
+// PrefixReport groups the keys matched in "report" mode by the path segment
+// following the literal part of the scan pattern, e.g. scanning "cache:*"
+// groups "cache:users:1" and "cache:users:2" under "cache:users:".
+// It is safe for concurrent use.
+type PrefixReport struct {
+	// Separator delimits path segments, ":" when empty.
+	Separator string
+
+	mu     sync.Mutex
+	groups map[string]*PrefixStats
+}
+
+func (r *PrefixReport) observe(prefix, key string, ttl time.Duration) {
+	if r == nil {
+		return
+	}
+	group := r.group(prefix, key)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groups == nil {
+		r.groups = map[string]*PrefixStats{}
+	}
+	stats, found := r.groups[group]
+	if !found {
+		stats = &PrefixStats{Prefix: group}
+		r.groups[group] = stats
+	}
+	stats.observe(ttl)
+}
+
+func (r *PrefixReport) group(prefix, key string) string {
+	sep := r.Separator
+	if sep == "" {
+		sep = ":"
+	}
+	rest := strings.TrimPrefix(key, prefix)
+	i := strings.Index(rest, sep)
+	if i < 0 {
+		return prefix
+	}
+	return key[:len(key)-len(rest)+i+len(sep)]
+}
+
+// Groups returns the statistics of every prefix, largest groups first.
+func (r *PrefixReport) Groups() []PrefixStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	groups := make([]PrefixStats, 0, len(r.groups))
+	for _, g := range r.groups {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Keys != groups[j].Keys {
+			return groups[i].Keys > groups[j].Keys
+		}
+		return groups[i].Prefix < groups[j].Prefix
+	})
+	return groups
+}
+
+// keyPrefix returns the literal prefix of the pattern key was scanned with:
+// the first of ScanPatterns matching it when set, ScanPrefix otherwise.
+func (f *Scanner) keyPrefix(key string) string {
+	for _, p := range f.ScanPatterns {
+		if matchGlob(p, key) {
+			return literalPrefix(p)
+		}
+	}
+	return literalPrefix(f.ScanPrefix)
+}
+
+// literalPrefix returns the part of a MATCH pattern preceding its first
+// glob metacharacter.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
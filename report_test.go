@@ -0,0 +1,92 @@
+package redisttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLiteralPrefix(t *testing.T) {
+	m := map[string]string{
+		"cache:*":     "cache:",
+		"cache:?:x":   "cache:",
+		"cache:[ab]*": "cache:",
+		"*":           "",
+		"exact":       "exact",
+	}
+	for pattern, want := range m {
+		if got := literalPrefix(pattern); got != want {
+			t.Fatalf("pattern %s want: %q got: %q", pattern, want, got)
+		}
+	}
+}
+
+func TestReportMode(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"cache:users:1", "cache:users:2", "cache:orders:1", "cache:flat"} {
+		_ = s.Set(k, "v")
+	}
+	s.SetTTL("cache:users:1", time.Hour)
+	s.SetTTL("cache:users:2", 3*time.Hour)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	report := &PrefixReport{}
+	f := Scanner{
+		Mode:       "report",
+		ScanPrefix: "cache:*",
+		Client:     rdb,
+		Report:     report,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	groups := report.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("want 3 groups, got: %+v", groups)
+	}
+	users := groups[0]
+	if users.Prefix != "cache:users:" || users.Keys != 2 || users.WithTTL != 2 {
+		t.Fatalf("unexpected users group: %+v", users)
+	}
+	if users.MinTTL != time.Hour || users.MaxTTL != 3*time.Hour || users.AvgTTL() != 2*time.Hour {
+		t.Fatalf("unexpected ttl stats: %+v avg=%s", users, users.AvgTTL())
+	}
+	if groups[1].Prefix != "cache:" || groups[2].Prefix != "cache:orders:" || groups[2].WithTTL != 0 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	if s.TTL("cache:flat") != 0 || s.TTL("cache:users:1") != time.Hour {
+		t.Fatal("report mode should not modify keys")
+	}
+}
+
+func TestReportModeScanPatterns(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"cache:users:1", "cache:users:2", "session:web:1"} {
+		_ = s.Set(k, "v")
+	}
+
+	report := &PrefixReport{}
+	f := Scanner{
+		Mode:         "report",
+		ScanPatterns: []string{"cache:*", "session:*"},
+		Client:       redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		Report:       report,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the keys are grouped after the prefix of the pattern matching them
+	groups := report.Groups()
+	if len(groups) != 2 || groups[0].Prefix != "cache:users:" || groups[0].Keys != 2 || groups[1].Prefix != "session:web:" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+}
@@ -0,0 +1,50 @@
+package redisttl
+
+import (
+	"context"
+	"time"
+)
+
+// Result is the outcome of a key processed by a run.
+type Result struct {
+	Node string
+	Key  string
+	// Modified is true when the mode changed the key.
+	Modified bool
+	// Err is the error of the command sent for Key, if any.
+	Err error
+	// Elapsed is how long the command sent for Key took, 0 when none was.
+	Elapsed time.Duration
+}
+
+// streamBuffer is the number of results a Stream buffers before the run
+// waits for them to be received.
+const streamBuffer = 100
+
+// Stream runs f like Run in a goroutine, sending the outcome of every key
+// processed on the first channel while the scan is in progress, errors
+// included. The channel is closed once the run returns, its error then
+// sent on the second channel. The run waits for the results to be
+// received, so they must be, until the channel is closed or ctx is done.
+func (f *Scanner) Stream(ctx context.Context) (<-chan Result, <-chan error) {
+	results := make(chan Result, streamBuffer)
+	errc := make(chan error, 1)
+	go func() {
+		err := f.start(ctx, results, 0)
+		close(results)
+		errc <- err
+		close(errc)
+	}()
+	return results, errc
+}
+
+// sendResult sends res to the results of the Stream of r, if any.
+func (r *scanRun) sendResult(res Result) {
+	if r.results == nil {
+		return
+	}
+	select {
+	case r.results <- res:
+	case <-r.done:
+	}
+}
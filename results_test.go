@@ -0,0 +1,86 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStream(t *testing.T) {
+	testCases := map[string]struct {
+		addrs int
+	}{
+		"standalone": {addrs: 1},
+		"cluster":    {addrs: 2},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+				_ = s.Set(key, "bar")
+			}
+			addrs := make([]string, tc.addrs)
+			for i := range addrs {
+				addrs[i] = s.Addr()
+			}
+			rdb := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: addrs})
+			defer rdb.Close()
+			rdb.AddHook(&keyHook{key: "foo:2", err: readOnlyError("NOPERM this user has no permissions to run the 'expire' command")})
+
+			f := Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo:*",
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+			}
+			results, errc := f.Stream(context.Background())
+			var modified, failed []string
+			for res := range results {
+				if res.Node != s.Addr() && tc.addrs > 1 {
+					t.Fatalf("want the node of every key, got %+v", res)
+				}
+				switch {
+				case res.Err != nil:
+					failed = append(failed, res.Key)
+				case res.Modified:
+					modified = append(modified, res.Key)
+				}
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			slices.Sort(modified)
+			if !slices.Equal(modified, []string{"foo:1", "foo:3"}) || !slices.Equal(failed, []string{"foo:2"}) {
+				t.Fatalf("want foo:1 and foo:3 modified and foo:2 failed, got %v and %v", modified, failed)
+			}
+		})
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := range 2 * streamBuffer {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+	}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		ScanCount:  10,
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL: time.Hour,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results, errc := f.Stream(ctx)
+	<-results
+	cancel()
+	// the run stops without the results being received
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want the run canceled, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RetryPolicy decides whether a SCAN batch or a command sent for a key which
+// failed is sent again.
+type RetryPolicy interface {
+	// ShouldRetry reports whether the command which failed with err on its
+	// attempt-th attempt, from 1, is sent again, and the delay to wait for
+	// before.
+	ShouldRetry(err error, attempt int) (time.Duration, bool)
+}
+
+// Backoff is a RetryPolicy retrying the timeouts and connection errors, see
+// ClassifyError, up to Retries times, waiting for Delay before the first
+// retry and twice as long before every next one, up to MaxDelay when set.
+// Errors reported by the server, such as WRONGTYPE, aren't retried: sending
+// the command again would fail the same way.
+type Backoff struct {
+	Retries  int
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy retries transient errors twice, after 100ms then 200ms.
+var DefaultRetryPolicy RetryPolicy = Backoff{Retries: 2, Delay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// ShouldRetry implements RetryPolicy.
+func (b Backoff) ShouldRetry(err error, attempt int) (time.Duration, bool) {
+	if attempt > b.Retries {
+		return 0, false
+	}
+	switch ClassifyError(err) {
+	case ErrorTimeout, ErrorConnection:
+	default:
+		return 0, false
+	}
+	delay := b.Delay
+	for i := 1; i < attempt && (b.MaxDelay <= 0 || delay < b.MaxDelay); i++ {
+		delay *= 2
+	}
+	if b.MaxDelay > 0 {
+		delay = min(delay, b.MaxDelay)
+	}
+	return delay, true
+}
+
+// retryAfter reports whether what failed with err on its attempt-th attempt
+// is sent again according to Retry, once its delay is over.
+func (f *Scanner) retryAfter(ctx context.Context, what string, err error, attempt int) (bool, error) {
+	if f.Retry == nil || err == nil {
+		return false, nil
+	}
+	delay, ok := f.Retry.ShouldRetry(err, attempt)
+	if !ok {
+		return false, nil
+	}
+	log.Printf("retrying %s in %v: %v\n", what, delay, err)
+	return true, f.clock().Sleep(ctx, delay)
+}
+
+// scanRetrying scans the batch at cursor, again as long as it fails and
+// Retry allows it.
+func (f *Scanner) scanRetrying(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+	for attempt := 1; ; attempt++ {
+		keys, next, err := f.scan(ctx, cursor)
+		retried, werr := f.retryAfter(ctx, "scan", err, attempt)
+		if werr != nil {
+			return nil, 0, werr
+		}
+		if !retried {
+			return keys, next, err
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Retries: 4, Delay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	testCases := map[string]struct {
+		err     error
+		attempt int
+		delay   time.Duration
+		retried bool
+	}{
+		"first retry":     {err: context.DeadlineExceeded, attempt: 1, delay: 100 * time.Millisecond, retried: true},
+		"doubles":         {err: context.DeadlineExceeded, attempt: 2, delay: 200 * time.Millisecond, retried: true},
+		"capped":          {err: context.DeadlineExceeded, attempt: 4, delay: 300 * time.Millisecond, retried: true},
+		"no retry left":   {err: context.DeadlineExceeded, attempt: 5},
+		"server error":    {err: readOnlyError("WRONGTYPE Operation against a key holding the wrong kind of value"), attempt: 1},
+		"connection lost": {err: redis.ErrClosed, attempt: 1, delay: 100 * time.Millisecond, retried: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			delay, retried := b.ShouldRetry(tc.err, tc.attempt)
+			if delay != tc.delay || retried != tc.retried {
+				t.Fatalf("want %v %v, got %v %v", tc.delay, tc.retried, delay, retried)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	testCases := map[string]struct {
+		cmd    string
+		fails  int
+		failed bool
+		errors int64
+	}{
+		"expire retried":      {cmd: "expire", fails: 2},
+		"expire out of retry": {cmd: "expire", fails: 3, errors: 1},
+		"scan retried":        {cmd: "scan", fails: 2},
+		"scan out of retry":   {cmd: "scan", fails: 3, failed: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("foo:1", "bar")
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			rdb.AddHook(&flakyHook{name: tc.cmd, err: context.DeadlineExceeded, fails: tc.fails})
+
+			summary := &Summary{}
+			f := Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo:*",
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+				Summary:    summary,
+				Retry:      Backoff{Retries: 2, Delay: time.Millisecond},
+			}
+			err := f.Run(context.Background())
+			if (err != nil) != tc.failed {
+				t.Fatalf("want failed %v, got %v", tc.failed, err)
+			}
+			if tc.failed && !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("want the error of the last attempt, got %v", err)
+			}
+			if n := summary.Errors.Load(); n != tc.errors {
+				t.Fatalf("want %d errors, got %d: %s", tc.errors, n, summary)
+			}
+			if expired := s.TTL("foo:1") == time.Hour; expired != (tc.errors == 0 && !tc.failed) {
+				t.Fatalf("unexpected ttl %v", s.TTL("foo:1"))
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TTLRule picks the TTL of the keys matching Pattern from the text captured
+// by its first group, e.g. with `^cache:(\w+):` the TTL of "cache:billing:1"
+// is TTLs["billing"].
+type TTLRule struct {
+	Pattern *regexp.Regexp
+	TTLs    map[string]time.Duration
+	// Default is the TTL of the matching keys whose captured text is not in
+	// TTLs. When 0, those keys are left untouched.
+	Default time.Duration
+}
+
+// ttl returns the TTL of key, and whether the rule covers it at all.
+func (r TTLRule) ttl(key string) (ttl time.Duration, matched, covered bool) {
+	m := r.Pattern.FindStringSubmatch(key)
+	if m == nil {
+		return 0, false, false
+	}
+	if len(m) > 1 {
+		if ttl, found := r.TTLs[m[1]]; found {
+			return ttl, true, true
+		}
+	}
+	return r.Default, true, r.Default > 0
+}
+
+// desiredTTL returns the TTL key, of type typ, is set to: the TTL picked by
+// the first of TTLRules matching key, otherwise the TTL of its type in
+// TypeTTLs, otherwise DesiredTTL. Keys no rule or type covers are left
+// untouched.
+func (f *Scanner) desiredTTL(key, typ string) (time.Duration, bool) {
+	if len(f.TTLRules) == 0 {
+		if len(f.TypeTTLs) > 0 {
+			ttl, found := f.TypeTTLs[typ]
+			return ttl, found
+		}
+		return f.DesiredTTL, true
+	}
+	for _, r := range f.TTLRules {
+		if ttl, matched, covered := r.ttl(key); matched {
+			return ttl, covered
+		}
+	}
+	return 0, false
+}
+
+// loadTypes sets r.types to the types of keys when TypeTTLs, Rules or the
+// Inventory of "noop" mode need them: ScanType when set, otherwise read with
+// a TYPE per key, pipelined.
+func (f *Scanner) loadTypes(ctx context.Context, r *scanRun, keys []string) error {
+	inventory := f.Inventory != nil && f.Mode == "noop"
+	if (len(f.TypeTTLs) == 0 || len(f.TTLRules) > 0) && !f.Rules.needTypes() && !inventory {
+		return nil
+	}
+	r.types = make(map[string]string, len(keys))
+	if f.ScanType != "" {
+		for _, key := range keys {
+			r.types[key] = f.ScanType
+		}
+		return nil
+	}
+	cmds, err := f.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range keys {
+			p.Type(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("type error: %w", err)
+	}
+	for i, key := range keys {
+		r.types[key] = cmds[i].(*redis.StatusCmd).Val()
+	}
+	return nil
+}
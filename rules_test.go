@@ -0,0 +1,129 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTTLRules(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"cache:billing:1", "cache:search:1", "cache:other:1", "session:1", "tmp:1"} {
+		_ = s.Set(k, "bar")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "*",
+		Client:     rdb,
+		DesiredTTL: time.Minute, // ignored with rules
+		TTLRules: []TTLRule{
+			{
+				Pattern: regexp.MustCompile(`^cache:(\w+):`),
+				TTLs:    map[string]time.Duration{"billing": 24 * time.Hour, "search": time.Hour},
+				Default: 6 * time.Hour,
+			},
+			{
+				Pattern: regexp.MustCompile(`^(session):`),
+				TTLs:    map[string]time.Duration{"session": 30 * time.Minute},
+			},
+		},
+		Summary: summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"cache:billing:1": 24 * time.Hour,
+		"cache:search:1":  time.Hour,
+		"cache:other:1":   6 * time.Hour,
+		"session:1":       30 * time.Minute,
+		"tmp:1":           0, // no rule covers it
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if summary.Scanned.Load() != 5 || summary.Modified.Load() != 4 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+func TestTTLRulesDryRun(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("cache:billing:1", "bar")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	var diff bytes.Buffer
+	f := Scanner{
+		Mode:       "nx",
+		ScanPrefix: "cache:*",
+		Client:     rdb,
+		TTLRules: []TTLRule{{
+			Pattern: regexp.MustCompile(`^cache:(\w+):`),
+			TTLs:    map[string]time.Duration{"billing": 24 * time.Hour},
+		}},
+		DryRun: true,
+		Diff:   &diff,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "cache:billing:1: no-ttl → 24h [nx would apply]\n"; !strings.Contains(diff.String(), want) {
+		t.Fatalf("missing line %q in:\n%s", want, diff.String())
+	}
+}
+
+func TestTypeTTLs(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo:string", "bar")
+	s.HSet("foo:hash", "f", "v")
+	_, _ = s.SetAdd("foo:set", "m")
+	_, _ = s.XAdd("foo:stream", "*", []string{"f", "v"})
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		Client:     rdb,
+		DesiredTTL: time.Minute,
+		TypeTTLs: map[string]time.Duration{
+			"string": 24 * time.Hour,
+			"hash":   7 * 24 * time.Hour,
+			"stream": 30 * 24 * time.Hour,
+		},
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo:string": 24 * time.Hour,
+		"foo:hash":   7 * 24 * time.Hour,
+		"foo:set":    0,
+		"foo:stream": 30 * 24 * time.Hour,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package redisttl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is returned by ParseRule and RuleSet.Validate.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Rule applies Mode, with TTL, to the keys matching Pattern in "rules"
+// mode, see Scanner.Rules.
+type Rule struct {
+	// Pattern is a glob-style pattern, like ScanPrefix.
+	Pattern string
+	// Type, when set, restricts the rule to the keys of this type, e.g.
+	// "hash", at the cost of reading the type of every key unless ScanType
+	// restricts them to one.
+	Type string
+	// Mode is any mode modifying keys, or noop to leave the keys matching
+	// Pattern untouched whatever the rules after it.
+	Mode string
+	// TTL is the desired TTL of the keys, as DesiredTTL is for Mode.
+	TTL time.Duration
+	// Priority orders the rules, the highest first. Rules of equal priority
+	// are tried in their order.
+	Priority int
+}
+
+// ParseRule parses a rule written as
+// "<pattern> <mode>[=<ttl>] [type=<type>] [priority=<n>]", e.g.
+// "session:* exp=1h" or "session:admin:* noop priority=10", the ttl being
+// parsed by time.ParseDuration. The rule is not validated.
+func ParseRule(s string) (Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return Rule{}, fmt.Errorf("rule %q isn't \"<pattern> <mode>[=<ttl>] [type=<type>] [priority=<n>]\": %w", s, ErrInvalidRule)
+	}
+	rule := Rule{Pattern: fields[0]}
+	mode, value, found := strings.Cut(fields[1], "=")
+	rule.Mode = mode
+	if found {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: invalid ttl %q: %w", s, value, ErrInvalidRule)
+		}
+		rule.TTL = ttl
+	}
+	for _, option := range fields[2:] {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "type":
+			rule.Type = value
+		case "priority":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return Rule{}, fmt.Errorf("rule %q: invalid priority %q: %w", s, value, ErrInvalidRule)
+			}
+			rule.Priority = n
+		default:
+			return Rule{}, fmt.Errorf("rule %q: unknown option %s: %w", s, name, ErrInvalidRule)
+		}
+	}
+	return rule, nil
+}
+
+// String formats r the way ParseRule parses it.
+func (r Rule) String() string {
+	s := r.Pattern + " " + r.Mode
+	if r.TTL != 0 {
+		s += "=" + r.TTL.String()
+	}
+	if r.Type != "" {
+		s += " type=" + r.Type
+	}
+	if r.Priority != 0 {
+		s += fmt.Sprintf(" priority=%d", r.Priority)
+	}
+	return s
+}
+
+// ruleModesWithTTL are the builtin modes setting the TTL of a rule, which
+// must then be set.
+var ruleModesWithTTL = map[string]bool{"exp": true, "gt": true, "lt": true, "nx": true, "xx": true, "ztrim": true, "purge": true}
+
+// Validate reports whether r can be applied: a pattern, a mode modifying
+// keys, builtin or registered, and a TTL for the modes setting it.
+func (r Rule) Validate() error {
+	// the readers only use their run once called
+	_, reads := (&Scanner{}).readers(nil)[r.Mode]
+	switch {
+	case r.Pattern == "":
+		return fmt.Errorf("rule %q: empty pattern: %w", r, ErrInvalidRule)
+	case reads || r.Mode == "rules" || !slices.Contains(Modes(), r.Mode):
+		return fmt.Errorf("rule %q: mode %s doesn't modify keys: %w", r, r.Mode, ErrInvalidRule)
+	case r.TTL < 0 || (r.TTL == 0 && ruleModesWithTTL[r.Mode]):
+		return fmt.Errorf("rule %q: mode %s needs a ttl greater than 0: %w", r, r.Mode, ErrInvalidRule)
+	}
+	return nil
+}
+
+func (r Rule) matches(key, typ string) bool {
+	return matchGlob(r.Pattern, key) && (r.Type == "" || r.Type == typ)
+}
+
+// RuleSet is the rules of "rules" mode, see Scanner.Rules. The zero value
+// is an empty set matching no key.
+type RuleSet []Rule
+
+// ParseRuleSet parses rules written one per line as ParseRule parses them.
+// Blank lines and lines starting with # are ignored. The rules are not
+// validated.
+func ParseRuleSet(s string) (RuleSet, error) {
+	var rules RuleSet
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, sc.Err()
+}
+
+// String formats rs the way ParseRuleSet parses it.
+func (rs RuleSet) String() string {
+	var b strings.Builder
+	for _, r := range rs {
+		b.WriteString(r.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Validate validates every rule of rs, see Rule.Validate.
+func (rs RuleSet) Validate() error {
+	var errs []error
+	for _, r := range rs {
+		errs = append(errs, r.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// Evaluate returns the rule applied to key: the first rule matching it by
+// priority. typ is the type of key, only compared to the Type of the rules
+// setting one. It reports false when no rule matches key, which is then
+// left untouched.
+func (rs RuleSet) Evaluate(key, typ string) (Rule, bool) {
+	for _, r := range rs.sorted() {
+		if r.matches(key, typ) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// sorted returns the rules of rs by priority.
+func (rs RuleSet) sorted() RuleSet {
+	rules := append(RuleSet(nil), rs...)
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority > rules[j].Priority })
+	return rules
+}
+
+// needTypes reports whether the rules filter keys by type.
+func (rs RuleSet) needTypes() bool {
+	for _, r := range rs {
+		if r.Type != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleRun is a rule with the copy of the Scanner applying it, whose Mode and
+// DesiredTTL are the ones of the rule, and its run.
+type ruleRun struct {
+	Rule
+	f *Scanner
+	r *scanRun
+}
+
+// ruleRuns returns the runs of f.Rules within r, by priority.
+func (f *Scanner) ruleRuns(r *scanRun) ([]ruleRun, error) {
+	if len(f.TTLRules) > 0 || len(f.TypeTTLs) > 0 {
+		return nil, fmt.Errorf("rules mode doesn't support ttl rules and type ttls: %w", errInvalidMode)
+	}
+	if err := f.Rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	rules := f.Rules.sorted()
+	runs := make([]ruleRun, 0, len(rules))
+	for _, rule := range rules {
+		g := *f
+		g.Mode, g.DesiredTTL = rule.Mode, rule.TTL
+		g.Rules, g.Notifier = nil, nil
+		gr, err := g.newRun()
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Pattern, err)
+		}
+		// the outcome of every rule is the one of the run
+		gr.summary, gr.namespaces, gr.live, gr.breaker = r.summary, r.namespaces, r.live, r.breaker
+		runs = append(runs, ruleRun{Rule: rule, f: &g, r: gr})
+	}
+	return runs, nil
+}
+
+// rule returns the Scanner and run applying the first rule matching key,
+// of type typ, nil when none does.
+func (r *scanRun) rule(key, typ string) (*Scanner, *scanRun) {
+	for _, rr := range r.rules {
+		if rr.matches(key, typ) {
+			return rr.f, rr.r
+		}
+	}
+	return nil, nil
+}
+
+// emulateRules emulates the mode of every rule the server is too old for.
+func (r *scanRun) emulateRules(ctx context.Context) error {
+	for _, rr := range r.rules {
+		if err := rr.f.emulate(ctx, rr.r); err != nil {
+			return fmt.Errorf("rule %s: %w", rr.Rule.Pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRules(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"session:1", "session:admin:1", "cache:1", "tmp:1"} {
+		_ = s.Set(k, "bar")
+	}
+	s.HSet("cache:2", "f", "v")
+	_ = s.Set("keep:1", "bar")
+	s.SetTTL("keep:1", time.Minute)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "rules",
+		ScanPrefix: "*",
+		Client:     rdb,
+		Rules: RuleSet{
+			{Pattern: "session:*", Mode: "exp", TTL: time.Hour},
+			// tried before the rule above despite being listed after it
+			{Pattern: "session:admin:*", Mode: "noop", Priority: 1},
+			{Pattern: "cache:*", Type: "hash", Mode: "exp", TTL: 24 * time.Hour},
+			{Pattern: "cache:*", Mode: "exp", TTL: 10 * time.Minute},
+			{Pattern: "keep:*", Mode: "persist"},
+		},
+		Summary: summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"session:1":       time.Hour,
+		"session:admin:1": 0,
+		"cache:1":         10 * time.Minute,
+		"cache:2":         24 * time.Hour,
+		"keep:1":          0,
+		"tmp:1":           0, // no rule matches it
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if summary.Scanned.Load() != 6 || summary.Modified.Load() != 4 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+func TestRulesInvalid(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	testCases := map[string]struct {
+		f   Scanner
+		err error
+	}{
+		"rules without rules mode": {
+			f: Scanner{
+				Mode:  "exp",
+				Rules: RuleSet{{Pattern: "*", Mode: "exp", TTL: time.Hour}},
+			},
+			err: errInvalidMode,
+		},
+		"rules mode without rules": {
+			f:   Scanner{Mode: "rules"},
+			err: errInvalidMode,
+		},
+		"invalid rule": {
+			f: Scanner{
+				Mode:  "rules",
+				Rules: RuleSet{{Pattern: "*", Mode: "audit"}},
+			},
+			err: ErrInvalidRule,
+		},
+		"batch script": {
+			f: Scanner{
+				Mode:        "rules",
+				Rules:       RuleSet{{Pattern: "*", Mode: "exp", TTL: time.Hour}},
+				BatchScript: true,
+			},
+			err: errInvalidMode,
+		},
+		"transaction": {
+			f: Scanner{
+				Mode:        "rules",
+				Rules:       RuleSet{{Pattern: "*", Mode: "exp", TTL: time.Hour}},
+				Transaction: true,
+			},
+			err: errInvalidMode,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			tc.f.Client = rdb
+			if err := tc.f.Run(context.Background()); !errors.Is(err, tc.err) {
+				t.Fatalf("want: %v got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestParseRuleSet(t *testing.T) {
+	rules, err := ParseRuleSet(`
+# sessions expire, but the admin ones
+session:* exp=1h
+session:admin:* noop priority=10
+
+cache:* exp=24h type=hash
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RuleSet{
+		{Pattern: "session:*", Mode: "exp", TTL: time.Hour},
+		{Pattern: "session:admin:*", Mode: "noop", Priority: 10},
+		{Pattern: "cache:*", Mode: "exp", TTL: 24 * time.Hour, Type: "hash"},
+	}
+	if !slices.Equal(rules, want) {
+		t.Fatalf("want: %v got: %v", want, rules)
+	}
+	if again, err := ParseRuleSet(rules.String()); err != nil || !slices.Equal(again, rules) {
+		t.Fatalf("rules differ after a round trip: %v %v", again, err)
+	}
+
+	for _, s := range []string{
+		"session:*", // missing mode
+		"session:* exp=soon",
+		"session:* exp=1h priority=high",
+		"session:* exp=1h prio=1",
+	} {
+		if _, err := ParseRule(s); !errors.Is(err, ErrInvalidRule) {
+			t.Fatalf("%q: want: %v got: %v", s, ErrInvalidRule, err)
+		}
+	}
+}
+
+func TestRuleSetValidate(t *testing.T) {
+	testCases := map[string]struct {
+		rule  Rule
+		valid bool
+	}{
+		"exp":            {rule: Rule{Pattern: "*", Mode: "exp", TTL: time.Hour}, valid: true},
+		"persist":        {rule: Rule{Pattern: "*", Mode: "persist"}, valid: true},
+		"noop":           {rule: Rule{Pattern: "*", Mode: "noop"}, valid: true},
+		"missing ttl":    {rule: Rule{Pattern: "*", Mode: "exp"}},
+		"negative ttl":   {rule: Rule{Pattern: "*", Mode: "persist", TTL: -time.Hour}},
+		"read-only mode": {rule: Rule{Pattern: "*", Mode: "report"}},
+		"rules mode":     {rule: Rule{Pattern: "*", Mode: "rules"}},
+		"unknown mode":   {rule: Rule{Pattern: "*", Mode: "nope"}},
+		"empty pattern":  {rule: Rule{Mode: "persist"}},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := RuleSet{{Pattern: "ok:*", Mode: "persist"}, tc.rule}.Validate()
+			if (err == nil) != tc.valid || (err != nil && !errors.Is(err, ErrInvalidRule)) {
+				t.Fatalf("want valid: %v got: %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestRuleSetEvaluate(t *testing.T) {
+	rules := RuleSet{
+		{Pattern: "session:*", Mode: "exp", TTL: time.Hour},
+		{Pattern: "session:admin:*", Mode: "noop", Priority: 1},
+		{Pattern: "cache:*", Mode: "exp", TTL: time.Minute, Type: "hash"},
+	}
+
+	testCases := map[string]struct {
+		key, typ string
+		want     string
+		found    bool
+	}{
+		"first match":       {key: "session:1", want: "exp", found: true},
+		"higher priority":   {key: "session:admin:1", want: "noop", found: true},
+		"type filter":       {key: "cache:1", typ: "hash", want: "exp", found: true},
+		"other type":        {key: "cache:1", typ: "string"},
+		"no matching rules": {key: "tmp:1"},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rule, found := rules.Evaluate(tc.key, tc.typ)
+			if found != tc.found || rule.Mode != tc.want {
+				t.Fatalf("want: %s %v got: %+v %v", tc.want, tc.found, rule, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var errInvalidJob = errors.New("invalid job")
+
+// Runner is what a Scheduler runs, e.g. a *Scanner, a *Follower or a
+// *Worker.
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// Scheduler runs jobs every interval, e.g. to reconcile the TTL of keyspaces
+// from within a service rather than the redis-ttl daemon. A job runs as soon
+// as the Scheduler starts, then every interval after the previous run
+// started, a run lasting longer than the interval delaying the next one
+// rather than overlapping it. The zero value is ready to use and safe for
+// concurrent use.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*scheduledJob
+	// ctx is the context of the runs, nil until Start.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// JobStatus is the state of a job of a Scheduler, see Scheduler.Jobs.
+type JobStatus struct {
+	Name     string
+	Interval time.Duration
+	Running  bool
+	Runs     int64
+	Failures int64
+	// LastStart, LastDuration and LastErr are the ones of the latest run,
+	// the one in progress aside.
+	LastStart    time.Time
+	LastDuration time.Duration
+	LastErr      error
+	// NextRun is when the next run starts, zero before Start and while
+	// running.
+	NextRun time.Time
+}
+
+type scheduledJob struct {
+	runner Runner
+	status JobStatus
+}
+
+// AddJob schedules r every interval under name, which must be unique.
+// Jobs added once the Scheduler started run right away.
+func (s *Scheduler) AddJob(name string, interval time.Duration, r Runner) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("job requires a name: %w", errInvalidJob)
+	case interval <= 0:
+		return fmt.Errorf("job %s: interval must be greater than 0, got %s: %w", name, interval, errInvalidJob)
+	case r == nil:
+		return fmt.Errorf("job %s requires a runner: %w", name, errInvalidJob)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.jobs[name]; found {
+		return fmt.Errorf("job %s already exists: %w", name, errInvalidJob)
+	}
+	if s.jobs == nil {
+		s.jobs = map[string]*scheduledJob{}
+	}
+	j := &scheduledJob{runner: r, status: JobStatus{Name: name, Interval: interval}}
+	s.jobs[name] = j
+	if s.ctx != nil {
+		s.start(j)
+	}
+	return nil
+}
+
+// Start runs the jobs until ctx is done or Stop is called. It returns right
+// away, the jobs running in their own goroutine.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx != nil {
+		return errors.New("scheduler already started")
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.start(j)
+	}
+	return nil
+}
+
+// Stop cancels the runs in progress and waits for them to return. A stopped
+// Scheduler can't be started again.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// Jobs returns the status of every job, sorted by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.status)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].Name < jobs[k].Name
+	})
+	return jobs
+}
+
+// start runs j in its own goroutine, s.mu being held.
+func (s *Scheduler) start(j *scheduledJob) {
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(j.status.Interval)
+		defer ticker.Stop()
+		for {
+			s.run(ctx, j)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) run(ctx context.Context, j *scheduledJob) {
+	start := time.Now()
+	s.mu.Lock()
+	j.status.Running, j.status.NextRun = true, time.Time{}
+	s.mu.Unlock()
+
+	err := j.runner.Run(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("job %s: run error: %v\n", j.status.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &j.status
+	st.Running = false
+	st.Runs++
+	if err != nil {
+		st.Failures++
+	}
+	st.LastStart, st.LastDuration, st.LastErr = start, time.Since(start), err
+	st.NextRun = start.Add(st.Interval)
+	if now := time.Now(); st.NextRun.Before(now) {
+		// the ticker dropped the ticks of a run lasting longer than the
+		// interval
+		st.NextRun = now
+	}
+}
@@ -0,0 +1,81 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingRunner struct {
+	runs atomic.Int64
+	err  error
+}
+
+func (r *countingRunner) Run(ctx context.Context) error {
+	r.runs.Add(1)
+	return r.err
+}
+
+func TestScheduler(t *testing.T) {
+	ok, failing := &countingRunner{}, &countingRunner{err: errors.New("connection refused")}
+	var s Scheduler
+	if err := s.AddJob("ok", 5*time.Millisecond, ok); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// added once started, it runs right away
+	if err := s.AddJob("failing", time.Hour, failing); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for (ok.runs.Load() < 3 || failing.runs.Load() < 1) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	s.Stop()
+
+	jobs := s.Jobs()
+	if len(jobs) != 2 || jobs[0].Name != "failing" || jobs[1].Name != "ok" {
+		t.Fatalf("want the jobs sorted by name, got %+v", jobs)
+	}
+	if f := jobs[0]; f.Runs != 1 || f.Failures != 1 || f.LastErr == nil || f.NextRun.Before(f.LastStart.Add(time.Hour)) {
+		t.Fatalf("unexpected status of the failing job: %+v", f)
+	}
+	if o := jobs[1]; o.Runs < 3 || o.Failures != 0 || o.LastErr != nil || o.Running {
+		t.Fatalf("unexpected status of the ok job: %+v", o)
+	}
+
+	runs := ok.runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if ok.runs.Load() != runs {
+		t.Fatal("want no run once stopped")
+	}
+}
+
+func TestSchedulerInvalidJobs(t *testing.T) {
+	var s Scheduler
+	if err := s.AddJob("ok", time.Minute, &countingRunner{}); err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		name     string
+		interval time.Duration
+		runner   Runner
+	}{
+		"no name":     {interval: time.Minute, runner: &countingRunner{}},
+		"no interval": {name: "a", runner: &countingRunner{}},
+		"no runner":   {name: "a", interval: time.Minute},
+		"duplicate":   {name: "ok", interval: time.Minute, runner: &countingRunner{}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := s.AddJob(tc.name, tc.interval, tc.runner); !errors.Is(err, errInvalidJob) {
+				t.Fatalf("want errInvalidJob, got %v", err)
+			}
+		})
+	}
+}
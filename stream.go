@@ -0,0 +1,60 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ChangeStream appends every key modified by a run to a Redis Stream, a
+// real-time and replayable feed of the TTL changes for other services.
+type ChangeStream struct {
+	// Client is the instance Stream lives on, e.g. a separate ops instance,
+	// or a cluster client routing it to its node.
+	Client redis.Cmdable
+	Stream string
+	// MaxLen, when greater than 0, trims the stream to about MaxLen entries.
+	MaxLen int64
+}
+
+// unknownTTL is the previous TTL of the keys modified by BatchScript and
+// Transaction, which don't read it.
+const unknownTTL = time.Duration(-3)
+
+// streamChange adds an entry for key to ChangeStream, when set, with the
+// node, the mode, and the TTL in milliseconds before and after, -1 for
+// none. The previous TTL is omitted when unknown.
+// Failures are logged, the key being modified anyway.
+func (f *Scanner) streamChange(ctx context.Context, key string, previous, applied time.Duration) {
+	s := f.ChangeStream
+	if s == nil || f.DryRun {
+		return
+	}
+	values := []any{
+		"key", key,
+		"node", f.Node,
+		"mode", f.Mode,
+		"ttl_ms", streamTTL(applied),
+	}
+	if previous != unknownTTL {
+		values = append(values, "previous_ttl_ms", streamTTL(previous))
+	}
+	err := s.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.Stream,
+		MaxLen: s.MaxLen,
+		Approx: s.MaxLen > 0,
+		Values: values,
+	}).Err()
+	if err != nil {
+		log.Printf("change stream error for %s: %v\n", key, err)
+	}
+}
+
+func streamTTL(ttl time.Duration) int64 {
+	if ttl < 0 {
+		return -1
+	}
+	return ttl.Milliseconds()
+}
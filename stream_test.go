@@ -0,0 +1,96 @@
+package redisttl
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestChangeStream(t *testing.T) {
+	tests := map[string]struct {
+		scanner Scanner
+		want    []map[string]any
+	}{
+		"exp": {
+			scanner: Scanner{Mode: "exp", DesiredTTL: time.Hour},
+			want: []map[string]any{
+				{"key": "user:1", "node": "a:6379", "mode": "exp", "ttl_ms": "3600000", "previous_ttl_ms": "-1"},
+				{"key": "user:2", "node": "a:6379", "mode": "exp", "ttl_ms": "3600000", "previous_ttl_ms": "86400000"},
+			},
+		},
+		"batch script": {
+			scanner: Scanner{Mode: "exp", DesiredTTL: time.Hour, BatchScript: true},
+			want: []map[string]any{
+				{"key": "user:1", "node": "a:6379", "mode": "exp", "ttl_ms": "3600000"},
+				{"key": "user:2", "node": "a:6379", "mode": "exp", "ttl_ms": "3600000"},
+			},
+		},
+		"persist": {
+			scanner: Scanner{Mode: "persist"},
+			want: []map[string]any{
+				{"key": "user:2", "node": "a:6379", "mode": "persist", "ttl_ms": "-1", "previous_ttl_ms": "86400000"},
+			},
+		},
+		"dry-run": {
+			scanner: Scanner{Mode: "exp", DesiredTTL: time.Hour, DryRun: true, Diff: io.Discard},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			ops := miniredis.RunT(t)
+			_ = s.Set("user:1", "bar")
+			_ = s.Set("user:2", "bar")
+			s.SetTTL("user:2", 24*time.Hour)
+
+			opsClient := redis.NewClient(&redis.Options{Addr: ops.Addr()})
+			f := tc.scanner
+			f.Client, f.ScanPrefix, f.Node = redis.NewClient(&redis.Options{Addr: s.Addr()}), "user:*", "a:6379"
+			f.ChangeStream = &ChangeStream{Client: opsClient, Stream: "ttl-changes"}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entries, err := opsClient.XRange(context.Background(), "ttl-changes", "-", "+").Result()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []map[string]any
+			for _, e := range entries {
+				got = append(got, e.Values)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want: %v got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestChangeStreamProtected(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("user:1", "bar")
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	_ = rdb.XAdd(context.Background(), &redis.XAddArgs{Stream: "user:changes", Values: []any{"key", "user:0"}}).Err()
+
+	f := Scanner{
+		Mode:         "exp",
+		ScanPrefix:   "user:*",
+		Client:       rdb,
+		DesiredTTL:   time.Hour,
+		ChangeStream: &ChangeStream{Client: rdb, Stream: "user:changes", MaxLen: 10},
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := s.TTL("user:changes"); ttl != 0 {
+		t.Fatalf("want the stream left without a ttl, got %v", ttl)
+	}
+	if n, _ := rdb.XLen(context.Background(), "user:changes").Result(); n != 2 {
+		t.Fatalf("want 2 entries, got %d", n)
+	}
+}
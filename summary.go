@@ -0,0 +1,220 @@
+package redisttl
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Summary accumulates the outcome of one or more runs.
+// It is safe for concurrent use.
+type Summary struct {
+	Scanned  atomic.Int64
+	Modified atomic.Int64
+	Errors   atomic.Int64
+	// Companions counts the companion keys modified, see Scanner.Companions.
+	Companions atomic.Int64
+	// Tolerated counts the keys left untouched because their TTL already was
+	// within Scanner.Tolerance of the desired one.
+	Tolerated atomic.Int64
+	// BreakerTrips counts the times Scanner.CircuitBreaker paused the run.
+	BreakerTrips atomic.Int64
+	// Failovers counts the times Scanner.Failover resumed the run on a new
+	// primary.
+	Failovers atomic.Int64
+	// ErrorClasses breaks Errors down by class, see ClassifyError.
+	ErrorClasses ErrorCounts
+	// ExpireLatency records the duration of every expire command.
+	ExpireLatency Histogram
+	// ScanLatency records the duration of every SCAN batch.
+	ScanLatency Histogram
+	// KeySizes records the size of the keys sampled by
+	// Scanner.KeySizeEvery.
+	KeySizes SizeHistogram
+}
+
+func (s *Summary) observeScan(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.ScanLatency.Observe(d)
+}
+
+func (s *Summary) observeBreakerTrip() {
+	if s == nil {
+		return
+	}
+	s.BreakerTrips.Add(1)
+}
+
+func (s *Summary) observeFailover() {
+	if s == nil {
+		return
+	}
+	s.Failovers.Add(1)
+}
+
+// observeKey records the outcome of the command sent for a key.
+// A zero duration means no command was sent.
+func (s *Summary) observeKey(d time.Duration, modified bool, err error) {
+	if s == nil {
+		return
+	}
+	s.Scanned.Add(1)
+	if d > 0 {
+		s.ExpireLatency.Observe(d)
+	}
+	switch {
+	case err != nil:
+		s.Errors.Add(1)
+		s.ErrorClasses.Add(err)
+	case modified:
+		s.Modified.Add(1)
+	}
+}
+
+func (s *Summary) observeTolerated() {
+	if s == nil {
+		return
+	}
+	s.Tolerated.Add(1)
+}
+
+// observeCompanion records the outcome of the command sent for a
+// companion key.
+func (s *Summary) observeCompanion(modified bool, err error) {
+	if s == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		s.Errors.Add(1)
+		s.ErrorClasses.Add(err)
+	case modified:
+		s.Companions.Add(1)
+	}
+}
+
+func (s *Summary) String() string {
+	str := fmt.Sprintf("scanned=%d modified=%d errors=%d expire_latency[%s] scan_latency[%s]",
+		s.Scanned.Load(), s.Modified.Load(), s.Errors.Load(), &s.ExpireLatency, &s.ScanLatency)
+	if n := s.Companions.Load(); n > 0 {
+		str += fmt.Sprintf(" companions=%d", n)
+	}
+	if n := s.Tolerated.Load(); n > 0 {
+		str += fmt.Sprintf(" tolerated=%d", n)
+	}
+	if n := s.BreakerTrips.Load(); n > 0 {
+		str += fmt.Sprintf(" breaker_trips=%d", n)
+	}
+	if n := s.Failovers.Load(); n > 0 {
+		str += fmt.Sprintf(" failovers=%d", n)
+	}
+	if s.KeySizes.Snapshot().Count > 0 {
+		str += " key_sizes[" + s.KeySizes.String() + "]"
+	}
+	if classes := s.ErrorClasses.String(); classes != "" {
+		str += " errors_by_class[" + classes + "]"
+	}
+	return str
+}
+
+const latencyBuckets = 16
+
+// latencyBounds are the upper bounds of the histogram buckets, from 50µs
+// doubling up to ~1.6s. Slower observations land in an overflow bucket.
+var latencyBounds = func() []time.Duration {
+	bounds := make([]time.Duration, latencyBuckets)
+	d := 50 * time.Microsecond
+	for i := range bounds {
+		bounds[i] = d
+		d *= 2
+	}
+	return bounds
+}()
+
+// Histogram is a latency histogram with exponentially sized buckets.
+// The zero value is ready to use and safe for concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	counts [latencyBuckets + 1]int64
+	count  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// Observe records a single latency.
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBounds) && d > latencyBounds[i] {
+		i++
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// HistogramSnapshot is a point in time copy of a Histogram.
+type HistogramSnapshot struct {
+	// Bounds are the bucket upper bounds; Counts has one extra trailing
+	// entry for observations greater than the last bound.
+	Bounds []time.Duration
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+	Max    time.Duration
+}
+
+// Snapshot returns a copy of the current state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return HistogramSnapshot{
+		Bounds: slices.Clone(latencyBounds),
+		Counts: append([]int64(nil), h.counts[:]...),
+		Count:  h.count,
+		Sum:    h.sum,
+		Max:    h.max,
+	}
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) by interpolating
+// linearly within the bucket it falls in.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(s.Count)))
+	var seen int64
+	for i, c := range s.Counts {
+		if seen+c < rank {
+			seen += c
+			continue
+		}
+		if i == len(s.Bounds) {
+			return s.Max
+		}
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = s.Bounds[i-1]
+		}
+		frac := float64(rank-seen) / float64(c)
+		return min(lower+time.Duration(frac*float64(s.Bounds[i]-lower)), s.Max)
+	}
+	return s.Max
+}
+
+func (h *Histogram) String() string {
+	s := h.Snapshot()
+	return fmt.Sprintf("count=%d p50=%s p95=%s p99=%s max=%s",
+		s.Count, s.Quantile(0.50), s.Quantile(0.95), s.Quantile(0.99), s.Max)
+}
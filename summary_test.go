@@ -0,0 +1,84 @@
+package redisttl
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	h := Histogram{}
+	if q := h.Snapshot().Quantile(0.5); q != 0 {
+		t.Fatalf("empty histogram should report 0, got: %v", q)
+	}
+
+	for i := 0; i < 98; i++ {
+		h.Observe(40 * time.Microsecond)
+	}
+	h.Observe(time.Millisecond)
+	h.Observe(10 * time.Second)
+
+	s := h.Snapshot()
+	if s.Count != 100 {
+		t.Fatalf("want: 100 got: %d", s.Count)
+	}
+	if p50 := s.Quantile(0.5); p50 > 50*time.Microsecond {
+		t.Fatalf("p50 should fall in the first bucket, got: %v", p50)
+	}
+	if p99 := s.Quantile(0.99); p99 <= 800*time.Microsecond || p99 > 1600*time.Microsecond {
+		t.Fatalf("p99 should fall in the 1ms bucket, got: %v", p99)
+	}
+	if max := s.Quantile(1); max != 10*time.Second {
+		t.Fatalf("overflow bucket should report the max, got: %v", max)
+	}
+
+	// snapshots don't share the bounds of every histogram
+	s.Bounds[0] = time.Hour
+	if b := h.Snapshot().Bounds[0]; b == time.Hour {
+		t.Fatalf("modifying a snapshot shouldn't modify the bounds, got: %v", b)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	_ = s.Set("far", "bar")
+	_ = s.Set("zoo", "bar")
+	s.SetTTL("far", time.Minute)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:       "nx",
+		ScanPrefix: "f*",
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Summary:    summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := summary.Scanned.Load(); got != 2 {
+		t.Fatalf("scanned want: 2 got: %d", got)
+	}
+	if got := summary.Modified.Load(); got != 1 {
+		t.Fatalf("modified want: 1 got: %d", got)
+	}
+	if got := summary.ExpireLatency.Snapshot().Count; got != 2 {
+		t.Fatalf("expire latency observations want: 2 got: %d", got)
+	}
+	if got := summary.ScanLatency.Snapshot().Count; got == 0 {
+		t.Fatal("expected at least one scan latency observation")
+	}
+	if !strings.Contains(summary.String(), "scanned=2 modified=1 errors=0") {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
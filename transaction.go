@@ -0,0 +1,175 @@
+package redisttl
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// txModes are the modes Transaction supports, the ones sending a single
+// command per key.
+var txModes = map[string]bool{
+	"exp":     true,
+	"gt":      true,
+	"lt":      true,
+	"nx":      true,
+	"xx":      true,
+	"persist": true,
+}
+
+// txFuncs returns the ttlFuncs of txModes queuing their command on c.
+func txFuncs(c redis.Cmdable) map[string]ttlFunc {
+	return map[string]ttlFunc{
+		"exp": c.Expire,
+		"gt":  c.ExpireGT,
+		"lt":  c.ExpireLT,
+		"nx":  c.ExpireNX,
+		"xx":  c.ExpireXX,
+		"persist": func(ctx context.Context, key string, _ time.Duration) *redis.BoolCmd {
+			return c.Persist(ctx, key)
+		},
+	}
+}
+
+// applyTx is applyBatch for Transaction.
+func (f *Scanner) applyTx(ctx context.Context, r *scanRun, keys []string) error {
+	if err := f.loadTypes(ctx, r, keys); err != nil {
+		return err
+	}
+	var batch scriptBatch
+	for _, key := range keys {
+		desired, covered := f.desiredTTL(key, r.types[key])
+		if !covered || f.protected(key) || !f.sampled(key) {
+			r.observeKey(key, 0, false, nil)
+			continue
+		}
+		if err := f.throttle(ctx, r); err != nil {
+			return err
+		}
+		for range f.companionKeys(key) {
+			if err := f.wait(ctx); err != nil {
+				return err
+			}
+		}
+		batch.keys = append(batch.keys, key)
+		batch.ttls = append(batch.ttls, desired)
+	}
+
+	if len(batch.keys) == 0 {
+		return nil
+	}
+	if err := f.runTx(ctx, r, batch); err != nil {
+		return err
+	}
+	for _, key := range batch.keys {
+		f.inspect(ctx, r, key)
+	}
+	return nil
+}
+
+// txCmd is a command queued in a transaction, for the key of a batch at
+// index key, or for one of its companions.
+type txCmd struct {
+	name      string
+	key       int
+	companion bool
+}
+
+// slotTxs groups the commands of b and of the companions of its keys in a
+// single transaction, or by hash slot on a cluster, a transaction only
+// accessing the keys of a single slot there.
+func (f *Scanner) slotTxs(b scriptBatch) [][]txCmd {
+	var txs [][]txCmd
+	slots := make(map[uint16]int)
+	cluster := f.cluster()
+	add := func(c txCmd) {
+		var slot uint16
+		if cluster {
+			slot = hashSlot(c.name)
+		}
+		n, found := slots[slot]
+		if !found {
+			n = len(txs)
+			slots[slot] = n
+			txs = append(txs, nil)
+		}
+		txs[n] = append(txs[n], c)
+	}
+	for i, key := range b.keys {
+		add(txCmd{name: key, key: i})
+		for _, companion := range f.companionKeys(key) {
+			add(txCmd{name: companion, key: i, companion: true})
+		}
+	}
+	return txs
+}
+
+// runTx sends the commands of b and of the companions of its keys within a
+// MULTI/EXEC, one per hash slot on a cluster, and records the outcome of
+// every key. When a transaction is discarded, e.g. because the connection was
+// lost before EXEC, every key it holds is recorded as failed and none was
+// modified.
+func (f *Scanner) runTx(ctx context.Context, r *scanRun, b scriptBatch) error {
+	r.live.key.Store(b.keys[0])
+	cmds := make([]*redis.BoolCmd, len(b.keys))
+	companions := make([][]*redis.BoolCmd, len(b.keys))
+
+	var txErr error
+	var failed []string
+	start := time.Now()
+	for _, tx := range f.slotTxs(b) {
+		_, err := f.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			fn := txFuncs(pipe)[f.Mode]
+			for _, c := range tx {
+				cmd := fn(ctx, c.name, b.ttls[c.key])
+				if c.companion {
+					companions[c.key] = append(companions[c.key], cmd)
+				} else {
+					cmds[c.key] = cmd
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("transaction error: %v\n", err)
+			if txErr == nil {
+				txErr = err
+			}
+			for _, c := range tx {
+				failed = append(failed, c.name)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	for i, key := range b.keys {
+		// the transactions of the batch are timed once
+		d := time.Duration(0)
+		if i == 0 {
+			d = elapsed
+		}
+		ok, keyErr := cmds[i].Result()
+		r.observeKey(key, d, ok, keyErr)
+		for _, cmd := range companions[i] {
+			r.summary.observeCompanion(cmd.Result())
+		}
+		if !ok || keyErr != nil {
+			continue
+		}
+		applied := b.ttls[i]
+		if f.Mode == "persist" {
+			applied = noTTL
+		}
+		f.Projection.observe(applied)
+		f.indexExpiry(ctx, key, applied)
+		f.streamChange(ctx, key, unknownTTL, applied)
+		f.logModified(ctx, key, unknownTTL, applied, d)
+	}
+	if txErr != nil {
+		return f.failFast(strings.Join(failed, ","), txErr)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// execHook fails every transaction the way go-redis does when EXEC can't be
+// sent, setting err on every queued command.
+type execHook struct {
+	err error
+}
+
+func (h *execHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *execHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *execHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(cmds) == 0 || cmds[0].Name() != "multi" {
+			return next(ctx, cmds)
+		}
+		for _, cmd := range cmds {
+			cmd.SetErr(h.err)
+		}
+		return h.err
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	testCases := map[string]struct {
+		mode     string
+		failExec bool
+		failFast bool
+		expected map[string]time.Duration
+		modified int64
+		errors   int64
+		err      bool
+	}{
+		"exp": {
+			mode:     "exp",
+			expected: map[string]time.Duration{"a": time.Hour, "a:meta": time.Hour, "short": time.Hour, "short:meta": time.Hour},
+			modified: 2,
+		},
+		"nx": {
+			mode:     "nx",
+			expected: map[string]time.Duration{"a": time.Hour, "a:meta": time.Hour, "short": time.Minute, "short:meta": time.Hour},
+			modified: 1,
+		},
+		"persist": {
+			mode:     "persist",
+			expected: map[string]time.Duration{"a": 0, "a:meta": 0, "short": 0, "short:meta": 0},
+			modified: 1,
+		},
+		"discarded transaction": {
+			mode:     "exp",
+			failExec: true,
+			expected: map[string]time.Duration{"a": 0, "a:meta": 0, "short": time.Minute, "short:meta": 0},
+			errors:   4,
+		},
+		"discarded transaction with fail fast": {
+			mode:     "exp",
+			failExec: true,
+			failFast: true,
+			expected: map[string]time.Duration{"a": 0, "a:meta": 0, "short": time.Minute, "short:meta": 0},
+			errors:   4,
+			err:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			if tc.failExec {
+				rdb.AddHook(&execHook{err: errors.New("connection lost")})
+			}
+			for _, key := range []string{"a", "a:meta", "short", "short:meta"} {
+				_ = s.Set(key, "bar")
+			}
+			s.SetTTL("short", time.Minute)
+
+			summary := &Summary{}
+			f := Scanner{
+				Mode:         tc.mode,
+				ScanPatterns: []string{"a", "short"},
+				Client:       rdb,
+				DesiredTTL:   time.Hour,
+				Companions:   []string{"{key}:meta"},
+				Transaction:  true,
+				FailFast:     tc.failFast,
+				Summary:      summary,
+			}
+			err := f.Run(context.Background())
+			if tc.err != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for k, dur := range tc.expected {
+				if ttl := s.TTL(k); ttl != dur {
+					t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+				}
+			}
+			if summary.Modified.Load() != tc.modified || summary.Errors.Load() != tc.errors {
+				t.Fatalf("unexpected summary: %s", summary)
+			}
+		})
+	}
+}
+
+// execCounter counts the transactions sent.
+type execCounter struct {
+	execs int
+}
+
+func (h *execCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *execCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *execCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(cmds) > 0 && cmds[0].Name() == "multi" {
+			h.execs++
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestTransactionPerBatch(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	h := &execCounter{}
+	rdb.AddHook(h)
+	for i := range 10 {
+		_ = s.Set(fmt.Sprintf("foo:%d", i), "bar")
+	}
+
+	f := Scanner{
+		Mode:        "exp",
+		ScanPrefix:  "foo:*",
+		ScanCount:   100,
+		Client:      rdb,
+		DesiredTTL:  time.Hour,
+		Companions:  []string{"{key}:meta"},
+		Transaction: true,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// miniredis returns every key in a single batch
+	if h.execs != 1 {
+		t.Fatalf("want a single transaction for the batch, got %d", h.execs)
+	}
+	for _, key := range s.Keys() {
+		if ttl := s.TTL(key); ttl != time.Hour {
+			t.Fatalf("want %s expiring in an hour, got %v", key, ttl)
+		}
+	}
+}
+
+func TestTransactionCluster(t *testing.T) {
+	s := miniredis.RunT(t)
+	// the client of a single node, as when scanning one primary of a cluster
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	rdb.AddHook(crossSlotHook{})
+	keys := []string{"{a}", "{a}:meta", "{b}", "{b}:meta", "c", "c:meta"}
+	for _, key := range keys {
+		_ = s.Set(key, "bar")
+	}
+
+	summary := &Summary{}
+	f := Scanner{
+		Mode:         "exp",
+		ScanPatterns: []string{"{a}", "{b}", "c"},
+		Client:       rdb,
+		DesiredTTL:   time.Hour,
+		Companions:   []string{"{key}:meta"},
+		Transaction:  true,
+		Cluster:      true,
+		FailFast:     true,
+		Summary:      summary,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, key := range keys {
+		if ttl := s.TTL(key); ttl != time.Hour {
+			t.Fatalf("want %s expiring in an hour, got %v", key, ttl)
+		}
+	}
+	if summary.Modified.Load() != 3 || summary.Errors.Load() != 0 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+func TestTransactionUnsupported(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	testCases := map[string]Scanner{
+		"mode":         {Mode: "percent", TTLPercent: 50},
+		"batch script": {Mode: "exp", DesiredTTL: time.Hour, BatchScript: true},
+		"rules":        {Mode: "rules", Rules: RuleSet{{Pattern: "*", Mode: "exp", TTL: time.Hour}}},
+		"tolerance":    {Mode: "exp", DesiredTTL: time.Hour, Tolerance: time.Minute},
+		"verify":       {Mode: "exp", DesiredTTL: time.Hour, VerifyWrites: true},
+	}
+	for name, f := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f.ScanPrefix, f.Client, f.Transaction = "*", rdb, true
+			if err := f.Run(context.Background()); !errors.Is(err, errInvalidMode) {
+				t.Fatalf("want errInvalidMode, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTransactionLogger(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	var buf bytes.Buffer
+	f := Scanner{
+		Mode:        "exp",
+		ScanPrefix:  "foo",
+		Client:      redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL:  time.Hour,
+		Transaction: true,
+		Logger:      slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single record, got %q: %v", buf.String(), err)
+	}
+	if record["key"] != "foo" || record["applied_ttl"] != float64(time.Hour) {
+		t.Fatalf("unexpected record: %v", record)
+	}
+	// the transaction doesn't read the previous TTL
+	if _, found := record["previous_ttl"]; found {
+		t.Fatalf("unexpected previous_ttl in %v", record)
+	}
+}
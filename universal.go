@@ -0,0 +1,86 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const nodePlaceholder = "{node}"
+
+// runPrimaries runs a copy of f against every primary of the cluster c at
+// once: SCAN only returns the keys of the node it's sent to, so each copy
+// scans its primary while the commands modifying keys go through Client,
+// following the slots as they move. The Notifier is told about the start
+// and completion of the whole run, and the copies share the Summary and
+// stats of f. It returns the errors of every primary, or the first one
+// with FailFast, which aborts the scan of the others.
+func (f *Scanner) runPrimaries(ctx context.Context, c *redis.ClusterClient, results chan<- Result) error {
+	if f.CursorFile != "" && !strings.Contains(f.CursorFile, nodePlaceholder) {
+		return fmt.Errorf("the cursor file of a cluster client needs a %s placeholder, got %s", nodePlaceholder, f.CursorFile)
+	}
+	summary := f.Summary
+	if summary == nil {
+		summary = &Summary{}
+	}
+	info := RunInfo{Mode: f.Mode, ScanPrefix: f.scanPattern(), StartedAt: f.clock().Now()}
+	if f.Notifier != nil {
+		f.Notifier.OnStart(ctx, info)
+	}
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var mu sync.Mutex
+	var errs []error
+	var failed error
+	err := c.ForEachMaster(scanCtx, func(ctx context.Context, node *redis.Client) error {
+		addr := node.Options().Addr
+		g := *f
+		g.ScanClient, g.Summary = node, summary
+		g.CursorFile = strings.ReplaceAll(f.CursorFile, nodePlaceholder, addr)
+		if f.Notifier != nil {
+			g.Notifier = progressNotifier{f.Notifier}
+		}
+		// Resolve returns the clients of a single node
+		g.Failover = nil
+		if g.Node == "" {
+			g.Node = addr
+		}
+		err := g.start(ctx, results, 0)
+		if err == nil {
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		err = fmt.Errorf("node %s: %w", addr, err)
+		if f.FailFast {
+			if failed == nil {
+				failed = err
+				cancel()
+			}
+			return failed
+		}
+		errs = append(errs, err)
+		return nil
+	})
+	err = errors.Join(append(errs, err)...)
+	if f.Notifier != nil {
+		f.Notifier.OnComplete(ctx, info, summary, err)
+	}
+	return err
+}
+
+// progressNotifier only tells Notifier about the progress of a primary, the
+// run being told about once by runPrimaries.
+type progressNotifier struct {
+	Notifier
+}
+
+func (progressNotifier) OnStart(context.Context, RunInfo) {}
+
+func (progressNotifier) OnComplete(context.Context, RunInfo, *Summary, error) {}
@@ -0,0 +1,206 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRunUniversal(t *testing.T) {
+	testCases := map[string]struct {
+		addrs      int
+		cursorFile string
+		failed     bool
+	}{
+		"standalone":              {addrs: 1},
+		"cluster":                 {addrs: 2},
+		"cluster cursor per node": {addrs: 2, cursorFile: "{node}.json"},
+		"cluster shared cursor":   {addrs: 2, cursorFile: "cursor.json", failed: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			for _, key := range []string{"foo:1", "foo:2", "foo:3"} {
+				_ = s.Set(key, "bar")
+			}
+			addrs := make([]string, tc.addrs)
+			for i := range addrs {
+				addrs[i] = s.Addr()
+			}
+			rdb := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: addrs})
+			defer rdb.Close()
+
+			f := Scanner{
+				Mode:       "exp",
+				ScanPrefix: "foo:*",
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+			}
+			if tc.cursorFile != "" {
+				f.CursorFile = filepath.Join(t.TempDir(), tc.cursorFile)
+			}
+			err := f.Run(context.Background())
+			if (err != nil) != tc.failed {
+				t.Fatalf("want failed %v, got %v", tc.failed, err)
+			}
+			if tc.failed {
+				return
+			}
+			for _, key := range s.Keys() {
+				if ttl := s.TTL(key); ttl != time.Hour {
+					t.Fatalf("want %s expired, got %v", key, ttl)
+				}
+			}
+			if st := f.Stats(); st.Scanned != 3 || st.Modified != 3 {
+				t.Fatalf("want every key counted, got %+v", st)
+			}
+		})
+	}
+}
+
+// holdHook holds the SCAN commands until release is closed or their
+// context is done.
+type holdHook struct {
+	release <-chan struct{}
+}
+
+func (h holdHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h holdHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "scan" {
+			select {
+			case <-h.release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h holdHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// twoPrimaries returns the client of a cluster of two primaries holding 20
+// keys, the number of keys of each, and the channel releasing the SCAN
+// commands of the second one. hooks, when set, are added to the first one.
+func twoPrimaries(t *testing.T, hooks ...redis.Hook) (*redis.ClusterClient, *miniredis.Miniredis, *miniredis.Miniredis, map[*miniredis.Miniredis]int, chan struct{}) {
+	first, second := miniredis.RunT(t), miniredis.RunT(t)
+	keys := map[*miniredis.Miniredis]int{}
+	for i := range 20 {
+		key := fmt.Sprintf("foo:%d", i)
+		s := first
+		if hashSlot(key) >= ClusterSlots/2 {
+			s = second
+		}
+		_ = s.Set(key, "bar")
+		keys[s]++
+	}
+
+	release := make(chan struct{})
+	rdb := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: ClusterSlots/2 - 1, Nodes: []redis.ClusterNode{{Addr: first.Addr()}}},
+				{Start: ClusterSlots / 2, End: ClusterSlots - 1, Nodes: []redis.ClusterNode{{Addr: second.Addr()}}},
+			}, nil
+		},
+		// the second primary only scans once released
+		NewClient: func(opt *redis.Options) *redis.Client {
+			c := redis.NewClient(opt)
+			switch opt.Addr {
+			case first.Addr():
+				for _, h := range hooks {
+					c.AddHook(h)
+				}
+			case second.Addr():
+				c.AddHook(holdHook{release: release})
+			}
+			return c
+		},
+	})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, first, second, keys, release
+}
+
+func TestRunPrimaries(t *testing.T) {
+	rdb, first, second, keys, release := twoPrimaries(t)
+	n := &recordingNotifier{}
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		ScanCount:  1,
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Notifier:   n,
+	}
+	done := make(chan error, 1)
+	go func() { done <- f.Run(context.Background()) }()
+
+	deadline := time.Now().Add(time.Second)
+	for f.Stats().Scanned < int64(keys[first]) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := f.Stats(); st.Scanned != int64(keys[first]) {
+		t.Fatalf("want the keys of the first primary counted while the second one waits, got %+v", st)
+	}
+	close(release)
+	// a copy made while the second primary runs shares the stats of f
+	g := *f
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range []*miniredis.Miniredis{first, second} {
+		for _, key := range s.Keys() {
+			if ttl := s.TTL(key); ttl != time.Hour {
+				t.Fatalf("want %s expired, got %v", key, ttl)
+			}
+		}
+	}
+	if st := f.Stats(); st.Scanned != 20 || st.Modified != 20 || g.Stats() != st {
+		t.Fatalf("want every key counted, got %+v and %+v for the copy", st, g.Stats())
+	}
+	if want := []string{"start", "complete"}; !slices.Equal(n.events, want) {
+		t.Fatalf("want the run notified once, got %v", n.events)
+	}
+}
+
+func TestRunPrimariesFailFast(t *testing.T) {
+	errNoPerm := readOnlyError("NOPERM this user has no permissions to run the 'expire' command")
+	rdb, _, second, _, _ := twoPrimaries(t, &flakyHook{name: "expire", err: errNoPerm, fails: 1})
+	n := &recordingNotifier{}
+	f := Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		ScanCount:  1,
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		FailFast:   true,
+		Notifier:   n,
+	}
+	// the second primary is never released, the error of the first one
+	// aborts its scan
+	err := f.Run(context.Background())
+	if !errors.Is(err, errNoPerm) || errors.Is(err, context.Canceled) {
+		t.Fatalf("want only the error of the first primary, got %v", err)
+	}
+	for _, key := range second.Keys() {
+		if ttl := second.TTL(key); ttl != 0 {
+			t.Fatalf("want %s untouched, got %v", key, ttl)
+		}
+	}
+	if want := []string{"start", "complete"}; !slices.Equal(n.events, want) || !errors.Is(n.err, errNoPerm) {
+		t.Fatalf("want the run notified once, got %v with %v", n.events, n.err)
+	}
+}
@@ -0,0 +1,90 @@
+package redisttl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnsupportedMode is returned by Scanner.Preflight when the server is too
+// old for the commands the mode sends.
+var ErrUnsupportedMode = errors.New("mode not supported by the server")
+
+// ServerVersion is the major.minor version of a Redis server.
+type ServerVersion struct {
+	Major int
+	Minor int
+}
+
+func (v ServerVersion) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+func (v ServerVersion) less(o ServerVersion) bool {
+	return v.Major < o.Major || (v.Major == o.Major && v.Minor < o.Minor)
+}
+
+// modeVersions are the oldest servers supporting the commands of the modes
+// that need more than EXPIRE, PEXPIRE, PTTL and PERSIST.
+var modeVersions = map[string]ServerVersion{
+	// EXPIRE options
+	"gt": {7, 0},
+	"lt": {7, 0},
+	"nx": {7, 0},
+	"xx": {7, 0},
+}
+
+// ReadServerVersion reads the version the server reports in INFO.
+func ReadServerVersion(ctx context.Context, c redis.Cmdable) (ServerVersion, error) {
+	raw, err := c.Info(ctx, "server").Result()
+	if err != nil {
+		return ServerVersion{}, err
+	}
+	return parseServerVersion(raw)
+}
+
+func parseServerVersion(raw string) (ServerVersion, error) {
+	sc := bufio.NewScanner(strings.NewReader(raw))
+	for sc.Scan() {
+		value, found := strings.CutPrefix(strings.TrimSpace(sc.Text()), "redis_version:")
+		if !found {
+			continue
+		}
+		major, rest, _ := strings.Cut(value, ".")
+		minor, _, _ := strings.Cut(rest, ".")
+		var v ServerVersion
+		var err error
+		if v.Major, err = strconv.Atoi(major); err != nil {
+			return ServerVersion{}, fmt.Errorf("invalid redis_version %s: %w", value, err)
+		}
+		if v.Minor, err = strconv.Atoi(minor); err != nil {
+			return ServerVersion{}, fmt.Errorf("invalid redis_version %s: %w", value, err)
+		}
+		return v, nil
+	}
+	return ServerVersion{}, errors.New("redis_version not found")
+}
+
+// checkVersion rejects the modes the server is too old for, unless they are
+// emulated. Servers whose version can't be read are given the benefit of
+// the doubt.
+func (f *Scanner) checkVersion(ctx context.Context) error {
+	want, found := modeVersions[f.Mode]
+	// transactions don't emulate the modes
+	if !found || f.DryRun || (emulatedModes[f.Mode] && !f.NoEmulation && !f.Transaction) {
+		return nil
+	}
+	v, err := ReadServerVersion(ctx, f.Client)
+	if err != nil {
+		return nil
+	}
+	if v.less(want) {
+		return fmt.Errorf("mode %s needs Redis %s or later, server runs %s: %w", f.Mode, want, v, ErrUnsupportedMode)
+	}
+	return nil
+}
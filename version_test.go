@@ -0,0 +1,77 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	testCases := []struct {
+		raw     string
+		version ServerVersion
+		err     bool
+	}{
+		{raw: "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n", version: ServerVersion{7, 2}},
+		{raw: "redis_version:6.0.20\r\n", version: ServerVersion{6, 0}},
+		{raw: "redis_version:255.255.255\r\n", version: ServerVersion{255, 255}},
+		{raw: "redis_version:seven\r\n", err: true},
+		{raw: "redis_mode:standalone\r\n", err: true},
+	}
+	for _, tc := range testCases {
+		v, err := parseServerVersion(tc.raw)
+		if (err != nil) != tc.err || v != tc.version {
+			t.Fatalf("%q: want %s (error %v), got %s (%v)", tc.raw, tc.version, tc.err, v, err)
+		}
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+
+	testCases := []struct {
+		mode        string
+		version     string
+		dryRun      bool
+		noEmulation bool
+		transaction bool
+		err         error
+	}{
+		{mode: "gt", version: "6.2.14", noEmulation: true, err: ErrUnsupportedMode},
+		{mode: "nx", version: "6.2.14", noEmulation: true, err: ErrUnsupportedMode},
+		{mode: "gt", version: "6.2.14", dryRun: true, noEmulation: true},
+		// emulated
+		{mode: "gt", version: "6.2.14"},
+		{mode: "gt", version: "7.0.0"},
+		{mode: "exp", version: "6.2.14"},
+		// transactions queue the native commands
+		{mode: "gt", version: "6.2.14", transaction: true, err: ErrUnsupportedMode},
+		{mode: "gt", version: "7.0.0", transaction: true},
+		// unreadable versions don't block the run
+		{mode: "gt", version: "unknown"},
+	}
+	for _, tc := range testCases {
+		rdb := redis.NewClient(&redis.Options{
+			Addr: s.Addr(),
+		})
+		rdb.AddHook(&infoHook{replies: []string{"redis_version:" + tc.version + "\r\n"}})
+		f := Scanner{
+			Mode:            tc.mode,
+			ScanPrefix:      "f*",
+			Client:          rdb,
+			DesiredTTL:      time.Hour,
+			DryRun:          tc.dryRun,
+			NoEmulation:     tc.noEmulation,
+			Transaction:     tc.transaction,
+			PreflightChecks: true,
+		}
+		if err := f.Run(context.Background()); !errors.Is(err, tc.err) {
+			t.Fatalf("mode %s on %s: want error %v, got %v", tc.mode, tc.version, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Webhook payload formats.
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+)
+
+// WebhookNotifier posts a JSON summary to URL once a run completes,
+// whether it succeeded, failed or was aborted.
+type WebhookNotifier struct {
+	URL string
+	// Format is either FormatJSON (the default) or FormatSlack.
+	Format string
+	// Nodes also posts a NodeWebhookPayload as each node of a cluster run
+	// finishes.
+	Nodes bool
+	// Client defaults to an http.Client with a 10s timeout.
+	Client *http.Client
+}
+
+type latencyPayload struct {
+	Count int64  `json:"count"`
+	P50   string `json:"p50"`
+	P95   string `json:"p95"`
+	P99   string `json:"p99"`
+	Max   string `json:"max"`
+}
+
+func newLatencyPayload(h *Histogram) latencyPayload {
+	s := h.Snapshot()
+	return latencyPayload{
+		Count: s.Count,
+		P50:   s.Quantile(0.50).String(),
+		P95:   s.Quantile(0.95).String(),
+		P99:   s.Quantile(0.99).String(),
+		Max:   s.Max.String(),
+	}
+}
+
+// WebhookPayload is the JSON document posted by a WebhookNotifier.
+type WebhookPayload struct {
+	Status        string           `json:"status"`
+	Error         string           `json:"error,omitempty"`
+	Mode          string           `json:"mode"`
+	ScanPrefix    string           `json:"scan_prefix"`
+	StartedAt     time.Time        `json:"started_at"`
+	FinishedAt    time.Time        `json:"finished_at"`
+	Duration      string           `json:"duration"`
+	Scanned       int64            `json:"scanned"`
+	Modified      int64            `json:"modified"`
+	Errors        int64            `json:"errors"`
+	ErrorsByClass map[string]int64 `json:"errors_by_class,omitempty"`
+	ExpireLatency latencyPayload   `json:"expire_latency"`
+	ScanLatency   latencyPayload   `json:"scan_latency"`
+}
+
+func newWebhookPayload(run RunInfo, s *Summary, err error) WebhookPayload {
+	finished := time.Now()
+	p := WebhookPayload{
+		Status:        RunStatus(err),
+		Mode:          run.Mode,
+		ScanPrefix:    run.ScanPrefix,
+		StartedAt:     run.StartedAt,
+		FinishedAt:    finished,
+		Duration:      finished.Sub(run.StartedAt).String(),
+		Scanned:       s.Scanned.Load(),
+		Modified:      s.Modified.Load(),
+		Errors:        s.Errors.Load(),
+		ErrorsByClass: s.ErrorClasses.Snapshot(),
+		ExpireLatency: newLatencyPayload(&s.ExpireLatency),
+		ScanLatency:   newLatencyPayload(&s.ScanLatency),
+	}
+	if err != nil {
+		p.Error = err.Error()
+	}
+	return p
+}
+
+// slackText formats p as a Slack compatible message.
+func (p WebhookPayload) slackText() string {
+	text := fmt.Sprintf("redis-ttl %s: mode=%s prefix=%s scanned=%d modified=%d errors=%d duration=%s",
+		p.Status, p.Mode, p.ScanPrefix, p.Scanned, p.Modified, p.Errors, p.Duration)
+	if len(p.ErrorsByClass) > 0 {
+		text += fmt.Sprintf(" errors_by_class=%v", p.ErrorsByClass)
+	}
+	if p.Error != "" {
+		text += "\nerror: " + p.Error
+	}
+	return text
+}
+
+func (w *WebhookNotifier) OnStart(context.Context, RunInfo) {}
+
+func (w *WebhookNotifier) OnProgress(context.Context, RunInfo, *Summary) {}
+
+func (w *WebhookNotifier) OnComplete(ctx context.Context, run RunInfo, s *Summary, err error) {
+	// an aborted run still has to be reported
+	ctx = context.WithoutCancel(ctx)
+	if perr := w.post(ctx, newWebhookPayload(run, s, err)); perr != nil {
+		log.Printf("webhook error: %v\n", perr)
+	}
+}
+
+func (w *WebhookNotifier) OnNodeStart(context.Context, RunInfo, string) {}
+
+func (w *WebhookNotifier) OnNodeFinish(ctx context.Context, run RunInfo, node NodeResult, err error) {
+	if !w.Nodes {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if perr := w.post(ctx, newNodeWebhookPayload(run, node, err)); perr != nil {
+		log.Printf("webhook error: %v\n", perr)
+	}
+}
+
+// NodeWebhookPayload is the JSON document posted by a WebhookNotifier for
+// every node of a cluster run when Nodes is set.
+type NodeWebhookPayload struct {
+	Event      string `json:"event"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Mode       string `json:"mode"`
+	ScanPrefix string `json:"scan_prefix"`
+	Node       string `json:"node"`
+	Duration   string `json:"duration"`
+	Scanned    int64  `json:"scanned"`
+	Modified   int64  `json:"modified"`
+	Errors     int64  `json:"errors"`
+}
+
+func newNodeWebhookPayload(run RunInfo, node NodeResult, err error) NodeWebhookPayload {
+	p := NodeWebhookPayload{
+		Event:      "node_finish",
+		Status:     RunStatus(err),
+		Mode:       run.Mode,
+		ScanPrefix: run.ScanPrefix,
+		Node:       node.Addr,
+		Duration:   node.Duration.String(),
+		Scanned:    node.Stats.Scanned,
+		Modified:   node.Stats.Modified,
+		Errors:     node.Stats.Errors,
+	}
+	if err != nil {
+		p.Error = err.Error()
+	}
+	return p
+}
+
+func (p NodeWebhookPayload) slackText() string {
+	text := fmt.Sprintf("redis-ttl node %s %s: mode=%s prefix=%s scanned=%d modified=%d errors=%d duration=%s",
+		p.Node, p.Status, p.Mode, p.ScanPrefix, p.Scanned, p.Modified, p.Errors, p.Duration)
+	if p.Error != "" {
+		text += "\nerror: " + p.Error
+	}
+	return text
+}
+
+// slackTexter is implemented by the payloads posted by a WebhookNotifier.
+type slackTexter interface {
+	slackText() string
+}
+
+func (w *WebhookNotifier) post(ctx context.Context, p slackTexter) error {
+	var payload any = p
+	if w.Format == FormatSlack {
+		payload = map[string]string{"text": p.slackText()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post %s: unexpected status %s", w.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package redisttl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	s := &Summary{}
+	s.Scanned.Add(2)
+	s.ErrorClasses.Add(context.DeadlineExceeded)
+	info := RunInfo{Mode: "exp", ScanPrefix: "f*", StartedAt: time.Now()}
+
+	w := &WebhookNotifier{URL: srv.URL}
+	w.OnComplete(context.Background(), info, s, errors.New("boom"))
+	classes, _ := got["errors_by_class"].(map[string]any)
+	if got["status"] != StatusFailed || got["error"] != "boom" || got["scanned"] != float64(2) || classes[ErrorTimeout] != float64(1) {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+
+	w.Format = FormatSlack
+	w.OnComplete(context.Background(), info, s, nil)
+	text, _ := got["text"].(string)
+	if !strings.HasPrefix(text, "redis-ttl succeeded: mode=exp prefix=f* scanned=2") {
+		t.Fatalf("unexpected slack payload: %v", got)
+	}
+}
+
+func TestWebhookNotifierAbortedRun(t *testing.T) {
+	var p WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&p)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w := &WebhookNotifier{URL: srv.URL}
+	w.OnComplete(ctx, RunInfo{}, &Summary{}, ctx.Err())
+	if p.Status != StatusAborted {
+		t.Fatalf("want: %s got: %s", StatusAborted, p.Status)
+	}
+}
+
+func TestWebhookNonSuccessStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	w := &WebhookNotifier{URL: srv.URL}
+	if err := w.post(context.Background(), WebhookPayload{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestWebhookNotifierNodes(t *testing.T) {
+	var got []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		got = append(got, p)
+	}))
+	defer srv.Close()
+
+	info := RunInfo{Mode: "exp", ScanPrefix: "f*", StartedAt: time.Now()}
+	node := NodeResult{Addr: "a:6379", Duration: time.Second, Stats: ScanStats{Scanned: 3, Modified: 2}}
+
+	w := &WebhookNotifier{URL: srv.URL}
+	w.OnNodeFinish(context.Background(), info, node, nil)
+	if len(got) != 0 {
+		t.Fatalf("node events should only be posted with Nodes, got %v", got)
+	}
+
+	w.Nodes = true
+	w.OnNodeStart(context.Background(), info, node.Addr)
+	w.OnNodeFinish(context.Background(), info, node, errors.New("boom"))
+	if len(got) != 1 || got[0]["event"] != "node_finish" || got[0]["node"] != "a:6379" || got[0]["status"] != StatusFailed ||
+		got[0]["modified"] != float64(2) || got[0]["duration"] != "1s" {
+		t.Fatalf("unexpected payloads: %v", got)
+	}
+
+	w.Format = FormatSlack
+	w.OnNodeFinish(context.Background(), info, node, nil)
+	text, _ := got[1]["text"].(string)
+	if !strings.HasPrefix(text, "redis-ttl node a:6379 succeeded: mode=exp prefix=f* scanned=3 modified=2") {
+		t.Fatalf("unexpected slack payload: %v", got[1])
+	}
+}
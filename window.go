@@ -0,0 +1,182 @@
+package redisttl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var errInvalidWindow = errors.New("invalid window")
+
+// Window is a daily time range, e.g. off-peak hours, outside which a run
+// pauses.
+type Window struct {
+	// Start and End are offsets from midnight. A window ending before it
+	// starts spans midnight, e.g. 22:00-04:00.
+	Start, End time.Duration
+	// Location is the time zone of Start and End, time.Local when nil.
+	Location *time.Location
+}
+
+// ParseWindow parses a window written as "01:00-05:00" in loc.
+func ParseWindow(s string, loc *time.Location) (*Window, error) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		return nil, fmt.Errorf("window %q isn't start-end: %w", s, errInvalidWindow)
+	}
+	w := &Window{Location: loc}
+	for _, v := range []struct {
+		dst *time.Duration
+		raw string
+	}{{&w.Start, start}, {&w.End, end}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(v.raw))
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %w", s, errInvalidWindow)
+		}
+		*v.dst = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if w.Start == w.End {
+		return nil, fmt.Errorf("window %q is empty: %w", s, errInvalidWindow)
+	}
+	return w, nil
+}
+
+func (w *Window) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(w.Start) + "-" + format(w.End)
+}
+
+func (w *Window) location() *time.Location {
+	if w.Location == nil {
+		return time.Local
+	}
+	return w.Location
+}
+
+// midnight returns the start of the day of t in the window's time zone.
+func (w *Window) midnight(t time.Time) time.Time {
+	t = t.In(w.location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Contains reports whether t is within the window.
+func (w *Window) Contains(t time.Time) bool {
+	offset := t.Sub(w.midnight(t))
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Next returns when the window opens next, t when it is open.
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	day := w.midnight(t)
+	if start := day.Add(w.Start); start.After(t) {
+		return start
+	}
+	return day.AddDate(0, 0, 1).Add(w.Start)
+}
+
+// wait blocks until the window is open on clock, calling pause first when
+// it isn't, then beat every interval while it waits when interval is set.
+func (w *Window) wait(ctx context.Context, clock Clock, interval time.Duration, pause, beat func()) error {
+	now := clock.Now()
+	next := w.Next(now)
+	if !next.After(now) {
+		return nil
+	}
+	pause()
+	log.Printf("outside of window %s, pausing until %s\n", w, next.Format(time.RFC3339))
+
+	for d := next.Sub(now); d > 0; d = next.Sub(clock.Now()) {
+		if interval > 0 {
+			d = min(d, interval)
+		}
+		if err := clock.Sleep(ctx, d); err != nil {
+			return err
+		}
+		beat()
+	}
+	return nil
+}
+
+// scanCheckpoint is the content of Scanner.CursorFile.
+type scanCheckpoint struct {
+	Cursor    uint64    `json:"cursor"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// waitWindow pauses the run outside of Window, saving the cursor of the
+// next batch before. The Notifier is told about the progress every
+// ProgressInterval while the run waits, health checks telling a run paused
+// until the next night from a wedged one.
+func (f *Scanner) waitWindow(ctx context.Context, r *scanRun, cursor uint64) error {
+	if f.Window == nil {
+		return nil
+	}
+	var interval time.Duration
+	beat := func() {}
+	if f.Notifier != nil && f.ProgressInterval > 0 {
+		interval = f.ProgressInterval
+		beat = func() { f.Notifier.OnProgress(ctx, r.info, r.summary) }
+	}
+	return f.Window.wait(ctx, f.clock(), interval, func() { f.saveCursor(cursor) }, beat)
+}
+
+// loadCursor returns the cursor saved in CursorFile, 0 when there is none.
+func (f *Scanner) loadCursor() (uint64, error) {
+	if f.CursorFile == "" {
+		return 0, nil
+	}
+	b, err := os.ReadFile(f.CursorFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("read cursor file: %w", err)
+	}
+	var cp scanCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return 0, fmt.Errorf("parse cursor file %s: %w", f.CursorFile, err)
+	}
+	if cp.Cursor != 0 {
+		log.Printf("resuming scan from cursor %d saved at %s\n", cp.Cursor, cp.UpdatedAt.Format(time.RFC3339))
+	}
+	return cp.Cursor, nil
+}
+
+// saveCursor replaces CursorFile with cursor. Failures are logged, the run
+// keeping on.
+func (f *Scanner) saveCursor(cursor uint64) {
+	if f.CursorFile == "" {
+		return
+	}
+	b, _ := json.Marshal(scanCheckpoint{Cursor: cursor, UpdatedAt: f.clock().Now()})
+	if err := writeFile(f.CursorFile, b); err != nil {
+		log.Printf("save cursor error: %v\n", err)
+	}
+}
+
+// finishCursor saves the cursor the run stopped at after an error, or
+// removes CursorFile once the whole keyspace has been scanned.
+func (f *Scanner) finishCursor(err error) {
+	switch {
+	case f.CursorFile == "":
+	case err != nil:
+		f.saveCursor(f.Stats().Cursor)
+	default:
+		if err := os.Remove(f.CursorFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("remove cursor file error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package redisttl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseWindow(t *testing.T) {
+	testCases := map[string]struct {
+		window string
+		want   Window
+		err    error
+	}{
+		"night": {
+			window: "01:00-05:30",
+			want:   Window{Start: time.Hour, End: 5*time.Hour + 30*time.Minute},
+		},
+		"spanning midnight": {
+			window: "22:00 - 04:00",
+			want:   Window{Start: 22 * time.Hour, End: 4 * time.Hour},
+		},
+		"missing end": {
+			window: "01:00",
+			err:    errInvalidWindow,
+		},
+		"invalid time": {
+			window: "01:00-25:00",
+			err:    errInvalidWindow,
+		},
+		"empty": {
+			window: "01:00-01:00",
+			err:    errInvalidWindow,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			w, err := ParseWindow(tc.window, time.UTC)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want: %v got: %v", tc.err, err)
+			}
+			if err == nil && (w.Start != tc.want.Start || w.End != tc.want.End) {
+				t.Fatalf("want: %+v got: %+v", tc.want, *w)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 3, 12, hour, min, 0, 0, paris)
+	}
+	night := &Window{Start: time.Hour, End: 5 * time.Hour, Location: paris}
+	late := &Window{Start: 22 * time.Hour, End: 4 * time.Hour, Location: paris}
+
+	testCases := map[string]struct {
+		window *Window
+		t      time.Time
+		open   bool
+		next   time.Time
+	}{
+		"inside": {
+			window: night,
+			t:      at(2, 0),
+			open:   true,
+			next:   at(2, 0),
+		},
+		"before": {
+			window: night,
+			t:      at(0, 30),
+			next:   at(1, 0),
+		},
+		"end is excluded": {
+			window: night,
+			t:      at(5, 0),
+			next:   at(1, 0).AddDate(0, 0, 1),
+		},
+		"other time zone": {
+			window: night,
+			t:      at(0, 30).UTC(),
+			next:   at(1, 0),
+		},
+		"spanning midnight, after midnight": {
+			window: late,
+			t:      at(3, 0),
+			open:   true,
+			next:   at(3, 0),
+		},
+		"spanning midnight, closed": {
+			window: late,
+			t:      at(12, 0),
+			next:   at(22, 0),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			if got := tc.window.Contains(tc.t); got != tc.open {
+				t.Fatalf("want open: %v got: %v", tc.open, got)
+			}
+			if got := tc.window.Next(tc.t); !got.Equal(tc.next) {
+				t.Fatalf("want next: %v got: %v", tc.next, got)
+			}
+		})
+	}
+}
+
+func TestWindowPause(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo:1", "foo:2", "foo:3", "foo:4"} {
+		_ = s.Set(k, "bar")
+	}
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	// a window opening in an hour
+	now := time.Now().UTC()
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	closed := &Window{
+		Start:    (offset + time.Hour) % (24 * time.Hour),
+		End:      (offset + 2*time.Hour) % (24 * time.Hour),
+		Location: time.UTC,
+	}
+	cursorFile := filepath.Join(t.TempDir(), "cursor.json")
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		ScanCount:  2,
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		Window:     closed,
+		CursorFile: cursorFile,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := f.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want: %v got: %v", context.DeadlineExceeded, err)
+	}
+	if ttl := s.TTL("foo:1"); ttl != 0 {
+		t.Fatalf("no key should be modified outside of the window, got ttl: %v", ttl)
+	}
+	if _, err := os.Stat(cursorFile); err != nil {
+		t.Fatalf("expected the cursor to be saved: %v", err)
+	}
+}
+
+func TestWindowHeartbeat(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("foo", "bar")
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	midnight := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	notifier := &recordingNotifier{}
+	f := &Scanner{
+		Mode:             "exp",
+		ScanPrefix:       "foo",
+		Client:           rdb,
+		DesiredTTL:       time.Hour,
+		Window:           &Window{Start: time.Hour, End: 2 * time.Hour, Location: time.UTC},
+		Clock:            NewFakeClock(midnight),
+		Notifier:         notifier,
+		ProgressInterval: 10 * time.Minute,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a beat every 10 minutes of the hour until the window opens
+	progress := 0
+	for _, e := range notifier.events {
+		if e == "progress" {
+			progress++
+		}
+	}
+	if progress < 6 {
+		t.Fatalf("want a progress event every 10 minutes while paused, got %v", notifier.events)
+	}
+	if ttl := s.TTL("foo"); ttl != time.Hour {
+		t.Fatalf("want foo modified once the window opens, got ttl: %v", ttl)
+	}
+}
+
+func TestCursorFile(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, k := range []string{"foo:1", "foo:2", "foo:3", "foo:4"} {
+		_ = s.Set(k, "bar")
+	}
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	cursorFile := filepath.Join(t.TempDir(), "cursor.json")
+	if err := os.WriteFile(cursorFile, []byte(`{"cursor": 2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := &Scanner{
+		Mode:       "exp",
+		ScanPrefix: "foo:*",
+		ScanCount:  2,
+		Client:     rdb,
+		DesiredTTL: time.Hour,
+		CursorFile: cursorFile,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"foo:1": 0,
+		"foo:2": 0,
+		"foo:3": time.Hour,
+		"foo:4": time.Hour,
+	}
+	for k, dur := range expected {
+		if ttl := s.TTL(k); ttl != dur {
+			t.Fatalf("ttl don't match for: %s, got:%v want: %v", k, ttl, dur)
+		}
+	}
+	if _, err := os.Stat(cursorFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the cursor file to be removed, got: %v", err)
+	}
+}
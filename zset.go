@@ -0,0 +1,67 @@
+package redisttl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// zCutoff returns the score below which the members of a sorted set are
+// older than retention, scores being timestamps in ZScoreUnit.
+func (f *Scanner) zCutoff(retention time.Duration) string {
+	unit := f.ZScoreUnit
+	if unit <= 0 {
+		unit = time.Second
+	}
+	cutoff := f.clock().Now().Add(-retention).UnixNano() / int64(unit)
+	// exclusive, members scored at the cutoff are kept
+	return "(" + strconv.FormatInt(cutoff, 10)
+}
+
+// ztrim removes the members of the sorted set key scored before now minus
+// retention, the "zset as time index" pattern whose entries key-level TTLs
+// can't expire one by one. Redis deletes the sorted sets left empty. Keys of
+// other types are left untouched.
+func (f *Scanner) ztrim(ctx context.Context, key string, retention time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx)
+	n, err := f.Client.ZRemRangeByScore(ctx, key, "-inf", f.zCutoff(retention)).Result()
+	if redis.HasErrorPrefix(err, "WRONGTYPE") {
+		return cmd
+	}
+	cmd.SetVal(n > 0)
+	cmd.SetErr(err)
+	return cmd
+}
+
+// ztrimDryRun is the dry-run counterpart of ztrim, printing how many members
+// of every key it would remove.
+func (f *Scanner) ztrimDryRun(ctx context.Context, key string, retention time.Duration) *redis.BoolCmd {
+	w := f.Diff
+	if w == nil {
+		w = os.Stdout
+	}
+	cmd := redis.NewBoolCmd(ctx)
+	n, err := f.Client.ZCount(ctx, key, "-inf", f.zCutoff(retention)).Result()
+	switch {
+	case redis.HasErrorPrefix(err, "WRONGTYPE"):
+		return cmd
+	case err != nil:
+		cmd.SetErr(err)
+		return cmd
+	}
+	verdict := "would skip"
+	if n > 0 {
+		verdict = "would apply"
+	}
+	diffMu.Lock()
+	_, err = fmt.Fprintf(w, "%s: %d members older than %s [%s %s]\n", key, n, formatTTL(retention), f.Mode, verdict)
+	diffMu.Unlock()
+
+	cmd.SetVal(n > 0)
+	cmd.SetErr(err)
+	return cmd
+}
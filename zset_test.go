@@ -0,0 +1,97 @@
+package redisttl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestZTrimMode(t *testing.T) {
+	tests := map[string]struct {
+		unit  time.Duration
+		score func(time.Time) float64
+	}{
+		"seconds": {
+			score: func(at time.Time) float64 { return float64(at.Unix()) },
+		},
+		"milliseconds": {
+			unit:  time.Millisecond,
+			score: func(at time.Time) float64 { return float64(at.UnixMilli()) },
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			now := time.Now()
+			_, _ = s.ZAdd("events:1", tc.score(now.Add(-2*time.Hour)), "old")
+			_, _ = s.ZAdd("events:1", tc.score(now.Add(-time.Minute)), "recent")
+			_, _ = s.ZAdd("events:2", tc.score(now.Add(-3*time.Hour)), "old")
+			_, _ = s.ZAdd("events:3", tc.score(now), "recent")
+			_ = s.Set("events:string", "bar")
+			s.SetTTL("events:1", 24*time.Hour)
+
+			rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			summary := &Summary{}
+			f := Scanner{
+				Mode:       "ztrim",
+				ScanPrefix: "events:*",
+				Client:     rdb,
+				DesiredTTL: time.Hour,
+				ZScoreUnit: tc.unit,
+				Summary:    summary,
+			}
+			if err := f.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if members, _ := s.ZMembers("events:1"); len(members) != 1 || members[0] != "recent" {
+				t.Fatalf("want the recent member kept, got: %v", members)
+			}
+			if s.Exists("events:2") {
+				t.Fatal("want the sorted set left empty deleted")
+			}
+			if ttl := s.TTL("events:1"); ttl != 24*time.Hour {
+				t.Fatalf("want the ttl of the key kept, got: %v", ttl)
+			}
+			if got := summary.Modified.Load(); got != 2 || summary.Errors.Load() != 0 {
+				t.Fatalf("want 2 keys modified without error, got: %s", summary)
+			}
+		})
+	}
+}
+
+func TestZTrimDryRun(t *testing.T) {
+	s := miniredis.RunT(t)
+	now := time.Now()
+	_, _ = s.ZAdd("events:1", float64(now.Add(-2*time.Hour).Unix()), "old")
+	_, _ = s.ZAdd("events:2", float64(now.Unix()), "recent")
+
+	var diff bytes.Buffer
+	f := Scanner{
+		Mode:       "ztrim",
+		ScanPrefix: "events:*",
+		Client:     redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		DesiredTTL: time.Hour,
+		DryRun:     true,
+		Diff:       &diff,
+	}
+	if err := f.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if members, _ := s.ZMembers("events:1"); len(members) != 1 {
+		t.Fatalf("dry-run modified events:1: %v", members)
+	}
+	for _, want := range []string{
+		"events:1: 1 members older than 1h [ztrim would apply]",
+		"events:2: 0 members older than 1h [ztrim would skip]",
+	} {
+		if !strings.Contains(diff.String(), want) {
+			t.Fatalf("want %q in:\n%s", want, diff.String())
+		}
+	}
+}